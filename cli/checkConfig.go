@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/initializers"
+)
+
+func checkConfigCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		problems := 0
+		for _, check := range initializers.CheckConfig(ctx, config.Current()) {
+			if check.Err != nil {
+				problems++
+				fmt.Fprintf(streams.Stdout, "FAIL  %s: %s\n", check.Name, indent(check.Err, "      "))
+				continue
+			}
+			fmt.Fprintf(streams.Stdout, "OK    %s\n", check.Name)
+		}
+		if problems > 0 {
+			return fmt.Errorf("%d of the checks failed", problems)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+// Package cli is the inventory command. With no arguments it serves the
+// API, and its other subcommands do the admin tasks that would otherwise
+// need SQL against the database.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ringtho/inventory/config"
+)
+
+// IO is where commands read their input and write their output
+type IO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	// define registers the command's flags and returns what runs it once
+	// they have been parsed, with the arguments that are left
+	define func(flags *flag.FlagSet) func(ctx context.Context, streams IO, args []string) error
+}
+
+// commands is filled in by init since usage refers to it
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "", "Start the API server (the default)", serveCommand},
+		{"migrate", "up|down|status|redo", "Apply or roll back database migrations", migrateCommand},
+		{"create-admin", "--email EMAIL [--username NAME] [--name NAME]", "Create an admin, reading the password from stdin", createAdminCommand},
+		{"reset-password", "--email EMAIL", "Set a user's password, reading it from stdin", resetPasswordCommand},
+		{"import", "[--org SLUG] products.csv", "Create or update products from a CSV file", importCommand},
+		{"export", "[--org SLUG] [--format csv|xlsx] [--output FILE] products|categories|suppliers", "Export an organisation's inventory", exportCommand},
+		{"seed", "--demo [--org SLUG]", "Fill an empty organisation with demo data", seedCommand},
+		{"check-config", "", "Check the configuration without starting the server", checkConfigCommand},
+		{"config", "", "Print the configuration with secrets redacted", configCommand},
+	}
+}
+
+// errUsage makes Run print the command's usage
+var errUsage = errors.New("invalid arguments")
+
+// Run runs the command named by args[0], or serve when there is none, and
+// returns the exit code. The configuration is loaded before the command
+// runs and becomes config.Current.
+func Run(ctx context.Context, args []string, streams IO) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(streams.Stdout)
+		return 0
+	}
+
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		flags := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		flags.SetOutput(streams.Stderr)
+		flags.Usage = func() {
+			fmt.Fprintf(streams.Stderr, "Usage: inventory %s %s\n", c.name, c.args)
+			flags.PrintDefaults()
+		}
+		run := c.define(flags)
+		if err := flags.Parse(args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			return 2
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "Invalid configuration: %v\n", err)
+			return 1
+		}
+		config.Set(&cfg)
+
+		err = run(ctx, streams, flags.Args())
+		if errors.Is(err, errUsage) {
+			flags.Usage()
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(streams.Stderr, "Unknown command %q\n\n", args[0])
+	usage(streams.Stderr)
+	return 2
+}
+
+// indent puts each of the errors joined in err on a line of its own,
+// indented to line up under the first
+func indent(err error, prefix string) string {
+	return strings.ReplaceAll(err.Error(), "\n", "\n"+prefix)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: inventory <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/ringtho/inventory/config"
+)
+
+func configCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		cfg := config.Current()
+		if err := cfg.WriteRedacted(streams.Stdout); err != nil {
+			return err
+		}
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("the configuration isn't valid:\n  %s", indent(err, "  "))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"slices"
+
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/db"
+)
+
+func migrateCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 1 || !slices.Contains(db.MigrateCommands, args[0]) {
+			return errUsage
+		}
+		conn := db.ConnectToDatabase(config.Current().Database)
+		defer conn.Close()
+		return db.Migrate(ctx, conn, args[0], streams.Stdout)
+	}
+}
@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/initializers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/models"
+)
+
+// findOrganization returns the organisation with slug
+func findOrganization(ctx context.Context, DB store.Store, slug string) (database.Organization, error) {
+	org, err := DB.GetOrganizationBySlug(ctx, slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return org, fmt.Errorf("there's no organisation %q", slug)
+	}
+	return org, err
+}
+
+// productRow is a product read from an import, with the line it's on
+type productRow struct {
+	line int
+	id uuid.NullUUID
+	params database.UpdateProductParams
+}
+
+// readProducts reads products from CSV with a header row. The columns are
+// the ones exports have, so an export can be imported again. Only name and
+// price are required, and id, created_at, updated_at and deleted_at are
+// optional or ignored.
+func readProducts(r io.Reader) ([]productRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the %s column is missing", required)
+		}
+	}
+
+	products := []productRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return products, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		value := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row, err := parseProduct(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		row.line = line
+		products = append(products, row)
+	}
+}
+
+func parseProduct(value func(column string) string) (productRow, error) {
+	row := productRow{}
+	params := &row.params
+
+	params.Name = value("name")
+	if params.Name == "" {
+		return row, errors.New("name is required")
+	}
+	price, err := strconv.ParseInt(value("price"), 10, 32)
+	if err != nil || price <= 0 {
+		return row, fmt.Errorf("price must be a whole number greater than zero, got %q", value("price"))
+	}
+	params.Price = int32(price)
+
+	if description := value("description"); description != "" {
+		params.Description = sql.NullString{String: description, Valid: true}
+	}
+	if sku := value("sku"); sku != "" {
+		params.Sku = sql.NullString{String: sku, Valid: true}
+	}
+	if stockLevel := value("stock_level"); stockLevel != "" {
+		stock, err := strconv.ParseInt(stockLevel, 10, 32)
+		if err != nil {
+			return row, fmt.Errorf("stock_level must be a whole number, got %q", stockLevel)
+		}
+		params.StockLevel = sql.NullInt32{Int32: int32(stock), Valid: true}
+	}
+
+	uuids := []struct {
+		column string
+		dest *uuid.NullUUID
+	}{
+		{"id", &row.id},
+		{"category_id", &params.CategoryID},
+		{"supplier_id", &params.SupplierID},
+	}
+	for _, u := range uuids {
+		if text := value(u.column); text != "" {
+			id, err := uuid.Parse(text)
+			if err != nil {
+				return row, fmt.Errorf("invalid %s %q", u.column, text)
+			}
+			*u.dest = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+	return row, nil
+}
+
+// importProduct updates the product with the row's id when the
+// organisation has it, and creates it otherwise. It reports whether the
+// product was created.
+func importProduct(ctx context.Context, DB store.Store, orgId uuid.UUID, row productRow) (bool, error) {
+	now := time.Now().UTC()
+	params := row.params
+	params.OrgID = orgId
+	params.UpdatedAt = now
+
+	if row.id.Valid {
+		existing, err := DB.GetProduct(ctx, database.GetProductParams{ID: row.id.UUID, OrgID: orgId})
+		if err == nil {
+			params.ID = row.id.UUID
+			params.ExpectedUpdatedAt = existing.UpdatedAt
+			_, err = DB.UpdateProduct(ctx, params)
+			return false, err
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+	}
+
+	id := uuid.New()
+	if row.id.Valid {
+		id = row.id.UUID
+	}
+	_, err := DB.CreateProduct(ctx, database.CreateProductParams{
+		ID: id,
+		OrgID: orgId,
+		Name: params.Name,
+		Description: params.Description,
+		Price: params.Price,
+		StockLevel: params.StockLevel,
+		CategoryID: params.CategoryID,
+		SupplierID: params.SupplierID,
+		Sku: params.Sku,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	return true, err
+}
+
+func importCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	orgSlug := flags.String("org", "default", "slug of the organisation to import into")
+
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		input := streams.Stdin
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
+		}
+		// Every row is checked before anything is written
+		rows, err := readProducts(input)
+		if err != nil {
+			return fmt.Errorf("couldn't read %s: %w", args[0], err)
+		}
+
+		DB, conn := initializers.SetupDatabase()
+		defer conn.Close()
+		org, err := findOrganization(ctx, DB, *orgSlug)
+		if err != nil {
+			return err
+		}
+
+		created, updated := 0, 0
+		for _, row := range rows {
+			isNew, err := importProduct(ctx, DB, org.ID, row)
+			if err != nil {
+				if pqErr, ok := err.(*pq.Error); ok {
+					switch pqErr.Code {
+					case "23505":
+						err = errors.New("the SKU or id is already used")
+					case "23503":
+						err = errors.New("category or supplier not found")
+					}
+				}
+				return fmt.Errorf("line %d: %w (%d products were imported before it)", row.line, err, created+updated)
+			}
+			if isNew {
+				created++
+			} else {
+				updated++
+			}
+		}
+		fmt.Fprintf(streams.Stdout, "Imported %d products, %d new and %d updated\n", created+updated, created, updated)
+		return nil
+	}
+}
+
+func exportCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	orgSlug := flags.String("org", "default", "slug of the organisation to export")
+	format := flags.String("format", "csv", "csv or xlsx")
+	output := flags.String("output", "-", "file to write, - for stdout")
+
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 1 || !slices.Contains([]string{"products", "categories", "suppliers"}, args[0]) {
+			return errUsage
+		}
+		if *format != "csv" && *format != "xlsx" {
+			return fmt.Errorf("unsupported format %q, use csv or xlsx", *format)
+		}
+
+		DB, conn := initializers.SetupDatabase()
+		defer conn.Close()
+		org, err := findOrganization(ctx, DB, *orgSlug)
+		if err != nil {
+			return err
+		}
+
+		if *output == "-" {
+			return export(ctx, DB, org.ID, args[0], *format, streams.Stdout)
+		}
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if err := export(ctx, DB, org.ID, args[0], *format, file); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+}
+
+// export writes an organisation's products, categories or suppliers to w
+func export(ctx context.Context, DB store.Store, orgId uuid.UUID, entity string, format string, w io.Writer) error {
+	switch entity {
+	case "categories":
+		return helpers.WriteExport(w, format, models.CategoryExportColumns, func(fn func(database.Category) error) error {
+			return DB.IterCategories(ctx, orgId, false, fn)
+		})
+	case "suppliers":
+		return helpers.WriteExport(w, format, models.SupplierExportColumns, func(fn func(database.Supplier) error) error {
+			return DB.IterSuppliers(ctx, orgId, false, fn)
+		})
+	}
+	return helpers.WriteExport(w, format, models.ProductExportColumns, func(fn func(database.Product) error) error {
+		return DB.IterProducts(ctx, orgId, false, fn)
+	})
+}
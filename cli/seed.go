@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/initializers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+)
+
+// demoCategories, demoSuppliers and demoProducts are what seed --demo
+// creates. Products refer to their category and supplier by name.
+var demoCategories = []string{"Kitchen", "Electronics", "Office"}
+
+var demoSuppliers = []struct {
+	name string
+	email string
+	country string
+}{
+	{"Acme Supplies", "orders@acme.example", "Uganda"},
+	{"Globex Trading", "sales@globex.example", "Kenya"},
+}
+
+var demoProducts = []struct {
+	name string
+	sku string
+	price int32
+	stock int32
+	category string
+	supplier string
+}{
+	{"Microwave", "KIT-001", 450000, 12, "Kitchen", "Acme Supplies"},
+	{"Electric Kettle", "KIT-002", 85000, 30, "Kitchen", "Acme Supplies"},
+	{"Blender", "KIT-003", 120000, 8, "Kitchen", "Globex Trading"},
+	{"USB-C Charger", "ELE-001", 45000, 60, "Electronics", "Globex Trading"},
+	{"Bluetooth Speaker", "ELE-002", 150000, 15, "Electronics", "Globex Trading"},
+	{"Desk Lamp", "OFF-001", 60000, 25, "Office", "Acme Supplies"},
+	{"Office Chair", "OFF-002", 350000, 0, "Office", "Acme Supplies"},
+}
+
+// seedDemo fills the organisation with the demo categories, suppliers and
+// products
+func seedDemo(ctx context.Context, DB store.Store, orgId uuid.UUID) error {
+	now := time.Now().UTC()
+
+	categories := map[string]uuid.UUID{}
+	for _, name := range demoCategories {
+		category, err := DB.CreateCategory(ctx, database.CreateCategoryParams{
+			ID: uuid.New(),
+			OrgID: orgId,
+			Name: name,
+			CreatedAt: now,
+			UpdatedAt: now,
+			// There's no user behind the command line
+			CreatedBy: uuid.Nil,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't create category %s: %w", name, err)
+		}
+		categories[name] = category.ID
+	}
+
+	suppliers := map[string]uuid.UUID{}
+	for _, s := range demoSuppliers {
+		supplier, err := DB.CreateSupplier(ctx, database.CreateSupplierParams{
+			ID: uuid.New(),
+			OrgID: orgId,
+			Name: s.name,
+			Email: sql.NullString{String: s.email, Valid: true},
+			Country: sql.NullString{String: s.country, Valid: true},
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't create supplier %s: %w", s.name, err)
+		}
+		suppliers[s.name] = supplier.ID
+	}
+
+	for _, p := range demoProducts {
+		_, err := DB.CreateProduct(ctx, database.CreateProductParams{
+			ID: uuid.New(),
+			OrgID: orgId,
+			Name: p.name,
+			Price: p.price,
+			StockLevel: sql.NullInt32{Int32: p.stock, Valid: true},
+			CategoryID: uuid.NullUUID{UUID: categories[p.category], Valid: true},
+			SupplierID: uuid.NullUUID{UUID: suppliers[p.supplier], Valid: true},
+			Sku: sql.NullString{String: p.sku, Valid: true},
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't create product %s: %w", p.name, err)
+		}
+	}
+	return nil
+}
+
+func seedCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	demo := flags.Bool("demo", false, "create demo categories, suppliers and products")
+	orgSlug := flags.String("org", "default", "slug of the organisation to seed")
+
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		if !*demo {
+			return errors.New("the migrations create everything the server needs, use --demo for demo data")
+		}
+
+		DB, conn := initializers.SetupDatabase()
+		defer conn.Close()
+		org, err := findOrganization(ctx, DB, *orgSlug)
+		if err != nil {
+			return err
+		}
+		products, err := DB.GetProducts(ctx, database.GetProductsParams{OrgID: org.ID, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+		if len(products) > 0 {
+			return fmt.Errorf("organisation %s already has products, demo data only goes in empty ones", org.Slug)
+		}
+
+		if err := seedDemo(ctx, DB, org.ID); err != nil {
+			return err
+		}
+		fmt.Fprintf(streams.Stdout, "Added %d categories, %d suppliers and %d products to %s\n",
+			len(demoCategories), len(demoSuppliers), len(demoProducts), org.Slug)
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/initializers"
+)
+
+func serveCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		cfg := config.Current()
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration:\n  %s", indent(err, "  "))
+		}
+		services, err := initializers.NewServices(cfg)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		server, health, DB, conn := initializers.SetupServer(services)
+		defer conn.Close()
+
+		if err := initializers.BootstrapAdmin(ctx, DB); err != nil {
+			return err
+		}
+		// Jobs keep running while requests drain and are stopped after
+		jobsCtx, stopJobs := context.WithCancel(context.WithoutCancel(ctx))
+		defer stopJobs()
+		waitForJobs := initializers.StartJobs(jobsCtx, DB)
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- server.ListenAndServe()
+		}()
+		log.Printf("Server running on port %s\n", server.Addr)
+
+		select {
+		case err := <-serveErr:
+			stopJobs()
+			waitForJobs()
+			return err
+		case <-ctx.Done():
+		}
+		// A second signal stops the process straight away
+		stop()
+
+		// Fail readiness while still serving, so load balancers stop
+		// sending traffic before connections are refused
+		health.ShuttingDown()
+		if delay := cfg.Server.ShutdownDrainDelay; delay > 0 {
+			log.Printf("Draining, waiting %v for load balancers to notice", delay)
+			time.Sleep(delay)
+		}
+
+		log.Printf("Shutting down, waiting up to %v for requests to finish", cfg.Server.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		err = server.Shutdown(shutdownCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("requests were still running after %v", cfg.Server.ShutdownTimeout)
+		}
+
+		stopJobs()
+		waitForJobs()
+		if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) {
+			err = errors.Join(err, serveErr)
+		}
+		log.Printf("Server stopped")
+		return err
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/initializers"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+// readPassword reads a password from the first line of stdin, so it stays
+// out of the shell history and the process list
+func readPassword(streams IO) (string, error) {
+	fmt.Fprint(streams.Stderr, "Password: ")
+	line, err := bufio.NewReader(streams.Stdin).ReadString('\n')
+	fmt.Fprintln(streams.Stderr)
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		if err != nil {
+			return "", fmt.Errorf("couldn't read the password: %w", err)
+		}
+		return "", errors.New("the password is empty")
+	}
+	return password, nil
+}
+
+func createAdminCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	email := flags.String("email", "", "email address of the admin")
+	username := flags.String("username", "admin", "username of the admin")
+	name := flags.String("name", "Administrator", "full name of the admin")
+
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 0 || *email == "" {
+			return errUsage
+		}
+		password, err := readPassword(streams)
+		if err != nil {
+			return err
+		}
+
+		DB, conn := initializers.SetupDatabase()
+		defer conn.Close()
+		user, err := initializers.CreateAdmin(ctx, DB, initializers.AdminParams{
+			Email: *email,
+			Username: *username,
+			Name: *name,
+			Password: password,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't create the admin: %w", err)
+		}
+		fmt.Fprintf(streams.Stdout, "Created admin %s (%s)\n", user.Email, user.ID)
+		return nil
+	}
+}
+
+func resetPasswordCommand(flags *flag.FlagSet) func(context.Context, IO, []string) error {
+	email := flags.String("email", "", "email address of the user")
+
+	return func(ctx context.Context, streams IO, args []string) error {
+		if len(args) != 0 || *email == "" {
+			return errUsage
+		}
+		password, err := readPassword(streams)
+		if err != nil {
+			return err
+		}
+		if !helpers.IsStrongPassword(password) {
+			return errors.New("password is not strong enough")
+		}
+
+		DB, conn := initializers.SetupDatabase()
+		defer conn.Close()
+		user, err := DB.GetUserByEmail(ctx, *email)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("there's no user with email %s", *email)
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		err = DB.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+			ID: user.ID,
+			Password: helpers.HashPassword(password),
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't update the password: %w", err)
+		}
+		// Same as a reset by email, whoever knew the old password is logged out
+		err = DB.RevokeUserSessions(ctx, database.RevokeUserSessionsParams{
+			UserID: user.ID,
+			RevokedAt: sql.NullTime{Time: now, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't revoke the user's sessions: %w", err)
+		}
+		fmt.Fprintf(streams.Stdout, "Reset the password of %s and logged them out everywhere\n", user.Email)
+		return nil
+	}
+}
@@ -0,0 +1,432 @@
+// Package config is the server's configuration. It's loaded once when the
+// program starts, from defaults, an optional YAML or TOML file and the
+// environment, and validated as a whole so every problem is reported at
+// once instead of whenever the setting is first used.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config holds every setting. Each one is read from the environment
+// variable in its env tag, and from the key in its yaml and toml tags,
+// under the section's key, in a config file.
+type Config struct {
+	Server         Server         `yaml:"server" toml:"server"`
+	Database       Database       `yaml:"database" toml:"database"`
+	Auth           Auth           `yaml:"auth" toml:"auth"`
+	Lockout        Lockout        `yaml:"lockout" toml:"lockout"`
+	Mail           Mail           `yaml:"mail" toml:"mail"`
+	OIDC           OIDC           `yaml:"oidc" toml:"oidc"`
+	BootstrapAdmin BootstrapAdmin `yaml:"bootstrap_admin" toml:"bootstrap_admin"`
+	Jobs           Jobs           `yaml:"jobs" toml:"jobs"`
+}
+
+type Server struct {
+	Port int `env:"PORT" yaml:"port" toml:"port"`
+	// PublicURL is where the API is reached from outside, for links in
+	// emails. It's required with MAILER=smtp.
+	PublicURL string `env:"PUBLIC_URL" yaml:"public_url" toml:"public_url"`
+	// PasswordResetURL and InvitationURL are frontend pages that emailed
+	// tokens are appended to
+	PasswordResetURL string `env:"PASSWORD_RESET_URL" yaml:"password_reset_url" toml:"password_reset_url"`
+	InvitationURL    string `env:"INVITATION_URL" yaml:"invitation_url" toml:"invitation_url"`
+	RequireIfMatch   bool   `env:"REQUIRE_IF_MATCH" yaml:"require_if_match" toml:"require_if_match"`
+	// DefaultOrg is the slug of the organisation people who register or
+	// sign in with SSO join, and that anonymous requests read when they
+	// don't pass ?org=. Empty leaves them out of every organisation.
+	DefaultOrg string `env:"DEFAULT_ORG" yaml:"default_org" toml:"default_org"`
+
+	// MetricsToken has to be sent as a bearer token to scrape /metrics.
+	// Product counts by organisation are only exported when it's set.
+	MetricsToken string `env:"METRICS_TOKEN" yaml:"metrics_token" toml:"metrics_token" secret:"true"`
+
+	// The HTTP timeouts limit how long a slow client can hold a
+	// connection, 0 turns one off. CSV and XLSX exports push the write
+	// timeout back while rows are still going out.
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout is how long requests in flight get to finish after
+	// SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// ShutdownDrainDelay is how long /readyz reports shutting down before
+	// the server stops accepting connections, so load balancers notice
+	// first
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" yaml:"shutdown_drain_delay" toml:"shutdown_drain_delay"`
+
+	// TrustedProxies are the IPs or CIDR ranges of the reverse proxies in
+	// front of the server, separated by spaces in the environment. Login
+	// throttling and the audit log take the client's IP from
+	// X-Forwarded-For or X-Real-IP only on requests that come from one of
+	// them. Without any every client is known by the address it connects
+	// from.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+type Database struct {
+	// URL is a Postgres URL, or sqlite://<path> for a SQLite database file
+	URL string `env:"DB_URL" yaml:"url" toml:"url" secret:"url"`
+	// MaxOpenConns of 0 doesn't limit the number of connections
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	MigrateOnStart  bool          `env:"MIGRATE_ON_START" yaml:"migrate_on_start" toml:"migrate_on_start"`
+	// ConnectTimeout is how long to keep retrying the database on start
+	ConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" yaml:"connect_timeout" toml:"connect_timeout"`
+}
+
+type Auth struct {
+	// SecretKey signs tokens with HS256 when JWTKeysFile isn't set
+	SecretKey   string `env:"SECRET_KEY" yaml:"secret_key" toml:"secret_key" secret:"true"`
+	JWTKeysFile string `env:"JWT_KEYS_FILE" yaml:"jwt_keys_file" toml:"jwt_keys_file"`
+
+	AccessTokenTTL          time.Duration `env:"ACCESS_TOKEN_TTL" yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL         time.Duration `env:"REFRESH_TOKEN_TTL" yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	PasswordResetTTL        time.Duration `env:"PASSWORD_RESET_TTL" yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+	EmailVerificationTTL    time.Duration `env:"EMAIL_VERIFICATION_TTL" yaml:"email_verification_ttl" toml:"email_verification_ttl"`
+	VerificationResendDelay time.Duration `env:"EMAIL_VERIFICATION_RESEND_DELAY" yaml:"email_verification_resend_delay" toml:"email_verification_resend_delay"`
+	InvitationTTL           time.Duration `env:"INVITATION_TTL" yaml:"invitation_ttl" toml:"invitation_ttl"`
+	MFAChallengeTTL         time.Duration `env:"MFA_CHALLENGE_TTL" yaml:"mfa_challenge_ttl" toml:"mfa_challenge_ttl"`
+
+	BcryptCost           int    `env:"BCRYPT_COST" yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	TOTPIssuer           string `env:"TOTP_ISSUER" yaml:"totp_issuer" toml:"totp_issuer"`
+	AllowUnverifiedLogin bool   `env:"ALLOW_UNVERIFIED_LOGIN" yaml:"allow_unverified_login" toml:"allow_unverified_login"`
+	DisableRegistration  bool   `env:"DISABLE_REGISTRATION" yaml:"disable_registration" toml:"disable_registration"`
+}
+
+// Lockout is when failed logins lock an account or IP address out
+type Lockout struct {
+	MaxFailures   int           `env:"LOGIN_MAX_FAILURES" yaml:"max_failures" toml:"max_failures"`
+	IPMaxFailures int           `env:"LOGIN_IP_MAX_FAILURES" yaml:"ip_max_failures" toml:"ip_max_failures"`
+	Base          time.Duration `env:"LOGIN_LOCKOUT_BASE" yaml:"base" toml:"base"`
+	Max           time.Duration `env:"LOGIN_LOCKOUT_MAX" yaml:"max" toml:"max"`
+	FailureWindow time.Duration `env:"LOGIN_FAILURE_WINDOW" yaml:"failure_window" toml:"failure_window"`
+}
+
+type Mail struct {
+	// Mailer is smtp, or log to write emails to LogFile or the log
+	Mailer       string `env:"MAILER" yaml:"mailer" toml:"mailer"`
+	SMTPHost     string `env:"SMTP_HOST" yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     int    `env:"SMTP_PORT" yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `env:"SMTP_USERNAME" yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `env:"SMTP_PASSWORD" yaml:"smtp_password" toml:"smtp_password" secret:"true"`
+	From         string `env:"MAIL_FROM" yaml:"from" toml:"from"`
+	LogFile      string `env:"MAIL_LOG_FILE" yaml:"log_file" toml:"log_file"`
+}
+
+// OIDC is the single sign-on identity provider, turned off while IssuerURL
+// is empty
+type OIDC struct {
+	IssuerURL    string   `env:"OIDC_ISSUER_URL" yaml:"issuer_url" toml:"issuer_url"`
+	ClientID     string   `env:"OIDC_CLIENT_ID" yaml:"client_id" toml:"client_id"`
+	ClientSecret string   `env:"OIDC_CLIENT_SECRET" yaml:"client_secret" toml:"client_secret" secret:"true"`
+	RedirectURL  string   `env:"OIDC_REDIRECT_URL" yaml:"redirect_url" toml:"redirect_url"`
+	Scopes       []string `env:"OIDC_SCOPES" yaml:"scopes" toml:"scopes"`
+	RoleClaim    string   `env:"OIDC_ROLE_CLAIM" yaml:"role_claim" toml:"role_claim"`
+	// RoleMapping is claim=role,claim=role in the environment
+	RoleMapping map[string]string `env:"OIDC_ROLE_MAPPING" yaml:"role_mapping" toml:"role_mapping"`
+	DefaultRole string            `env:"OIDC_DEFAULT_ROLE" yaml:"default_role" toml:"default_role"`
+	// TrustUnverifiedEmail treats an email without an email_verified claim
+	// as verified, for providers such as company directories that only
+	// hand out addresses they own. It lets the provider sign in as any
+	// local account with that address.
+	TrustUnverifiedEmail bool `env:"OIDC_TRUST_UNVERIFIED_EMAIL" yaml:"trust_unverified_email" toml:"trust_unverified_email"`
+}
+
+// BootstrapAdmin is the admin created on start when there's none yet,
+// turned off while Email is empty
+type BootstrapAdmin struct {
+	Email    string `env:"BOOTSTRAP_ADMIN_EMAIL" yaml:"email" toml:"email"`
+	Password string `env:"BOOTSTRAP_ADMIN_PASSWORD" yaml:"password" toml:"password" secret:"true"`
+	Username string `env:"BOOTSTRAP_ADMIN_USERNAME" yaml:"username" toml:"username"`
+	Name     string `env:"BOOTSTRAP_ADMIN_NAME" yaml:"name" toml:"name"`
+}
+
+type Jobs struct {
+	SoftDeleteRetention time.Duration `env:"SOFT_DELETE_RETENTION" yaml:"soft_delete_retention" toml:"soft_delete_retention"`
+	PurgeInterval       time.Duration `env:"PURGE_INTERVAL" yaml:"purge_interval" toml:"purge_interval"`
+}
+
+// Default returns the settings used when nothing else sets them
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port: 8080,
+			DefaultOrg: "default",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout: 30 * time.Second,
+			WriteTimeout: time.Minute,
+			IdleTimeout: 2 * time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+		},
+		Database: Database{
+			MaxOpenConns: 25,
+			MaxIdleConns: 5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout: 30 * time.Second,
+		},
+		Auth: Auth{
+			AccessTokenTTL: 15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+			PasswordResetTTL: time.Hour,
+			EmailVerificationTTL: 24 * time.Hour,
+			VerificationResendDelay: time.Minute,
+			InvitationTTL: 7 * 24 * time.Hour,
+			MFAChallengeTTL: 5 * time.Minute,
+			BcryptCost: 14,
+			TOTPIssuer: "Inventory",
+		},
+		Lockout: Lockout{
+			MaxFailures: 5,
+			IPMaxFailures: 20,
+			Base: time.Minute,
+			Max: time.Hour,
+			FailureWindow: 15 * time.Minute,
+		},
+		Mail: Mail{
+			Mailer: "log",
+			SMTPPort: 587,
+		},
+		OIDC: OIDC{
+			RoleMapping: map[string]string{},
+			DefaultRole: "user",
+		},
+		BootstrapAdmin: BootstrapAdmin{
+			Username: "admin",
+			Name: "Administrator",
+		},
+		Jobs: Jobs{
+			SoftDeleteRetention: 30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+	}
+}
+
+// Validate checks every setting and returns all the problems it finds
+// joined together, or nil
+func (c Config) Validate() error {
+	return errors.Join(
+		c.Server.Validate(),
+		c.Database.Validate(),
+		c.Auth.Validate(),
+		c.Lockout.Validate(),
+		c.Mail.Validate(),
+		c.OIDC.Validate(),
+		c.BootstrapAdmin.Validate(),
+		c.Jobs.Validate(),
+		c.validateEmailLinks(),
+	)
+}
+
+// validateEmailLinks makes sure emails sent to real inboxes don't link to
+// the request's Host header, which whoever triggers the email chooses
+func (c Config) validateEmailLinks() error {
+	var p problems
+	if c.Mail.Mailer == "smtp" && c.Server.PublicURL == "" {
+		p.add("PUBLIC_URL is required with MAILER=smtp")
+	}
+	return p.err()
+}
+
+// problems collects what's wrong with a section
+type problems []error
+
+func (p *problems) add(format string, args ...any) {
+	*p = append(*p, fmt.Errorf(format, args...))
+}
+
+func (p *problems) positive(name string, value time.Duration) {
+	if value <= 0 {
+		p.add("%s must be positive, got %v", name, value)
+	}
+}
+
+func (p *problems) notNegative(name string, value time.Duration) {
+	if value < 0 {
+		p.add("%s can't be negative, got %v", name, value)
+	}
+}
+
+func (p *problems) url(name, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		p.add("%s must be an absolute URL, got %q", name, value)
+	}
+}
+
+func (p problems) err() error {
+	return errors.Join(p...)
+}
+
+func (s Server) Validate() error {
+	var p problems
+	if s.Port < 1 || s.Port > 65535 {
+		p.add("PORT must be between 1 and 65535, got %d", s.Port)
+	}
+	p.url("PUBLIC_URL", s.PublicURL)
+	p.url("PASSWORD_RESET_URL", s.PasswordResetURL)
+	p.url("INVITATION_URL", s.InvitationURL)
+	p.notNegative("HTTP_READ_HEADER_TIMEOUT", s.ReadHeaderTimeout)
+	p.notNegative("HTTP_READ_TIMEOUT", s.ReadTimeout)
+	p.notNegative("HTTP_WRITE_TIMEOUT", s.WriteTimeout)
+	p.notNegative("HTTP_IDLE_TIMEOUT", s.IdleTimeout)
+	p.positive("SHUTDOWN_TIMEOUT", s.ShutdownTimeout)
+	p.notNegative("SHUTDOWN_DRAIN_DELAY", s.ShutdownDrainDelay)
+	for _, proxy := range s.TrustedProxies {
+		if _, err := parseNetwork(proxy); err != nil {
+			p.add("TRUSTED_PROXIES must be IPs or CIDR ranges, got %q", proxy)
+		}
+	}
+	return p.err()
+}
+
+// TrustedProxyNetworks parses TrustedProxies, a single IP is a network of
+// just that address. Entries that don't parse are left out, Validate
+// reports them.
+func (s Server) TrustedProxyNetworks() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, proxy := range s.TrustedProxies {
+		if network, err := parseNetwork(proxy); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+func parseNetwork(value string) (*net.IPNet, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	return network, err
+}
+
+func (d Database) Validate() error {
+	var p problems
+	if d.URL == "" {
+		p.add("DB_URL is required")
+	}
+	if d.MaxOpenConns < 0 {
+		p.add("DB_MAX_OPEN_CONNS can't be negative, got %d", d.MaxOpenConns)
+	}
+	if d.MaxIdleConns < 0 {
+		p.add("DB_MAX_IDLE_CONNS can't be negative, got %d", d.MaxIdleConns)
+	}
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		p.add("DB_MAX_IDLE_CONNS (%d) can't be more than DB_MAX_OPEN_CONNS (%d)", d.MaxIdleConns, d.MaxOpenConns)
+	}
+	p.notNegative("DB_CONN_MAX_LIFETIME", d.ConnMaxLifetime)
+	p.notNegative("DB_CONN_MAX_IDLE_TIME", d.ConnMaxIdleTime)
+	p.positive("DB_CONNECT_TIMEOUT", d.ConnectTimeout)
+	return p.err()
+}
+
+func (a Auth) Validate() error {
+	var p problems
+	if a.SecretKey == "" && a.JWTKeysFile == "" {
+		p.add("either JWT_KEYS_FILE or SECRET_KEY has to be set")
+	}
+	p.positive("ACCESS_TOKEN_TTL", a.AccessTokenTTL)
+	p.positive("REFRESH_TOKEN_TTL", a.RefreshTokenTTL)
+	p.positive("PASSWORD_RESET_TTL", a.PasswordResetTTL)
+	p.positive("EMAIL_VERIFICATION_TTL", a.EmailVerificationTTL)
+	p.positive("EMAIL_VERIFICATION_RESEND_DELAY", a.VerificationResendDelay)
+	p.positive("INVITATION_TTL", a.InvitationTTL)
+	p.positive("MFA_CHALLENGE_TTL", a.MFAChallengeTTL)
+	if a.BcryptCost < bcrypt.MinCost || a.BcryptCost > bcrypt.MaxCost {
+		p.add("BCRYPT_COST must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, a.BcryptCost)
+	}
+	if a.TOTPIssuer == "" {
+		p.add("TOTP_ISSUER can't be empty")
+	}
+	return p.err()
+}
+
+func (l Lockout) Validate() error {
+	var p problems
+	if l.MaxFailures < 1 {
+		p.add("LOGIN_MAX_FAILURES must be at least 1, got %d", l.MaxFailures)
+	}
+	if l.IPMaxFailures < 1 {
+		p.add("LOGIN_IP_MAX_FAILURES must be at least 1, got %d", l.IPMaxFailures)
+	}
+	p.positive("LOGIN_LOCKOUT_BASE", l.Base)
+	p.positive("LOGIN_LOCKOUT_MAX", l.Max)
+	p.positive("LOGIN_FAILURE_WINDOW", l.FailureWindow)
+	if l.Base > l.Max {
+		p.add("LOGIN_LOCKOUT_BASE (%v) can't be more than LOGIN_LOCKOUT_MAX (%v)", l.Base, l.Max)
+	}
+	return p.err()
+}
+
+func (m Mail) Validate() error {
+	var p problems
+	switch m.Mailer {
+	case "", "log":
+	case "smtp":
+		if m.SMTPHost == "" {
+			p.add("SMTP_HOST is required with MAILER=smtp")
+		}
+		if m.From == "" {
+			p.add("MAIL_FROM is required with MAILER=smtp")
+		}
+		if m.SMTPPort < 1 || m.SMTPPort > 65535 {
+			p.add("SMTP_PORT must be between 1 and 65535, got %d", m.SMTPPort)
+		}
+	default:
+		p.add("MAILER must be log or smtp, got %q", m.Mailer)
+	}
+	return p.err()
+}
+
+func (o OIDC) Validate() error {
+	if o.IssuerURL == "" {
+		return nil
+	}
+	var p problems
+	p.url("OIDC_ISSUER_URL", o.IssuerURL)
+	if o.ClientID == "" || o.RedirectURL == "" {
+		p.add("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER_URL")
+	}
+	p.url("OIDC_REDIRECT_URL", o.RedirectURL)
+	return p.err()
+}
+
+func (b BootstrapAdmin) Validate() error {
+	if b.Email == "" {
+		return nil
+	}
+	var p problems
+	if b.Password == "" {
+		p.add("BOOTSTRAP_ADMIN_PASSWORD is required with BOOTSTRAP_ADMIN_EMAIL")
+	}
+	if b.Username == "" || b.Name == "" {
+		p.add("BOOTSTRAP_ADMIN_USERNAME and BOOTSTRAP_ADMIN_NAME can't be empty")
+	}
+	return p.err()
+}
+
+func (j Jobs) Validate() error {
+	var p problems
+	p.positive("SOFT_DELETE_RETENTION", j.SoftDeleteRetention)
+	p.positive("PURGE_INTERVAL", j.PurgeInterval)
+	return p.err()
+}
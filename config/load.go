@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the configuration. Settings start at their defaults, the
+// YAML or TOML file named by CONFIG_FILE is applied over them, and then
+// the environment variables that are set, including ones from a .env file
+// in the working directory when there is one. It returns an error when a
+// value can't be read, but the result still has to be checked with
+// Validate.
+func Load() (Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Config{}, fmt.Errorf("couldn't read .env: %w", err)
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return cfg, fmt.Errorf("couldn't read %s: %w", path, err)
+		}
+	}
+	return cfg, errors.Join(applyEnv(&cfg)...)
+}
+
+// loadFile applies the settings in a YAML or TOML file over cfg. Keys that
+// aren't settings are an error, since they're most likely typos.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	case ".toml":
+		metadata, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return err
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown settings %v", undecoded)
+		}
+		return nil
+	}
+	return errors.New("the file has to end in .yaml, .yml or .toml")
+}
+
+// eachSetting calls fn with every setting in cfg, along with the key of
+// the section it's in
+func eachSetting(cfg *Config, fn func(section string, field reflect.StructField, value reflect.Value)) {
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		name := sections.Type().Field(i).Tag.Get("yaml")
+		section := sections.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			fn(name, section.Type().Field(j), section.Field(j))
+		}
+	}
+}
+
+// applyEnv sets the settings whose environment variables aren't empty,
+// returning an error for each value that can't be parsed and leaving
+// those settings as they were
+func applyEnv(cfg *Config) []error {
+	var errs []error
+	eachSetting(cfg, func(_ string, field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("env")
+		text := os.Getenv(name)
+		if text == "" {
+			return
+		}
+		if err := parse(value, text); err != nil {
+			errs = append(errs, fmt.Errorf("%s %w", name, err))
+		}
+	})
+	return errs
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func parse(value reflect.Value, text string) error {
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 15m or 720h, got %q", text)
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", text)
+		}
+		value.SetBool(b)
+	case reflect.Int:
+		number, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", text)
+		}
+		value.SetInt(int64(number))
+	case reflect.Slice:
+		value.Set(reflect.ValueOf(strings.Fields(text)))
+	case reflect.Map:
+		pairs := map[string]string{}
+		for _, pair := range strings.Split(text, ",") {
+			key, mapped, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || key == "" || mapped == "" {
+				return fmt.Errorf("must be key=value pairs separated by commas, got %q", pair)
+			}
+			pairs[key] = mapped
+		}
+		value.Set(reflect.ValueOf(pairs))
+	default:
+		return fmt.Errorf("has unsupported type %v", value.Type())
+	}
+	return nil
+}
+
+var (
+	currentMu sync.RWMutex
+	current   *Config
+)
+
+// Set makes cfg the configuration Current returns. With nil, Current goes
+// back to reading the environment.
+func Set(cfg *Config) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = cfg
+}
+
+// Current is the configuration the program was started with. Until Set is
+// called it's the defaults with the environment variables applied, read
+// again on every call, and values that can't be parsed are logged and
+// ignored.
+func Current() Config {
+	currentMu.RLock()
+	cfg := current
+	currentMu.RUnlock()
+	if cfg != nil {
+		return *cfg
+	}
+
+	fromEnv := Default()
+	for _, err := range applyEnv(&fromEnv) {
+		log.Printf("%v, using the default", err)
+	}
+	return fromEnv
+}
+
+// WriteRedacted writes the settings to w as environment variables,
+// grouped by section. Secrets are replaced with [redacted] and the
+// password in DB_URL is masked.
+func (c Config) WriteRedacted(w io.Writer) error {
+	var out strings.Builder
+	previous := ""
+	eachSetting(&c, func(section string, field reflect.StructField, value reflect.Value) {
+		if section != previous {
+			if previous != "" {
+				out.WriteString("\n")
+			}
+			fmt.Fprintf(&out, "# %s\n", section)
+			previous = section
+		}
+		text := format(value)
+		switch field.Tag.Get("secret") {
+		case "true":
+			if text != "" {
+				text = "[redacted]"
+			}
+		case "url":
+			text = redactURL(text)
+		}
+		fmt.Fprintf(&out, "%s=%s\n", field.Tag.Get("env"), text)
+	})
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// format writes a setting the way it's read from the environment
+func format(value reflect.Value) string {
+	switch v := value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case []string:
+		return strings.Join(v, " ")
+	case map[string]string:
+		pairs := []string{}
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			pairs = append(pairs, key+"="+v[key])
+		}
+		return strings.Join(pairs, ",")
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// redactURL masks the password in a URL. Values that aren't URLs, such as
+// a key=value Postgres connection string, are redacted completely.
+func redactURL(text string) string {
+	if text == "" {
+		return ""
+	}
+	u, err := url.Parse(text)
+	if err != nil || u.Scheme == "" {
+		return "[redacted]"
+	}
+	return u.Redacted()
+}
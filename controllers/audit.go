@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+)
+
+const (
+	auditCreate  = "create"
+	auditUpdate  = "update"
+	auditDelete  = "delete"
+	auditRestore = "restore"
+
+	auditPasswordReset  = "password_reset"
+	auditPasswordChange = "password_change"
+	auditVerifyEmail    = "verify_email"
+	auditEnable2FA      = "enable_2fa"
+	auditDisable2FA     = "disable_2fa"
+	auditUnlock         = "unlock"
+)
+
+var auditEntities = map[string]bool{
+	"user":            true,
+	"category":        true,
+	"supplier":        true,
+	"product":         true,
+	"invitation":      true,
+	"service_account": true,
+	"api_key":         true,
+	"organization":    true,
+}
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// recordAudit stores who made a write and what it changed. before and after
+// are the entity as the API returns it, nil on creates and deletes. The
+// write has already happened by the time this runs, so a failure is logged
+// rather than turned into an error response.
+func (cfg ApiCfg) recordAudit(
+	r *http.Request,
+	actor database.User,
+	action string,
+	entity string,
+	entityId uuid.UUID,
+	before interface{},
+	after interface{},
+	) {
+	changes, err := helpers.JSONDiff(before, after, "updated_at")
+	if err != nil {
+		log.Printf("Couldn't diff %v %v for audit log: %v", entity, entityId, err)
+		return
+	}
+
+	err = cfg.DB.CreateAuditLog(r.Context(), database.CreateAuditLogParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		ActorID: actor.ID,
+		ActorEmail: actor.Email,
+		Action: action,
+		Entity: entity,
+		EntityID: entityId,
+		Changes: changes,
+		RequestID: middleware.GetReqID(r.Context()),
+		Ip: cfg.clientIP(r),
+	})
+	if err != nil {
+		log.Printf("Couldn't write audit log for %v %v %v: %v", action, entity, entityId, err)
+	}
+}
+
+// clientIP is the address of the client that sent r. Requests from a
+// trusted proxy are followed back through X-Forwarded-For, from the hop
+// nearest the server, until an address that isn't a trusted proxy, or
+// taken from X-Real-IP when there's no X-Forwarded-For. Anyone can send
+// those headers, so they're ignored on requests from other addresses.
+func (cfg ApiCfg) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !cfg.trustedProxy(ip) {
+		return ip
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			ip = hop
+			if !cfg.trustedProxy(ip) {
+				break
+			}
+		}
+		return ip
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return ip
+}
+
+func (cfg ApiCfg) trustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range cfg.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAuditLogsController lists audit log entries, newest first, optionally
+// filtered with ?entity= and ?entity_id=
+func (cfg ApiCfg) GetAuditLogsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	query := r.URL.Query()
+	params := database.GetAuditLogsParams{RowLimit: defaultAuditLimit}
+
+	if entity := query.Get("entity"); entity != "" {
+		if !auditEntities[entity] {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown entity: %v", entity))
+			return
+		}
+		params.Entity = helpers.NewNullString(&entity)
+	}
+
+	if entityIdStr := query.Get("entity_id"); entityIdStr != "" {
+		entityId, err := uuid.Parse(entityIdStr)
+		if err != nil {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse entity_id: %v", err))
+			return
+		}
+		params.EntityID = helpers.NewNullUUID(&entityId)
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			helpers.RespondWithError(w, 400,
+				fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit))
+			return
+		}
+		params.RowLimit = int32(limit)
+	}
+
+	auditLogs, err := cfg.DB.GetAuditLogs(r.Context(), params)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch audit logs: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseAuditLogsToAuditLogs(auditLogs))
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	assert.NoError(t, err)
+	cfg := ApiCfg{TrustedProxies: []*net.IPNet{proxies}}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"direct", "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"spoofed by a client", "192.0.2.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.9", "X-Real-IP": "198.51.100.9"}, "192.0.2.1"},
+		{"proxy", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "192.0.2.1"}, "192.0.2.1"},
+		{"proxy chain", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "192.0.2.1, 10.0.0.3"}, "192.0.2.1"},
+		// The client can put anything in front of what the proxy appends
+		{"spoofed behind a proxy", "10.0.0.2:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.9, 192.0.2.1"}, "192.0.2.1"},
+		{"real ip", "10.0.0.2:1234", map[string]string{"X-Real-IP": "192.0.2.1"}, "192.0.2.1"},
+		{"proxy without headers", "10.0.0.2:1234", nil, "10.0.0.2"},
+		{"garbage", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "unknown"}, "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			assert.Equal(t, tt.want, cfg.clientIP(req))
+		})
+	}
+
+	// Without trusted proxies the headers are never read
+	req := httptest.NewRequest("POST", "/login", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-For", "192.0.2.1")
+	assert.Equal(t, "10.0.0.2", ApiCfg{}.clientIP(req))
+}
+
+// changesArg captures the changes column written to the audit log
+type changesArg struct {
+	changes map[string]helpers.FieldChange
+}
+
+func (c *changesArg) Match(v driver.Value) bool {
+	data, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, &c.changes) == nil
+}
+
+var auditLogColumns = []string{
+	"id", "created_at", "actor_id", "actor_email", "action", "entity", "entity_id", "changes", "request_id", "ip",
+}
+
+func TestUpdateProduct_RecordsAudit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{ID: uuid.New(), Email: "admin@example.com", Role: "admin"}
+	productId := uuid.New()
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, "MC-20L", createdAt, createdAt, nil, uuid.Nil))
+	mock.ExpectQuery(`UPDATE products`).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 45000, 3, nil, nil, "MC-20L", createdAt, time.Now(), nil, uuid.Nil))
+
+	changes := &changesArg{}
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WithArgs(
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			adminUser.ID,
+			"admin@example.com",
+			"update",
+			"product",
+			productId,
+			changes,
+			sqlmock.AnyArg(),
+			"192.0.2.1",
+		).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("PUT", fmt.Sprintf("/products/%v", productId),
+		bytes.NewBufferString(`{"name": "Microwave", "price": 45000, "stock_level": 3, "sku": "MC-20L"}`))
+	assert.NoError(t, err)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Put("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.UpdateProductController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, map[string]helpers.FieldChange{
+		"price": {Before: json.RawMessage("50000"), After: json.RawMessage("45000")},
+	}, changes.changes)
+}
+
+func TestDeleteSupplier_RecordsAudit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{ID: uuid.New(), Role: "admin"}
+	supplierId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM suppliers WHERE id=\$1`).
+		WithArgs(supplierId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "email", "description", "phone", "country", "created_at", "updated_at", "deleted_at", "org_id",
+		}).AddRow(supplierId, "Acme", nil, nil, nil, nil, time.Now(), time.Now(), nil, uuid.Nil))
+	mock.ExpectExec(`UPDATE suppliers SET deleted_at`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	changes := &changesArg{}
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WithArgs(
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			adminUser.ID,
+			sqlmock.AnyArg(),
+			"delete",
+			"supplier",
+			supplierId,
+			changes,
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("/suppliers/%v", supplierId), nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Delete("/suppliers/{supplierId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.DeleteSupplierController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, json.RawMessage(`"Acme"`), changes.changes["name"].Before)
+	assert.Equal(t, json.RawMessage("null"), changes.changes["name"].After)
+}
+
+func TestGetAuditLogs_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	productId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM audit_logs`).
+		WithArgs("product", productId, 100).
+		WillReturnRows(sqlmock.NewRows(auditLogColumns).
+			AddRow(uuid.New(), time.Now(), uuid.New(), "admin@example.com", "update", "product",
+				productId, []byte(`{"price":{"before":50000,"after":45000}}`), "req-1", "192.0.2.1"))
+
+	req, err := http.NewRequest("GET",
+		fmt.Sprintf("/audit?entity=product&entity_id=%v", productId), nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetAuditLogsController(w, r, database.User{Role: "admin"})
+	})
+	handler.ServeHTTP(rr, req)
+
+	var response []models.AuditLog
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, "admin@example.com", response[0].ActorEmail)
+	assert.JSONEq(t, `{"price":{"before":50000,"after":45000}}`, string(response[0].Changes))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAuditLogs_UnknownEntity(t *testing.T) {
+	cfg := ApiCfg{}
+
+	req, err := http.NewRequest("GET", "/audit?entity=order", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetAuditLogsController(w, r, database.User{Role: "admin"})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Unknown entity")
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+)
+
+type refreshTokenParams struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issueRefreshToken stores a new refresh token for the session and returns
+// it. Only its hash is kept in the database.
+func (cfg ApiCfg) issueRefreshToken(ctx context.Context, sessionId uuid.UUID) (string, error) {
+	token, hash, err := helpers.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	err = cfg.DB.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID: uuid.New(),
+		SessionID: sessionId,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(helpers.RefreshTokenTTL()),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (cfg ApiCfg) revokeSession(ctx context.Context, sessionId uuid.UUID) error {
+	return cfg.DB.RevokeSession(ctx, database.RevokeSessionParams{
+		ID: sessionId,
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+}
+
+// decodeRefreshToken reads the refresh token from the request body and
+// looks it up. It writes the error response and returns false when the
+// token is missing or unknown.
+func (cfg ApiCfg) decodeRefreshToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	) (database.RefreshToken, bool) {
+	decoder := json.NewDecoder(r.Body)
+	params := refreshTokenParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return database.RefreshToken{}, false
+	}
+
+	if params.RefreshToken == "" {
+		helpers.RespondWithError(w, 400, "Refresh token is required")
+		return database.RefreshToken{}, false
+	}
+
+	refreshToken, err := cfg.DB.GetRefreshTokenByHash(
+		r.Context(), helpers.HashToken(params.RefreshToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 401, "Invalid refresh token")
+			return refreshToken, false
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch refresh token: %v", err))
+		return refreshToken, false
+	}
+	return refreshToken, true
+}
+
+// RefreshController swaps a refresh token for a new access token and a new
+// refresh token. Each refresh token works once; presenting one again means
+// it has leaked, so the whole session is revoked.
+func (cfg ApiCfg) RefreshController(w http.ResponseWriter, r *http.Request) {
+	refreshToken, ok := cfg.decodeRefreshToken(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := cfg.DB.GetSessionById(r.Context(), refreshToken.SessionID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch session: %v", err))
+		return
+	}
+
+	if session.RevokedAt.Valid {
+		helpers.RespondWithError(w, 401, "Session has been revoked")
+		return
+	}
+
+	if time.Now().UTC().After(refreshToken.ExpiresAt) {
+		helpers.RespondWithError(w, 401, "Refresh token has expired")
+		return
+	}
+
+	used, err := cfg.DB.MarkRefreshTokenUsed(r.Context(), database.MarkRefreshTokenUsedParams{
+		ID: refreshToken.ID,
+		UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't use refresh token: %v", err))
+		return
+	}
+
+	if used == 0 {
+		log.Printf("Refresh token reuse detected, revoking session %v of user %v",
+			session.ID, session.UserID)
+		if err := cfg.revokeSession(r.Context(), session.ID); err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke session: %v", err))
+			return
+		}
+		helpers.RespondWithError(w, 401, "Refresh token has already been used")
+		return
+	}
+
+	// The role is read again so a changed role shows up in the new token
+	user, err := cfg.DB.GetUserById(r.Context(), session.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, 401, "User not found")
+		return
+	}
+
+	token, err := helpers.GenerateJWT(user.ID, user.Role, session.ID, session.OrgID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate token: %v", err))
+		return
+	}
+
+	newRefreshToken, err := cfg.issueRefreshToken(r.Context(), session.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create refresh token: %v", err))
+		return
+	}
+
+	helpers.JSON(w, 200, models.SanitizeLoginResponse(user, token, newRefreshToken))
+}
+
+// LogoutController revokes the session the refresh token belongs to, which
+// invalidates every access and refresh token issued for it
+func (cfg ApiCfg) LogoutController(w http.ResponseWriter, r *http.Request) {
+	refreshToken, ok := cfg.decodeRefreshToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.revokeSession(r.Context(), refreshToken.SessionID); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke session: %v", err))
+		return
+	}
+	helpers.TextResponse(w, 200, "Successfully logged out")
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
 	"github.com/ringtho/inventory/internal/database"
 	"github.com/ringtho/inventory/models"
 )
@@ -26,12 +28,6 @@ func (cfg ApiCfg) CreateCategoryController(
 	r *http.Request, 
 	user database.User,
 	) {
-
-	if user.Role == "user" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
-		return
-	} 
-
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
@@ -50,6 +46,7 @@ func (cfg ApiCfg) CreateCategoryController(
 
 	category, err := cfg.DB.CreateCategory(r.Context(), database.CreateCategoryParams{
 		ID: uuid.New(),
+		OrgID: auth.OrgID(r.Context()),
 		Name: params.Name,
 		Description: description,
 		CreatedAt: time.Now().UTC(),
@@ -67,11 +64,32 @@ func (cfg ApiCfg) CreateCategoryController(
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create category: %v", err))
 		return
 	}
-	helpers.JSON(w, 201, models.DatabaseCategoryToCategory(category))
+	created := models.DatabaseCategoryToCategory(category)
+	cfg.recordAudit(r, user, auditCreate, "category", category.ID, nil, created)
+	helpers.JSON(w, 201, created)
 }
 
-func (cfg ApiCfg) GetCategoriesController(w http.ResponseWriter, r *http.Request) {
-	categories, err := cfg.DB.GetCategories(r.Context())
+func (cfg ApiCfg) GetCategoriesController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	include, ok := includeDeleted(w, r, auth.CategoriesDelete)
+	if !ok {
+		return
+	}
+
+	if exportList(w, r, "categories", models.CategoryExportColumns,
+		func(ctx context.Context, fn func(database.Category) error) error {
+			return cfg.DB.IterCategories(ctx, auth.OrgID(ctx), include, fn)
+		}) {
+		return
+	}
+
+	categories, err := cfg.DB.GetCategories(r.Context(), database.GetCategoriesParams{
+		OrgID: auth.OrgID(r.Context()),
+		IncludeDeleted: include,
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't fetch categories: %v", err))
 	}
@@ -83,11 +101,6 @@ func (cfg ApiCfg) DeleteCategoryController(
 	r *http.Request,
 	user database.User,
 	) {
-	if user.Role == "user" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
-		return
-	}
-
 	idStr := chi.URLParam(r, "categoryId")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -95,29 +108,73 @@ func (cfg ApiCfg) DeleteCategoryController(
 		return
 	}
 
-	if !cfg.checkCategoryExists(w, r, id) {
+	category, ok := cfg.checkCategoryExists(w, r, id)
+	if !ok {
 		return
 	}
 
-	err = cfg.DB.DeleteCategory(r.Context(), id)
+	deleted, err := cfg.DB.SoftDeleteCategory(r.Context(), database.SoftDeleteCategoryParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+		DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		UpdatedAt: category.UpdatedAt,
+	})
 
 	if err != nil {
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't delete category: %v", err))
 		return
 	}
+	if deleted == 0 {
+		modified(w)
+		return
+	}
+	cfg.recordAudit(r, user, auditDelete, "category", id,
+		models.DatabaseCategoryToCategory(category), nil)
 	helpers.TextResponse(w, 200, fmt.Sprintf("Successfully deleted category with id %v", id))
 }
 
-func (cfg ApiCfg) UpdateCategoryController(
+// RestoreCategoryController brings back a soft deleted category
+func (cfg ApiCfg) RestoreCategoryController(
 	w http.ResponseWriter,
 	r *http.Request,
 	user database.User,
 	) {
-	if user.Role == "user" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
+	idStr := chi.URLParam(r, "categoryId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse string: %v", err))
 		return
 	}
 
+	category, err := cfg.DB.RestoreCategory(r.Context(), database.RestoreCategoryParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Deleted category not found")
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { 
+				helpers.RespondWithError(w, 409, "Category Name already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't restore category: %v", err))
+		return
+	}
+	restored := models.DatabaseCategoryToCategory(category)
+	cfg.recordAudit(r, user, auditRestore, "category", id, nil, restored)
+	helpers.JSON(w, 200, restored)
+}
+
+func (cfg ApiCfg) UpdateCategoryController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
@@ -140,20 +197,88 @@ func (cfg ApiCfg) UpdateCategoryController(
 		return
 	}
 
-	if !cfg.checkCategoryExists(w, r, id){
+	category, ok := cfg.checkCategoryExists(w, r, id)
+	if !ok {
+		return
+	}
+
+	cfg.updateCategory(w, r, user, category, params)
+}
+
+// PatchCategoryController applies a JSON merge patch to a category so
+// clients can change single fields without resending the whole object
+func (cfg ApiCfg) PatchCategoryController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "categoryId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse string: %v", err))
+		return
+	}
+
+	category, err := cfg.DB.GetCategoryById(r.Context(), database.GetCategoryByIdParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Category not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Failed to fetch category %v", err))
+		return
+	}
+
+	if !cfg.checkPreconditions(w, r, category.UpdatedAt) {
+		return
+	}
+
+	current := parameters{Name: category.Name}
+	if category.Description.Valid {
+		current.Description = &category.Description.String
+	}
+
+	params := parameters{}
+	if !decodeMergePatch(w, r, current, &params) {
+		return
+	}
+
+	if params.Name == "" {
+		helpers.RespondWithError(w, 400, "Category name is required")
 		return
 	}
 
+	cfg.updateCategory(w, r, user, category, params)
+}
+
+// updateCategory replaces the fields of before with params and records the
+// change in the audit log
+func (cfg ApiCfg) updateCategory(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	before database.Category,
+	params parameters,
+	) {
 	description := helpers.NewNullString(params.Description)
 
 	category, err := cfg.DB.UpdateCategory(r.Context(), database.UpdateCategoryParams{
-		ID: id,
+		ID: before.ID,
+		OrgID: before.OrgID,
 		Name: params.Name,
 		Description: description,
 		UpdatedAt: time.Now().UTC(),
+		ExpectedUpdatedAt: before.UpdatedAt,
 	})
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			modified(w)
+			return
+		}
 		if pqErr, ok := err.(*pq.Error); ok {
 			if pqErr.Code == "23505" { 
 				helpers.RespondWithError(w, 409, "Category Name already exists")
@@ -163,7 +288,11 @@ func (cfg ApiCfg) UpdateCategoryController(
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update category: %v", err))
 		return
 	}
-	helpers.JSON(w, 200, models.DatabaseCategoryToCategory(category))
+	updated := models.DatabaseCategoryToCategory(category)
+	cfg.recordAudit(r, user, auditUpdate, "category", category.ID,
+		models.DatabaseCategoryToCategory(before), updated)
+	w.Header().Set("ETag", helpers.ETag(category.UpdatedAt))
+	helpers.JSON(w, 200, updated)
 
 }
 
@@ -173,6 +302,11 @@ func (cfg ApiCfg) GetCategoryController(
 	user database.User,
 	) {
 	
+	include, ok := includeDeleted(w, r, auth.CategoriesDelete)
+	if !ok {
+		return
+	}
+
 	idStr := chi.URLParam(r, "categoryId")
 	id, err := uuid.Parse(idStr)
 
@@ -181,7 +315,16 @@ func (cfg ApiCfg) GetCategoryController(
 		return
 	}
 
-	category, err := cfg.DB.GetCategoryById(r.Context(), id)
+	getCategory := cfg.DB.GetCategoryById
+	if include {
+		getCategory = func(ctx context.Context, arg database.GetCategoryByIdParams) (database.Category, error) {
+			return cfg.DB.GetCategoryByIdIncludingDeleted(ctx, database.GetCategoryByIdIncludingDeletedParams(arg))
+		}
+	}
+	category, err := getCategory(r.Context(), database.GetCategoryByIdParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 				helpers.RespondWithError(w, 404, "Category not found")
@@ -191,18 +334,26 @@ func (cfg ApiCfg) GetCategoryController(
 		return
 	}
 
+	if notModified(w, r, category.UpdatedAt) {
+		return
+	}
 	helpers.JSON(w, 200, models.DatabaseCategoryToCategory(category))
 }
 
+// checkCategoryExists also checks the If-Match precondition of the request
+// against the category it finds and returns it
 func (cfg ApiCfg) checkCategoryExists(
 	w http.ResponseWriter,
 	r *http.Request,
 	id uuid.UUID,
-	) bool {
-	_, err := cfg.DB.GetCategoryById(r.Context(), id)
+	) (database.Category, bool) {
+	category, err := cfg.DB.GetCategoryById(r.Context(), database.GetCategoryByIdParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 404, "Category not found")
-		return false
+		return category, false
 	}
-	return true
+	return category, cfg.checkPreconditions(w, r, category.UpdatedAt)
 }
\ No newline at end of file
@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,113 +12,13 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
 	"github.com/ringtho/inventory/models"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCreateCategory_Success(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	categoryID := uuid.New()
-	userID := uuid.New()
-	mockCategory := parameters{
-		Name: "Sneakers",
-		Description: new(string),
-		CreatedBy: userID,
-	}
-
-	mockData := sqlmock.NewRows([]string{
-		"id","created_at", "updated_at","name","description","created_by",
-	}).AddRow(
-		categoryID, time.Now().UTC(), time.Now().UTC(), mockCategory.Name, mockCategory.Description, userID,
-	)
-
-	mock.ExpectQuery(`INSERT INTO categories`).
-	WithArgs(
-		sqlmock.AnyArg(),
-		mockCategory.Name,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-	).
-	WillReturnRows(mockData)
-
-	payload, err := json.Marshal(mockCategory)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("POST", "/categories", bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		cfg.CreateCategoryController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	var response models.Category
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 201, rr.Code)
-	assert.Equal(t, mockCategory.Name, response.Name)
-}
-
-func TestCreateCategory_NameExists(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	userID := uuid.New()
-	mockCategory := parameters{
-		Name: "Sneakers",
-		Description: new(string),
-		CreatedBy: userID,
-	}
-
-	mock.ExpectQuery(`INSERT INTO categories`).
-	WithArgs(
-		sqlmock.AnyArg(),
-		mockCategory.Name,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-	).
-	WillReturnError(&pq.Error{Code: "23505"})
-
-	payload, err := json.Marshal(mockCategory)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("POST", "/categories", bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		cfg.CreateCategoryController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 409, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Category Name already exists")
-}
-
 func TestCreateCategory_DBError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -140,6 +39,7 @@ func TestCreateCategory_DBError(t *testing.T) {
 	mock.ExpectQuery(`INSERT INTO categories`).
 	WithArgs(
 		sqlmock.AnyArg(),
+		uuid.Nil,
 		mockCategory.Name,
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
@@ -165,45 +65,6 @@ func TestCreateCategory_DBError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Couldn't create category")
 }
 
-func TestGetCategories_Success(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-
-	queries := database.New(db)
-	cfg := ApiCfg{ DB: queries}
-
-	mockCategories := sqlmock.NewRows([]string{
-		"id", "created_at", "updated_at", "name", "description", "created_by",
-	}).
-	AddRow(uuid.New(), time.Now(), time.Now(), 
-	"Wines and Spirits", "Elegant Wines", uuid.New()).
-	AddRow(uuid.New(), time.Now(), time.Now(), 
-	"Chocolates", "Best cocoa produced chocolates", uuid.New())
-
-	mock.ExpectQuery(`SELECT id, created_at, updated_at, name, description, created_by FROM categories`).
-	WillReturnRows(mockCategories)
-	
-
-	req, err := http.NewRequest("GET", "/categories", nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		cfg.GetCategoriesController(w, r)
-	})
-	handler.ServeHTTP(rr, req)
-
-	var categories []models.Category
-	err = json.NewDecoder(rr.Body).Decode(&categories)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Equal(t, 2, len(categories))
-	assert.Equal(t, "Wines and Spirits", categories[0].Name)
-	assert.Equal(t, "Best cocoa produced chocolates", categories[1].Description)
-}
-
 func TestGetCategories_DBError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -211,7 +72,7 @@ func TestGetCategories_DBError(t *testing.T) {
 	queries := database.New(db)
 	cfg := ApiCfg{ DB: queries}
 
-	mock.ExpectQuery(`SELECT id, created_at, updated_at, name, description, created_by FROM categories`).
+	mock.ExpectQuery(`SELECT id, created_at, updated_at, name, description, created_by, deleted_at, org_id FROM categories`).
 	WillReturnError(fmt.Errorf("database Error"))
 	
 	req, err := http.NewRequest("GET", "/categories", nil)
@@ -220,7 +81,7 @@ func TestGetCategories_DBError(t *testing.T) {
 
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		cfg.GetCategoriesController(w, r)
+		cfg.GetCategoriesController(w, r, database.User{})
 	})
 	handler.ServeHTTP(rr, req)
 	
@@ -228,7 +89,7 @@ func TestGetCategories_DBError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Couldn't fetch categories")
 }
 
-func TestDeleteCategory_Success(t *testing.T) {
+func TestDeleteCategory_DBError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
@@ -246,24 +107,23 @@ func TestDeleteCategory_Success(t *testing.T) {
 	}
 
 	mockData := sqlmock.NewRows([]string{
-		"id","created_at", "updated_at","name","description","created_by",
+		"id","created_at", "updated_at","name","description","created_by", "deleted_at", "org_id",
 	}).AddRow(
 		categoryID,
 		time.Now().UTC(),
 		time.Now().UTC(),
 		mockCategory.Name,
 		mockCategory.Description,
-		mockCategory.CreatedBy,
+		mockCategory.CreatedBy, nil, uuid.Nil,
 	)
 
 	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
+	WithArgs(categoryID, uuid.Nil).
 	WillReturnRows(mockData)
 
-	mock.ExpectExec(`DELETE FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
-	WillReturnResult(sqlmock.NewResult(1,1))
-
+	mock.ExpectExec(`UPDATE categories SET deleted_at = \$3 WHERE id = \$1`).
+	WithArgs(categoryID, uuid.Nil, sqlmock.AnyArg()).
+	WillReturnError(fmt.Errorf("Database error"))
 
 	req, err := http.NewRequest("DELETE", fmt.Sprintf("/categories/%v", categoryID), nil)
 	assert.NoError(t, err)
@@ -278,94 +138,81 @@ func TestDeleteCategory_Success(t *testing.T) {
 	})
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, 200, rr.Code)
-	assert.Contains(t, rr.Body.String(), categoryID.String())
-	assert.Contains(t, rr.Body.String(), "Successfully deleted category")
+	assert.Equal(t, 500, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Couldn't delete category")
 }
 
-func TestDeleteCategory_DBError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	categoryID := uuid.New()
-	mockCategory := parameters{
-		Name: "Sneakers",
-		Description: new(string),
-		CreatedBy: uuid.New(),
-	}
+func TestCreateCategory_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	mockData := sqlmock.NewRows([]string{
-		"id","created_at", "updated_at","name","description","created_by",
-	}).AddRow(
-		categoryID,
-		time.Now().UTC(),
-		time.Now().UTC(),
-		mockCategory.Name,
-		mockCategory.Description,
-		mockCategory.CreatedBy,
-	)
+		rr := serve(handler, "POST", "/categories", `{"name": "Sneakers", "description": ""}`)
 
-	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
-	WillReturnRows(mockData)
+		var response models.Category
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 201, rr.Code)
+		assert.Equal(t, "Sneakers", response.Name)
+	})
+}
 
-	mock.ExpectExec(`DELETE FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
-	WillReturnError(fmt.Errorf("Database error"))
+func TestCreateCategory_NameExists(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/categories/%v", categoryID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+		assert.Equal(t, 201, serve(handler, "POST", "/categories", `{"name": "Sneakers"}`).Code)
+		rr := serve(handler, "POST", "/categories", `{"name": "Sneakers"}`)
 
-	rr := httptest.NewRecorder()
+		assert.Equal(t, 409, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Category Name already exists")
+	})
+}
 
-	handler := chi.NewRouter()
-	handler.Delete("/categories/{categoryId}", 
-	func(w http.ResponseWriter, r *http.Request){
-		cfg.DeleteCategoryController(w, r, adminUser)
+func TestGetCategories_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		assert.Equal(t, 201, serve(handler, "POST", "/categories",
+			`{"name": "Wines and Spirits", "description": "Elegant Wines"}`).Code)
+		assert.Equal(t, 201, serve(handler, "POST", "/categories",
+			`{"name": "Chocolates", "description": "Best cocoa produced chocolates"}`).Code)
+
+		rr := serve(handler, "GET", "/categories", "")
+
+		var categories []models.Category
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&categories))
+		assert.Equal(t, 200, rr.Code)
+		byName := map[string]models.Category{}
+		for _, category := range categories {
+			byName[category.Name] = category
+		}
+		assert.Len(t, byName, 2)
+		assert.Equal(t, "Elegant Wines", byName["Wines and Spirits"].Description)
+		assert.Equal(t, "Best cocoa produced chocolates", byName["Chocolates"].Description)
 	})
-	handler.ServeHTTP(rr, req)
+}
 
-	assert.Equal(t, 500, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Couldn't delete category")
+func TestDeleteCategory_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		rr := serve(handler, "POST", "/categories", `{"name": "Sneakers"}`)
+		var created models.Category
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+
+		rr = serve(handler, "DELETE", "/categories/"+created.ID.String(), "")
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), created.ID.String())
+		assert.Contains(t, rr.Body.String(), "Successfully deleted category")
+		assert.Equal(t, 404, serve(handler, "GET", "/categories/"+created.ID.String(), "").Code)
+	})
 }
 
 func TestDeleteCategory_CategoryNotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	categoryID := uuid.New()
-
-	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
-	WillReturnError(sql.ErrNoRows)
-
-	req, err := http.NewRequest("DELETE", 
-	fmt.Sprintf("/categories/%v", categoryID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	rr := httptest.NewRecorder()
+		rr := serve(handler, "DELETE", "/categories/"+uuid.New().String(), "")
 
-	handler := chi.NewRouter()
-	handler.Delete("/categories/{categoryId}", 
-	func(w http.ResponseWriter, r *http.Request){
-		cfg.DeleteCategoryController(w, r, adminUser)
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Category not found")
 	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Category not found")
-}
\ No newline at end of file
+}
@@ -11,8 +11,8 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
 	"github.com/ringtho/inventory/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -90,55 +90,43 @@ func TestCreateUserController(t *testing.T) {
 	assert.Equal(t, mockUser.ProfilePictureUrl, response.ProfilePictureUrl)
 }
 
-// Test the CreateUserController function with a duplicate user
-func TestCreateUserController_DuplicateUser(t *testing.T) {
+// Registered users join the default organisation as plain users
+func TestCreateUserController_JoinsDefaultOrg(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
-	queries := database.New(db)
-	apiCfg := ApiCfg{DB: queries}
-
-	mockUser := models.User{
-		Name:     "John Doe",
-		Username: "johndoe",
-		Email:    "johndoe@gmail.com",
-		Password: "StrongPass123",
-		Role:    "user",
-	}
+	apiCfg := ApiCfg{DB: database.New(db), DefaultOrg: "default"}
+	userID, orgID := uuid.New(), uuid.New()
 
 	mock.ExpectQuery(`INSERT INTO users`).
-	WithArgs(
-		sqlmock.AnyArg(), 
-		mockUser.Username, 
-		mockUser.Email, 
-		mockUser.Name, 
-		sqlmock.AnyArg(), 
-		mockUser.Role, 
-		sqlmock.AnyArg(), 
-		sqlmock.AnyArg(), 
-		sqlmock.AnyArg(),
-	).
-	WillReturnError(&pq.Error{Code: "23505"})
-
-	payload, _ := json.Marshal(mockUser)
-
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id","username","email","name","role","profile_picture_url","created_at","updated_at",
+		}).AddRow(userID, "janedoe", "janedoe@gmail.com", "Jane Doe", "user", nil, time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM organizations WHERE slug = \$1`).
+		WithArgs("default").
+		WillReturnRows(sqlmock.NewRows(organizationColumns).
+			AddRow(orgID, "Default", "default", time.Now(), time.Now()))
+	mock.ExpectQuery(`INSERT INTO organization_members`).
+		WithArgs(orgID, userID, "user", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(memberColumns).AddRow(orgID, userID, "user", time.Now()))
+
+	payload, _ := json.Marshal(models.User{
+		Name: "Jane Doe",
+		Username: "janedoe",
+		Email: "janedoe@gmail.com",
+		Password: "StrongPass123",
+	})
 	req, err := http.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(payload))
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(apiCfg.CreateUserController)
-	handler.ServeHTTP(rr, req)
+	http.HandlerFunc(apiCfg.CreateUserController).ServeHTTP(rr, req)
 
-	assert.Equal(t, http.StatusConflict, rr.Code)
-	assert.Contains(t, rr.Body.String(), 
-	"Email or Username already exists", 
-	"Expected the response body to contain the error message",
-	)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// Test the CreateUserController function with weak password
+// Test the CreateUserController function with a duplicate user
 func TestCreateUserController_WeakPassword(t *testing.T) {
 
 	db, _, err := sqlmock.New()
@@ -308,4 +296,21 @@ func TestCreateUserController_DBCreateError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Couldn't create user")
-}
\ No newline at end of file
+}
+
+// Test the CreateUserController function with a duplicate user
+func TestCreateUserController_DuplicateUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := http.HandlerFunc(ApiCfg{DB: db}.CreateUserController)
+		body := `{"name": "John Doe", "username": "johndoe", "email": "johndoe@gmail.com", "password": "StrongPass123"}`
+
+		assert.Equal(t, http.StatusCreated, serve(handler, "POST", "/api/v1/register", body).Code)
+		rr := serve(handler, "POST", "/api/v1/register", body)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(),
+			"Email or Username already exists",
+			"Expected the response body to contain the error message",
+		)
+	})
+}
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -12,50 +14,11 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/models"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestDeleteUserController_Success(t *testing.T){
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	apiCfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	userId := uuid.New()
-
-	mockUser := sqlmock.NewRows([]string{
-		"id", "created_at", "updated_at", "username", "email", "password", "role", "profile_picture_url", "name",
-		}).
-        AddRow(
-			userId, time.Now(), time.Now(), "username", "user@example.com", "hashedPassword", "admin", nil, "User Name",
-		)
-
-	mock.ExpectQuery(`SELECT (.*) FROM users WHERE id = \$1`).
-	WithArgs(userId).
-	WillReturnRows(mockUser)
-
-	mock.ExpectExec(`DELETE FROM users WHERE id = \$1 AND role != 'admin'`).
-	WithArgs(userId).
-	WillReturnResult(sqlmock.NewResult(1,1))
-
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/users/%v", userId), nil)
-	assert.NoError(t, err)
-
-	rr := httptest.NewRecorder()
-	router := chi.NewRouter()
-	router.Delete("/users/{userId}", func(w http.ResponseWriter, r *http.Request) {
-		apiCfg.DeleteUserController(w, r, adminUser)
-	})
-	router.ServeHTTP(rr, req)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Contains(t, rr.Body.String(), userId.String())
-}
-
 func TestDeleteUserController_InvalidUUID(t *testing.T){
 	db,_,err := sqlmock.New()
 	assert.NoError(t, err)
@@ -81,35 +44,6 @@ func TestDeleteUserController_InvalidUUID(t *testing.T){
 	assert.Contains(t, rr.Body.String(), "Couldn't parse userId")
 }
 
-func TestDeleteUserController_UserNotFound(t *testing.T){
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	apiCfg := ApiCfg{DB: queries}
-
-	userId := uuid.New()
-	adminUser := database.User{Role: "admin"}
-
-	mock.ExpectQuery(`SELECT (.*) FROM users WHERE id = \$1`). 
-	WithArgs(userId). 
-	WillReturnError(sql.ErrNoRows)
-
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/users/%v", userId), nil)
-	assert.NoError(t, err)
-
-	rr := httptest.NewRecorder()
-	router := chi.NewRouter()
-	router.Delete("/users/{userId}", func(w http.ResponseWriter, r *http.Request) {
-		apiCfg.DeleteUserController(w, r, adminUser)
-	})
-	router.ServeHTTP(rr, req)
-
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "User not found")
-}
-
 func TestDeleteUserController_DBError(t *testing.T){
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -151,10 +85,10 @@ func TestDeleteUserController_DBDeleteErr(t *testing.T){
 	adminUser := database.User{Role: "admin"}
 
 	mockUser := sqlmock.NewRows([]string{
-		"id", "created_at", "updated_at", "username", "email", "password", "role", "profile_picture_url", "name",
+		"id", "created_at", "updated_at", "username", "email", "password", "role", "profile_picture_url", "name", "email_verified_at",
 		}).
         AddRow(
-			userId, time.Now(), time.Now(), "username", "user@example.com", "hashedPassword", "admin", nil, "User Name",
+			userId, time.Now(), time.Now(), "username", "user@example.com", "hashedPassword", "admin", nil, "User Name", time.Now(),
 		)
 
 	mock.ExpectQuery(`SELECT (.*) FROM users WHERE id = \$1`).
@@ -177,4 +111,41 @@ func TestDeleteUserController_DBDeleteErr(t *testing.T){
 	
 	assert.Equal(t, 500, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Failed to delete user")
-}
\ No newline at end of file
+}
+
+func TestDeleteUserController_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		apiCfg := ApiCfg{DB: db}
+		router := chi.NewRouter()
+		router.Post("/register", apiCfg.CreateUserController)
+		router.Delete("/users/{userId}", func(w http.ResponseWriter, r *http.Request) {
+			apiCfg.DeleteUserController(w, r, database.User{Role: "admin"})
+		})
+		rr := serve(router, "POST", "/register",
+			`{"name": "User Name", "username": "username", "email": "user@example.com", "password": "StrongPass123"}`)
+		var created models.UserResponse
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+
+		rr = serve(router, "DELETE", "/users/"+created.ID.String(), "")
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), created.ID.String())
+		_, err := db.GetUserById(context.Background(), created.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestDeleteUserController_UserNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		apiCfg := ApiCfg{DB: db}
+		router := chi.NewRouter()
+		router.Delete("/users/{userId}", func(w http.ResponseWriter, r *http.Request) {
+			apiCfg.DeleteUserController(w, r, database.User{Role: "admin"})
+		})
+
+		rr := serve(router, "DELETE", "/users/"+uuid.New().String(), "")
+
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "User not found")
+	})
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/mailer"
+)
+
+type resendVerificationParams struct {
+	Email string `json:"email"`
+}
+
+const resendVerificationMessage = "If that email belongs to an unverified account, a verification link has been sent to it"
+
+// mailer returns the configured mailer, falling back to logging emails so
+// a missing mailer never fails a request
+func (cfg ApiCfg) mailer() mailer.Mailer {
+	if cfg.Mailer == nil {
+		return mailer.LogMailer{}
+	}
+	return cfg.Mailer
+}
+
+// publicURL is the base URL links in emails point to. Falling back to the
+// request's host is only for the log mailer, configuration requires
+// PUBLIC_URL once emails really go out.
+func (cfg ApiCfg) publicURL(r *http.Request) string {
+	if cfg.PublicURL != "" {
+		return cfg.PublicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+// sendVerificationEmail stores a new verification token for the user and
+// emails them a link to GET /auth/verify with it
+func (cfg ApiCfg) sendVerificationEmail(r *http.Request, userId uuid.UUID, email string) error {
+	token, hash, err := helpers.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = cfg.DB.CreateEmailVerificationToken(r.Context(), database.CreateEmailVerificationTokenParams{
+		ID: uuid.New(),
+		UserID: userId,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(helpers.EmailVerificationTTL()),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/auth/verify?token=%s", cfg.publicURL(r), url.QueryEscape(token))
+	return cfg.mailer().Send(r.Context(), mailer.Message{
+		To: email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Follow this link to verify your email address: %s\n\nIt expires in %v.",
+			link, helpers.EmailVerificationTTL()),
+	})
+}
+
+// revokeEmailTokens stops the verification and password reset links sent
+// to a user's old email address from working once it has changed
+func (cfg ApiCfg) revokeEmailTokens(r *http.Request, userId uuid.UUID, now time.Time) error {
+	usedAt := sql.NullTime{Time: now, Valid: true}
+	err := cfg.DB.RevokeUserEmailVerificationTokens(r.Context(), database.RevokeUserEmailVerificationTokensParams{
+		UserID: userId,
+		UsedAt: usedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.DB.RevokeUserPasswordResetTokens(r.Context(), database.RevokeUserPasswordResetTokensParams{
+		UserID: userId,
+		UsedAt: usedAt,
+	})
+}
+
+// VerifyEmailController marks the user's email as verified using the token
+// from the link sent by sendVerificationEmail
+func (cfg ApiCfg) VerifyEmailController(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		helpers.RespondWithError(w, 400, "Token is required")
+		return
+	}
+
+	verification, err := cfg.DB.GetEmailVerificationTokenByHash(r.Context(), helpers.HashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, "Invalid or expired verification token")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch verification token: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	if verification.UsedAt.Valid || now.After(verification.ExpiresAt) {
+		helpers.RespondWithError(w, 400, "Invalid or expired verification token")
+		return
+	}
+
+	used, err := cfg.DB.UseEmailVerificationToken(r.Context(), database.UseEmailVerificationTokenParams{
+		ID: verification.ID,
+		UsedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't use verification token: %v", err))
+		return
+	}
+	if used == 0 {
+		helpers.RespondWithError(w, 400, "Invalid or expired verification token")
+		return
+	}
+
+	err = cfg.DB.SetUserEmailVerified(r.Context(), database.SetUserEmailVerifiedParams{
+		ID: verification.UserID,
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't verify email: %v", err))
+		return
+	}
+
+	cfg.recordAudit(r, database.User{ID: verification.UserID}, auditVerifyEmail, "user",
+		verification.UserID, nil, nil)
+	helpers.TextResponse(w, 200, "Email address has been verified")
+}
+
+// ResendVerificationController sends a new verification link. Only one
+// email per VerificationResendDelay is sent for an account, and like
+// ForgotPasswordController it answers 200 whether or not the account
+// exists, is verified or was emailed recently.
+func (cfg ApiCfg) ResendVerificationController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	params := resendVerificationParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Email == "" {
+		helpers.RespondWithError(w, 400, "Email is required")
+		return
+	}
+
+	user, err := cfg.DB.GetUserByEmail(r.Context(), params.Email)
+	if err != nil || user.EmailVerifiedAt.Valid {
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Couldn't fetch user for verification resend: %v", err)
+		}
+		helpers.TextResponse(w, 200, resendVerificationMessage)
+		return
+	}
+
+	// A different answer while throttled would tell an unverified account
+	// apart from a missing one
+	latest, err := cfg.DB.GetLatestEmailVerificationToken(r.Context(), user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Couldn't fetch verification token for %v: %v", user.ID, err)
+		helpers.TextResponse(w, 200, resendVerificationMessage)
+		return
+	}
+	if err == nil && time.Now().UTC().Before(latest.CreatedAt.Add(helpers.VerificationResendDelay())) {
+		helpers.TextResponse(w, 200, resendVerificationMessage)
+		return
+	}
+
+	if err := cfg.sendVerificationEmail(r, user.ID, user.Email); err != nil {
+		log.Printf("Couldn't send verification email to %v: %v", user.Email, err)
+	}
+	helpers.TextResponse(w, 200, resendVerificationMessage)
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+var emailVerificationColumns = []string{
+	"id", "user_id", "token_hash", "created_at", "expires_at", "used_at",
+}
+
+func TestCreateUser_SendsVerificationEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mail := &fakeMailer{}
+	cfg := ApiCfg{DB: database.New(db), Mailer: mail, PublicURL: "https://api.example.com"}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`INSERT INTO users`).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "username", "email", "name", "role", "profile_picture_url", "created_at", "updated_at",
+		}).AddRow(userId, "johndoe", "johndoe@gmail.com", "john doe", "user", nil, time.Now(), time.Now()))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO email_verification_tokens`).
+		WithArgs(sqlmock.AnyArg(), userId, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.CreateUserController).ServeHTTP(rr,
+		jsonRequest(t, "/register", map[string]string{
+			"name": "john doe",
+			"username": "johndoe",
+			"email": "johndoe@gmail.com",
+			"password": "StrongPass123",
+		}))
+
+	assert.Equal(t, 201, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, len(mail.sent))
+	assert.Equal(t, "johndoe@gmail.com", mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Body, "https://api.example.com/api/v1/auth/verify?token=")
+}
+
+func TestVerifyEmail_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	token := "verify-token"
+	tokenId, userId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM email_verification_tokens WHERE token_hash = \$1`).
+		WithArgs(helpers.HashToken(token)).
+		WillReturnRows(sqlmock.NewRows(emailVerificationColumns).
+			AddRow(tokenId, userId, helpers.HashToken(token), time.Now(), time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`UPDATE email_verification_tokens SET used_at = \$2 WHERE id = \$1 AND used_at IS NULL`).
+		WithArgs(tokenId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET email_verified_at = \$2`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("GET", "/auth/verify?token="+token, nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.VerifyEmailController).ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyEmail_ExpiredToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	mock.ExpectQuery(`SELECT (.+) FROM email_verification_tokens WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(emailVerificationColumns).
+			AddRow(uuid.New(), uuid.New(), "hash", time.Now().Add(-48*time.Hour), time.Now().Add(-time.Hour), nil))
+
+	req, err := http.NewRequest("GET", "/auth/verify?token=verify-token", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.VerifyEmailController).ServeHTTP(rr, req)
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid or expired verification token")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyEmail_MissingToken(t *testing.T) {
+	cfg := ApiCfg{}
+
+	req, err := http.NewRequest("GET", "/auth/verify", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.VerifyEmailController).ServeHTTP(rr, req)
+
+	assert.Equal(t, 400, rr.Code)
+}
+
+func TestResendVerification_RateLimitedLooksTheSame(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mail := &fakeMailer{}
+	cfg := ApiCfg{DB: database.New(db), Mailer: mail}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WithArgs("johndoe@gmail.com").
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", nil))
+	mock.ExpectQuery(`SELECT (.+) FROM email_verification_tokens\s+WHERE user_id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(emailVerificationColumns).
+			AddRow(uuid.New(), userId, "hash", time.Now().UTC(), time.Now().Add(time.Hour), nil))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ResendVerificationController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/verify/resend", map[string]string{"email": "johndoe@gmail.com"}))
+
+	// Same answer as for an unknown email, so it can't be used to find accounts
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), resendVerificationMessage)
+	assert.Empty(t, rr.Header().Get("Retry-After"))
+	assert.Empty(t, mail.sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResendVerification_SendsAfterDelay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mail := &fakeMailer{}
+	cfg := ApiCfg{DB: database.New(db), Mailer: mail}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", nil))
+	mock.ExpectQuery(`SELECT (.+) FROM email_verification_tokens\s+WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(emailVerificationColumns).
+			AddRow(uuid.New(), userId, "hash", time.Now().UTC().Add(-time.Hour), time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`INSERT INTO email_verification_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ResendVerificationController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/verify/resend", map[string]string{"email": "johndoe@gmail.com"}))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, 1, len(mail.sent))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLogin_UnverifiedEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	password := "StrongPass123"
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(uuid.New(), time.Now(), time.Now(), "johndoe", "johndoe@gmail.com",
+				helpers.HashPassword(password), "user", nil, "john doe", nil))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	cfg := ApiCfg{DB: database.New(db)}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.LoginController).ServeHTTP(rr,
+		jsonRequest(t, "/login", map[string]string{"email": "johndoe@gmail.com", "password": password}))
+
+	assert.Equal(t, 403, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Email address has not been verified")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
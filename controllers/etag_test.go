@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProduct_ReturnsETag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	productId := uuid.New()
+	updatedAt := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, nil, updatedAt, updatedAt, nil, uuid.Nil))
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/products/%v", productId), nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Get("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetProductController(w, r, database.User{})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, helpers.ETag(updatedAt), rr.Header().Get("ETag"))
+}
+
+func TestGetProduct_NotModified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	productId := uuid.New()
+	updatedAt := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, nil, updatedAt, updatedAt, nil, uuid.Nil))
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/products/%v", productId), nil)
+	assert.NoError(t, err)
+	req.Header.Set("If-None-Match", helpers.ETag(updatedAt))
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Get("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetProductController(w, r, database.User{})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 304, rr.Code)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestUpdateProduct_StaleIfMatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{Role: "admin"}
+	productId := uuid.New()
+	updatedAt := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, nil, updatedAt, updatedAt, nil, uuid.Nil))
+
+	req, err := http.NewRequest("PUT", fmt.Sprintf("/products/%v", productId),
+		bytes.NewBufferString(`{"name": "Microwave", "price": 45000}`))
+	assert.NoError(t, err)
+	req.Header.Set("If-Match", helpers.ETag(updatedAt.Add(-time.Minute)))
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Put("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.UpdateProductController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 412, rr.Code)
+	assert.Equal(t, helpers.ETag(updatedAt), rr.Header().Get("ETag"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteSupplier_MatchingIfMatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{Role: "admin"}
+	supplierId := uuid.New()
+	updatedAt := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM suppliers WHERE id=\$1`).
+		WithArgs(supplierId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "email", "description", "phone", "country", "created_at", "updated_at", "deleted_at", "org_id",
+		}).AddRow(supplierId, "Acme", nil, nil, nil, nil, updatedAt, updatedAt, nil, uuid.Nil))
+	mock.ExpectExec(`UPDATE suppliers SET deleted_at = \$3 WHERE id=\$1`).
+		WithArgs(supplierId, uuid.Nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("/suppliers/%v", supplierId), nil)
+	assert.NoError(t, err)
+	req.Header.Set("If-Match", helpers.ETag(updatedAt))
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Delete("/suppliers/{supplierId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.DeleteSupplierController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCategory_IfMatchRequired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db), RequireIfMatch: true}
+	adminUser := database.User{Role: "admin"}
+	categoryId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
+		WithArgs(categoryId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "created_at", "updated_at", "name", "description", "created_by", "deleted_at", "org_id",
+		}).AddRow(categoryId, time.Now(), time.Now(), "Kitchen", nil, uuid.New(), nil, uuid.Nil))
+
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("/categories/%v", categoryId), nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Delete("/categories/{categoryId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.DeleteCategoryController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 428, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// racingStore lets another request in when the next product write reaches
+// the store, after the handler has checked If-Match
+type racingStore struct {
+	store.Store
+	race func()
+}
+
+func (s *racingStore) runRace() {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+}
+
+func (s *racingStore) UpdateProduct(ctx context.Context, arg database.UpdateProductParams) (database.Product, error) {
+	s.runRace()
+	return s.Store.UpdateProduct(ctx, arg)
+}
+
+func (s *racingStore) SoftDeleteProduct(ctx context.Context, arg database.SoftDeleteProductParams) (int64, error) {
+	s.runRace()
+	return s.Store.SoftDeleteProduct(ctx, arg)
+}
+
+func TestProduct_ConcurrentIfMatch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		racing := &racingStore{Store: db}
+		handler := inventoryRouter(ApiCfg{DB: racing}, memory.DefaultOrgID)
+		other := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+
+		rr := serve(handler, "POST", "/products", `{"name": "Microwave", "price": 50000}`)
+		assert.Equal(t, 201, rr.Code)
+		var created models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+		path := "/products/" + created.ID.String()
+
+		ifMatch := func(handler http.Handler, etag, method, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", etag)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			return rr
+		}
+
+		// Both requests pass the If-Match check with the same ETag, and
+		// only the one that writes first wins
+		etag := serve(handler, "GET", path, "").Header().Get("ETag")
+		var first *httptest.ResponseRecorder
+		racing.race = func() {
+			first = ifMatch(other, etag, "PUT", `{"name": "Grill", "price": 60000}`)
+		}
+		rr = ifMatch(handler, etag, "PUT", `{"name": "Toaster", "price": 40000}`)
+		assert.Equal(t, 200, first.Code)
+		assert.Equal(t, 412, rr.Code)
+		assert.Contains(t, serve(handler, "GET", path, "").Body.String(), "Grill")
+
+		etag = serve(handler, "GET", path, "").Header().Get("ETag")
+		racing.race = func() {
+			first = ifMatch(other, etag, "PUT", `{"name": "Kettle", "price": 3000}`)
+		}
+		rr = ifMatch(handler, etag, "DELETE", "")
+		assert.Equal(t, 200, first.Code)
+		assert.Equal(t, 412, rr.Code)
+		rr = serve(handler, "GET", path, "")
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Kettle")
+	})
+}
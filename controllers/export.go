@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ringtho/inventory/helpers"
+)
+
+// exportStallTimeout is how long an export may go without producing a row
+// before the server's write deadline cuts it off. Exports outlast
+// HTTP_WRITE_TIMEOUT, so the deadline is moved along as rows go out.
+const exportStallTimeout = time.Minute
+
+// exportList streams the rows produced by iterate as CSV or XLSX when the
+// client asked for an export, and reports whether the request was handled
+func exportList[T any](
+	w http.ResponseWriter,
+	r *http.Request,
+	filename string,
+	columns []helpers.ExportColumn[T],
+	iterate func(context.Context, func(T) error) error,
+) bool {
+	format, err := helpers.ExportFormat(r)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't export %s: %v", filename, err))
+		return true
+	}
+	if format == "" {
+		return false
+	}
+
+	columns, err = helpers.SelectColumns(columns, r.URL.Query().Get("fields"))
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't export %s: %v", filename, err))
+		return true
+	}
+
+	// Recorders and other writers without a deadline don't need one moved
+	rc := http.NewResponseController(w)
+	extended := time.Now()
+	_ = rc.SetWriteDeadline(extended.Add(exportStallTimeout))
+
+	helpers.Export(w, format, filename, columns, func(fn func(T) error) error {
+		return iterate(r.Context(), func(row T) error {
+			if now := time.Now(); now.Sub(extended) > time.Second {
+				extended = now
+				_ = rc.SetWriteDeadline(now.Add(exportStallTimeout))
+			}
+			return fn(row)
+		})
+	})
+	return true
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockProductRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
+	}).
+		AddRow(uuid.New(), "Microwave", "20 litres", 50000, 3, nil, nil, "MC-20L", time.Now(), time.Now(), nil, uuid.Nil).
+		AddRow(uuid.New(), "Kettle, steel", nil, 12000, nil, nil, nil, nil, time.Now(), time.Now(), nil, uuid.Nil)
+}
+
+func runExportRequest(t *testing.T, cfg ApiCfg, url, accept string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Get("/products", func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetAllProductsController(w, r, database.User{})
+	})
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExportProducts_CSVFromAcceptHeader(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	mock.ExpectQuery(`SELECT (.+) FROM products`).WillReturnRows(mockProductRows())
+
+	rr := runExportRequest(t, cfg, "/products", "text/csv")
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), `filename="products.csv"`)
+
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "name", records[0][1])
+	assert.Equal(t, "Microwave", records[1][1])
+	assert.Equal(t, "Kettle, steel", records[2][1])
+	assert.Equal(t, "", records[2][4])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportProducts_SelectedFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	mock.ExpectQuery(`SELECT (.+) FROM products`).WillReturnRows(mockProductRows())
+
+	rr := runExportRequest(t, cfg, "/products?format=csv&fields=sku,price", "")
+
+	assert.Equal(t, 200, rr.Code)
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sku", "price"}, records[0])
+	assert.Equal(t, []string{"MC-20L", "50000"}, records[1])
+}
+
+func TestExportProducts_XLSX(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	mock.ExpectQuery(`SELECT (.+) FROM products`).WillReturnRows(mockProductRows())
+
+	rr := runExportRequest(t, cfg, "/products?format=xlsx", "")
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t,
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		rr.Header().Get("Content-Type"),
+	)
+
+	body := rr.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	assert.NoError(t, err)
+
+	var sheet []byte
+	for _, f := range archive.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := f.Open()
+			assert.NoError(t, err)
+			sheet, err = io.ReadAll(rc)
+			assert.NoError(t, err)
+			rc.Close()
+		}
+	}
+	assert.Contains(t, string(sheet), "Microwave")
+	assert.Contains(t, string(sheet), `<c r="D2"><v>50000</v></c>`)
+}
+
+func TestExportProducts_EscapesFormulas(t *testing.T) {
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
+		}).AddRow(uuid.New(), `=HYPERLINK("https://evil.example","click")`, "@SUM(A1)", -500, 3, nil, nil, "-MC", time.Now(), time.Now(), nil, uuid.Nil)
+	}
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	mock.ExpectQuery(`SELECT (.+) FROM products`).WillReturnRows(rows())
+	mock.ExpectQuery(`SELECT (.+) FROM products`).WillReturnRows(rows())
+
+	rr := runExportRequest(t, cfg, "/products?format=csv&fields=name,description,price,sku", "")
+	assert.Equal(t, 200, rr.Code)
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`'=HYPERLINK("https://evil.example","click")`, "'@SUM(A1)", "-500", "'-MC"}, records[1])
+
+	rr = runExportRequest(t, cfg, "/products?format=xlsx&fields=name,price", "")
+	assert.Equal(t, 200, rr.Code)
+	body := rr.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	assert.NoError(t, err)
+	var sheet []byte
+	for _, f := range archive.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := f.Open()
+			assert.NoError(t, err)
+			sheet, err = io.ReadAll(rc)
+			assert.NoError(t, err)
+			rc.Close()
+		}
+	}
+	assert.Contains(t, string(sheet), `<t xml:space="preserve">&#39;=HYPERLINK(`)
+	assert.Contains(t, string(sheet), `<c r="B2"><v>-500</v></c>`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportProducts_UnknownField(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	rr := runExportRequest(t, cfg, "/products?format=csv&fields=password", "")
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unknown field")
+}
+
+func TestExportProducts_UnsupportedFormat(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	rr := runExportRequest(t, cfg, "/products?format=pdf", "")
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unsupported export format")
+}
+
+func TestExport_OutlastsWriteTimeout(t *testing.T) {
+	columns := []helpers.ExportColumn[int]{
+		{Name: "n", Numeric: true, Value: func(n int) string { return strconv.Itoa(n) }},
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exportList(w, r, "numbers", columns, func(ctx context.Context, fn func(int) error) error {
+			for n := 0; n < 5; n++ {
+				time.Sleep(60 * time.Millisecond)
+				if err := fn(n); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "?format=csv")
+	assert.NoError(t, err)
+	defer response.Body.Close()
+
+	records, err := csv.NewReader(response.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 6, "the export is sent in full")
+}
@@ -1,17 +1,19 @@
 package controllers
 
 import (
-	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
-	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
+	"github.com/ringtho/inventory/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,7 +31,7 @@ func TestGetCategory_DBError(t *testing.T) {
 	categoryID := uuid.New()
 
 	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
+	WithArgs(categoryID, uuid.Nil).
 	WillReturnError(fmt.Errorf("Database Error"))
 
 	req, err := http.NewRequest("GET", 
@@ -51,83 +53,26 @@ func TestGetCategory_DBError(t *testing.T) {
 }
 
 func TestGetCategory_Success(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	categoryID := uuid.New()
-	mockCategory := parameters{
-		Name: "Sneakers",
-		Description: new(string),
-		CreatedBy: uuid.New(),
-	}
-
-	mockData := sqlmock.NewRows([]string{
-		"id","created_at", "updated_at","name","description","created_by",
-	}).AddRow(
-		categoryID,
-		time.Now().UTC(),
-		time.Now().UTC(),
-		mockCategory.Name,
-		mockCategory.Description,
-		mockCategory.CreatedBy,
-	)
-	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
-	WillReturnRows(mockData)
-
-	req, err := http.NewRequest("GET", 
-	fmt.Sprintf("/categories/%v", categoryID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		rr := serve(handler, "POST", "/categories", `{"name": "Sneakers"}`)
+		var created models.Category
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
 
-	rr := httptest.NewRecorder()
+		rr = serve(handler, "GET", "/categories/"+created.ID.String(), "")
 
-	handler := chi.NewRouter()
-	handler.Get("/categories/{categoryId}", 
-	func(w http.ResponseWriter, r *http.Request){
-		cfg.GetCategoryController(w, r, adminUser)
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Sneakers")
 	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 200, rr.Code)
 }
 
 func TestGetCategory_NotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	categoryID := uuid.New()
-
-	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
-	WithArgs(categoryID).
-	WillReturnError(sql.ErrNoRows)
-
-	req, err := http.NewRequest("GET", 
-	fmt.Sprintf("/categories/%v", categoryID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	rr := httptest.NewRecorder()
+		rr := serve(handler, "GET", "/categories/"+uuid.New().String(), "")
 
-	handler := chi.NewRouter()
-	handler.Get("/categories/{categoryId}", 
-	func(w http.ResponseWriter, r *http.Request){
-		cfg.GetCategoryController(w, r, adminUser)
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Category not found")
 	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Category not found")
-}
\ No newline at end of file
+}
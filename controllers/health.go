@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ringtho/inventory/db"
+	"github.com/ringtho/inventory/helpers"
+)
+
+// HealthCfg answers the probes an orchestrator uses to decide whether to
+// restart the server and whether to send it traffic
+type HealthCfg struct {
+	// Conn is the database readiness is checked against and whose pool
+	// stats are exported at /metrics, nothing is checked when it's nil
+	Conn *sql.DB
+
+	shuttingDown atomic.Bool
+}
+
+// ShuttingDown makes the server report that it isn't ready, so traffic
+// moves elsewhere while requests in flight finish
+func (h *HealthCfg) ShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// HealthzController reports that the process is up and serving requests
+func (h *HealthCfg) HealthzController(w http.ResponseWriter, r *http.Request) {
+	helpers.JSON(w, 200, map[string]string{"status": "ok"})
+}
+
+// readinessTimeout bounds the checks so a hung database fails the probe
+// instead of holding it open
+const readinessTimeout = 2 * time.Second
+
+// ReadyzController reports whether the server can handle requests: the
+// database answers, every migration has been applied and it isn't shutting
+// down. The reasons are kept short since the endpoint isn't authenticated,
+// and the errors behind them are logged.
+func (h *HealthCfg) ReadyzController(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{}
+	ready := true
+	fail := func(check, reason string) {
+		checks[check] = reason
+		ready = false
+	}
+
+	if h.shuttingDown.Load() {
+		fail("shutdown", "shutting down")
+	} else {
+		checks["shutdown"] = "ok"
+	}
+
+	if h.Conn != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+		if err := h.Conn.PingContext(ctx); err != nil {
+			log.Printf("Readiness: database ping failed: %v", err)
+			fail("database", "unreachable")
+			fail("migrations", "not checked")
+		} else {
+			checks["database"] = "ok"
+			if pending, err := hasPendingMigrations(ctx, h.Conn); err != nil {
+				log.Printf("Readiness: couldn't read the schema version: %v", err)
+				fail("migrations", "unknown")
+			} else if pending {
+				fail("migrations", "pending")
+			} else {
+				checks["migrations"] = "ok"
+			}
+		}
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}{Status: "ready", Checks: checks}
+	if !ready {
+		response.Status = "not ready"
+		helpers.JSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+	helpers.JSON(w, 200, response)
+}
+
+func hasPendingMigrations(ctx context.Context, conn *sql.DB) (bool, error) {
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return false, err
+	}
+	return migrator.HasPending(ctx)
+}
+
+// BuildTime is when the binary was built. Go doesn't record it, so it's
+// set with
+//
+//	go build -ldflags "-X github.com/ringtho/inventory/controllers.BuildTime=$(date -u +%FT%TZ)"
+var BuildTime string
+
+// BuildInfo describes the binary that's running
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	CommitTime string `json:"commit_time"`
+	// Modified is true when the binary was built with uncommitted changes
+	Modified  bool   `json:"modified"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// buildInfo reads the version control details Go stamps into binaries
+// built from a checkout. They're empty under go run and go test.
+var buildInfo = sync.OnceValue(func() BuildInfo {
+	build := BuildInfo{BuildTime: BuildTime}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	build.Version = info.Main.Version
+	build.GoVersion = info.GoVersion
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Commit = setting.Value
+		case "vcs.time":
+			build.CommitTime = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+})
+
+// VersionController reports which build is running
+func (h *HealthCfg) VersionController(w http.ResponseWriter, r *http.Request) {
+	helpers.JSON(w, 200, buildInfo())
+}
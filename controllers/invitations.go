@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/mailer"
+	"github.com/ringtho/inventory/models"
+)
+
+type createInvitationParams struct {
+	Email string     `json:"email"`
+	Role  string     `json:"role"`
+	OrgID *uuid.UUID `json:"org_id"`
+}
+
+type acceptInvitationParams struct {
+	Token             string  `json:"token"`
+	Name              string  `json:"name"`
+	Username          string  `json:"username"`
+	Password          string  `json:"password"`
+	ProfilePictureUrl *string `json:"profile_picture_url"`
+}
+
+const invalidInvitationMessage = "Invalid or expired invitation"
+
+// CreateInvitationController invites someone to create an account with the
+// given role. The token is emailed to them and also returned so it can be
+// handed over another way. Inviting with any role but the default takes
+// roles:manage, the same as changing a user's role. The invitee joins the
+// organisation in org_id with the role, by default the inviter's active
+// one. Naming another organisation takes organizations:manage.
+func (cfg ApiCfg) CreateInvitationController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := createInvitationParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	params.Email = strings.TrimSpace(params.Email)
+	if params.Email == "" || params.Role == "" {
+		helpers.RespondWithError(w, 400, "Email and Role are required")
+		return
+	}
+
+	if !helpers.IsValidEmail(params.Email) {
+		helpers.RespondWithError(w, 400, "Invalid email address")
+		return
+	}
+
+	// users:write alone mustn't let a role invite new admins
+	if params.Role != defaultRole && !auth.HasPermission(r.Context(), auth.RolesManage) {
+		helpers.RespondWithError(w, 403, "Inviting with a role other than user requires the roles:manage permission")
+		return
+	}
+
+	orgId := uuid.NullUUID{}
+	if activeOrg := auth.OrgID(r.Context()); activeOrg != uuid.Nil {
+		orgId = uuid.NullUUID{UUID: activeOrg, Valid: true}
+	}
+	if params.OrgID != nil && *params.OrgID != orgId.UUID {
+		if !auth.HasPermission(r.Context(), auth.OrganizationsManage) {
+			helpers.RespondWithError(w, 403,
+				"Inviting into another organisation requires the organizations:manage permission")
+			return
+		}
+		if _, err := cfg.DB.GetOrganizationById(r.Context(), *params.OrgID); err != nil {
+			if err == sql.ErrNoRows {
+				helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown organisation: %v", *params.OrgID))
+				return
+			}
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisation: %v", err))
+			return
+		}
+		orgId = uuid.NullUUID{UUID: *params.OrgID, Valid: true}
+	}
+
+	if _, err := cfg.DB.GetRole(r.Context(), params.Role); err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown role: %v", params.Role))
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch role: %v", err))
+		return
+	}
+
+	_, err = cfg.DB.GetUserByEmail(r.Context(), params.Email)
+	if err == nil {
+		helpers.RespondWithError(w, 409, "A user with that email already exists")
+		return
+	}
+	if err != sql.ErrNoRows {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch user: %v", err))
+		return
+	}
+
+	token, hash, err := helpers.GenerateToken()
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate invitation token: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	invitation, err := cfg.DB.CreateInvitation(r.Context(), database.CreateInvitationParams{
+		ID: uuid.New(),
+		Email: params.Email,
+		Role: params.Role,
+		TokenHash: hash,
+		InvitedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+		CreatedAt: now,
+		ExpiresAt: now.Add(helpers.InvitationTTL()),
+		OrgID: orgId,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create invitation: %v", err))
+		return
+	}
+
+	err = cfg.mailer().Send(r.Context(), mailer.Message{
+		To: invitation.Email,
+		Subject: "You have been invited to the Inventory API",
+		Body: cfg.invitationBody(token),
+	})
+	if err != nil {
+		log.Printf("Couldn't send invitation email to %v: %v", invitation.Email, err)
+	}
+
+	created := models.DatabaseInvitationToInvitation(invitation)
+	cfg.recordAudit(r, user, auditCreate, "invitation", invitation.ID, nil, created)
+
+	created.Token = token
+	helpers.JSON(w, 201, created)
+}
+
+func (cfg ApiCfg) invitationBody(token string) string {
+	expiry := helpers.InvitationTTL()
+	if cfg.InvitationURL == "" {
+		return fmt.Sprintf(
+			"Use this token to create your account: %s\n\nIt expires in %v.", token, expiry)
+	}
+	return fmt.Sprintf(
+		"Follow this link to create your account: %s?token=%s\n\nIt expires in %v.",
+		cfg.InvitationURL, url.QueryEscape(token), expiry)
+}
+
+// GetInvitationsController lists the invitations that can still be accepted
+func (cfg ApiCfg) GetInvitationsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	invitations, err := cfg.DB.GetPendingInvitations(r.Context(), time.Now().UTC())
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch invitations: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseInvitationsToInvitations(invitations))
+}
+
+// DeleteInvitationController revokes an invitation that hasn't been accepted
+func (cfg ApiCfg) DeleteInvitationController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "invitationId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse invitationId: %v", err))
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteInvitation(r.Context(), id)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't delete invitation: %v", err))
+		return
+	}
+	if deleted == 0 {
+		helpers.RespondWithError(w, 404, "Invitation not found")
+		return
+	}
+
+	cfg.recordAudit(r, user, auditDelete, "invitation", id, nil, nil)
+	helpers.TextResponse(w, 200, fmt.Sprintf("Successfully deleted invitation: %v", id))
+}
+
+// AcceptInvitationController creates the invited account and adds it to
+// the invitation's organisation with the invited role. It works when
+// public registration is disabled, and the email counts as verified since
+// the token could only have come from it or from an admin.
+func (cfg ApiCfg) AcceptInvitationController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	params := acceptInvitationParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Token == "" || params.Name == "" || params.Username == "" || params.Password == "" {
+		helpers.RespondWithError(w, 400, "Token, Name, Username and Password are required")
+		return
+	}
+
+	if !helpers.IsStrongPassword(params.Password) {
+		helpers.RespondWithError(w, 400, "Password is not strong enough")
+		return
+	}
+
+	invitation, err := cfg.DB.GetInvitationByHash(r.Context(), helpers.HashToken(params.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, invalidInvitationMessage)
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch invitation: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	if invitation.AcceptedAt.Valid || now.After(invitation.ExpiresAt) {
+		helpers.RespondWithError(w, 400, invalidInvitationMessage)
+		return
+	}
+
+	user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name: params.Name,
+		Username: params.Username,
+		Email: invitation.Email,
+		Password: helpers.HashPassword(params.Password),
+		Role: invitation.Role,
+		ProfilePictureUrl: helpers.NewNullString(params.ProfilePictureUrl),
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" {
+				helpers.RespondWithError(w, 409, "Email or Username already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create user: %v", err))
+		return
+	}
+
+	if err := cfg.joinOrganization(r.Context(), user.ID, invitation.OrgID, invitation.Role); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't add organisation member: %v", err))
+		return
+	}
+
+	// The unique email stops a second account being made from the same
+	// invitation, so it's only marked accepted once the user exists. A taken
+	// username leaves the invitation usable for another try.
+	_, err = cfg.DB.AcceptInvitation(r.Context(), database.AcceptInvitationParams{
+		ID: invitation.ID,
+		AcceptedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't accept invitation: %v", err))
+		return
+	}
+
+	err = cfg.DB.SetUserEmailVerified(r.Context(), database.SetUserEmailVerifiedParams{
+		ID: user.ID,
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't verify email: %v", err))
+		return
+	}
+
+	created := models.DatabaseUserToUserResponse(user)
+	created.EmailVerifiedAt = &now
+	cfg.recordAudit(r, database.User{ID: user.ID, Email: user.Email},
+		auditCreate, "user", user.ID, nil, created)
+	helpers.JSON(w, 201, created)
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var invitationColumns = []string{
+	"id", "email", "role", "token_hash", "invited_by", "created_at", "expires_at", "accepted_at", "org_id",
+}
+
+var createUserRowColumns = []string{
+	"id", "username", "email", "name", "role", "profile_picture_url", "created_at", "updated_at",
+}
+
+func TestCreateInvitation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mail := &fakeMailer{}
+	cfg := ApiCfg{
+		DB: database.New(db),
+		Mailer: mail,
+		InvitationURL: "https://inventory.example.com/accept",
+	}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+	invitationId, orgId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+		WithArgs("warehouse_clerk").
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("warehouse_clerk", nil, true, time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WithArgs("clerk@example.com").
+		WillReturnRows(sqlmock.NewRows(userColumns))
+	mock.ExpectQuery(`INSERT INTO invitations`).
+		WithArgs(sqlmock.AnyArg(), "clerk@example.com", "warehouse_clerk", sqlmock.AnyArg(),
+			uuid.NullUUID{UUID: admin.ID, Valid: true}, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			uuid.NullUUID{UUID: orgId, Valid: true}).
+		WillReturnRows(sqlmock.NewRows(invitationColumns).AddRow(
+			invitationId, "clerk@example.com", "warehouse_clerk", "hash", admin.ID,
+			time.Now(), time.Now().Add(time.Hour), nil, orgId))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("POST", "/invitations", strings.NewReader(
+		`{"email": "clerk@example.com", "role": "warehouse_clerk"}`))
+	assert.NoError(t, err)
+	ctx := auth.WithPermissions(req.Context(), []string{auth.UsersWrite, auth.RolesManage})
+	req = req.WithContext(auth.WithOrgID(ctx, orgId))
+	rr := httptest.NewRecorder()
+	cfg.CreateInvitationController(rr, req, admin)
+
+	var response models.Invitation
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 201, rr.Code)
+	assert.Equal(t, invitationId, response.ID)
+	assert.Equal(t, &orgId, response.OrgID, "invitees join the inviter's organisation")
+	assert.NotEmpty(t, response.Token)
+	assert.Len(t, mail.sent, 1)
+	assert.Equal(t, "clerk@example.com", mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Body, "https://inventory.example.com/accept?token="+response.Token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateInvitation_RoleRequiresRolesManage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	manager := database.User{ID: uuid.New(), Role: "user_manager"}
+
+	req, err := http.NewRequest("POST", "/invitations", strings.NewReader(
+		`{"email": "mallory@example.com", "role": "admin"}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.UsersWrite}))
+	rr := httptest.NewRecorder()
+	cfg.CreateInvitationController(rr, req, manager)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "no invitation must be created")
+}
+
+func TestCreateInvitation_OtherOrgRequiresOrganizationsManage(t *testing.T) {
+	cfg := ApiCfg{}
+	manager := database.User{ID: uuid.New(), Role: "user_manager"}
+
+	req, err := http.NewRequest("POST", "/invitations", strings.NewReader(
+		`{"email": "clerk@example.com", "role": "user", "org_id": "`+uuid.New().String()+`"}`))
+	assert.NoError(t, err)
+	ctx := auth.WithPermissions(req.Context(), []string{auth.UsersWrite})
+	req = req.WithContext(auth.WithOrgID(ctx, uuid.New()))
+	rr := httptest.NewRecorder()
+	cfg.CreateInvitationController(rr, req, manager)
+
+	assert.Equal(t, 403, rr.Code)
+}
+
+func TestCreateInvitation_ExistingUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+		WithArgs("user").
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("user", nil, true, time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WithArgs("johndoe@gmail.com").
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(uuid.New(), time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+
+	req, err := http.NewRequest("POST", "/invitations", strings.NewReader(
+		`{"email": "johndoe@gmail.com", "role": "user"}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.CreateInvitationController(rr, req, database.User{ID: uuid.New(), Role: "admin"})
+
+	assert.Equal(t, 409, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	token, hash, err := helpers.GenerateToken()
+	assert.NoError(t, err)
+	invitationId, userId, orgId := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM invitations WHERE token_hash = \$1`).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows(invitationColumns).AddRow(
+			invitationId, "clerk@example.com", "warehouse_clerk", hash, uuid.New(),
+			time.Now(), time.Now().Add(time.Hour), nil, orgId))
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs(sqlmock.AnyArg(), "clerk", "clerk@example.com", "Clerk", sqlmock.AnyArg(),
+			"warehouse_clerk", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(createUserRowColumns).AddRow(
+			userId, "clerk", "clerk@example.com", "Clerk", "warehouse_clerk", nil, time.Now(), time.Now()))
+	mock.ExpectQuery(`INSERT INTO organization_members`).
+		WithArgs(orgId, userId, "warehouse_clerk", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(memberColumns).AddRow(orgId, userId, "warehouse_clerk", time.Now()))
+	mock.ExpectExec(`UPDATE invitations SET accepted_at = \$2`).
+		WithArgs(invitationId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET email_verified_at = \$2`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := jsonRequest(t, "/invitations/accept", map[string]string{
+		"token": token, "name": "Clerk", "username": "clerk", "password": "StrongPass123",
+	})
+	rr := httptest.NewRecorder()
+	cfg.AcceptInvitationController(rr, req)
+
+	var response models.UserResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 201, rr.Code)
+	assert.Equal(t, "warehouse_clerk", response.Role)
+	assert.NotNil(t, response.EmailVerifiedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptInvitation_Expired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	token, hash, err := helpers.GenerateToken()
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM invitations WHERE token_hash = \$1`).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows(invitationColumns).AddRow(
+			uuid.New(), "clerk@example.com", "user", hash, nil,
+			time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), nil, nil))
+
+	req := jsonRequest(t, "/invitations/accept", map[string]string{
+		"token": token, "name": "Clerk", "username": "clerk", "password": "StrongPass123",
+	})
+	rr := httptest.NewRecorder()
+	cfg.AcceptInvitationController(rr, req)
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), invalidInvitationMessage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateUserController_IgnoresRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	apiCfg := ApiCfg{DB: database.New(db), Mailer: &fakeMailer{}}
+
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs(sqlmock.AnyArg(), "johndoe", "johndoe@gmail.com", "John Doe", sqlmock.AnyArg(),
+			"user", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(createUserRowColumns).AddRow(
+			uuid.New(), "johndoe", "johndoe@gmail.com", "John Doe", "user", nil, time.Now(), time.Now()))
+
+	payload, err := json.Marshal(map[string]string{
+		"name": "John Doe", "username": "johndoe", "email": "johndoe@gmail.com",
+		"password": "StrongPass123", "role": "admin",
+	})
+	assert.NoError(t, err)
+	req, err := http.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(payload))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	apiCfg.CreateUserController(rr, req)
+
+	assert.Equal(t, 201, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"role":"user"`)
+}
+
+func TestCreateUserController_RegistrationDisabled(t *testing.T) {
+	apiCfg := ApiCfg{DisableRegistration: true}
+
+	req, err := http.NewRequest("POST", "/api/v1/register", strings.NewReader(`{}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	apiCfg.CreateUserController(rr, req)
+
+	assert.Equal(t, 403, rr.Code)
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/ringtho/inventory/helpers"
+)
+
+// JWKSController publishes the public keys access tokens are signed with
+// so other services can verify them. It's empty while tokens are signed
+// with SECRET_KEY, which can't be shared.
+func (cfg ApiCfg) JWKSController(w http.ResponseWriter, r *http.Request) {
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	if keys := helpers.SigningKeys(); keys != nil {
+		set = keys.JWKS(time.Now())
+	}
+
+	// Short enough that a key published ahead of a rotation is picked up
+	// before it starts signing
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helpers.JSON(w, 200, set)
+}
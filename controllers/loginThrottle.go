@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func (cfg ApiCfg) ipThrottleKey(r *http.Request) string {
+	return "ip:" + cfg.clientIP(r)
+}
+
+// loginRetryAfter is how long the account or the client's IP is locked out
+// of logging in for, 0 when neither is
+func (cfg ApiCfg) loginRetryAfter(ctx context.Context, accountKey string, ipKey string) (time.Duration, error) {
+	throttles, err := cfg.DB.GetLoginThrottles(ctx, database.GetLoginThrottlesParams{
+		AccountKey: accountKey,
+		IpKey: ipKey,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.Sub(now) > wait {
+			wait = throttle.LockedUntil.Time.Sub(now)
+		}
+	}
+	return wait, nil
+}
+
+// recordLoginFailure counts a failed login against key and locks it once
+// policy allows no more. Failing to record is logged, the login has failed
+// either way.
+func (cfg ApiCfg) recordLoginFailure(ctx context.Context, key string, policy helpers.LockoutPolicy) {
+	now := time.Now().UTC()
+	throttle, err := cfg.DB.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key: key,
+		FailedAt: now,
+		WindowStart: now.Add(-policy.Window),
+	})
+	if err != nil {
+		log.Printf("Couldn't record failed login for %v: %v", key, err)
+		return
+	}
+
+	lock := policy.LockDuration(int(throttle.Failures))
+	if lock == 0 {
+		return
+	}
+
+	err = cfg.DB.LockLogin(ctx, database.LockLoginParams{
+		Key: key,
+		LockedUntil: sql.NullTime{Time: now.Add(lock), Valid: true},
+	})
+	if err != nil {
+		log.Printf("Couldn't lock logins for %v: %v", key, err)
+		return
+	}
+	log.Printf("Locked logins for %v for %v after %d failed attempts", key, lock, throttle.Failures)
+}
+
+func respondLoginLocked(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	helpers.RespondWithError(w, 429, "Too many failed login attempts, try again later")
+}
+
+// UnlockUserController clears a user's failed logins and second factors so
+// they can log in again straight away
+func (cfg ApiCfg) UnlockUserController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "userId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse userId: %v", err))
+		return
+	}
+
+	target, err := cfg.DB.GetUserById(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "User not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch user: %v", err))
+		return
+	}
+
+	for _, key := range []string{accountThrottleKey(target.Email), mfaThrottleKey(target.ID)} {
+		if err := cfg.DB.ClearLoginThrottle(r.Context(), key); err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't unlock user: %v", err))
+			return
+		}
+	}
+
+	log.Printf("User %v unlocked logins for %v", user.ID, target.Email)
+	cfg.recordAudit(r, user, auditUnlock, "user", target.ID, nil, nil)
+	helpers.TextResponse(w, 200, fmt.Sprintf("Successfully unlocked user: %v", target.ID))
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// timeArg matches any time argument and keeps it for the test to check
+type timeArg struct {
+	value time.Time
+}
+
+func (a *timeArg) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	a.value = t
+	return ok
+}
+
+var loginThrottleColumns = []string{"key", "failures", "last_failure_at", "locked_until"}
+
+// expectLoginThrottles expects a login to find neither the account nor the
+// IP locked
+func expectLoginThrottles(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT (.+) FROM login_throttles`).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns))
+}
+
+func TestLogin_LockedAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM login_throttles`).
+		WithArgs("account:johndoe@gmail.com", "ip:192.0.2.1").
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("account:johndoe@gmail.com", 5, time.Now(), time.Now().Add(90*time.Second)))
+
+	cfg := ApiCfg{DB: database.New(db)}
+	req := jsonRequest(t, "/login", map[string]string{"email": "JohnDoe@gmail.com", "password": "StrongPass123"})
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.LoginController).ServeHTTP(rr, req)
+
+	assert.Equal(t, 429, rr.Code)
+	assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet(), "a locked account must not reach the password check")
+}
+
+func TestLogin_ExpiredLockAllowsLogin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	password := "StrongPass123"
+	mock.ExpectQuery(`SELECT (.+) FROM login_throttles`).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("account:johndoe@gmail.com", 5, time.Now(), time.Now().Add(-time.Second)))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(uuid.New(), time.Now(), time.Now(), "johndoe", "johndoe@gmail.com",
+				helpers.HashPassword(password), "user", nil, "john doe", nil))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WithArgs("account:johndoe@gmail.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	cfg := ApiCfg{DB: database.New(db)}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.LoginController).ServeHTTP(rr,
+		jsonRequest(t, "/login", map[string]string{"email": "johndoe@gmail.com", "password": password}))
+
+	// Past the lock, so the password is checked and the unverified email
+	// is what stops the login
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLogin_FailureLocksAfterMaxFailures(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	t.Setenv("LOGIN_LOCKOUT_BASE", "1m")
+
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(uuid.New(), time.Now(), time.Now(), "johndoe", "johndoe@gmail.com",
+				helpers.HashPassword("StrongPass123"), "user", nil, "john doe", time.Now()))
+
+	lockedUntil := timeArg{}
+	mock.ExpectQuery(`INSERT INTO login_throttles`).
+		WithArgs("account:johndoe@gmail.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("account:johndoe@gmail.com", 3, time.Now(), nil))
+	mock.ExpectExec(`UPDATE login_throttles SET locked_until`).
+		WithArgs("account:johndoe@gmail.com", &lockedUntil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO login_throttles`).
+		WithArgs("ip:192.0.2.1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("ip:192.0.2.1", 1, time.Now(), nil))
+
+	cfg := ApiCfg{DB: database.New(db)}
+	req := jsonRequest(t, "/login", map[string]string{"email": "johndoe@gmail.com", "password": "WrongPass123"})
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.LoginController).ServeHTTP(rr, req)
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid email or password")
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.WithinDuration(t, time.Now().Add(time.Minute), lockedUntil.value, 5*time.Second)
+}
+
+func TestUnlockUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	userId := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "JohnDoe@gmail.com",
+				"hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WithArgs("account:johndoe@gmail.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WithArgs("mfa:" + userId.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	cfg := ApiCfg{DB: database.New(db)}
+	req := withURLParam(httptest.NewRequest("POST", "/users/"+userId.String()+"/unlock", nil),
+		"userId", userId.String())
+	rr := httptest.NewRecorder()
+	cfg.UnlockUserController(rr, req, database.User{ID: uuid.New(), Role: "admin"})
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnlockUser_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	userId := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns))
+
+	cfg := ApiCfg{DB: database.New(db)}
+	req := withURLParam(httptest.NewRequest("POST", "/users/"+userId.String()+"/unlock", nil),
+		"userId", userId.String())
+	rr := httptest.NewRecorder()
+	cfg.UnlockUserController(rr, req, database.User{ID: uuid.New(), Role: "admin"})
+
+	assert.Equal(t, 404, rr.Code)
+}
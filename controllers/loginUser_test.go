@@ -52,6 +52,7 @@ func TestLoginUser(t *testing.T) {
 		"role", 
 		"profile_picture_url", 
 		"name",
+		"email_verified_at",
 		}).
 		AddRow(
 			mockUser.ID.String(),
@@ -63,15 +64,32 @@ func TestLoginUser(t *testing.T) {
 			mockUser.Role,
 			nil,
 			mockUser.Name,
+			time.Now(),
 		)
 
+	expectLoginThrottles(mock)
+
 	mock.ExpectQuery(
 		`SELECT id, created_at, updated_at, username, 
 		email, password, role, profile_picture_url, 
-		name FROM users WHERE email = \$1`,
+		name, email_verified_at FROM users WHERE email = \$1`,
 		).
 		WithArgs(email).
 		WillReturnRows(mockRows)
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	expectNoTwoFactor(mock, userId)
+
+	orgId := uuid.New()
+	expectUserOrganizations(mock, userId, orgId)
+
+	mock.ExpectExec(`INSERT INTO sessions`).
+		WithArgs(sqlmock.AnyArg(), userId, uuid.NullUUID{UUID: orgId, Valid: true}, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(`INSERT INTO refresh_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	payload, _ := json.Marshal(map[string] string {
 		"email": email,
@@ -92,6 +110,8 @@ func TestLoginUser(t *testing.T) {
 
 	assert.Equal(t,200, rr.Code)
 	assert.Equal(t, mockUser.Email, response.User.Email)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestLoginUser_InvalidCredentials(t *testing.T) {
@@ -129,6 +149,7 @@ func TestLoginUser_InvalidCredentials(t *testing.T) {
 		"role", 
 		"profile_picture_url", 
 		"name",
+		"email_verified_at",
 		}).
 		AddRow(
 			mockUser.ID.String(),
@@ -140,12 +161,15 @@ func TestLoginUser_InvalidCredentials(t *testing.T) {
 			mockUser.Role,
 			nil,
 			mockUser.Name,
+			time.Now(),
 		)
 
+	expectLoginThrottles(mock)
+
 	mock.ExpectQuery(
 		`SELECT id, created_at, updated_at, username, 
 		email, password, role, profile_picture_url, 
-		name FROM users WHERE email = \$1`,
+		name, email_verified_at FROM users WHERE email = \$1`,
 		).
 		WithArgs(email).
 		WillReturnRows(mockRows)
@@ -203,6 +227,7 @@ func TestLoginUser_GenerateTokenError(t *testing.T) {
 		"role", 
 		"profile_picture_url", 
 		"name",
+		"email_verified_at",
 		}).
 		AddRow(
 			mockUser.ID.String(),
@@ -214,15 +239,23 @@ func TestLoginUser_GenerateTokenError(t *testing.T) {
 			mockUser.Role,
 			nil,
 			mockUser.Name,
+			time.Now(),
 		)
 
+	expectLoginThrottles(mock)
+
 	mock.ExpectQuery(
 		`SELECT id, created_at, updated_at, username, 
 		email, password, role, profile_picture_url, 
-		name FROM users WHERE email = \$1`,
+		name, email_verified_at FROM users WHERE email = \$1`,
 		).
 		WithArgs(email).
 		WillReturnRows(mockRows)
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	expectNoTwoFactor(mock, userId)
+	expectUserOrganizations(mock, userId)
 
 	payload, _ := json.Marshal(map[string] string {
 		"email": email,
@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/metrics"
+	"github.com/ringtho/inventory/models"
+	"github.com/ringtho/inventory/sso"
+)
+
+// oidcLoginTTL is how long a user has to sign in at the identity provider
+const oidcLoginTTL = 10 * time.Minute
+
+// ssoPassword is stored for users created by single sign-on. It isn't a
+// bcrypt hash so no password ever matches it.
+const ssoPassword = "!"
+
+const invalidSSOLoginMessage = "Invalid or expired single sign-on login"
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// OIDCLoginController starts a single sign-on login by redirecting to the
+// identity provider with a fresh state, nonce and PKCE challenge
+func (cfg ApiCfg) OIDCLoginController(w http.ResponseWriter, r *http.Request) {
+	if cfg.SSO == nil {
+		helpers.RespondWithError(w, 404, "Single sign-on is not configured")
+		return
+	}
+
+	state, stateHash, err := helpers.GenerateToken()
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate state: %v", err))
+		return
+	}
+	nonce, _, err := helpers.GenerateToken()
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate nonce: %v", err))
+		return
+	}
+
+	login, err := cfg.SSO.AuthCodeURL(state, nonce)
+	if err != nil {
+		helpers.RespondWithError(w, 502, fmt.Sprintf("Couldn't reach identity provider: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	err = cfg.DB.CreateOIDCLogin(r.Context(), database.CreateOIDCLoginParams{
+		ID: uuid.New(),
+		StateHash: stateHash,
+		Nonce: login.Nonce,
+		CodeVerifier: login.CodeVerifier,
+		CreatedAt: now,
+		ExpiresAt: now.Add(oidcLoginTTL),
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't start login: %v", err))
+		return
+	}
+
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// OIDCCallbackController finishes a single sign-on login. The user signed
+// in at the identity provider is linked to a user here, created if needed,
+// and logged in like they would be with a password.
+func (cfg ApiCfg) OIDCCallbackController(w http.ResponseWriter, r *http.Request) {
+	if cfg.SSO == nil {
+		helpers.RespondWithError(w, 404, "Single sign-on is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	if message := query.Get("error"); message != "" {
+		if description := query.Get("error_description"); description != "" {
+			message = description
+		}
+		metrics.RecordLogin(metrics.LoginOIDC, metrics.LoginFailure)
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Single sign-on failed: %v", message))
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		helpers.RespondWithError(w, 400, "Code and State are required")
+		return
+	}
+
+	login, err := cfg.DB.GetOIDCLoginByStateHash(r.Context(), helpers.HashToken(state))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, invalidSSOLoginMessage)
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch login: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	if login.UsedAt.Valid || now.After(login.ExpiresAt) {
+		helpers.RespondWithError(w, 400, invalidSSOLoginMessage)
+		return
+	}
+
+	used, err := cfg.DB.UseOIDCLogin(r.Context(), database.UseOIDCLoginParams{
+		ID: login.ID,
+		UsedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't use login: %v", err))
+		return
+	}
+	if used == 0 {
+		helpers.RespondWithError(w, 400, invalidSSOLoginMessage)
+		return
+	}
+
+	identity, err := cfg.SSO.Exchange(r.Context(), code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		metrics.RecordLogin(metrics.LoginOIDC, metrics.LoginFailure)
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Single sign-on failed: %v", err))
+		return
+	}
+
+	user, ok := cfg.ssoUser(w, r, identity)
+	if !ok {
+		return
+	}
+
+	user, ok = cfg.syncSSORole(w, r, user, identity)
+	if !ok {
+		return
+	}
+	metrics.RecordLogin(metrics.LoginOIDC, metrics.LoginSuccess)
+	cfg.completeLogin(w, r, user)
+}
+
+// ssoUser finds the user an identity is linked to. A new identity is linked
+// to the user with the same verified email, or a new user is created.
+func (cfg ApiCfg) ssoUser(w http.ResponseWriter, r *http.Request, identity sso.Identity) (database.User, bool) {
+	now := time.Now().UTC()
+	linked, err := cfg.DB.GetUserIdentity(r.Context(), database.GetUserIdentityParams{
+		Issuer: identity.Issuer,
+		Subject: identity.Subject,
+	})
+	if err == nil {
+		err = cfg.DB.TouchUserIdentity(r.Context(), database.TouchUserIdentityParams{
+			ID: linked.ID,
+			LastLoginAt: now,
+		})
+		if err != nil {
+			log.Printf("Couldn't record login of identity %v: %v", linked.ID, err)
+		}
+
+		user, err := cfg.DB.GetUserById(r.Context(), linked.UserID)
+		if err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch user: %v", err))
+			return database.User{}, false
+		}
+		return user, true
+	}
+	if err != sql.ErrNoRows {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch identity: %v", err))
+		return database.User{}, false
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		helpers.RespondWithError(w, 403, "The identity provider didn't give a verified email address")
+		return database.User{}, false
+	}
+
+	user, err := cfg.DB.GetUserByEmail(r.Context(), identity.Email)
+	switch {
+	case err == nil:
+		// Someone could have registered the address without owning it, so
+		// only accounts that proved it are linked
+		if !user.EmailVerifiedAt.Valid {
+			helpers.RespondWithError(w, 409, "An unverified account already uses this email address")
+			return database.User{}, false
+		}
+	case err == sql.ErrNoRows:
+		var ok bool
+		user, ok = cfg.createSSOUser(w, r, identity)
+		if !ok {
+			return database.User{}, false
+		}
+	default:
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch user: %v", err))
+		return database.User{}, false
+	}
+
+	err = cfg.DB.CreateUserIdentity(r.Context(), database.CreateUserIdentityParams{
+		ID: uuid.New(),
+		UserID: user.ID,
+		Issuer: identity.Issuer,
+		Subject: identity.Subject,
+		CreatedAt: now,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't link identity: %v", err))
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func (cfg ApiCfg) createSSOUser(w http.ResponseWriter, r *http.Request, identity sso.Identity) (database.User, bool) {
+	username := ssoUsername(identity)
+	name := identity.Name
+	if name == "" {
+		name = username
+	}
+
+	now := time.Now().UTC()
+	params := database.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name: name,
+		Username: username,
+		Email: identity.Email,
+		Password: ssoPassword,
+		Role: cfg.SSO.Role(identity),
+	}
+
+	created, err := cfg.DB.CreateUser(r.Context(), params)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		// The email was checked already, so it's the username that's taken
+		suffix, _, tokenErr := helpers.GenerateToken()
+		if tokenErr != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate username: %v", tokenErr))
+			return database.User{}, false
+		}
+		params.Username = truncate(username, 44) + "-" + strings.ToLower(suffix[:5])
+		created, err = cfg.DB.CreateUser(r.Context(), params)
+	}
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create user: %v", err))
+		return database.User{}, false
+	}
+
+	if err := cfg.joinOrganization(r.Context(), created.ID, uuid.NullUUID{}, params.Role); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't add organisation member: %v", err))
+		return database.User{}, false
+	}
+
+	err = cfg.DB.SetUserEmailVerified(r.Context(), database.SetUserEmailVerifiedParams{
+		ID: created.ID,
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't verify email: %v", err))
+		return database.User{}, false
+	}
+
+	user, err := cfg.DB.GetUserById(r.Context(), created.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch user: %v", err))
+		return database.User{}, false
+	}
+
+	cfg.recordAudit(r, user, auditCreate, "user", user.ID, nil, models.DatabaseUserToUser(user))
+	return user, true
+}
+
+// syncSSORole gives the user the role their claims map to when the
+// provider is set up to manage roles
+func (cfg ApiCfg) syncSSORole(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	identity sso.Identity,
+	) (database.User, bool) {
+	role := cfg.SSO.Role(identity)
+	if !cfg.SSO.SyncsRoles() || role == user.Role {
+		return user, true
+	}
+
+	now := time.Now().UTC()
+	updated, err := cfg.DB.UpdateUserRole(r.Context(), database.UpdateUserRoleParams{
+		ID: user.ID,
+		Role: role,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update role to %v: %v", role, err))
+		return database.User{}, false
+	}
+
+	// Sessions from before the change carry the old role
+	err = cfg.DB.RevokeUserSessions(r.Context(), database.RevokeUserSessionsParams{
+		UserID: user.ID,
+		RevokedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke sessions: %v", err))
+		return database.User{}, false
+	}
+
+	cfg.recordAudit(r, updated, auditUpdate, "user", user.ID,
+		models.DatabaseUserToUser(user), models.DatabaseUserToUser(updated))
+	return updated, true
+}
+
+// ssoUsername picks a username from the preferred_username claim, or the
+// start of the email address
+func ssoUsername(identity sso.Identity) string {
+	username := identity.PreferredUsername
+	if username == "" {
+		username, _, _ = strings.Cut(identity.Email, "@")
+	}
+	username = usernameInvalidChars.ReplaceAllString(username, "")
+	if username == "" {
+		username = "user"
+	}
+	return truncate(username, 50)
+}
+
+func truncate(s string, length int) string {
+	if len(s) > length {
+		return s[:length]
+	}
+	return s
+}
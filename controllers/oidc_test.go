@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/sso"
+	"github.com/ringtho/inventory/sso/ssotest"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	oidcLoginColumns = []string{
+		"id", "state_hash", "nonce", "code_verifier", "created_at", "expires_at", "used_at",
+	}
+	userIdentityColumns = []string{
+		"id", "user_id", "issuer", "subject", "created_at", "last_login_at",
+	}
+)
+
+// captureArg matches any string argument and keeps it
+type captureArg struct {
+	value *string
+}
+
+func (c captureArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*c.value = s
+	return ok
+}
+
+func newSSOTest(t *testing.T) (*ssotest.IdP, ApiCfg, sqlmock.Sqlmock) {
+	t.Setenv("SECRET_KEY", "mysecretkey")
+
+	idp, err := ssotest.NewIdP("inventory")
+	assert.NoError(t, err)
+	t.Cleanup(idp.Close)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	cfg := ApiCfg{
+		DB: database.New(db),
+		SSO: sso.New(sso.Config{
+			IssuerURL: idp.Issuer(),
+			ClientID: "inventory",
+			RedirectURL: "http://inventory.test/api/v1/auth/oidc/callback",
+			RoleClaim: "groups",
+			RoleMapping: map[string]string{"warehouse": "warehouse_clerk"},
+		}),
+	}
+	return idp, cfg, mock
+}
+
+// ssoSignIn starts a login, signs in at the stub IdP with claims and
+// expects the callback to look the login up
+func ssoSignIn(t *testing.T, idp *ssotest.IdP, cfg ApiCfg, mock sqlmock.Sqlmock, claims map[string]interface{}) *http.Request {
+	var stateHash, nonce, verifier string
+	mock.ExpectExec(`INSERT INTO oidc_logins`).
+		WithArgs(sqlmock.AnyArg(), captureArg{&stateHash}, captureArg{&nonce}, captureArg{&verifier},
+			sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	cfg.OIDCLoginController(rr, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	callback, err := idp.SignIn(rr.Header().Get("Location"), claims)
+	assert.NoError(t, err)
+
+	loginId := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM oidc_logins WHERE state_hash = \$1`).
+		WithArgs(stateHash).
+		WillReturnRows(sqlmock.NewRows(oidcLoginColumns).AddRow(
+			loginId, stateHash, nonce, verifier, time.Now(), time.Now().Add(time.Minute), nil))
+	mock.ExpectExec(`UPDATE oidc_logins SET used_at = \$2`).
+		WithArgs(loginId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	return httptest.NewRequest("GET", callback, nil)
+}
+
+func expectSession(mock sqlmock.Sqlmock, userId uuid.UUID) {
+	expectNoTwoFactor(mock, userId)
+	expectUserOrganizations(mock, userId)
+	mock.ExpectExec(`INSERT INTO sessions`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO refresh_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestOIDCCallback_ProvisionsUser(t *testing.T) {
+	idp, cfg, mock := newSSOTest(t)
+	userId := uuid.New()
+
+	req := ssoSignIn(t, idp, cfg, mock, map[string]interface{}{
+		"sub": "jane-1", "email": "jane@example.com", "email_verified": true,
+		"name": "Jane Doe", "preferred_username": "jane", "groups": []string{"staff", "warehouse"},
+	})
+
+	mock.ExpectQuery(`SELECT (.+) FROM user_identities WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(idp.Issuer(), "jane-1").
+		WillReturnRows(sqlmock.NewRows(userIdentityColumns))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows(userColumns))
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs(sqlmock.AnyArg(), "jane", "jane@example.com", "Jane Doe", ssoPassword,
+			"warehouse_clerk", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(createUserRowColumns).AddRow(
+			userId, "jane", "jane@example.com", "Jane Doe", "warehouse_clerk", nil, time.Now(), time.Now()))
+	mock.ExpectExec(`UPDATE users SET email_verified_at = \$2`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(
+			userId, time.Now(), time.Now(), "jane", "jane@example.com", ssoPassword, "warehouse_clerk", nil, "Jane Doe", time.Now()))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_identities`).
+		WithArgs(sqlmock.AnyArg(), userId, idp.Issuer(), "jane-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectSession(mock, userId)
+
+	rr := httptest.NewRecorder()
+	cfg.OIDCCallbackController(rr, req)
+
+	assert.Equal(t, 200, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"refresh_token"`)
+	assert.Contains(t, rr.Body.String(), `"role":"warehouse_clerk"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallback_LinkedUserRoleSynced(t *testing.T) {
+	idp, cfg, mock := newSSOTest(t)
+	userId := uuid.New()
+
+	req := ssoSignIn(t, idp, cfg, mock, map[string]interface{}{
+		"sub": "jane-1", "email": "jane@example.com", "groups": "warehouse",
+	})
+
+	identityId := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM user_identities WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(idp.Issuer(), "jane-1").
+		WillReturnRows(sqlmock.NewRows(userIdentityColumns).AddRow(
+			identityId, userId, idp.Issuer(), "jane-1", time.Now(), time.Now()))
+	mock.ExpectExec(`UPDATE user_identities SET last_login_at = \$2`).
+		WithArgs(identityId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(
+			userId, time.Now(), time.Now(), "jane", "jane@example.com", ssoPassword, "user", nil, "Jane Doe", time.Now()))
+	mock.ExpectQuery(`UPDATE users SET role = \$2`).
+		WithArgs(userId, "warehouse_clerk", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(
+			userId, time.Now(), time.Now(), "jane", "jane@example.com", ssoPassword, "warehouse_clerk", nil, "Jane Doe", time.Now()))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = \$2 WHERE user_id = \$1`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectSession(mock, userId)
+
+	rr := httptest.NewRecorder()
+	cfg.OIDCCallbackController(rr, req)
+
+	assert.Equal(t, 200, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"role":"warehouse_clerk"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallback_UnverifiedAccountNotLinked(t *testing.T) {
+	idp, cfg, mock := newSSOTest(t)
+
+	req := ssoSignIn(t, idp, cfg, mock, map[string]interface{}{
+		"sub": "jane-1", "email": "jane@example.com", "email_verified": true,
+	})
+
+	mock.ExpectQuery(`SELECT (.+) FROM user_identities WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(idp.Issuer(), "jane-1").
+		WillReturnRows(sqlmock.NewRows(userIdentityColumns))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(
+			uuid.New(), time.Now(), time.Now(), "jane", "jane@example.com", "hash", "user", nil, "Jane Doe", nil))
+
+	rr := httptest.NewRecorder()
+	cfg.OIDCCallbackController(rr, req)
+
+	assert.Equal(t, 409, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallback_MissingEmailVerifiedRefused(t *testing.T) {
+	idp, cfg, mock := newSSOTest(t)
+
+	req := ssoSignIn(t, idp, cfg, mock, map[string]interface{}{
+		"sub": "mallory-1", "email": "admin@example.com",
+	})
+
+	mock.ExpectQuery(`SELECT (.+) FROM user_identities WHERE issuer = \$1 AND subject = \$2`).
+		WithArgs(idp.Issuer(), "mallory-1").
+		WillReturnRows(sqlmock.NewRows(userIdentityColumns))
+
+	rr := httptest.NewRecorder()
+	cfg.OIDCCallbackController(rr, req)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the account with the email mustn't be looked up")
+}
+
+func TestOIDCCallback_NonceMismatch(t *testing.T) {
+	idp, cfg, mock := newSSOTest(t)
+
+	var stateHash string
+	mock.ExpectExec(`INSERT INTO oidc_logins`).
+		WithArgs(sqlmock.AnyArg(), captureArg{&stateHash}, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	cfg.OIDCLoginController(rr, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	callback, err := idp.SignIn(rr.Header().Get("Location"), map[string]interface{}{"sub": "jane-1"})
+	assert.NoError(t, err)
+
+	// A login whose PKCE verifier and nonce don't belong to this code
+	mock.ExpectQuery(`SELECT (.+) FROM oidc_logins WHERE state_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(oidcLoginColumns).AddRow(
+			uuid.New(), stateHash, "other-nonce", "other-verifier-that-is-long-enough-for-pkce-0123456789",
+			time.Now(), time.Now().Add(time.Minute), nil))
+	mock.ExpectExec(`UPDATE oidc_logins SET used_at = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr = httptest.NewRecorder()
+	cfg.OIDCCallbackController(rr, httptest.NewRequest("GET", callback, nil))
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Single sign-on failed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCCallback_UnknownState(t *testing.T) {
+	_, cfg, mock := newSSOTest(t)
+
+	mock.ExpectQuery(`SELECT (.+) FROM oidc_logins WHERE state_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(oidcLoginColumns))
+
+	rr := httptest.NewRecorder()
+	cfg.OIDCCallbackController(rr, httptest.NewRequest("GET", "/auth/oidc/callback?code=abc&state=xyz", nil))
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), invalidSSOLoginMessage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCLogin_NotConfigured(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ApiCfg{}.OIDCLoginController(rr, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+
+	assert.Equal(t, 404, rr.Code)
+}
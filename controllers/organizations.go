@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+)
+
+var orgSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,49}$`)
+
+type organizationParams struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type memberParams struct {
+	Role string `json:"role"`
+}
+
+type switchOrgParams struct {
+	OrgID uuid.UUID `json:"org_id"`
+}
+
+// organization fetches the organisation in the URL, responding with 404
+// when it doesn't exist
+func (cfg ApiCfg) organization(w http.ResponseWriter, r *http.Request) (database.Organization, bool) {
+	idStr := chi.URLParam(r, "orgId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse orgId: %v", err))
+		return database.Organization{}, false
+	}
+
+	org, err := cfg.DB.GetOrganizationById(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Organisation not found")
+			return database.Organization{}, false
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisation: %v", err))
+		return database.Organization{}, false
+	}
+	return org, true
+}
+
+// joinOrganization makes a new user a member of orgId with role. Without
+// orgId they join DefaultOrg, or nothing when there's no default.
+func (cfg ApiCfg) joinOrganization(ctx context.Context, userId uuid.UUID, orgId uuid.NullUUID, role string) error {
+	if !orgId.Valid {
+		if cfg.DefaultOrg == "" {
+			return nil
+		}
+		org, err := cfg.DB.GetOrganizationBySlug(ctx, cfg.DefaultOrg)
+		if err == sql.ErrNoRows {
+			log.Printf("Default organisation %v doesn't exist, user %v joins none", cfg.DefaultOrg, userId)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		orgId = uuid.NullUUID{UUID: org.ID, Valid: true}
+	}
+
+	_, err := cfg.DB.SetOrganizationMember(ctx, database.SetOrganizationMemberParams{
+		OrgID: orgId.UUID,
+		UserID: userId,
+		Role: role,
+		CreatedAt: time.Now().UTC(),
+	})
+	return err
+}
+
+// GetOrganizationsController lists every organisation
+func (cfg ApiCfg) GetOrganizationsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	orgs, err := cfg.DB.GetOrganizations(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisations: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseOrganizationsToOrganizations(orgs))
+}
+
+// CreateOrganizationController adds an organisation. The user creating it
+// joins it as an admin.
+func (cfg ApiCfg) CreateOrganizationController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := organizationParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Name == "" {
+		helpers.RespondWithError(w, 400, "Organisation name is required")
+		return
+	}
+	if !orgSlugPattern.MatchString(params.Slug) {
+		helpers.RespondWithError(w, 400,
+			"Organisation slug must be 2 to 50 lower case letters, digits or dashes")
+		return
+	}
+
+	now := time.Now().UTC()
+	org, err := cfg.DB.CreateOrganization(r.Context(), database.CreateOrganizationParams{
+		ID: uuid.New(),
+		Name: params.Name,
+		Slug: params.Slug,
+		CreatedAt: now,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" {
+				helpers.RespondWithError(w, 409, "Organisation already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create organisation: %v", err))
+		return
+	}
+
+	// Service accounts aren't users so they can't be members
+	if !auth.IsServiceAccount(r.Context()) {
+		_, err = cfg.DB.SetOrganizationMember(r.Context(), database.SetOrganizationMemberParams{
+			OrgID: org.ID,
+			UserID: user.ID,
+			Role: adminRole,
+			CreatedAt: now,
+		})
+		if err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't add organisation member: %v", err))
+			return
+		}
+	}
+
+	created := models.DatabaseOrganizationToOrganization(org)
+	cfg.recordAudit(r, user, auditCreate, "organization", org.ID, nil, created)
+	helpers.JSON(w, 201, created)
+}
+
+// GetOrganizationMembersController lists an organisation's members and
+// their roles in it
+func (cfg ApiCfg) GetOrganizationMembersController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	org, ok := cfg.organization(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := cfg.DB.GetOrganizationMembers(r.Context(), org.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisation members: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseMembersToMembers(members))
+}
+
+// SetOrganizationMemberController adds a user to an organisation, or
+// changes the role they have in it
+func (cfg ApiCfg) SetOrganizationMemberController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	org, ok := cfg.organization(w, r)
+	if !ok {
+		return
+	}
+
+	userIdStr := chi.URLParam(r, "userId")
+	userId, err := uuid.Parse(userIdStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse userId: %v", err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := memberParams{}
+	err = decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if _, err := cfg.DB.GetRole(r.Context(), params.Role); err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown role: %v", params.Role))
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch role: %v", err))
+		return
+	}
+
+	member, err := cfg.DB.SetOrganizationMember(r.Context(), database.SetOrganizationMemberParams{
+		OrgID: org.ID,
+		UserID: userId,
+		Role: params.Role,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23503" {
+				helpers.RespondWithError(w, 404, "User not found")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't set organisation member: %v", err))
+		return
+	}
+
+	after := models.DatabaseMemberToMember(member)
+	cfg.recordAudit(r, user, auditUpdate, "organization", org.ID, nil, after)
+	helpers.JSON(w, 200, after)
+}
+
+// RemoveOrganizationMemberController takes a user out of an organisation
+func (cfg ApiCfg) RemoveOrganizationMemberController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	org, ok := cfg.organization(w, r)
+	if !ok {
+		return
+	}
+
+	userIdStr := chi.URLParam(r, "userId")
+	userId, err := uuid.Parse(userIdStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse userId: %v", err))
+		return
+	}
+
+	removed, err := cfg.DB.RemoveOrganizationMember(r.Context(), database.RemoveOrganizationMemberParams{
+		OrgID: org.ID,
+		UserID: userId,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't remove organisation member: %v", err))
+		return
+	}
+	if removed == 0 {
+		helpers.RespondWithError(w, 404, "Member not found")
+		return
+	}
+
+	cfg.recordAudit(r, user, auditDelete, "organization", org.ID,
+		models.OrganizationMember{OrgID: org.ID, UserID: userId}, nil)
+	helpers.TextResponse(w, 200, fmt.Sprintf("Successfully removed member: %v", userId))
+}
+
+// GetMyOrganizationsController lists the organisations the user belongs to
+func (cfg ApiCfg) GetMyOrganizationsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	orgs, err := cfg.DB.GetUserOrganizations(r.Context(), user.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisations: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseMembershipsToMemberships(orgs))
+}
+
+// SwitchOrganizationController moves the session to another organisation
+// the user belongs to and returns an access token for it. Refreshed tokens
+// stay in that organisation.
+func (cfg ApiCfg) SwitchOrganizationController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := switchOrgParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	_, err = cfg.DB.GetOrganizationMember(r.Context(), database.GetOrganizationMemberParams{
+		OrgID: params.OrgID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 403, "You aren't a member of that organisation")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisation member: %v", err))
+		return
+	}
+
+	sessionId := auth.SessionID(r.Context())
+	orgId := uuid.NullUUID{UUID: params.OrgID, Valid: true}
+	err = cfg.DB.SetSessionOrg(r.Context(), database.SetSessionOrgParams{
+		ID: sessionId,
+		OrgID: orgId,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't switch organisation: %v", err))
+		return
+	}
+
+	token, err := helpers.GenerateJWT(user.ID, user.Role, sessionId, orgId)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate token: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, map[string]string{"token": token})
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	organizationColumns = []string{"id", "name", "slug", "created_at", "updated_at"}
+	memberColumns       = []string{"org_id", "user_id", "role", "created_at"}
+)
+
+// expectUserOrganizations sets up the organisations startSession finds for
+// a user, who is an admin in each of orgIds
+func expectUserOrganizations(mock sqlmock.Sqlmock, userId uuid.UUID, orgIds ...uuid.UUID) {
+	rows := sqlmock.NewRows([]string{"id", "name", "slug", "role"})
+	for _, orgId := range orgIds {
+		rows.AddRow(orgId, "Shop", "shop-"+orgId.String()[:8], "admin")
+	}
+	mock.ExpectQuery(`SELECT (.+) FROM organization_members JOIN organizations`).
+		WithArgs(userId).
+		WillReturnRows(rows)
+}
+
+func TestCreateOrganization(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+	orgId := uuid.New()
+
+	mock.ExpectQuery(`INSERT INTO organizations`).
+		WithArgs(sqlmock.AnyArg(), "Corner Shop", "corner-shop", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(organizationColumns).
+			AddRow(orgId, "Corner Shop", "corner-shop", time.Now(), time.Now()))
+	mock.ExpectQuery(`INSERT INTO organization_members`).
+		WithArgs(orgId, admin.ID, "admin", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(memberColumns).AddRow(orgId, admin.ID, "admin", time.Now()))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), admin.ID, sqlmock.AnyArg(), "create", "organization",
+			orgId, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	cfg.CreateOrganizationController(rr, jsonRequest(t, "/organizations", map[string]string{
+		"name": "Corner Shop", "slug": "corner-shop",
+	}), admin)
+
+	assert.Equal(t, 201, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"slug":"corner-shop"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrganization_InvalidSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	rr := httptest.NewRecorder()
+	cfg.CreateOrganizationController(rr, jsonRequest(t, "/organizations", map[string]string{
+		"name": "Corner Shop", "slug": "Corner Shop",
+	}), database.User{ID: uuid.New()})
+
+	assert.Equal(t, 400, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrganization_SlugTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	mock.ExpectQuery(`INSERT INTO organizations`).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	rr := httptest.NewRecorder()
+	cfg.CreateOrganizationController(rr, jsonRequest(t, "/organizations", map[string]string{
+		"name": "Corner Shop", "slug": "corner-shop",
+	}), database.User{ID: uuid.New()})
+
+	assert.Equal(t, 409, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetOrganizationMember_UnknownRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	orgId, userId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM organizations WHERE id = \$1`).
+		WithArgs(orgId).
+		WillReturnRows(sqlmock.NewRows(organizationColumns).
+			AddRow(orgId, "Corner Shop", "corner-shop", time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+		WithArgs("owner").
+		WillReturnRows(sqlmock.NewRows(roleColumns))
+
+	req := withURLParam(jsonRequest(t, "/organizations/members", map[string]string{"role": "owner"}),
+		"orgId", orgId.String())
+	chi.RouteContext(req.Context()).URLParams.Add("userId", userId.String())
+
+	rr := httptest.NewRecorder()
+	cfg.SetOrganizationMemberController(rr, req, database.User{ID: uuid.New()})
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Unknown role")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveOrganizationMember_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	orgId, userId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM organizations WHERE id = \$1`).
+		WithArgs(orgId).
+		WillReturnRows(sqlmock.NewRows(organizationColumns).
+			AddRow(orgId, "Corner Shop", "corner-shop", time.Now(), time.Now()))
+	mock.ExpectExec(`DELETE FROM organization_members WHERE org_id = \$1 AND user_id = \$2`).
+		WithArgs(orgId, userId).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req := withURLParam(httptest.NewRequest("DELETE", "/organizations/members", nil), "orgId", orgId.String())
+	chi.RouteContext(req.Context()).URLParams.Add("userId", userId.String())
+	rr := httptest.NewRecorder()
+	cfg.RemoveOrganizationMemberController(rr, req, database.User{ID: uuid.New()})
+
+	assert.Equal(t, 404, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSwitchOrganization(t *testing.T) {
+	t.Setenv("SECRET_KEY", "mysecretkey")
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Role: "user"}
+	orgId, sessionId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM organization_members WHERE org_id = \$1 AND user_id = \$2`).
+		WithArgs(orgId, user.ID).
+		WillReturnRows(sqlmock.NewRows(memberColumns).AddRow(orgId, user.ID, "warehouse_clerk", time.Now()))
+	mock.ExpectExec(`UPDATE sessions SET org_id = \$2 WHERE id = \$1`).
+		WithArgs(sessionId, uuid.NullUUID{UUID: orgId, Valid: true}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := jsonRequest(t, "/auth/switch-org", map[string]string{"org_id": orgId.String()})
+	req = req.WithContext(auth.WithSessionID(req.Context(), sessionId))
+	rr := httptest.NewRecorder()
+	cfg.SwitchOrganizationController(rr, req, user)
+
+	assert.Equal(t, 200, rr.Code)
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	claims, err := helpers.VerifyToken(body["token"])
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.NullUUID{UUID: orgId, Valid: true}, claims.OrgID)
+	assert.Equal(t, sessionId, claims.SessionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSwitchOrganization_NotMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Role: "user"}
+	orgId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM organization_members WHERE org_id = \$1 AND user_id = \$2`).
+		WithArgs(orgId, user.ID).
+		WillReturnRows(sqlmock.NewRows(memberColumns))
+
+	rr := httptest.NewRecorder()
+	cfg.SwitchOrganizationController(rr, jsonRequest(t, "/auth/switch-org",
+		map[string]string{"org_id": orgId.String()}), user)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
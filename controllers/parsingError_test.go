@@ -212,7 +212,7 @@ func runStringParsingErrorTestProducts(t *testing.T, method, route string) {
 		cfg.UpdateProductController(w,r,adminUser)
 	})
 	router.Get("/products/{productId}", func(w http.ResponseWriter, r *http.Request)  {
-		cfg.GetProductController(w,r)
+		cfg.GetProductController(w,r,database.User{})
 	})
 	router.ServeHTTP(rr, req)
 
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/mailer"
+)
+
+type forgotPasswordParams struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordParams struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+const forgotPasswordMessage = "If that email belongs to an account, a reset link has been sent to it"
+
+// ForgotPasswordController emails a single use password reset token. It
+// answers the same way whether or not the email exists so it can't be used
+// to find out who has an account.
+func (cfg ApiCfg) ForgotPasswordController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	params := forgotPasswordParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Email == "" {
+		helpers.RespondWithError(w, 400, "Email is required")
+		return
+	}
+
+	user, err := cfg.DB.GetUserByEmail(r.Context(), params.Email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Couldn't fetch user for password reset: %v", err)
+		}
+		helpers.TextResponse(w, 200, forgotPasswordMessage)
+		return
+	}
+
+	token, hash, err := helpers.GenerateToken()
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate reset token: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	err = cfg.DB.CreatePasswordResetToken(r.Context(), database.CreatePasswordResetTokenParams{
+		ID: uuid.New(),
+		UserID: user.ID,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(helpers.PasswordResetTTL()),
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create reset token: %v", err))
+		return
+	}
+
+	err = cfg.mailer().Send(r.Context(), mailer.Message{
+		To: user.Email,
+		Subject: "Reset your password",
+		Body: cfg.passwordResetBody(token),
+	})
+	if err != nil {
+		log.Printf("Couldn't send password reset email to %v: %v", user.Email, err)
+	}
+	helpers.TextResponse(w, 200, forgotPasswordMessage)
+}
+
+func (cfg ApiCfg) passwordResetBody(token string) string {
+	expiry := helpers.PasswordResetTTL()
+	if cfg.PasswordResetURL == "" {
+		return fmt.Sprintf(
+			"Use this token to reset your password: %s\n\nIt expires in %v.", token, expiry)
+	}
+	return fmt.Sprintf(
+		"Follow this link to reset your password: %s?token=%s\n\nIt expires in %v.",
+		cfg.PasswordResetURL, url.QueryEscape(token), expiry)
+}
+
+// ResetPasswordController sets a new password using a token from
+// ForgotPasswordController and logs the user out everywhere
+func (cfg ApiCfg) ResetPasswordController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	params := resetPasswordParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Token == "" || params.Password == "" {
+		helpers.RespondWithError(w, 400, "Token and Password are required")
+		return
+	}
+
+	if !helpers.IsStrongPassword(params.Password) {
+		helpers.RespondWithError(w, 400, "Password is not strong enough")
+		return
+	}
+
+	resetToken, err := cfg.DB.GetPasswordResetTokenByHash(r.Context(), helpers.HashToken(params.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, "Invalid or expired reset token")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch reset token: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	if resetToken.UsedAt.Valid || now.After(resetToken.ExpiresAt) {
+		helpers.RespondWithError(w, 400, "Invalid or expired reset token")
+		return
+	}
+
+	used, err := cfg.DB.UsePasswordResetToken(r.Context(), database.UsePasswordResetTokenParams{
+		ID: resetToken.ID,
+		UsedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't use reset token: %v", err))
+		return
+	}
+	if used == 0 {
+		helpers.RespondWithError(w, 400, "Invalid or expired reset token")
+		return
+	}
+
+	err = cfg.DB.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID: resetToken.UserID,
+		Password: helpers.HashPassword(params.Password),
+		UpdatedAt: now,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update password: %v", err))
+		return
+	}
+
+	err = cfg.DB.RevokeUserSessions(r.Context(), database.RevokeUserSessionsParams{
+		UserID: resetToken.UserID,
+		RevokedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke sessions: %v", err))
+		return
+	}
+
+	cfg.recordAudit(r, database.User{ID: resetToken.UserID}, auditPasswordReset, "user",
+		resetToken.UserID, nil, nil)
+	helpers.TextResponse(w, 200, "Password has been reset")
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMailer keeps sent emails so tests can read them
+type fakeMailer struct {
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var passwordResetColumns = []string{
+	"id", "user_id", "token_hash", "created_at", "expires_at", "used_at",
+}
+
+func jsonRequest(t *testing.T, path string, body map[string]string) *http.Request {
+	payload, err := json.Marshal(body)
+	assert.NoError(t, err)
+	req, err := http.NewRequest("POST", path, bytes.NewBuffer(payload))
+	assert.NoError(t, err)
+	return req
+}
+
+func TestForgotPassword_SendsToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mail := &fakeMailer{}
+	cfg := ApiCfg{
+		DB: database.New(db),
+		Mailer: mail,
+		PasswordResetURL: "https://inventory.example.com/reset-password",
+	}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WithArgs("johndoe@gmail.com").
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectExec(`INSERT INTO password_reset_tokens`).
+		WithArgs(sqlmock.AnyArg(), userId, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ForgotPasswordController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/forgot-password", map[string]string{"email": "johndoe@gmail.com"}))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, len(mail.sent))
+	assert.Equal(t, "johndoe@gmail.com", mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Body, "https://inventory.example.com/reset-password?token=")
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mail := &fakeMailer{}
+	cfg := ApiCfg{DB: database.New(db), Mailer: mail}
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WithArgs("nobody@gmail.com").
+		WillReturnRows(sqlmock.NewRows(userColumns))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ForgotPasswordController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/forgot-password", map[string]string{"email": "nobody@gmail.com"}))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "If that email belongs to an account")
+	assert.Empty(t, mail.sent)
+}
+
+func TestResetPassword_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	token := "reset-token"
+	tokenId, userId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM password_reset_tokens WHERE token_hash = \$1`).
+		WithArgs(helpers.HashToken(token)).
+		WillReturnRows(sqlmock.NewRows(passwordResetColumns).
+			AddRow(tokenId, userId, helpers.HashToken(token), time.Now(), time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`UPDATE password_reset_tokens SET used_at = \$2 WHERE id = \$1 AND used_at IS NULL`).
+		WithArgs(tokenId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET password = \$2`).
+		WithArgs(userId, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = \$2 WHERE user_id = \$1`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ResetPasswordController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/reset-password", map[string]string{
+			"token": token,
+			"password": "NewStrongPass1",
+		}))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPassword_UsedToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	mock.ExpectQuery(`SELECT (.+) FROM password_reset_tokens WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(passwordResetColumns).
+			AddRow(uuid.New(), uuid.New(), "hash", time.Now(), time.Now().Add(time.Hour), time.Now()))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ResetPasswordController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/reset-password", map[string]string{
+			"token": "reset-token",
+			"password": "NewStrongPass1",
+		}))
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid or expired reset token")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPassword_ExpiredToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	mock.ExpectQuery(`SELECT (.+) FROM password_reset_tokens WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(passwordResetColumns).
+			AddRow(uuid.New(), uuid.New(), "hash", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), nil))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ResetPasswordController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/reset-password", map[string]string{
+			"token": "reset-token",
+			"password": "NewStrongPass1",
+		}))
+
+	assert.Equal(t, 400, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPassword_WeakPassword(t *testing.T) {
+	cfg := ApiCfg{}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.ResetPasswordController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/reset-password", map[string]string{
+			"token": "reset-token",
+			"password": "weak",
+		}))
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Password is not strong enough")
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/ringtho/inventory/helpers"
+)
+
+// decodeMergePatch applies the JSON merge patch in the request body to
+// current and decodes the result into params. It writes an error response
+// and returns false when the patch can't be applied.
+func decodeMergePatch(
+	w http.ResponseWriter,
+	r *http.Request,
+	current interface{},
+	params interface{},
+) bool {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil ||
+			(mediaType != helpers.MergePatchContentType && mediaType != "application/json") {
+			helpers.RespondWithError(w, 415,
+				fmt.Sprintf("Content-Type must be %s", helpers.MergePatchContentType))
+			return false
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error reading body: %v", err))
+		return false
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Error encoding JSON: %v", err))
+		return false
+	}
+
+	merged, err := helpers.MergePatch(original, patch)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return false
+	}
+
+	if err := json.Unmarshal(merged, params); err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var productColumns = []string{
+	"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
+}
+
+func TestPatchProduct_OnlyPrice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{Role: "admin"}
+	productId := uuid.New()
+	categoryId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", "20 litres", 50000, 3, categoryId, nil, "MC-20L", time.Now(), time.Now(), nil, uuid.Nil))
+
+	mock.ExpectQuery(`UPDATE products`).
+		WithArgs(
+			"Microwave",
+			"20 litres",
+			45000,
+			3,
+			categoryId,
+			nil,
+			"MC-20L",
+			sqlmock.AnyArg(),
+			productId,
+			uuid.Nil,
+			sqlmock.AnyArg(),
+		).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", "20 litres", 45000, 3, categoryId, nil, "MC-20L", time.Now(), time.Now(), nil, uuid.Nil))
+
+	req, err := http.NewRequest("PATCH",
+		fmt.Sprintf("/products/%v", productId), bytes.NewBufferString(`{"price": 45000}`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Patch("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.PatchProductController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	var response models.Product
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, int32(45000), response.Price)
+	assert.Equal(t, "MC-20L", *response.Sku)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatchProduct_NullRemovesField(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{Role: "admin"}
+	productId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", "20 litres", 50000, 3, nil, nil, "MC-20L", time.Now(), time.Now(), nil, uuid.Nil))
+
+	mock.ExpectQuery(`UPDATE products`).
+		WithArgs("Microwave", nil, 50000, 3, nil, nil, "MC-20L", sqlmock.AnyArg(), productId, uuid.Nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, "MC-20L", time.Now(), time.Now(), nil, uuid.Nil))
+
+	req, err := http.NewRequest("PATCH",
+		fmt.Sprintf("/products/%v", productId), bytes.NewBufferString(`{"description": null}`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Patch("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.PatchProductController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatchProduct_UnsupportedContentType(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{Role: "admin"}
+	productId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, nil, time.Now(), time.Now(), nil, uuid.Nil))
+
+	req, err := http.NewRequest("PATCH",
+		fmt.Sprintf("/products/%v", productId), bytes.NewBufferString(`[]`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json-patch+json")
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Patch("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.PatchProductController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 415, rr.Code)
+}
+
+func TestPatchCategory_NameRequired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{Role: "admin"}
+	categoryId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM categories WHERE id = \$1`).
+		WithArgs(categoryId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "created_at", "updated_at", "name", "description", "created_by", "deleted_at", "org_id",
+		}).AddRow(categoryId, time.Now(), time.Now(), "Kitchen", "Pots and pans", uuid.New(), nil, uuid.Nil))
+
+	req, err := http.NewRequest("PATCH",
+		fmt.Sprintf("/categories/%v", categoryId), bytes.NewBufferString(`{"name": null}`))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Patch("/categories/{categoryId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.PatchCategoryController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Category name is required")
+}
+
+func TestPatchSupplier_OnlyPhone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	adminUser := database.User{Role: "admin"}
+	supplierId := uuid.New()
+	supplierColumns := []string{
+		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at", "deleted_at", "org_id",
+	}
+
+	mock.ExpectQuery(`SELECT (.+) FROM suppliers WHERE id=\$1`).
+		WithArgs(supplierId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(supplierColumns).
+			AddRow(supplierId, "Acme", "sales@acme.com", nil, "0700000000", "Uganda", time.Now(), time.Now(), nil, uuid.Nil))
+
+	mock.ExpectQuery(`UPDATE suppliers`).
+		WithArgs("Acme", "sales@acme.com", nil, "0711111111", "Uganda", sqlmock.AnyArg(), supplierId, uuid.Nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(supplierColumns).
+			AddRow(supplierId, "Acme", "sales@acme.com", nil, "0711111111", "Uganda", time.Now(), time.Now(), nil, uuid.Nil))
+
+	req, err := http.NewRequest("PATCH",
+		fmt.Sprintf("/suppliers/%v", supplierId), bytes.NewBufferString(`{"phone": "0711111111"}`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Patch("/suppliers/{supplierId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.PatchSupplierController(w, r, adminUser)
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ringtho/inventory/helpers"
+)
+
+// checkPreconditions compares the If-Match header with the current version
+// of a resource so that concurrent edits don't silently overwrite each
+// other. It writes the error response and returns false on a mismatch.
+// The write that follows has to expect updatedAt as well and answer with
+// modified when it finds another request changed the resource since.
+func (cfg ApiCfg) checkPreconditions(
+	w http.ResponseWriter,
+	r *http.Request,
+	updatedAt time.Time,
+	) bool {
+	if r.Header.Get("If-Match") == "" {
+		if cfg.RequireIfMatch {
+			helpers.RespondWithError(w, 428, "If-Match header is required")
+			return false
+		}
+		return true
+	}
+
+	etag := helpers.ETag(updatedAt)
+	if !helpers.IfMatch(r, etag) {
+		w.Header().Set("ETag", etag)
+		modified(w)
+		return false
+	}
+	return true
+}
+
+// modified answers a write that found the resource changed since its
+// preconditions were checked
+func modified(w http.ResponseWriter) {
+	helpers.RespondWithError(w, 412, "Resource has been modified by another request")
+}
+
+// notModified sets the ETag header and answers 304 when the client's cached
+// copy is still current
+func notModified(w http.ResponseWriter, r *http.Request, updatedAt time.Time) bool {
+	etag := helpers.ETag(updatedAt)
+	w.Header().Set("ETag", etag)
+	if helpers.IfNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
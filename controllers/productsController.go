@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
 	"github.com/ringtho/inventory/internal/database"
 	"github.com/ringtho/inventory/models"
 )
@@ -31,11 +33,6 @@ func (cfg ApiCfg) CreateProductController(
 	r *http.Request,
 	user database.User,
 	) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
-		return
-	}
-
 	decoder := json.NewDecoder(r.Body)
 	params := productParams{}
 	err := decoder.Decode(&params)
@@ -45,13 +42,7 @@ func (cfg ApiCfg) CreateProductController(
 		return
 	}
 
-	if params.Name == "" {
-		helpers.RespondWithError(w, 400, "Product Name is required")
-		return
-	}
-
-	if params.Price <= 0 {
-		helpers.RespondWithError(w, 400, "Product Price must be greater than zero")
+	if !validateProductParams(w, params) {
 		return
 	}
 
@@ -63,6 +54,7 @@ func (cfg ApiCfg) CreateProductController(
 
 	product, err := cfg.DB.CreateProduct(r.Context(), database.CreateProductParams{
 		ID: uuid.New(),
+		OrgID: auth.OrgID(r.Context()),
 		Name: params.Name,
 		Description: description,
 		Price: params.Price,
@@ -80,15 +72,40 @@ func (cfg ApiCfg) CreateProductController(
 				helpers.RespondWithError(w, 409, "Product SKU already exists")
 				return
 			}
+			if pqErr.Code == "23503" {
+				helpers.RespondWithError(w, 400, "Category or supplier not found")
+				return
+			}
 		}
 		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't create product: %v", err))
 		return
 	}
-	helpers.JSON(w, 201, models.DatabaseProductToProduct(product))
+	created := models.DatabaseProductToProduct(product)
+	cfg.recordAudit(r, user, auditCreate, "product", product.ID, nil, created)
+	helpers.JSON(w, 201, created)
 }
 
-func (cfg ApiCfg) GetAllProductsController(w http.ResponseWriter, r *http.Request) {
-	products, err := cfg.DB.GetProducts(r.Context())
+func (cfg ApiCfg) GetAllProductsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	include, ok := includeDeleted(w, r, auth.ProductsDelete)
+	if !ok {
+		return
+	}
+
+	if exportList(w, r, "products", models.ProductExportColumns,
+		func(ctx context.Context, fn func(database.Product) error) error {
+			return cfg.DB.IterProducts(ctx, auth.OrgID(ctx), include, fn)
+		}) {
+		return
+	}
+
+	products, err := cfg.DB.GetProducts(r.Context(), database.GetProductsParams{
+		OrgID: auth.OrgID(r.Context()),
+		IncludeDeleted: include,
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch products %v", err))
 		return
@@ -96,7 +113,16 @@ func (cfg ApiCfg) GetAllProductsController(w http.ResponseWriter, r *http.Reques
 	helpers.JSON(w, 200, models.DatabaseProductsToProducts(products))
 }
 
-func (cfg ApiCfg) GetProductController(w http.ResponseWriter, r *http.Request) {
+func (cfg ApiCfg) GetProductController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	include, ok := includeDeleted(w, r, auth.ProductsDelete)
+	if !ok {
+		return
+	}
+
 	idStr := chi.URLParam(r, "productId")
 	id, err := uuid.Parse(idStr)
 
@@ -105,7 +131,16 @@ func (cfg ApiCfg) GetProductController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := cfg.DB.GetProduct(r.Context(), id)
+	getProduct := cfg.DB.GetProduct
+	if include {
+		getProduct = func(ctx context.Context, arg database.GetProductParams) (database.Product, error) {
+			return cfg.DB.GetProductIncludingDeleted(ctx, database.GetProductIncludingDeletedParams(arg))
+		}
+	}
+	product, err := getProduct(r.Context(), database.GetProductParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			helpers.RespondWithError(w, 404, "Product not found")
@@ -114,6 +149,10 @@ func (cfg ApiCfg) GetProductController(w http.ResponseWriter, r *http.Request) {
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch product: %v", err))
 		return
 	}
+
+	if notModified(w, r, product.UpdatedAt) {
+		return
+	}
 	helpers.JSON(w, 200, models.DatabaseProductToProduct(product))
 }
 
@@ -122,10 +161,6 @@ func (cfg ApiCfg) DeleteProductController(
 	r *http.Request,
 	user database.User,
 	) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
-		return
-	}
 	idStr := chi.URLParam(r, "productId")
 	id, err := uuid.Parse(idStr)
 
@@ -135,29 +170,75 @@ func (cfg ApiCfg) DeleteProductController(
 		return
 	}
 
-	if !cfg.checkProductExists(w, r, id) {
+	product, ok := cfg.checkProductExists(w, r, id)
+	if !ok {
 		return
 	}
 
-	err = cfg.DB.DeleteProduct(r.Context(), id)
+	deleted, err := cfg.DB.SoftDeleteProduct(r.Context(), database.SoftDeleteProductParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+		DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		UpdatedAt: product.UpdatedAt,
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 500, 
 			fmt.Sprintf("Failed to delete product: %v", err))
 		return
 	}
+	if deleted == 0 {
+		modified(w)
+		return
+	}
+	cfg.recordAudit(r, user, auditDelete, "product", id,
+		models.DatabaseProductToProduct(product), nil)
 	helpers.TextResponse(w, 200, "Successfully deleted product")
 }
 
-func (cfg ApiCfg) UpdateProductController(
+// RestoreProductController brings back a soft deleted product
+func (cfg ApiCfg) RestoreProductController(
 	w http.ResponseWriter,
 	r *http.Request,
 	user database.User,
 	) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
+	idStr := chi.URLParam(r, "productId")
+	id, err := uuid.Parse(idStr)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, 
+			fmt.Sprintf("Couldn't parse string: %v", err))
+		return
+	}
+
+	product, err := cfg.DB.RestoreProduct(r.Context(), database.RestoreProductParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Deleted product not found")
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { 
+				helpers.RespondWithError(w, 409, "Product SKU already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't restore product: %v", err))
 		return
 	}
+	restored := models.DatabaseProductToProduct(product)
+	cfg.recordAudit(r, user, auditRestore, "product", id, nil, restored)
+	helpers.JSON(w, 200, restored)
+}
 
+func (cfg ApiCfg) UpdateProductController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
 	decoder := json.NewDecoder(r.Body)
 	params := productParams{}
 	err := decoder.Decode(&params)
@@ -167,16 +248,34 @@ func (cfg ApiCfg) UpdateProductController(
 		return
 	}
 
-	if params.Name == "" {
-		helpers.RespondWithError(w, 400, "Product Name is required")
+	if !validateProductParams(w, params) {
 		return
 	}
 
-	if params.Price <= 0 {
-		helpers.RespondWithError(w, 400, "Product Price must be greater than zero")
+	idStr := chi.URLParam(r, "productId")
+	id, err := uuid.Parse(idStr)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, 
+			fmt.Sprintf("Couldn't parse string: %v", err))
 		return
 	}
 
+	product, ok := cfg.checkProductExists(w, r, id)
+	if !ok {
+		return
+	}
+
+	cfg.updateProduct(w, r, user, product, params)
+}
+
+// PatchProductController applies a JSON merge patch to a product so clients
+// can change single fields without resending the whole object
+func (cfg ApiCfg) PatchProductController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
 	idStr := chi.URLParam(r, "productId")
 	id, err := uuid.Parse(idStr)
 
@@ -186,19 +285,153 @@ func (cfg ApiCfg) UpdateProductController(
 		return
 	}
 
-	if !cfg.checkProductExists(w, r, id) {
+	product, err := cfg.DB.GetProduct(r.Context(), database.GetProductParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Product not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch product: %v", err))
+		return
+	}
+
+	if !cfg.checkPreconditions(w, r, product.UpdatedAt) {
+		return
+	}
+
+	params := productParams{}
+	if !decodeMergePatch(w, r, databaseProductToParams(product), &params) {
 		return
 	}
 
+	if !validateProductParams(w, params) {
+		return
+	}
+
+	cfg.updateProduct(w, r, user, product, params)
+}
+
+type stockAdjustmentParams struct {
+	Adjustment *int `json:"adjustment"`
+}
+
+// AdjustProductStockController changes a product's stock level by a relative
+// amount, so two clerks counting stock at once don't overwrite each other.
+// It needs products:stock rather than products:write, which lets a role
+// adjust stock without being able to change prices.
+func (cfg ApiCfg) AdjustProductStockController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "productId")
+	id, err := uuid.Parse(idStr)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, 
+			fmt.Sprintf("Couldn't parse string: %v", err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := stockAdjustmentParams{}
+	err = decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Adjustment == nil || *params.Adjustment == 0 {
+		helpers.RespondWithError(w, 400, "Adjustment is required and can't be zero")
+		return
+	}
+
+	before, ok := cfg.checkProductExists(w, r, id)
+	if !ok {
+		return
+	}
+
+	product, err := cfg.DB.AdjustProductStock(r.Context(), database.AdjustProductStockParams{
+		Adjustment: int32(*params.Adjustment),
+		UpdatedAt: time.Now().UTC(),
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, "Stock level can't go below zero")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't adjust stock: %v", err))
+		return
+	}
+
+	updated := models.DatabaseProductToProduct(product)
+	cfg.recordAudit(r, user, auditUpdate, "product", product.ID,
+		models.DatabaseProductToProduct(before), updated)
+	w.Header().Set("ETag", helpers.ETag(product.UpdatedAt))
+	helpers.JSON(w, 200, updated)
+}
+
+func validateProductParams(w http.ResponseWriter, params productParams) bool {
+	if params.Name == "" {
+		helpers.RespondWithError(w, 400, "Product Name is required")
+		return false
+	}
+
+	if params.Price <= 0 {
+		helpers.RespondWithError(w, 400, "Product Price must be greater than zero")
+		return false
+	}
+	return true
+}
+
+func databaseProductToParams(product database.Product) productParams {
+	params := productParams{
+		Name: product.Name,
+		Price: product.Price,
+	}
+	if product.Description.Valid {
+		params.Description = &product.Description.String
+	}
+	if product.StockLevel.Valid {
+		stockLevel := int(product.StockLevel.Int32)
+		params.StockLevel = &stockLevel
+	}
+	if product.CategoryID.Valid {
+		params.CategoryID = &product.CategoryID.UUID
+	}
+	if product.SupplierID.Valid {
+		params.SupplierID = &product.SupplierID.UUID
+	}
+	if product.Sku.Valid {
+		params.Sku = &product.Sku.String
+	}
+	return params
+}
+
+// updateProduct replaces the fields of before with params and records the
+// change in the audit log
+func (cfg ApiCfg) updateProduct(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	before database.Product,
+	params productParams,
+	) {
 	description := helpers.NewNullString(params.Description)
 	sku := helpers.NewNullString(params.Sku)
 	stock_level := helpers.NewNullInt(params.StockLevel)
 	categoryId := helpers.NewNullUUID(params.CategoryID)
 	supplierId := helpers.NewNullUUID(params.SupplierID)
 
-
 	product, err := cfg.DB.UpdateProduct(r.Context(), database.UpdateProductParams{
-		ID: id,
+		ID: before.ID,
+		OrgID: before.OrgID,
 		Name: params.Name,
 		Description: description,
 		Price: params.Price,
@@ -207,29 +440,47 @@ func (cfg ApiCfg) UpdateProductController(
 		SupplierID: supplierId,
 		Sku: sku,
 		UpdatedAt: time.Now().UTC(),
+		ExpectedUpdatedAt: before.UpdatedAt,
 	})
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			modified(w)
+			return
+		}
 		if pqErr, ok := err.(*pq.Error); ok {
 			if pqErr.Code == "23505" { 
 				helpers.RespondWithError(w, 409, "Product SKU already exists")
 				return
 			}
+			if pqErr.Code == "23503" {
+				helpers.RespondWithError(w, 400, "Category or supplier not found")
+				return
+			}
 		}
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update product: %v", err))
 		return
 	}
-	helpers.JSON(w, 200, models.DatabaseProductToProduct(product))
+	updated := models.DatabaseProductToProduct(product)
+	cfg.recordAudit(r, user, auditUpdate, "product", product.ID,
+		models.DatabaseProductToProduct(before), updated)
+	w.Header().Set("ETag", helpers.ETag(product.UpdatedAt))
+	helpers.JSON(w, 200, updated)
 }
 
+// checkProductExists also checks the If-Match precondition of the request
+// against the product it finds and returns it
 func (cfg ApiCfg) checkProductExists(
 	w http.ResponseWriter, 
 	r *http.Request, 
-	id uuid.UUID) bool {
-	_, err := cfg.DB.GetProduct(r.Context(), id)
+	id uuid.UUID) (database.Product, bool) {
+	product, err := cfg.DB.GetProduct(r.Context(), database.GetProductParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 404, "Product not found")
-		return false
+		return product, false
 	}
-	return true
+	return product, cfg.checkPreconditions(w, r, product.UpdatedAt)
 }
\ No newline at end of file
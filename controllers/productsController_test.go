@@ -2,81 +2,26 @@ package controllers
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
 	"github.com/ringtho/inventory/models"
 	"github.com/stretchr/testify/assert"
 )
 
 
 
-func TestCreateProduct_Success(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-	
-	mockProduct := productParams{
-		Name: "Microwave",
-		Price: 50000,
-	}
-
-	mockRow := sqlmock.NewRows([]string{
-		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at",
-	}).AddRow(uuid.New(), mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now())
-
-	mock.ExpectQuery(`INSERT INTO products`).
-	WithArgs(
-		sqlmock.AnyArg(),
-		mockProduct.Name,
-		sqlmock.AnyArg(),
-		mockProduct.Price,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-	). 
-	WillReturnRows(mockRow)
-
-	payload, err := json.Marshal(mockProduct)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("POST", "/products", bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Post("/products", func(w http.ResponseWriter, r *http.Request){
-		cfg.CreateProductController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	var response models.Product
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 201, rr.Code)
-	assert.Equal(t, mockProduct.Name, response.Name)
-}
-
 func TestCreateProduct_PriceGreaterThanZero(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -93,12 +38,13 @@ func TestCreateProduct_PriceGreaterThanZero(t *testing.T) {
 	}
 
 	mockRow := sqlmock.NewRows([]string{
-		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at",
-	}).AddRow(uuid.New(), mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now())
+		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
+	}).AddRow(uuid.New(), mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now(), nil, uuid.Nil)
 
 	mock.ExpectQuery(`INSERT INTO products`).
 	WithArgs(
 		sqlmock.AnyArg(),
+		uuid.Nil,
 		mockProduct.Name,
 		sqlmock.AnyArg(),
 		mockProduct.Price,
@@ -129,56 +75,6 @@ func TestCreateProduct_PriceGreaterThanZero(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Product Price must be greater than zero")
 }
 
-func TestCreateProduct_SKUExists(t *testing.T) {
-	ptr := func(s string) *string { return &s}
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-	
-	mockProduct := productParams{
-		Name: "Microwave",
-		Price: 10000,
-		Sku: ptr("MC-20L"),
-	}
-
-	mock.ExpectQuery(`INSERT INTO products`).
-	WithArgs(
-		sqlmock.AnyArg(),
-		mockProduct.Name,
-		sqlmock.AnyArg(),
-		mockProduct.Price,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-	). 
-	WillReturnError(&pq.Error{Code: "23505"})
-
-	payload, err := json.Marshal(mockProduct)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("POST", "/products", bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Post("/products", func(w http.ResponseWriter, r *http.Request){
-		cfg.CreateProductController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 409, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Product SKU already exists")
-}
-
 func TestCreateProduct_DBError(t *testing.T) {
 	ptr := func(s string) *string { return &s}
 	db, mock, err := sqlmock.New()
@@ -199,6 +95,7 @@ func TestCreateProduct_DBError(t *testing.T) {
 	mock.ExpectQuery(`INSERT INTO products`).
 	WithArgs(
 		sqlmock.AnyArg(),
+		uuid.Nil,
 		mockProduct.Name,
 		sqlmock.AnyArg(),
 		mockProduct.Price,
@@ -229,7 +126,7 @@ func TestCreateProduct_DBError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Couldn't create product")
 }
 
-func TestGetAllProducts_Success(t *testing.T) {
+func TestGetAllProducts_DBError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
@@ -237,61 +134,53 @@ func TestGetAllProducts_Success(t *testing.T) {
 	queries := database.New(db)
 	cfg := ApiCfg{DB: queries}
 
-	mockProduct := productParams{
-		Name: "Microwave",
-		Price: 10000,
-	}
-
-	mockRow := sqlmock.NewRows([]string{
-		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at",
-	}).
-	AddRow(uuid.New(), mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now()).
-	AddRow(uuid.New(), "Dishwasher", "", 200000, 10, uuid.New(), uuid.New(), "", time.Now(), time.Now())
-
 	mock.ExpectQuery(`SELECT (.+) FROM products`). 
-	WillReturnRows(mockRow)
+	WillReturnError(fmt.Errorf("Database Error"))
 
 	req, err := http.NewRequest("GET", "/products", nil)
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(cfg.GetAllProductsController)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetAllProductsController(w, r, database.User{})
+	})
 	handler.ServeHTTP(rr, req)
 
-	var response []models.Product
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Equal(t, mockProduct.Name, response[0].Name)
-	assert.Equal(t, int32(200000), response[1].Price)
+	assert.Equal(t, 500, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Couldn't fetch products")
 }
 
-func TestGetAllProducts_DBError(t *testing.T) {
-	db, mock, err := sqlmock.New()
+func TestGetProduct_DBError(t *testing.T){
+		db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
 	queries := database.New(db)
 	cfg := ApiCfg{DB: queries}
 
-	mock.ExpectQuery(`SELECT (.+) FROM products`). 
+	productId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+	WithArgs(productId, uuid.Nil).
 	WillReturnError(fmt.Errorf("Database Error"))
 
-	req, err := http.NewRequest("GET", "/products", nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf("/products/%v", productId), nil)
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(cfg.GetAllProductsController)
+	handler := chi.NewRouter()
+	handler.Get("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetProductController(w, r, database.User{})
+	})
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 500, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Couldn't fetch products")
+	assert.Contains(t, rr.Body.String(), "Couldn't fetch product")
 }
 
-func TestGetProduct_Success(t *testing.T){
+func TestDeleteProduct_DBError(t *testing.T){
 		db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
@@ -304,206 +193,176 @@ func TestGetProduct_Success(t *testing.T){
 		Price: 10000,
 	}
 
+	adminUser := database.User{Role: "admin"}
 	productId := uuid.New()
 
 	mockRow := sqlmock.NewRows([]string{
-		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at",
+		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
 	}).
-	AddRow(productId, mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now())
+	AddRow(productId, mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now(), nil, uuid.Nil)
 
 	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
-	WithArgs(productId).
+	WithArgs(productId, uuid.Nil).
 	WillReturnRows(mockRow)
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("/products/%v", productId), nil)
+	mock.ExpectExec(`UPDATE products SET deleted_at = \$3 WHERE id = \$1`).
+	WithArgs(productId, uuid.Nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+	WillReturnError(fmt.Errorf("Databse Error"))
+
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("/products/%v", productId), nil)
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
 	handler := chi.NewRouter()
-	handler.Get("/products/{productId}", cfg.GetProductController)
+	handler.Delete("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
+		cfg.DeleteProductController(w, r, adminUser)
+	})
 	handler.ServeHTTP(rr, req)
 
-	var response models.Product
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Equal(t, mockProduct.Name, response.Name)
+	assert.Equal(t, 500, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Failed to delete product")
 }
 
-func TestGetProduct_ProductNotFound(t *testing.T){
-		db, mock, err := sqlmock.New()
+func TestAdjustProductStock_BelowZero(t *testing.T) {
+	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
+	cfg := ApiCfg{DB: database.New(db)}
 	productId := uuid.New()
 
 	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnError(sql.ErrNoRows)
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("/products/%v", productId), nil)
+		WithArgs(productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, nil, time.Now(), time.Now(), nil, uuid.Nil))
+	mock.ExpectQuery(`UPDATE products`).
+		WithArgs(-5, sqlmock.AnyArg(), productId, uuid.Nil).
+		WillReturnRows(sqlmock.NewRows(productColumns))
+
+	req, err := http.NewRequest("POST", "/products/"+productId.String()+"/stock",
+		strings.NewReader(`{"adjustment": -5}`))
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
 	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Get("/products/{productId}", cfg.GetProductController)
-	handler.ServeHTTP(rr, req)
+	cfg.AdjustProductStockController(rr, withURLParam(req, "productId", productId.String()),
+		database.User{Role: "warehouse_clerk"})
 
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Product not found")
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Stock level can't go below zero")
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestGetProduct_DBError(t *testing.T){
-		db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	productId := uuid.New()
+func TestAdjustProductStock_ZeroAdjustment(t *testing.T) {
+	cfg := ApiCfg{}
+	productId := uuid.New().String()
 
-	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnError(fmt.Errorf("Database Error"))
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("/products/%v", productId), nil)
+	req, err := http.NewRequest("POST", "/products/"+productId+"/stock", strings.NewReader(`{"adjustment": 0}`))
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
 	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Get("/products/{productId}", cfg.GetProductController)
-	handler.ServeHTTP(rr, req)
+	cfg.AdjustProductStockController(rr, withURLParam(req, "productId", productId), database.User{})
 
-	assert.Equal(t, 500, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Couldn't fetch product")
+	assert.Equal(t, 400, rr.Code)
 }
 
-func TestDeleteProduct_Success(t *testing.T){
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	mockProduct := productParams{
-		Name: "Microwave",
-		Price: 10000,
-	}
-
-	adminUser := database.User{Role: "admin"}
-	productId := uuid.New()
+func TestCreateProduct_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	mockRow := sqlmock.NewRows([]string{
-		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at",
-	}).
-	AddRow(productId, mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now())
+		rr := serve(handler, "POST", "/products", `{"name": "Microwave", "price": 50000}`)
 
-	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnRows(mockRow)
+		var response models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 201, rr.Code)
+		assert.Equal(t, "Microwave", response.Name)
+		assert.Equal(t, int32(50000), response.Price)
+	})
+}
 
-	mock.ExpectExec(`DELETE FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnResult(sqlmock.NewResult(1,1))
+func TestCreateProduct_SKUExists(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		body := `{"name": "Microwave", "price": 10000, "sku": "MC-20L"}`
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/products/%v", productId), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+		assert.Equal(t, 201, serve(handler, "POST", "/products", body).Code)
+		rr := serve(handler, "POST", "/products", body)
 
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Delete("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
-		cfg.DeleteProductController(w, r, adminUser)
+		assert.Equal(t, 409, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Product SKU already exists")
 	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Successfully deleted product")
 }
 
-func TestDeleteProduct_DBError(t *testing.T){
-		db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
+func TestGetAllProducts_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		assert.Equal(t, 201, serve(handler, "POST", "/products", `{"name": "Microwave", "price": 10000}`).Code)
+		assert.Equal(t, 201, serve(handler, "POST", "/products",
+			`{"name": "Dishwasher", "price": 200000, "stock_level": 10}`).Code)
+
+		rr := serve(handler, "GET", "/products", "")
+
+		var response []models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 200, rr.Code)
+		byName := map[string]models.Product{}
+		for _, product := range response {
+			byName[product.Name] = product
+		}
+		assert.Len(t, byName, 2)
+		assert.Equal(t, int32(10000), byName["Microwave"].Price)
+		assert.Equal(t, int32(200000), byName["Dishwasher"].Price)
+	})
+}
 
-	mockProduct := productParams{
-		Name: "Microwave",
-		Price: 10000,
-	}
+func TestGetProduct_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		rr := serve(handler, "POST", "/products", `{"name": "Microwave", "price": 10000}`)
+		var created models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
 
-	adminUser := database.User{Role: "admin"}
-	productId := uuid.New()
+		rr = serve(handler, "GET", "/products/"+created.ID.String(), "")
 
-	mockRow := sqlmock.NewRows([]string{
-		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at",
-	}).
-	AddRow(productId, mockProduct.Name, "", mockProduct.Price, 0, uuid.New(), uuid.New(), "", time.Now(), time.Now())
+		var response models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, "Microwave", response.Name)
+		assert.Equal(t, created.ID, response.ID)
+	})
+}
 
-	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnRows(mockRow)
+func TestGetProduct_ProductNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	mock.ExpectExec(`DELETE FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnError(fmt.Errorf("Databse Error"))
+		rr := serve(handler, "GET", "/products/"+uuid.New().String(), "")
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/products/%v", productId), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Delete("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
-		cfg.DeleteProductController(w, r, adminUser)
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Product not found")
 	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 500, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Failed to delete product")
 }
 
-func TestDeleteProduct_ProductNotFound(t *testing.T){
-		db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
+func TestDeleteProduct_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		rr := serve(handler, "POST", "/products", `{"name": "Microwave", "price": 10000}`)
+		var created models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
 
-	adminUser := database.User{Role: "admin"}
-	productId := uuid.New()
+		rr = serve(handler, "DELETE", "/products/"+created.ID.String(), "")
 
-	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnError(fmt.Errorf("Database Error"))
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Successfully deleted product")
+		assert.Equal(t, 404, serve(handler, "GET", "/products/"+created.ID.String(), "").Code)
+	})
+}
 
-	mock.ExpectExec(`DELETE FROM products WHERE id = \$1`).
-	WithArgs(productId).
-	WillReturnResult(sqlmock.NewResult(1,1))
+func TestDeleteProduct_ProductNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/products/%v", productId), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+		rr := serve(handler, "DELETE", "/products/"+uuid.New().String(), "")
 
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Delete("/products/{productId}", func(w http.ResponseWriter, r *http.Request) {
-		cfg.DeleteProductController(w, r, adminUser)
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Product not found")
 	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Product not found")
-}
\ No newline at end of file
+}
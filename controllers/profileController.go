@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+)
+
+// profileParams are the fields users can change about themselves. Email
+// and role are left to admins.
+type profileParams struct {
+	Name              string  `json:"name"`
+	Username          string  `json:"username"`
+	ProfilePictureUrl *string `json:"profile_picture_url"`
+}
+
+type changePasswordParams struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type updateUserParams struct {
+	Name              string  `json:"name"`
+	Username          string  `json:"username"`
+	Email             string  `json:"email"`
+	Role              string  `json:"role"`
+	ProfilePictureUrl *string `json:"profile_picture_url"`
+}
+
+// GetMeController returns the authenticated user
+func (cfg ApiCfg) GetMeController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	helpers.JSON(w, 200, models.DatabaseUserToUser(user))
+}
+
+// PatchMeController applies a JSON merge patch to the authenticated user's
+// name, username and profile picture
+func (cfg ApiCfg) PatchMeController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	current := profileParams{Name: user.Name, Username: user.Username}
+	if user.ProfilePictureUrl.Valid {
+		current.ProfilePictureUrl = &user.ProfilePictureUrl.String
+	}
+
+	params := profileParams{}
+	if !decodeMergePatch(w, r, current, &params) {
+		return
+	}
+
+	if params.Name == "" || params.Username == "" {
+		helpers.RespondWithError(w, 400, "Name and Username are required")
+		return
+	}
+
+	updated, err := cfg.DB.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams{
+		ID: user.ID,
+		Name: params.Name,
+		Username: params.Username,
+		ProfilePictureUrl: helpers.NewNullString(params.ProfilePictureUrl),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" {
+				helpers.RespondWithError(w, 409, "Username already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update profile: %v", err))
+		return
+	}
+
+	after := models.DatabaseUserToUser(updated)
+	cfg.recordAudit(r, user, auditUpdate, "user", user.ID, models.DatabaseUserToUser(user), after)
+	helpers.JSON(w, 200, after)
+}
+
+// ChangePasswordController sets a new password for the authenticated user
+// after checking the current one. Every other session is logged out.
+func (cfg ApiCfg) ChangePasswordController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := changePasswordParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.CurrentPassword == "" || params.NewPassword == "" {
+		helpers.RespondWithError(w, 400, "Current password and New password are required")
+		return
+	}
+
+	if !helpers.CheckPasswordHash(user.Password, params.CurrentPassword) {
+		helpers.RespondWithError(w, 400, "Current password is incorrect")
+		return
+	}
+
+	if !helpers.IsStrongPassword(params.NewPassword) {
+		helpers.RespondWithError(w, 400, "Password is not strong enough")
+		return
+	}
+
+	now := time.Now().UTC()
+	err = cfg.DB.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID: user.ID,
+		Password: helpers.HashPassword(params.NewPassword),
+		UpdatedAt: now,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update password: %v", err))
+		return
+	}
+
+	err = cfg.DB.RevokeOtherUserSessions(r.Context(), database.RevokeOtherUserSessionsParams{
+		UserID: user.ID,
+		ID: auth.SessionID(r.Context()),
+		RevokedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke sessions: %v", err))
+		return
+	}
+
+	cfg.recordAudit(r, user, auditPasswordChange, "user", user.ID, nil, nil)
+	helpers.TextResponse(w, 200, "Password has been changed")
+}
+
+// UpdateUserController lets admins replace a user's details and role.
+// Changing the role takes roles:manage like AssignUserRoleController, and
+// revokes the user's sessions so no token with the old role keeps working.
+// So does changing a user whose role has permissions the caller lacks,
+// since a new email would let the caller reset their password. A new
+// email has to be verified again: links already sent to the old address
+// stop working and a verification link goes to the new one.
+func (cfg ApiCfg) UpdateUserController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "userId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse userId: %v", err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := updateUserParams{}
+	err = decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Name == "" || params.Username == "" || params.Email == "" || params.Role == "" {
+		helpers.RespondWithError(w, 400, "Name, Username, Email and Role are required")
+		return
+	}
+
+	if !helpers.IsValidEmail(params.Email) {
+		helpers.RespondWithError(w, 400, "Invalid email address")
+		return
+	}
+
+	existing, err := cfg.DB.GetUserById(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "User not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch user: %v", err))
+		return
+	}
+
+	if !auth.HasPermission(r.Context(), auth.RolesManage) {
+		outranked, err := cfg.grantsMissingPermissions(r.Context(), existing.Role)
+		if err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch role permissions: %v", err))
+			return
+		}
+		if outranked {
+			helpers.RespondWithError(w, 403,
+				"Changing a user with permissions you don't have requires the roles:manage permission")
+			return
+		}
+	}
+
+	roleChanged := params.Role != existing.Role
+	if roleChanged {
+		// users:write alone mustn't let a role hand out admin
+		if !auth.HasPermission(r.Context(), auth.RolesManage) {
+			helpers.RespondWithError(w, 403, "Changing a user's role requires the roles:manage permission")
+			return
+		}
+		if id == user.ID {
+			helpers.RespondWithError(w, 400, "You can't change your own role")
+			return
+		}
+		if _, err := cfg.DB.GetRole(r.Context(), params.Role); err != nil {
+			if err == sql.ErrNoRows {
+				helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown role: %v", params.Role))
+				return
+			}
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch role: %v", err))
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	updated, err := cfg.DB.UpdateUser(r.Context(), database.UpdateUserParams{
+		ID: id,
+		Name: params.Name,
+		Username: params.Username,
+		Email: params.Email,
+		Role: params.Role,
+		ProfilePictureUrl: helpers.NewNullString(params.ProfilePictureUrl),
+		UpdatedAt: now,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" {
+				helpers.RespondWithError(w, 409, "Email or Username already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update user: %v", err))
+		return
+	}
+
+	if roleChanged {
+		err = cfg.DB.RevokeUserSessions(r.Context(), database.RevokeUserSessionsParams{
+			UserID: id,
+			RevokedAt: sql.NullTime{Time: now, Valid: true},
+		})
+		if err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke sessions: %v", err))
+			return
+		}
+	}
+
+	if updated.Email != existing.Email {
+		if err := cfg.revokeEmailTokens(r, id, now); err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke email tokens: %v", err))
+			return
+		}
+		if err := cfg.sendVerificationEmail(r, id, updated.Email); err != nil {
+			log.Printf("Couldn't send verification email to %v: %v", updated.Email, err)
+		}
+	}
+
+	after := models.DatabaseUserToUser(updated)
+	cfg.recordAudit(r, user, auditUpdate, "user", id, models.DatabaseUserToUser(existing), after)
+	helpers.JSON(w, 200, after)
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMe(t *testing.T) {
+	cfg := ApiCfg{}
+	user := database.User{ID: uuid.New(), Username: "johndoe", Email: "johndoe@gmail.com", Role: "user"}
+
+	req, err := http.NewRequest("GET", "/me", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.GetMeController(rr, req, user)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"username":"johndoe"`)
+	assert.NotContains(t, rr.Body.String(), "password")
+}
+
+func TestPatchMe(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Username: "johndoe", Name: "john doe", Role: "user"}
+
+	mock.ExpectQuery(`UPDATE users SET name = \$2, username = \$3`).
+		WithArgs(user.ID, "John Doe", "johndoe", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(user.ID, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "John Doe", time.Now()))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("PATCH", "/me", strings.NewReader(`{"name": "John Doe"}`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rr := httptest.NewRecorder()
+	cfg.PatchMeController(rr, req, user)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"John Doe"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChangePassword_WrongCurrent(t *testing.T) {
+	cfg := ApiCfg{}
+	user := database.User{ID: uuid.New(), Password: helpers.HashPassword("Password123!")}
+
+	req, err := http.NewRequest("POST", "/me/password", strings.NewReader(
+		`{"current_password": "Wrong123!", "new_password": "NewPassword123"}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.ChangePasswordController(rr, req, user)
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Current password is incorrect")
+}
+
+func TestChangePassword_RevokesOtherSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Password: helpers.HashPassword("Password123!")}
+	sessionId := uuid.New()
+
+	mock.ExpectExec(`UPDATE users SET password = \$2`).
+		WithArgs(user.ID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = \$3`).
+		WithArgs(user.ID, sessionId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("POST", "/me/password", strings.NewReader(
+		`{"current_password": "Password123!", "new_password": "NewPassword123"}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithSessionID(context.Background(), sessionId))
+	rr := httptest.NewRecorder()
+	cfg.ChangePasswordController(rr, req, user)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateUser_RoleChangeRevokesSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+		WithArgs("warehouse_clerk").
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("warehouse_clerk", nil, true, time.Now(), time.Now()))
+	mock.ExpectQuery(`UPDATE users SET name = \$2, username = \$3, email = \$4, role = \$5`).
+		WithArgs(userId, "John Doe", "johndoe", "john@example.com", "warehouse_clerk", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "john@example.com", "hash", "warehouse_clerk", nil, "John Doe", time.Now()))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = \$2 WHERE user_id = \$1`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE email_verification_tokens SET used_at = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE password_reset_tokens SET used_at = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO email_verification_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("PUT", "/users/"+userId.String(), strings.NewReader(
+		`{"name": "John Doe", "username": "johndoe", "email": "john@example.com", "role": "warehouse_clerk"}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.UsersWrite, auth.RolesManage}))
+	rr := httptest.NewRecorder()
+	cfg.UpdateUserController(rr, withURLParam(req, "userId", userId.String()), admin)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"role":"warehouse_clerk"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateUser_EmailChangeRevokesTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mail := &fakeMailer{}
+	cfg := ApiCfg{DB: database.New(db), Mailer: mail, PublicURL: "https://api.example.com"}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectQuery(`UPDATE users SET name = \$2, username = \$3, email = \$4, role = \$5`).
+		WithArgs(userId, "john doe", "johndoe", "john@example.com", "user", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "john@example.com", "hash", "user", nil, "john doe", nil))
+	mock.ExpectExec(`UPDATE email_verification_tokens SET used_at = \$2 WHERE user_id = \$1 AND used_at IS NULL`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE password_reset_tokens SET used_at = \$2 WHERE user_id = \$1 AND used_at IS NULL`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO email_verification_tokens`).
+		WithArgs(sqlmock.AnyArg(), userId, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("PUT", "/users/"+userId.String(), strings.NewReader(
+		`{"name": "john doe", "username": "johndoe", "email": "john@example.com", "role": "user"}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.UsersWrite, auth.RolesManage}))
+	rr := httptest.NewRecorder()
+	cfg.UpdateUserController(rr, withURLParam(req, "userId", userId.String()), admin)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, len(mail.sent))
+	assert.Equal(t, "john@example.com", mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Body, "https://api.example.com/api/v1/auth/verify?token=")
+}
+
+func TestUpdateUser_RoleChangeRequiresRolesManage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	manager := database.User{ID: uuid.New(), Role: "user_manager"}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("user").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(auth.ProductsRead))
+
+	req, err := http.NewRequest("PUT", "/users/"+userId.String(), strings.NewReader(
+		`{"name": "John Doe", "username": "johndoe", "email": "johndoe@gmail.com", "role": "admin"}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.UsersWrite}))
+	rr := httptest.NewRecorder()
+	cfg.UpdateUserController(rr, withURLParam(req, "userId", userId.String()), manager)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the user must not be updated")
+}
+
+func TestUpdateUser_PrivilegedUserRequiresRolesManage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	manager := database.User{ID: uuid.New(), Role: "user_manager"}
+	adminId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(adminId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(adminId, time.Now(), time.Now(), "admin", "admin@example.com", "hash", "admin", nil, "admin", time.Now()))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(auth.RolesManage).AddRow(auth.UsersWrite))
+
+	// Taking over the admin's email would let the manager reset their password
+	req, err := http.NewRequest("PUT", "/users/"+adminId.String(), strings.NewReader(
+		`{"name": "admin", "username": "admin", "email": "mallory@example.com", "role": "admin"}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.UsersWrite}))
+	rr := httptest.NewRecorder()
+	cfg.UpdateUserController(rr, withURLParam(req, "userId", adminId.String()), manager)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the user must not be updated")
+}
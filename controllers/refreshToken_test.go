@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	refreshTokenColumns = []string{
+		"id", "session_id", "token_hash", "created_at", "expires_at", "used_at",
+	}
+	sessionColumns = []string{"id", "user_id", "created_at", "revoked_at", "org_id"}
+	userColumns    = []string{
+		"id", "created_at", "updated_at", "username", "email", "password", "role", "profile_picture_url", "name", "email_verified_at",
+	}
+)
+
+func refreshRequest(t *testing.T, path string, refreshToken string) *http.Request {
+	payload, err := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	assert.NoError(t, err)
+	req, err := http.NewRequest("POST", path, bytes.NewBuffer(payload))
+	assert.NoError(t, err)
+	return req
+}
+
+func TestRefresh_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	err = os.Setenv("SECRET_KEY", "mysecretkey")
+	assert.NoError(t, err)
+
+	cfg := ApiCfg{DB: database.New(db)}
+	refreshToken := "old-refresh-token"
+	tokenId, sessionId, userId := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM refresh_tokens WHERE token_hash = \$1`).
+		WithArgs(helpers.HashToken(refreshToken)).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(tokenId, sessionId, helpers.HashToken(refreshToken), time.Now(), time.Now().Add(time.Hour), nil))
+	mock.ExpectQuery(`SELECT (.+) FROM sessions WHERE id = \$1`).
+		WithArgs(sessionId).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).AddRow(sessionId, userId, time.Now(), nil, nil))
+	mock.ExpectExec(`UPDATE refresh_tokens SET used_at = \$2 WHERE id = \$1 AND used_at IS NULL`).
+		WithArgs(tokenId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectExec(`INSERT INTO refresh_tokens`).
+		WithArgs(sqlmock.AnyArg(), sessionId, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.RefreshController).ServeHTTP(rr, refreshRequest(t, "/auth/refresh", refreshToken))
+
+	var response models.LoginResponse
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEqual(t, refreshToken, response.RefreshToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	claims, err := helpers.VerifyToken(response.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, sessionId, claims.SessionID)
+}
+
+func TestRefresh_ReuseRevokesSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	refreshToken := "stolen-refresh-token"
+	tokenId, sessionId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM refresh_tokens WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(tokenId, sessionId, helpers.HashToken(refreshToken), time.Now(), time.Now().Add(time.Hour), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM sessions WHERE id = \$1`).
+		WithArgs(sessionId).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).AddRow(sessionId, uuid.New(), time.Now(), nil, nil))
+	mock.ExpectExec(`UPDATE refresh_tokens SET used_at`).
+		WithArgs(tokenId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = \$2 WHERE id = \$1`).
+		WithArgs(sessionId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.RefreshController).ServeHTTP(rr, refreshRequest(t, "/auth/refresh", refreshToken))
+
+	assert.Equal(t, 401, rr.Code)
+	assert.Contains(t, rr.Body.String(), "already been used")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefresh_RevokedSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	sessionId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM refresh_tokens WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(uuid.New(), sessionId, "hash", time.Now(), time.Now().Add(time.Hour), nil))
+	mock.ExpectQuery(`SELECT (.+) FROM sessions WHERE id = \$1`).
+		WithArgs(sessionId).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).AddRow(sessionId, uuid.New(), time.Now(), time.Now(), nil))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.RefreshController).ServeHTTP(rr, refreshRequest(t, "/auth/refresh", "token"))
+
+	assert.Equal(t, 401, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Session has been revoked")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefresh_UnknownToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	mock.ExpectQuery(`SELECT (.+) FROM refresh_tokens WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.RefreshController).ServeHTTP(rr, refreshRequest(t, "/auth/refresh", "token"))
+
+	assert.Equal(t, 401, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid refresh token")
+}
+
+func TestLogout_RevokesSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	sessionId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM refresh_tokens WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(uuid.New(), sessionId, "hash", time.Now(), time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = \$2 WHERE id = \$1`).
+		WithArgs(sessionId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.LogoutController).ServeHTTP(rr, refreshRequest(t, "/auth/logout", "token"))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Successfully logged out")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
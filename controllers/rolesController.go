@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+)
+
+// adminRole always has every permission so there is no way to lock every
+// user out of role management
+const adminRole = "admin"
+
+// defaultRole is given to everyone who registers themselves
+const defaultRole = "user"
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,19}$`)
+
+type roleParams struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type assignRoleParams struct {
+	Role string `json:"role"`
+}
+
+// validatePermissions checks every permission exists and returns them
+// sorted without duplicates
+func validatePermissions(w http.ResponseWriter, permissions []string) ([]string, bool) {
+	set := map[string]bool{}
+	for _, permission := range permissions {
+		if !auth.IsPermission(permission) {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown permission: %v", permission))
+			return nil, false
+		}
+		set[permission] = true
+	}
+
+	unique := make([]string, 0, len(set))
+	for permission := range set {
+		unique = append(unique, permission)
+	}
+	sort.Strings(unique)
+	return unique, true
+}
+
+// grantsMissingPermissions reports whether role grants a permission the
+// authenticated user of ctx doesn't have. Organisation scoped permissions
+// don't count, a user's own role never grants those.
+func (cfg ApiCfg) grantsMissingPermissions(ctx context.Context, role string) (bool, error) {
+	permissions, err := cfg.DB.GetRolePermissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !auth.OrgScoped(permission) && !auth.HasPermission(ctx, permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetPermissionsController lists every permission that can be given to a
+// role
+func (cfg ApiCfg) GetPermissionsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	helpers.JSON(w, 200, auth.AllPermissions)
+}
+
+// GetRolesController lists the roles with their permissions
+func (cfg ApiCfg) GetRolesController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	roles, err := cfg.DB.GetRoles(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch roles: %v", err))
+		return
+	}
+
+	rolePermissions, err := cfg.DB.GetAllRolePermissions(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch permissions: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseRolesToRoles(roles, rolePermissions))
+}
+
+// CreateRoleController adds a role that grants the given permissions
+func (cfg ApiCfg) CreateRoleController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := roleParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if !roleNamePattern.MatchString(params.Name) {
+		helpers.RespondWithError(w, 400,
+			"Role name must be 2 to 20 lower case letters, digits or underscores")
+		return
+	}
+
+	permissions, ok := validatePermissions(w, params.Permissions)
+	if !ok {
+		return
+	}
+
+	role, err := cfg.DB.CreateRole(r.Context(), database.CreateRoleParams{
+		Name: params.Name,
+		Description: helpers.NewNullString(params.Description),
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" {
+				helpers.RespondWithError(w, 409, "Role already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create role: %v", err))
+		return
+	}
+
+	if err := cfg.DB.ReplaceRolePermissions(r.Context(), role.Name, permissions); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't set role permissions: %v", err))
+		return
+	}
+
+	log.Printf("User %v created role %v with permissions %v", user.ID, role.Name, permissions)
+	helpers.JSON(w, 201, models.DatabaseRoleToRole(role, permissions))
+}
+
+// UpdateRoleController replaces the description and permissions of a role.
+// The admin role can't be changed.
+func (cfg ApiCfg) UpdateRoleController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	name := chi.URLParam(r, "role")
+	if name == adminRole {
+		helpers.RespondWithError(w, 400, "The admin role can't be changed")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := roleParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	permissions, ok := validatePermissions(w, params.Permissions)
+	if !ok {
+		return
+	}
+
+	role, err := cfg.DB.UpdateRole(r.Context(), database.UpdateRoleParams{
+		Name: name,
+		Description: helpers.NewNullString(params.Description),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Role not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update role: %v", err))
+		return
+	}
+
+	if err := cfg.DB.ReplaceRolePermissions(r.Context(), role.Name, permissions); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't set role permissions: %v", err))
+		return
+	}
+
+	log.Printf("User %v set permissions of role %v to %v", user.ID, role.Name, permissions)
+	helpers.JSON(w, 200, models.DatabaseRoleToRole(role, permissions))
+}
+
+// DeleteRoleController removes a role nobody has. Built in roles can't be
+// deleted.
+func (cfg ApiCfg) DeleteRoleController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	name := chi.URLParam(r, "role")
+
+	role, err := cfg.DB.GetRole(r.Context(), name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Role not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch role: %v", err))
+		return
+	}
+
+	if role.BuiltIn {
+		helpers.RespondWithError(w, 400, "Built in roles can't be deleted")
+		return
+	}
+
+	count, err := cfg.DB.CountUsersWithRole(r.Context(), name)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't count users with role: %v", err))
+		return
+	}
+	if count > 0 {
+		helpers.RespondWithError(w, 409,
+			fmt.Sprintf("Role is assigned to %d users, give them another role first", count))
+		return
+	}
+
+	accounts, err := cfg.DB.CountServiceAccountsWithRole(r.Context(), name)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't count service accounts with role: %v", err))
+		return
+	}
+	if accounts > 0 {
+		helpers.RespondWithError(w, 409,
+			fmt.Sprintf("Role is assigned to %d service accounts, give them another role first", accounts))
+		return
+	}
+
+	members, err := cfg.DB.CountOrganizationMembersWithRole(r.Context(), name)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't count organisation members with role: %v", err))
+		return
+	}
+	if members > 0 {
+		helpers.RespondWithError(w, 409,
+			fmt.Sprintf("Role is assigned to %d organisation members, give them another role first", members))
+		return
+	}
+
+	if _, err := cfg.DB.DeleteRole(r.Context(), name); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't delete role: %v", err))
+		return
+	}
+
+	log.Printf("User %v deleted role %v", user.ID, name)
+	helpers.TextResponse(w, 200, fmt.Sprintf("Successfully deleted role: %v", name))
+}
+
+// AssignUserRoleController gives a user another role. Their sessions are
+// revoked so tokens carrying the old role stop working.
+func (cfg ApiCfg) AssignUserRoleController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "userId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse userId: %v", err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := assignRoleParams{}
+	err = decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if id == user.ID {
+		helpers.RespondWithError(w, 400, "You can't change your own role")
+		return
+	}
+
+	if _, err := cfg.DB.GetRole(r.Context(), params.Role); err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown role: %v", params.Role))
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch role: %v", err))
+		return
+	}
+
+	existing, err := cfg.DB.GetUserById(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "User not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch user: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	updated, err := cfg.DB.UpdateUserRole(r.Context(), database.UpdateUserRoleParams{
+		ID: id,
+		Role: params.Role,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update role: %v", err))
+		return
+	}
+
+	err = cfg.DB.RevokeUserSessions(r.Context(), database.RevokeUserSessionsParams{
+		UserID: id,
+		RevokedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke sessions: %v", err))
+		return
+	}
+
+	after := models.DatabaseUserToUser(updated)
+	cfg.recordAudit(r, user, auditUpdate, "user", id, models.DatabaseUserToUser(existing), after)
+	helpers.JSON(w, 200, after)
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var roleColumns = []string{"name", "description", "built_in", "created_at", "updated_at"}
+
+func withURLParam(req *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestCreateRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+
+	mock.ExpectQuery(`INSERT INTO roles`).
+		WithArgs("auditor", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("auditor", nil, false, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM role_permissions WHERE role = \$1`).
+		WithArgs("auditor").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO role_permissions`).
+		WithArgs("auditor", "audit:read").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO role_permissions`).
+		WithArgs("auditor", "products:read").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	req, err := http.NewRequest("POST", "/roles", strings.NewReader(
+		`{"name": "auditor", "permissions": ["products:read", "audit:read", "products:read"]}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.CreateRoleController(rr, req, admin)
+
+	var response models.Role
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 201, rr.Code)
+	assert.Equal(t, []string{"audit:read", "products:read"}, response.Permissions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateRole_Invalid(t *testing.T) {
+	cfg := ApiCfg{}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+
+	for body, message := range map[string]string{
+		`{"name": "Auditor!", "permissions": []}`:                "Role name must be",
+		`{"name": "auditor", "permissions": ["products:fly"]}`: "Unknown permission: products:fly",
+	} {
+		req, err := http.NewRequest("POST", "/roles", strings.NewReader(body))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		cfg.CreateRoleController(rr, req, admin)
+
+		assert.Equal(t, 400, rr.Code)
+		assert.Contains(t, rr.Body.String(), message)
+	}
+}
+
+func TestUpdateRole_AdminIsFixed(t *testing.T) {
+	cfg := ApiCfg{}
+
+	req, err := http.NewRequest("PUT", "/roles/admin", strings.NewReader(`{"permissions": []}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.UpdateRoleController(rr, withURLParam(req, "role", "admin"), database.User{Role: "admin"})
+
+	assert.Equal(t, 400, rr.Code)
+}
+
+func TestUpdateRole_FailedPermissionsRolledBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	mock.ExpectQuery(`UPDATE roles SET description = \$2`).
+		WithArgs("auditor", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("auditor", nil, false, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM role_permissions WHERE role = \$1`).
+		WithArgs("auditor").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO role_permissions`).
+		WithArgs("auditor", "audit:read").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	req, err := http.NewRequest("PUT", "/roles/auditor", strings.NewReader(`{"permissions": ["audit:read"]}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.UpdateRoleController(rr, withURLParam(req, "role", "auditor"), database.User{Role: "admin"})
+
+	assert.Equal(t, 500, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the old permissions must be kept")
+}
+
+func TestDeleteRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		builtIn bool
+		users   int
+		members int
+		code    int
+	}{
+		{"warehouse_clerk", true, 0, 0, 400},
+		{"auditor", false, 2, 0, 409},
+		{"auditor", false, 0, 3, 409},
+		{"auditor", false, 0, 0, 200},
+	}
+
+	for _, test := range tests {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+
+		cfg := ApiCfg{DB: database.New(db)}
+
+		mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+			WithArgs(test.name).
+			WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(test.name, nil, test.builtIn, time.Now(), time.Now()))
+		if !test.builtIn {
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE role = \$1`).
+				WithArgs(test.name).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(test.users))
+		}
+		if !test.builtIn && test.users == 0 {
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM service_accounts WHERE role = \$1`).
+				WithArgs(test.name).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM organization_members WHERE role = \$1`).
+				WithArgs(test.name).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(test.members))
+		}
+		if test.code == 200 {
+			mock.ExpectExec(`DELETE FROM roles WHERE name = \$1 AND built_in = FALSE`).
+				WithArgs(test.name).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		req, err := http.NewRequest("DELETE", "/roles/"+test.name, nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		cfg.DeleteRoleController(rr, withURLParam(req, "role", test.name), database.User{Role: "admin"})
+
+		assert.Equal(t, test.code, rr.Code, test.name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	}
+}
+
+func TestAssignUserRole_RevokesSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+		WithArgs("warehouse_clerk").
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("warehouse_clerk", nil, true, time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectQuery(`UPDATE users SET role = \$2`).
+		WithArgs(userId, "warehouse_clerk", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "warehouse_clerk", nil, "john doe", time.Now()))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = \$2 WHERE user_id = \$1`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("PUT", "/users/"+userId.String()+"/role", strings.NewReader(`{"role": "warehouse_clerk"}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.AssignUserRoleController(rr, withURLParam(req, "userId", userId.String()), admin)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"role":"warehouse_clerk"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignUserRole_Own(t *testing.T) {
+	cfg := ApiCfg{}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+
+	req, err := http.NewRequest("PUT", "/users/"+admin.ID.String()+"/role", strings.NewReader(`{"role": "user"}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.AssignUserRoleController(rr, withURLParam(req, "userId", admin.ID.String()), admin)
+
+	assert.Equal(t, 400, rr.Code)
+}
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+)
+
+var serviceAccountNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+type serviceAccountParams struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Role        string  `json:"role"`
+	// OrgID defaults to the organisation the request works in
+	OrgID *uuid.UUID `json:"org_id"`
+}
+
+type apiKeyParams struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// serviceAccount fetches the service account in the URL, responding with
+// 404 when it doesn't exist or belongs to another organisation
+func (cfg ApiCfg) serviceAccount(w http.ResponseWriter, r *http.Request) (database.ServiceAccount, bool) {
+	idStr := chi.URLParam(r, "serviceAccountId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse serviceAccountId: %v", err))
+		return database.ServiceAccount{}, false
+	}
+
+	account, err := cfg.DB.GetServiceAccountById(r.Context(), id)
+	if err == nil && account.OrgID != auth.OrgID(r.Context()) {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Service account not found")
+			return database.ServiceAccount{}, false
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch service account: %v", err))
+		return database.ServiceAccount{}, false
+	}
+	return account, true
+}
+
+// grantsMissingPermission reports whether handing out permissions would give
+// a service account something the authenticated user of ctx doesn't have.
+// Unlike a user's own role, a service account's role grants organisation
+// scoped permissions too, so none are skipped. roles:manage holders may
+// hand out anything.
+func grantsMissingPermission(ctx context.Context, permissions []string) bool {
+	if auth.HasPermission(ctx, auth.RolesManage) {
+		return false
+	}
+	for _, permission := range permissions {
+		if !auth.HasPermission(ctx, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetServiceAccountsController lists the organisation's service accounts
+func (cfg ApiCfg) GetServiceAccountsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	accounts, err := cfg.DB.GetServiceAccounts(r.Context(), auth.OrgID(r.Context()))
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch service accounts: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseServiceAccountsToServiceAccounts(accounts))
+}
+
+// CreateServiceAccountController adds a service account with a role in an
+// organisation. Another organisation than the one the request works in
+// needs organizations:manage, and without roles:manage the role can't grant
+// anything the caller doesn't have. It can't do anything until it's given
+// an API key.
+func (cfg ApiCfg) CreateServiceAccountController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := serviceAccountParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if !serviceAccountNamePattern.MatchString(params.Name) {
+		helpers.RespondWithError(w, 400,
+			"Service account name must be 2 to 50 lower case letters, digits, dashes or underscores")
+		return
+	}
+
+	orgId := auth.OrgID(r.Context())
+	if params.OrgID != nil && *params.OrgID != orgId {
+		if !auth.HasPermission(r.Context(), auth.OrganizationsManage) {
+			helpers.RespondWithError(w, 403,
+				"Creating a service account in another organisation requires the organizations:manage permission")
+			return
+		}
+		orgId = *params.OrgID
+	}
+	if orgId == uuid.Nil {
+		helpers.RespondWithError(w, 400, "Organisation is required")
+		return
+	}
+
+	if _, err := cfg.DB.GetRole(r.Context(), params.Role); err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Unknown role: %v", params.Role))
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch role: %v", err))
+		return
+	}
+
+	// service_accounts:manage alone mustn't mint an admin account
+	permissions, err := cfg.DB.GetRolePermissions(r.Context(), params.Role)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch permissions: %v", err))
+		return
+	}
+	if grantsMissingPermission(r.Context(), permissions) {
+		helpers.RespondWithError(w, 403,
+			"A role with permissions you don't have requires the roles:manage permission")
+		return
+	}
+
+	account, err := cfg.DB.CreateServiceAccount(r.Context(), database.CreateServiceAccountParams{
+		ID: uuid.New(),
+		OrgID: orgId,
+		Name: params.Name,
+		Description: helpers.NewNullString(params.Description),
+		Role: params.Role,
+		CreatedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" {
+				helpers.RespondWithError(w, 409, "Service account already exists")
+				return
+			}
+			if pqErr.Code == "23503" {
+				helpers.RespondWithError(w, 400, "Organisation not found")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create service account: %v", err))
+		return
+	}
+
+	created := models.DatabaseServiceAccountToServiceAccount(account)
+	cfg.recordAudit(r, user, auditCreate, "service_account", account.ID, nil, created)
+	helpers.JSON(w, 201, created)
+}
+
+// DeleteServiceAccountController removes one of the organisation's service
+// accounts and all of its API keys
+func (cfg ApiCfg) DeleteServiceAccountController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "serviceAccountId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse serviceAccountId: %v", err))
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteServiceAccount(r.Context(), database.DeleteServiceAccountParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't delete service account: %v", err))
+		return
+	}
+	if deleted == 0 {
+		helpers.RespondWithError(w, 404, "Service account not found")
+		return
+	}
+
+	cfg.recordAudit(r, user, auditDelete, "service_account", id, nil, nil)
+	helpers.TextResponse(w, 200, fmt.Sprintf("Successfully deleted service account: %v", id))
+}
+
+// GetAPIKeysController lists a service account's API keys without their
+// secrets
+func (cfg ApiCfg) GetAPIKeysController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	account, ok := cfg.serviceAccount(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := cfg.DB.GetServiceAccountAPIKeys(r.Context(), account.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch API keys: %v", err))
+		return
+	}
+
+	scopes, err := cfg.DB.GetServiceAccountAPIKeyScopes(r.Context(), account.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch API key scopes: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseAPIKeysToAPIKeys(keys, scopes))
+}
+
+// CreateAPIKeyController issues an API key limited to scopes, which must
+// all be granted by the service account's role and held by the caller. The key is only shown in
+// this response.
+func (cfg ApiCfg) CreateAPIKeyController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	account, ok := cfg.serviceAccount(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := apiKeyParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.Name == "" || len(params.Scopes) == 0 {
+		helpers.RespondWithError(w, 400, "Name and Scopes are required")
+		return
+	}
+
+	now := time.Now().UTC()
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(now) {
+		helpers.RespondWithError(w, 400, "Expiry must be in the future")
+		return
+	}
+
+	scopes, ok := validatePermissions(w, params.Scopes)
+	if !ok {
+		return
+	}
+
+	granted, err := cfg.DB.GetRolePermissions(r.Context(), account.Role)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch permissions: %v", err))
+		return
+	}
+	if allowed := auth.Scope(granted, scopes); len(allowed) != len(scopes) {
+		helpers.RespondWithError(w, 400,
+			fmt.Sprintf("Scopes must be granted by the %v role: %v", account.Role, granted))
+		return
+	}
+	if grantsMissingPermission(r.Context(), scopes) {
+		helpers.RespondWithError(w, 403,
+			"Scopes with permissions you don't have require the roles:manage permission")
+		return
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate API key: %v", err))
+		return
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	apiKey, err := cfg.DB.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		ID: uuid.New(),
+		ServiceAccountID: account.ID,
+		Name: params.Name,
+		Prefix: key.Prefix,
+		SecretHash: key.SecretHash,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create API key: %v", err))
+		return
+	}
+
+	for _, scope := range scopes {
+		err := cfg.DB.AddAPIKeyScope(r.Context(), database.AddAPIKeyScopeParams{
+			ApiKeyID: apiKey.ID,
+			Permission: scope,
+		})
+		if err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't set API key scopes: %v", err))
+			return
+		}
+	}
+
+	created := models.DatabaseAPIKeyToAPIKey(apiKey, scopes)
+	cfg.recordAudit(r, user, auditCreate, "api_key", apiKey.ID, nil, created)
+
+	created.Key = key.Key
+	helpers.JSON(w, 201, created)
+}
+
+// RevokeAPIKeyController stops an API key from working. Revoked keys stay
+// listed so their last use can still be seen.
+func (cfg ApiCfg) RevokeAPIKeyController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	account, ok := cfg.serviceAccount(w, r)
+	if !ok {
+		return
+	}
+	keyId, err := uuid.Parse(chi.URLParam(r, "keyId"))
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse keyId: %v", err))
+		return
+	}
+
+	revoked, err := cfg.DB.RevokeAPIKey(r.Context(), database.RevokeAPIKeyParams{
+		ID: keyId,
+		ServiceAccountID: account.ID,
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't revoke API key: %v", err))
+		return
+	}
+	if revoked == 0 {
+		helpers.RespondWithError(w, 404, "API key not found")
+		return
+	}
+
+	cfg.recordAudit(r, user, auditDelete, "api_key", keyId, nil, nil)
+	helpers.TextResponse(w, 200, fmt.Sprintf("Successfully revoked API key: %v", keyId))
+}
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	serviceAccountColumns = []string{
+		"id", "name", "description", "role", "created_by", "created_at", "updated_at", "org_id",
+	}
+	apiKeyColumns = []string{
+		"id", "service_account_id", "name", "prefix", "secret_hash", "created_at", "expires_at", "last_used_at", "revoked_at",
+	}
+)
+
+func TestCreateServiceAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+
+	mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+		WithArgs("warehouse_clerk").
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("warehouse_clerk", nil, true, time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("warehouse_clerk").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).
+			AddRow(auth.ProductsRead).AddRow(auth.ProductsStock))
+	orgId := uuid.New()
+	mock.ExpectQuery(`INSERT INTO service_accounts`).
+		WithArgs(sqlmock.AnyArg(), orgId, "pos-terminal", sqlmock.AnyArg(), "warehouse_clerk",
+			uuid.NullUUID{UUID: admin.ID, Valid: true}, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(serviceAccountColumns).AddRow(
+			uuid.New(), "pos-terminal", nil, "warehouse_clerk", admin.ID, time.Now(), time.Now(), orgId))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("POST", "/service-accounts", strings.NewReader(
+		`{"name": "pos-terminal", "role": "warehouse_clerk"}`))
+	assert.NoError(t, err)
+	ctx := auth.WithPermissions(req.Context(), []string{auth.ServiceAccountsManage, auth.ProductsRead, auth.ProductsStock})
+	req = req.WithContext(auth.WithOrgID(ctx, orgId))
+	rr := httptest.NewRecorder()
+	cfg.CreateServiceAccountController(rr, req, admin)
+
+	assert.Equal(t, 201, rr.Code)
+	assert.Contains(t, rr.Body.String(), orgId.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateServiceAccount_RoleRequiresRolesManage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	manager := database.User{ID: uuid.New(), Role: "integrations"}
+
+	mock.ExpectQuery(`SELECT (.+) FROM roles WHERE name = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow("admin", nil, true, time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).
+			AddRow(auth.RolesManage).AddRow(auth.UsersDelete))
+
+	req, err := http.NewRequest("POST", "/service-accounts", strings.NewReader(
+		`{"name": "backdoor", "role": "admin"}`))
+	assert.NoError(t, err)
+	ctx := auth.WithPermissions(req.Context(), []string{auth.ServiceAccountsManage})
+	req = req.WithContext(auth.WithOrgID(ctx, uuid.New()))
+	rr := httptest.NewRecorder()
+	cfg.CreateServiceAccountController(rr, req, manager)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the service account must not be created")
+}
+
+func TestCreateServiceAccount_RequiresOrganization(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	req, err := http.NewRequest("POST", "/service-accounts", strings.NewReader(
+		`{"name": "pos-terminal", "role": "warehouse_clerk"}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.CreateServiceAccountController(rr, req, database.User{ID: uuid.New(), Role: "admin"})
+
+	assert.Equal(t, 400, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Organisation is required")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateServiceAccount_OtherOrgRequiresOrganizationsManage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	otherOrg := uuid.New()
+	req, err := http.NewRequest("POST", "/service-accounts", strings.NewReader(
+		`{"name": "pos-terminal", "role": "warehouse_clerk", "org_id": "`+otherOrg.String()+`"}`))
+	assert.NoError(t, err)
+	ctx := auth.WithPermissions(req.Context(), []string{auth.ServiceAccountsManage, auth.RolesManage})
+	req = req.WithContext(auth.WithOrgID(ctx, uuid.New()))
+	rr := httptest.NewRecorder()
+	cfg.CreateServiceAccountController(rr, req, database.User{ID: uuid.New(), Role: "integrations"})
+
+	assert.Equal(t, 403, rr.Code)
+	assert.Contains(t, rr.Body.String(), "organizations:manage")
+	assert.NoError(t, mock.ExpectationsWereMet(), "the service account must not be created")
+}
+
+func TestGetAPIKeys_OtherOrgNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	accountId := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM service_accounts WHERE id = \$1`).
+		WithArgs(accountId).
+		WillReturnRows(sqlmock.NewRows(serviceAccountColumns).AddRow(
+			accountId, "pos-terminal", nil, "warehouse_clerk", nil, time.Now(), time.Now(), uuid.New()))
+
+	req, err := http.NewRequest("GET", "/service-accounts/"+accountId.String()+"/keys", nil)
+	assert.NoError(t, err)
+	req = withURLParam(req, "serviceAccountId", accountId.String())
+	req = req.WithContext(auth.WithOrgID(req.Context(), uuid.New()))
+	rr := httptest.NewRecorder()
+	cfg.GetAPIKeysController(rr, req, database.User{ID: uuid.New(), Role: "admin"})
+
+	assert.Equal(t, 404, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "keys of another organisation's account must not be listed")
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	admin := database.User{ID: uuid.New(), Role: "admin"}
+	accountId, keyId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_accounts WHERE id = \$1`).
+		WithArgs(accountId).
+		WillReturnRows(sqlmock.NewRows(serviceAccountColumns).AddRow(
+			accountId, "pos-terminal", nil, "warehouse_clerk", nil, time.Now(), time.Now(), uuid.Nil))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("warehouse_clerk").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).
+			AddRow(auth.ProductsRead).AddRow(auth.ProductsStock))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(sqlmock.AnyArg(), accountId, "till 1", sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(
+			keyId, accountId, "till 1", "0a1b2c3d4e5f", "hash", time.Now(), nil, nil, nil))
+	mock.ExpectExec(`INSERT INTO api_key_scopes`).
+		WithArgs(keyId, auth.ProductsStock).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("POST", "/service-accounts/"+accountId.String()+"/keys",
+		strings.NewReader(`{"name": "till 1", "scopes": ["products:stock"]}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.ServiceAccountsManage, auth.RolesManage}))
+	rr := httptest.NewRecorder()
+	cfg.CreateAPIKeyController(rr, withURLParam(req, "serviceAccountId", accountId.String()), admin)
+
+	var response models.APIKey
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 201, rr.Code)
+	assert.True(t, strings.HasPrefix(response.Key, "inv_"))
+	assert.Equal(t, []string{auth.ProductsStock}, response.Scopes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPIKey_ScopeNotGrantedByRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	accountId := uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM service_accounts WHERE id = \$1`).
+		WithArgs(accountId).
+		WillReturnRows(sqlmock.NewRows(serviceAccountColumns).AddRow(
+			accountId, "pos-terminal", nil, "user", nil, time.Now(), time.Now(), uuid.Nil))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("user").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(auth.ProductsRead))
+
+	req, err := http.NewRequest("POST", "/service-accounts/"+accountId.String()+"/keys",
+		strings.NewReader(`{"name": "till 1", "scopes": ["products:write"]}`))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.CreateAPIKeyController(rr, withURLParam(req, "serviceAccountId", accountId.String()),
+		database.User{ID: uuid.New(), Role: "admin"})
+
+	assert.Equal(t, 400, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPIKey_ScopeRequiresRolesManage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	accountId := uuid.New()
+
+	// An account made by an admin can't hand its admin scopes to a lesser manager
+	mock.ExpectQuery(`SELECT (.+) FROM service_accounts WHERE id = \$1`).
+		WithArgs(accountId).
+		WillReturnRows(sqlmock.NewRows(serviceAccountColumns).AddRow(
+			accountId, "provisioner", nil, "admin", nil, time.Now(), time.Now(), uuid.Nil))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).
+			AddRow(auth.RolesManage).AddRow(auth.UsersDelete))
+
+	req, err := http.NewRequest("POST", "/service-accounts/"+accountId.String()+"/keys",
+		strings.NewReader(`{"name": "ci", "scopes": ["users:delete"]}`))
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.ServiceAccountsManage}))
+	rr := httptest.NewRecorder()
+	cfg.CreateAPIKeyController(rr, withURLParam(req, "serviceAccountId", accountId.String()),
+		database.User{ID: uuid.New(), Role: "integrations"})
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the key must not be issued")
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+)
+
+// includeDeleted reports whether soft deleted rows were asked for with
+// ?include_deleted=true. Only users allowed to delete the entity, and so
+// restore it, may see them; anyone else gets a 403 and ok is false.
+func includeDeleted(
+	w http.ResponseWriter,
+	r *http.Request,
+	deletePermission string,
+	) (include bool, ok bool) {
+	if r.URL.Query().Get("include_deleted") != "true" {
+		return false, true
+	}
+	if !auth.HasPermission(r.Context(), deletePermission) {
+		helpers.RespondWithError(w, 403, "Unauthorized")
+		return false, false
+	}
+	return true, true
+}
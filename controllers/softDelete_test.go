@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProducts_IncludeDeletedForbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	req, err := http.NewRequest("GET", "/products?include_deleted=true", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetAllProductsController(w, r, database.User{Role: "user"})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetProducts_IncludeDeletedAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	deletedAt := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM products`).
+		WithArgs(uuid.Nil, true).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(uuid.New(), "Microwave", nil, 50000, 3, nil, nil, nil, time.Now(), time.Now(), deletedAt, uuid.Nil))
+
+	req, err := http.NewRequest("GET", "/products?include_deleted=true", nil)
+	assert.NoError(t, err)
+	req = req.WithContext(auth.WithPermissions(req.Context(), []string{auth.ProductsDelete}))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg.GetAllProductsController(w, r, database.User{Role: "admin"})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "deleted_at")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreProduct_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	productId := uuid.New()
+
+	mock.ExpectQuery(`UPDATE products`).
+		WithArgs(productId, uuid.Nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, nil, time.Now(), time.Now(), nil, uuid.Nil))
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("/products/%v/restore", productId), nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Post("/products/{productId}/restore", func(w http.ResponseWriter, r *http.Request) {
+		cfg.RestoreProductController(w, r, database.User{Role: "admin"})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), productId.String())
+	assert.NotContains(t, rr.Body.String(), "deleted_at")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreSupplier_NotDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	supplierId := uuid.New()
+
+	mock.ExpectQuery(`UPDATE suppliers`).
+		WithArgs(supplierId, uuid.Nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "email", "description", "phone", "country", "created_at", "updated_at", "deleted_at", "org_id",
+		}))
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("/suppliers/%v/restore", supplierId), nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Post("/suppliers/{supplierId}/restore", func(w http.ResponseWriter, r *http.Request) {
+		cfg.RestoreSupplierController(w, r, database.User{Role: "admin"})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 404, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Deleted supplier not found")
+}
+
+func TestRestoreCategory_NameTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	categoryId := uuid.New()
+
+	mock.ExpectQuery(`UPDATE categories`).
+		WithArgs(categoryId, uuid.Nil, sqlmock.AnyArg()).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("/categories/%v/restore", categoryId), nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := chi.NewRouter()
+	handler.Post("/categories/{categoryId}/restore", func(w http.ResponseWriter, r *http.Request) {
+		cfg.RestoreCategoryController(w, r, database.User{Role: "admin"})
+	})
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 409, rr.Code)
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/database/sqlite"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
+	"github.com/ringtho/inventory/internal/store/storetest"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// backends are the stores these tests run against. Postgres is skipped
+// unless TEST_DATABASE_URL is set.
+var backends = []struct {
+	name string
+	new func(t *testing.T) store.Store
+}{
+	{"Memory", func(t *testing.T) store.Store { return memory.New() }},
+	{"SQLite", func(t *testing.T) store.Store {
+		conn, err := sqlite.Open(t.TempDir() + "/inventory.db")
+		assert.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		assert.NoError(t, sqlite.Migrate(context.Background(), conn))
+		return database.New(conn)
+	}},
+	{"Postgres", storetest.Postgres},
+}
+
+// forEachBackend runs test against a new store of every backend
+func forEachBackend(t *testing.T, test func(t *testing.T, db store.Store)) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.new(t))
+		})
+	}
+}
+
+// inventoryRouter serves the product, category and supplier routes from
+// cfg as admin in orgId
+func inventoryRouter(cfg ApiCfg, orgId uuid.UUID) http.Handler {
+	admin := database.User{ID: uuid.New(), Email: "admin@example.com", Role: "admin"}
+	withUser := func(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r.WithContext(auth.WithOrgID(r.Context(), orgId)), admin)
+		}
+	}
+
+	router := chi.NewRouter()
+	router.Post("/products", withUser(cfg.CreateProductController))
+	router.Get("/products", withUser(cfg.GetAllProductsController))
+	router.Get("/products/{productId}", withUser(cfg.GetProductController))
+	router.Put("/products/{productId}", withUser(cfg.UpdateProductController))
+	router.Delete("/products/{productId}", withUser(cfg.DeleteProductController))
+	router.Post("/products/{productId}/stock", withUser(cfg.AdjustProductStockController))
+	router.Post("/categories", withUser(cfg.CreateCategoryController))
+	router.Get("/categories", withUser(cfg.GetCategoriesController))
+	router.Get("/categories/{categoryId}", withUser(cfg.GetCategoryController))
+	router.Put("/categories/{categoryId}", withUser(cfg.UpdateCategoryController))
+	router.Delete("/categories/{categoryId}", withUser(cfg.DeleteCategoryController))
+	router.Post("/suppliers", withUser(cfg.CreateSupplierController))
+	router.Get("/suppliers", withUser(cfg.GetAllSuppliersController))
+	router.Get("/suppliers/{supplierId}", withUser(cfg.GetSupplierController))
+	router.Put("/suppliers/{supplierId}", withUser(cfg.UpdateSupplierController))
+	router.Delete("/suppliers/{supplierId}", withUser(cfg.DeleteSupplierController))
+	return router
+}
+
+func serve(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestStores_ProductLifecycle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+
+		rr := serve(handler, "POST", "/products", `{"name": "Microwave", "price": 50000, "stock_level": 3, "sku": "MW-1"}`)
+		assert.Equal(t, 201, rr.Code)
+		var created models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+
+		rr = serve(handler, "GET", "/products/"+created.ID.String(), "")
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Microwave")
+
+		rr = serve(handler, "POST", "/products/"+created.ID.String()+"/stock", `{"adjustment": -5}`)
+		assert.Equal(t, 400, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Stock level can't go below zero")
+
+		rr = serve(handler, "POST", "/products/"+created.ID.String()+"/stock", `{"adjustment": -2}`)
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"stock_level":1`)
+
+		rr = serve(handler, "DELETE", "/products/"+created.ID.String(), "")
+		assert.Equal(t, 200, rr.Code)
+
+		rr = serve(handler, "GET", "/products/"+created.ID.String(), "")
+		assert.Equal(t, 404, rr.Code)
+
+		logs, err := db.GetAuditLogs(context.Background(), database.GetAuditLogsParams{
+			EntityID: uuid.NullUUID{UUID: created.ID, Valid: true},
+			RowLimit: 10,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, logs, 3)
+	})
+}
+
+func TestStores_SkuUniquePerOrganization(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		other, err := db.CreateOrganization(context.Background(), database.CreateOrganizationParams{
+			ID: uuid.New(),
+			Name: "Other",
+			Slug: "other",
+			CreatedAt: time.Now().UTC(),
+		})
+		assert.NoError(t, err)
+
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		otherHandler := inventoryRouter(ApiCfg{DB: db}, other.ID)
+		body := `{"name": "Kettle", "price": 2000, "sku": "KT-1"}`
+
+		assert.Equal(t, 201, serve(handler, "POST", "/products", body).Code)
+		rr := serve(handler, "POST", "/products", body)
+		assert.Equal(t, 409, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Product SKU already exists")
+
+		assert.Equal(t, 201, serve(otherHandler, "POST", "/products", body).Code)
+
+		rr = serve(otherHandler, "GET", "/products", "")
+		assert.Equal(t, 200, rr.Code)
+		var products []models.Product
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&products))
+		assert.Len(t, products, 1)
+	})
+}
+
+func TestStores_ProductCategoryFromOtherOrganization(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		category, err := db.CreateCategory(context.Background(), database.CreateCategoryParams{
+			ID: uuid.New(),
+			OrgID: memory.DefaultOrgID,
+			Name: "Kitchen",
+			Description: sql.NullString{},
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			CreatedBy: uuid.New(),
+		})
+		assert.NoError(t, err)
+
+		handler := inventoryRouter(ApiCfg{DB: db}, uuid.New())
+		rr := serve(handler, "POST", "/products",
+			fmt.Sprintf(`{"name": "Kettle", "price": 2000, "category_id": %q}`, category.ID))
+		assert.Equal(t, 400, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Category or supplier not found")
+	})
+}
+
+func TestStores_CategoryNameUnique(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		body := `{"name": "Kitchen"}`
+
+		assert.Equal(t, 201, serve(handler, "POST", "/categories", body).Code)
+		rr := serve(handler, "POST", "/categories", body)
+		assert.Equal(t, 409, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Category Name already exists")
+	})
+}
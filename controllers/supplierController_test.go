@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,127 +12,13 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
 	"github.com/ringtho/inventory/models"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCreateSupplier_Success(t *testing.T) {
-	ptr := func(s string) *string { return &s }
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	mockSupplier := Supplier{
-		Name: "Hisense",
-		Email: ptr("info@hisense.com"),
-		Description: ptr("Hisense appliances and accessories"),
-		Phone: ptr("0778 000000"),
-		Country: ptr("Uganda"),
-	}
-
-	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
-	}).AddRow(
-		uuid.New(),
-		mockSupplier.Name,
-		mockSupplier.Email,
-		mockSupplier.Description,
-		mockSupplier.Phone,
-		mockSupplier.Country,
-		time.Now().UTC(),
-		time.Now().UTC(),
-	)
-
-	mock.ExpectQuery(`INSERT INTO suppliers`). 
-	WithArgs(
-		sqlmock.AnyArg(),
-		mockSupplier.Name,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-	).
-	WillReturnRows(mockData)
-
-	payload, err := json.Marshal(mockSupplier)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("POST", "/suppliers", bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		cfg.CreateSupplierController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	var response models.Supplier
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 201, rr.Code)
-	assert.Equal(t, mockSupplier.Name, response.Name)
-}
-
-func TestCreateSupplier_NameExists(t *testing.T) {
-	ptr := func(s string) *string { return &s }
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	mockSupplier := Supplier{
-		Name: "Hisense",
-		Email: ptr("info@hisense.com"),
-		Description: ptr("Hisense appliances and accessories"),
-		Phone: ptr("0778 000000"),
-		Country: ptr("Uganda"),
-	}
-
-	mock.ExpectQuery(`INSERT INTO suppliers`). 
-	WithArgs(
-		sqlmock.AnyArg(),
-		mockSupplier.Name,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-	).
-	WillReturnError(&pq.Error{Code: "23505"})
-
-	payload, err := json.Marshal(mockSupplier)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("POST", "/suppliers", bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		cfg.CreateSupplierController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 409, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Category Email already exists")
-}
-
 func TestCreateSupplier_DBError(t *testing.T) {
 	ptr := func(s string) *string { return &s }
 	db, mock, err := sqlmock.New()
@@ -156,6 +41,7 @@ func TestCreateSupplier_DBError(t *testing.T) {
 	mock.ExpectQuery(`INSERT INTO suppliers`). 
 	WithArgs(
 		sqlmock.AnyArg(),
+		uuid.Nil,
 		mockSupplier.Name,
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
@@ -183,69 +69,6 @@ func TestCreateSupplier_DBError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Couldn't create category")
 }
 
-func TestGetSuppliers_Success(t *testing.T) {
-	ptr := func(s string) *string { return &s }
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-
-	mockSupplier := Supplier{
-		Name: "Hisense",
-		Email: ptr("info@hisense.com"),
-		Description: ptr("Hisense appliances and accessories"),
-		Phone: ptr("0778 000000"),
-		Country: ptr("Uganda"),
-	}
-
-	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
-	}).AddRow(
-		uuid.New(),
-		mockSupplier.Name,
-		mockSupplier.Email,
-		mockSupplier.Description,
-		mockSupplier.Phone,
-		mockSupplier.Country,
-		time.Now().UTC(),
-		time.Now().UTC(),
-	).AddRow(
-		uuid.New(),
-		"Sony",
-		"info@sony.com",
-		"",
-		"",
-		"",
-		time.Now().UTC(),
-		time.Now().UTC(),
-	)
-
-	mock.ExpectQuery(`SELECT (.+) FROM suppliers`). 
-	WillReturnRows(mockData)
-
-	req, err := http.NewRequest("GET", "/suppliers", nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		cfg.GetAllSuppliersController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	var response []models.Supplier
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Equal(t, mockSupplier.Name, response[0].Name)
-	assert.Equal(t, "Sony", response[1].Name)
-}
-
 func TestGetSuppliers_DBError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -273,96 +96,6 @@ func TestGetSuppliers_DBError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Couldn't fetch suppliers")
 }
 
-func TestGetSupplier_Success(t *testing.T) {
-	ptr := func(s string) *string { return &s }
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-	supplierID := uuid.New()
-
-	mockSupplier := Supplier{
-		Name: "Hisense",
-		Email: ptr("info@hisense.com"),
-		Description: ptr("Hisense appliances and accessories"),
-		Phone: ptr("0778 000000"),
-		Country: ptr("Uganda"),
-	}
-
-	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
-	}).AddRow(
-		supplierID,
-		mockSupplier.Name,
-		mockSupplier.Email,
-		mockSupplier.Description,
-		mockSupplier.Phone,
-		mockSupplier.Country,
-		time.Now().UTC(),
-		time.Now().UTC(),
-	)
-
-	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
-	).
-	WithArgs(supplierID).
-	WillReturnRows(mockData)
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("/suppliers/%v",supplierID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Get("/suppliers/{supplierId}", func(w http.ResponseWriter, r *http.Request){
-		cfg.GetSupplierController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	var response models.Supplier
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Equal(t, mockSupplier.Name, response.Name)
-	assert.Equal(t, supplierID, response.ID)
-}
-
-func TestGetSupplier_NotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-	supplierID := uuid.New()
-
-	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
-	).
-	WillReturnError(sql.ErrNoRows)
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("/suppliers/%v",supplierID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Get("/suppliers/{supplierId}", func(w http.ResponseWriter, r *http.Request){
-		cfg.GetSupplierController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Supplier not found")
-}
-
 func TestGetSupplier_DBError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -375,7 +108,7 @@ func TestGetSupplier_DBError(t *testing.T) {
 	supplierID := uuid.New()
 
 	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
+	SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers WHERE id=\$1 AND org_id=\$2`,
 	).
 	WillReturnError(fmt.Errorf("Database Error"))
 
@@ -394,37 +127,6 @@ func TestGetSupplier_DBError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Couldn't fetch supplier")
 }
 
-func TestDeleteSupplier_NotFound(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-	supplierID := uuid.New()
-
-	mock.ExpectExec(`
-	DELETE FROM suppliers WHERE id=\$1`,
-	).
-	WillReturnError(sql.ErrNoRows)
-
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/suppliers/%v",supplierID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Delete("/suppliers/{supplierId}", func(w http.ResponseWriter, r *http.Request){
-		cfg.DeleteSupplierController(w, r, adminUser)
-	})
-	handler.ServeHTTP(rr, req)
-
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Supplier not found")
-}
-
 func TestDeleteSupplier_DBError(t *testing.T) {
 	ptr := func(s string) *string { return &s }
 	db, mock, err := sqlmock.New()
@@ -446,7 +148,7 @@ func TestDeleteSupplier_DBError(t *testing.T) {
 	}
 
 	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
+		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at", "deleted_at", "org_id",
 	}).AddRow(
 		supplierID,
 		mockSupplier.Name,
@@ -455,17 +157,17 @@ func TestDeleteSupplier_DBError(t *testing.T) {
 		mockSupplier.Phone,
 		mockSupplier.Country,
 		time.Now().UTC(),
-		time.Now().UTC(),
+		time.Now().UTC(), nil, uuid.Nil,
 	)
 
 	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
+	SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers WHERE id=\$1 AND org_id=\$2`,
 	).
-	WithArgs(supplierID).
+	WithArgs(supplierID, uuid.Nil).
 	WillReturnRows(mockData)
 
 	mock.ExpectExec(`
-	DELETE FROM suppliers WHERE id=\$1`,
+	UPDATE suppliers SET deleted_at = \$3 WHERE id=\$1`,
 	).
 	WillReturnError(fmt.Errorf("Database Error"))
 
@@ -483,61 +185,103 @@ func TestDeleteSupplier_DBError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Couldn't delete supplier")
 }
 
-func TestDeleteSupplier_Success(t *testing.T) {
-	ptr := func(s string) *string { return &s }
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
+// hisense is a supplier with every field filled in
+const hisense = `{"name": "Hisense", "email": "info@hisense.com", "description": "Hisense appliances and accessories", "phone": "0778 000000", "country": "Uganda"}`
 
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
+func TestCreateSupplier_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	adminUser := database.User{Role: "admin"}
-	supplierID := uuid.New()
+		rr := serve(handler, "POST", "/suppliers", hisense)
 
-	mockSupplier := Supplier{
-		Name: "Hisense",
-		Email: ptr("info@hisense.com"),
-		Description: ptr("Hisense appliances and accessories"),
-		Phone: ptr("0778 000000"),
-		Country: ptr("Uganda"),
-	}
+		var response models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 201, rr.Code)
+		assert.Equal(t, "Hisense", response.Name)
+	})
+}
 
-	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
-	}).AddRow(
-		supplierID,
-		mockSupplier.Name,
-		mockSupplier.Email,
-		mockSupplier.Description,
-		mockSupplier.Phone,
-		mockSupplier.Country,
-		time.Now().UTC(),
-		time.Now().UTC(),
-	)
+func TestCreateSupplier_NameExists(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
-	).
-	WithArgs(supplierID).
-	WillReturnRows(mockData)
+		assert.Equal(t, 201, serve(handler, "POST", "/suppliers", hisense).Code)
+		rr := serve(handler, "POST", "/suppliers", hisense)
 
-	mock.ExpectExec(`
-	DELETE FROM suppliers WHERE id=\$1`,
-	).
-	WillReturnResult(sqlmock.NewResult(1,1))
+		assert.Equal(t, 409, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Category Email already exists")
+	})
+}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/suppliers/%v",supplierID), nil)
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+func TestGetSuppliers_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		assert.Equal(t, 201, serve(handler, "POST", "/suppliers", hisense).Code)
+		assert.Equal(t, 201, serve(handler, "POST", "/suppliers", `{"name": "Sony", "email": "info@sony.com"}`).Code)
+
+		rr := serve(handler, "GET", "/suppliers", "")
+
+		var response []models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 200, rr.Code)
+		var names []string
+		for _, supplier := range response {
+			names = append(names, supplier.Name)
+		}
+		assert.ElementsMatch(t, []string{"Hisense", "Sony"}, names)
+	})
+}
 
-	rr := httptest.NewRecorder()
-	handler := chi.NewRouter()
-	handler.Delete("/suppliers/{supplierId}", func(w http.ResponseWriter, r *http.Request){
-		cfg.DeleteSupplierController(w, r, adminUser)
+func TestGetSupplier_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		rr := serve(handler, "POST", "/suppliers", hisense)
+		var created models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+
+		rr = serve(handler, "GET", "/suppliers/"+created.ID.String(), "")
+
+		var response models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, "Hisense", response.Name)
+		assert.Equal(t, created.ID, response.ID)
 	})
-	handler.ServeHTTP(rr, req)
+}
+
+func TestGetSupplier_NotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+
+		rr := serve(handler, "GET", "/suppliers/"+uuid.New().String(), "")
+
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Supplier not found")
+	})
+}
+
+func TestDeleteSupplier_NotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+
+		rr := serve(handler, "DELETE", "/suppliers/"+uuid.New().String(), "")
+
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Supplier not found")
+	})
+}
 
-	assert.Equal(t, 200, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Successfully deleted supplier")
-}
\ No newline at end of file
+func TestDeleteSupplier_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		rr := serve(handler, "POST", "/suppliers", hisense)
+		var created models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+
+		rr = serve(handler, "DELETE", "/suppliers/"+created.ID.String(), "")
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Successfully deleted supplier")
+		assert.Equal(t, 404, serve(handler, "GET", "/suppliers/"+created.ID.String(), "").Code)
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
 	"github.com/ringtho/inventory/internal/database"
 	"github.com/ringtho/inventory/models"
 )
@@ -28,11 +30,6 @@ func (cfg ApiCfg) CreateSupplierController(
 	r *http.Request,
 	user database.User,
 	) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
-		return
-	}
-
 	decoder := json.NewDecoder(r.Body)
 	params := Supplier{}
 	err := decoder.Decode(&params)
@@ -54,6 +51,7 @@ func (cfg ApiCfg) CreateSupplierController(
 
 	supplier, err := cfg.DB.CreateSupplier(r.Context(), database.CreateSupplierParams{
 		ID: uuid.New(),
+		OrgID: auth.OrgID(r.Context()),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 		Name: params.Name,
@@ -73,16 +71,29 @@ func (cfg ApiCfg) CreateSupplierController(
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create category: %v", err))
 		return
 	}
-	helpers.JSON(w, 201, models.DatabaseSupplierToSupplier(supplier))
+	created := models.DatabaseSupplierToSupplier(supplier)
+	cfg.recordAudit(r, user, auditCreate, "supplier", supplier.ID, nil, created)
+	helpers.JSON(w, 201, created)
 }
 
 func (cfg ApiCfg) GetAllSuppliersController(w http.ResponseWriter, r *http.Request, user database.User) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
+
+	include, ok := includeDeleted(w, r, auth.SuppliersDelete)
+	if !ok {
+		return
+	}
+
+	if exportList(w, r, "suppliers", models.SupplierExportColumns,
+		func(ctx context.Context, fn func(database.Supplier) error) error {
+			return cfg.DB.IterSuppliers(ctx, auth.OrgID(ctx), include, fn)
+		}) {
 		return
 	}
 
-	suppliers, err := cfg.DB.GetAllSuppliers(r.Context())
+	suppliers, err := cfg.DB.GetAllSuppliers(r.Context(), database.GetAllSuppliersParams{
+		OrgID: auth.OrgID(r.Context()),
+		IncludeDeleted: include,
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch suppliers: %v", err))
 		return
@@ -92,11 +103,12 @@ func (cfg ApiCfg) GetAllSuppliersController(w http.ResponseWriter, r *http.Reque
 }
 
 func (cfg ApiCfg) GetSupplierController(w http.ResponseWriter, r *http.Request, user database.User) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
+	
+	include, ok := includeDeleted(w, r, auth.SuppliersDelete)
+	if !ok {
 		return
 	}
-	
+
 	idStr := chi.URLParam(r, "supplierId")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -104,7 +116,16 @@ func (cfg ApiCfg) GetSupplierController(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	supplier, err := cfg.DB.GetSupplierById(r.Context(), id)
+	getSupplier := cfg.DB.GetSupplierById
+	if include {
+		getSupplier = func(ctx context.Context, arg database.GetSupplierByIdParams) (database.Supplier, error) {
+			return cfg.DB.GetSupplierByIdIncludingDeleted(ctx, database.GetSupplierByIdIncludingDeletedParams(arg))
+		}
+	}
+	supplier, err := getSupplier(r.Context(), database.GetSupplierByIdParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			helpers.RespondWithError(w, 404, "Supplier not found")
@@ -115,6 +136,9 @@ func (cfg ApiCfg) GetSupplierController(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if notModified(w, r, supplier.UpdatedAt) {
+		return
+	}
 	helpers.JSON(w, 200, models.DatabaseSupplierToSupplier(supplier))
 }
 
@@ -123,10 +147,6 @@ func (cfg ApiCfg) DeleteSupplierController(
 	r *http.Request,
 	user database.User,
 	) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
-		return
-	}
 	
 	idstr := chi.URLParam(r, "supplierId")
 	id, err := uuid.Parse(idstr)
@@ -135,30 +155,73 @@ func (cfg ApiCfg) DeleteSupplierController(
 		return
 	}
 
-	if !cfg.checkSupplierExists(w, r, id) {
+	supplier, ok := cfg.checkSupplierExists(w, r, id)
+	if !ok {
 		return
 	}
 
-	err = cfg.DB.DeleteSupplier(r.Context(), id)
+	deleted, err := cfg.DB.SoftDeleteSupplier(r.Context(), database.SoftDeleteSupplierParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+		DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		UpdatedAt: supplier.UpdatedAt,
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't delete supplier %v", err))
 		return
 	}
+	if deleted == 0 {
+		modified(w)
+		return
+	}
+	cfg.recordAudit(r, user, auditDelete, "supplier", id,
+		models.DatabaseSupplierToSupplier(supplier), nil)
 
 	helpers.TextResponse(w, 200, "Successfully deleted supplier")
 }
 
-func (cfg ApiCfg) UpdateSupplierController(
-	w http.ResponseWriter, 
+// RestoreSupplierController brings back a soft deleted supplier
+func (cfg ApiCfg) RestoreSupplierController(
+	w http.ResponseWriter,
 	r *http.Request,
 	user database.User,
 	) {
+	idStr := chi.URLParam(r, "supplierId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse string: %v", err))
+		return
+	}
 
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
+	supplier, err := cfg.DB.RestoreSupplier(r.Context(), database.RestoreSupplierParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Deleted supplier not found")
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { 
+				helpers.RespondWithError(w, 409, "Supplier Email already exists")
+				return
+			}
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't restore supplier: %v", err))
 		return
 	}
+	restored := models.DatabaseSupplierToSupplier(supplier)
+	cfg.recordAudit(r, user, auditRestore, "supplier", id, nil, restored)
+	helpers.JSON(w, 200, restored)
+}
 
+func (cfg ApiCfg) UpdateSupplierController(
+	w http.ResponseWriter, 
+	r *http.Request,
+	user database.User,
+	) {
 	decoder := json.NewDecoder(r.Body)
 	params := Supplier{}
 	err := decoder.Decode(&params)
@@ -181,29 +244,108 @@ func (cfg ApiCfg) UpdateSupplierController(
 		return
 	}
 
-	if !cfg.checkSupplierExists(w, r, id) {
+	supplier, ok := cfg.checkSupplierExists(w, r, id)
+	if !ok {
 		return
 	}
 
+	cfg.updateSupplier(w, r, user, supplier, params)
+}
+
+// PatchSupplierController applies a JSON merge patch to a supplier so
+// clients can change single fields without resending the whole object
+func (cfg ApiCfg) PatchSupplierController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	idStr := chi.URLParam(r, "supplierId")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't parse string: %v", err))
+		return
+	}
+
+	supplier, err := cfg.DB.GetSupplierById(r.Context(), database.GetSupplierByIdParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Supplier not found")
+			return
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch supplier %v", err))
+		return
+	}
+
+	if !cfg.checkPreconditions(w, r, supplier.UpdatedAt) {
+		return
+	}
+
+	params := Supplier{}
+	if !decodeMergePatch(w, r, databaseSupplierToParams(supplier), &params) {
+		return
+	}
+
+	if params.Name == "" {
+		helpers.RespondWithError(w, 400, "Supplier Name is required")
+		return
+	}
+
+	cfg.updateSupplier(w, r, user, supplier, params)
+}
+
+func databaseSupplierToParams(supplier database.Supplier) Supplier {
+	params := Supplier{Name: supplier.Name}
+	if supplier.Email.Valid {
+		params.Email = &supplier.Email.String
+	}
+	if supplier.Description.Valid {
+		params.Description = &supplier.Description.String
+	}
+	if supplier.Phone.Valid {
+		params.Phone = &supplier.Phone.String
+	}
+	if supplier.Country.Valid {
+		params.Country = &supplier.Country.String
+	}
+	return params
+}
+
+// updateSupplier replaces the fields of before with params and records the
+// change in the audit log
+func (cfg ApiCfg) updateSupplier(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	before database.Supplier,
+	params Supplier,
+	) {
 	email := helpers.NewNullString(params.Email)
 	description := helpers.NewNullString(params.Description)
 	phone := helpers.NewNullString(params.Phone)
 	country := helpers.NewNullString(params.Country)
 
-
 	supplier, err := cfg.DB.UpdateSupplier(
 		r.Context(),
 		database.UpdateSupplierParams{
-		ID: id,
+		ID: before.ID,
+		OrgID: before.OrgID,
 		Name: params.Name,
 		Email: email,
 		Description: description,
 		Phone: phone,
 		Country: country,
 		UpdatedAt: time.Now().UTC(),
+		ExpectedUpdatedAt: before.UpdatedAt,
 	})
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			modified(w)
+			return
+		}
 		if pqErr, ok := err.(*pq.Error); ok {
 			if pqErr.Code == "23505" { 
 				helpers.RespondWithError(w, 409, "Supplier Email already exists")
@@ -213,18 +355,27 @@ func (cfg ApiCfg) UpdateSupplierController(
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update supplier: %v", err))
 		return
 	}
-	helpers.JSON(w, 200, models.DatabaseSupplierToSupplier(supplier))
+	updated := models.DatabaseSupplierToSupplier(supplier)
+	cfg.recordAudit(r, user, auditUpdate, "supplier", supplier.ID,
+		models.DatabaseSupplierToSupplier(before), updated)
+	w.Header().Set("ETag", helpers.ETag(supplier.UpdatedAt))
+	helpers.JSON(w, 200, updated)
 }
 
+// checkSupplierExists also checks the If-Match precondition of the request
+// against the supplier it finds and returns it
 func (cfg ApiCfg) checkSupplierExists(
 	w http.ResponseWriter,
 	r *http.Request,
 	id uuid.UUID,
-	) bool {
-	_, err := cfg.DB.GetSupplierById(r.Context(), id)
+	) (database.Supplier, bool) {
+	supplier, err := cfg.DB.GetSupplierById(r.Context(), database.GetSupplierByIdParams{
+		ID: id,
+		OrgID: auth.OrgID(r.Context()),
+	})
 	if err != nil {
 		helpers.RespondWithError(w, 404, "Supplier not found")
-		return false
+		return supplier, false
 	}
-	return true
+	return supplier, cfg.checkPreconditions(w, r, supplier.UpdatedAt)
 }
\ No newline at end of file
@@ -19,7 +19,7 @@ func TestGenerateToken(t *testing.T) {
 	assert.NoError(t, err)
 	// os.Unsetenv("SECRET_KEY")
 
-	token, err := helpers.GenerateJWT(id, role)
+	token, err := helpers.GenerateJWT(id, role, uuid.New(), uuid.NullUUID{})
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
@@ -0,0 +1,514 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/metrics"
+	"github.com/ringtho/inventory/models"
+)
+
+const recoveryCodeCount = 10
+
+type twoFactorCodeParams struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type mfaChallengeParams struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type securitySettingsParams struct {
+	RequireAdminMFA *bool `json:"require_admin_mfa"`
+}
+
+// mfaPurpose decides whether a login with a correct password needs a second
+// step. It's empty when the user can be logged in straight away.
+func (cfg ApiCfg) mfaPurpose(ctx context.Context, user database.User) (string, error) {
+	credential, err := cfg.DB.GetTotpCredential(ctx, user.ID)
+	if err == nil && credential.ConfirmedAt.Valid {
+		return helpers.MFAPurposeVerify, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	required, err := cfg.mfaRequired(ctx, user.Role)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		return helpers.MFAPurposeEnroll, nil
+	}
+	return "", nil
+}
+
+// mfaRequired reports whether users with role have to use 2FA. The
+// security settings can require it for administrator roles, which are told
+// apart by their permissions rather than their name.
+func (cfg ApiCfg) mfaRequired(ctx context.Context, role string) (bool, error) {
+	settings, err := cfg.DB.GetSecuritySettings(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !settings.RequireAdminMfa {
+		return false, nil
+	}
+	permissions, err := cfg.DB.GetRolePermissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	return auth.Administrator(permissions), nil
+}
+
+// mfaThrottleKey counts failed second factors for a user. A correct
+// password doesn't clear it, so new challenges don't buy more guesses.
+func mfaThrottleKey(userId uuid.UUID) string {
+	return "mfa:" + userId.String()
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused
+// recovery code, which is used up. A TOTP code is refused once it or a
+// later one has been accepted.
+func (cfg ApiCfg) checkSecondFactor(
+	ctx context.Context,
+	credential database.TotpCredential,
+	code string,
+	recoveryCode string,
+	) (bool, error) {
+	if code != "" {
+		step, valid := helpers.MatchTOTP(code, credential.Secret)
+		if !valid {
+			return false, nil
+		}
+		used, err := cfg.DB.UseTotpStep(ctx, database.UseTotpStepParams{
+			UserID: credential.UserID,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			return false, err
+		}
+		return used > 0, nil
+	}
+	if recoveryCode == "" {
+		return false, nil
+	}
+
+	used, err := cfg.DB.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID: credential.UserID,
+		CodeHash: helpers.HashToken(helpers.NormalizeRecoveryCode(recoveryCode)),
+		UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	return used > 0, nil
+}
+
+// replaceRecoveryCodes throws away the user's recovery codes and returns a
+// new set. Only their hashes are stored.
+func (cfg ApiCfg) replaceRecoveryCodes(ctx context.Context, userId uuid.UUID) ([]string, error) {
+	codes, err := helpers.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	params := make([]database.CreateRecoveryCodeParams, len(codes))
+	for i, code := range codes {
+		params[i] = database.CreateRecoveryCodeParams{
+			ID: uuid.New(),
+			UserID: userId,
+			CodeHash: helpers.HashToken(code),
+			CreatedAt: now,
+		}
+	}
+	if err := cfg.DB.ReplaceRecoveryCodes(ctx, userId, params); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// enrollTotp starts 2FA setup for the user with a fresh secret. Setup that
+// was started but never confirmed is thrown away.
+func (cfg ApiCfg) enrollTotp(w http.ResponseWriter, r *http.Request, user database.User) {
+	credential, err := cfg.DB.GetTotpCredential(r.Context(), user.ID)
+	if err == nil && credential.ConfirmedAt.Valid {
+		helpers.RespondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	if err != nil && err != sql.ErrNoRows {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch two-factor credential: %v", err))
+		return
+	}
+
+	key, err := helpers.GenerateTOTPKey(user.Email)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't generate two-factor secret: %v", err))
+		return
+	}
+
+	if err := cfg.DB.DeleteTotpCredential(r.Context(), user.ID); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't reset two-factor credential: %v", err))
+		return
+	}
+
+	err = cfg.DB.CreateTotpCredential(r.Context(), database.CreateTotpCredentialParams{
+		UserID: user.ID,
+		Secret: key.Secret,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't store two-factor credential: %v", err))
+		return
+	}
+	helpers.JSON(w, 201, models.TOTPKeyToEnrollmentResponse(key))
+}
+
+// confirmTotp turns on 2FA once the user proves their authenticator app
+// works, and returns their recovery codes. It writes the error response and
+// returns false when the code is wrong.
+func (cfg ApiCfg) confirmTotp(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	code string,
+	) ([]string, bool) {
+	if code == "" {
+		helpers.RespondWithError(w, 400, "Code is required")
+		return nil, false
+	}
+
+	credential, err := cfg.DB.GetTotpCredential(r.Context(), user.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 400, "Two-factor setup has not been started")
+			return nil, false
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch two-factor credential: %v", err))
+		return nil, false
+	}
+
+	if credential.ConfirmedAt.Valid {
+		helpers.RespondWithError(w, 409, "Two-factor authentication is already enabled")
+		return nil, false
+	}
+
+	// The confirming code counts as used, it mustn't pass the first login
+	// challenge as well
+	step, valid := helpers.MatchTOTP(code, credential.Secret)
+	if !valid {
+		helpers.RespondWithError(w, 400, "Invalid code")
+		return nil, false
+	}
+	used, err := cfg.DB.UseTotpStep(r.Context(), database.UseTotpStepParams{
+		UserID: user.ID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't confirm two-factor credential: %v", err))
+		return nil, false
+	}
+	if used == 0 {
+		helpers.RespondWithError(w, 400, "Invalid code")
+		return nil, false
+	}
+
+	err = cfg.DB.ConfirmTotpCredential(r.Context(), database.ConfirmTotpCredentialParams{
+		UserID: user.ID,
+		ConfirmedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't confirm two-factor credential: %v", err))
+		return nil, false
+	}
+
+	codes, err := cfg.replaceRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create recovery codes: %v", err))
+		return nil, false
+	}
+
+	cfg.recordAudit(r, user, auditEnable2FA, "user", user.ID, nil, nil)
+	return codes, true
+}
+
+// TwoFactorEnrollController returns a new TOTP secret as an otpauth URI and
+// a QR code. 2FA isn't on until TwoFactorConfirmController gets a code.
+func (cfg ApiCfg) TwoFactorEnrollController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	cfg.enrollTotp(w, r, user)
+}
+
+// TwoFactorConfirmController turns on 2FA with a code from the newly
+// enrolled secret and returns the one time recovery codes
+func (cfg ApiCfg) TwoFactorConfirmController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := twoFactorCodeParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	codes, ok := cfg.confirmTotp(w, r, user, params.Code)
+	if !ok {
+		return
+	}
+	helpers.JSON(w, 200, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// TwoFactorDisableController turns off 2FA after checking a code or a
+// recovery code. Admins can't turn it off while it's required for them.
+func (cfg ApiCfg) TwoFactorDisableController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := twoFactorCodeParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	credential, err := cfg.DB.GetTotpCredential(r.Context(), user.ID)
+	if err != nil || !credential.ConfirmedAt.Valid {
+		if err != nil && err != sql.ErrNoRows {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch two-factor credential: %v", err))
+			return
+		}
+		helpers.RespondWithError(w, 400, "Two-factor authentication is not enabled")
+		return
+	}
+
+	required, err := cfg.mfaRequired(r.Context(), user.Role)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch security settings: %v", err))
+		return
+	}
+	if required {
+		helpers.RespondWithError(w, 403, "Two-factor authentication is required for admins")
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), credential, params.Code, params.RecoveryCode)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't check code: %v", err))
+		return
+	}
+	if !ok {
+		helpers.RespondWithError(w, 400, "Invalid code")
+		return
+	}
+
+	if err := cfg.DB.DeleteTotpCredential(r.Context(), user.ID); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't delete two-factor credential: %v", err))
+		return
+	}
+	if err := cfg.DB.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't delete recovery codes: %v", err))
+		return
+	}
+
+	cfg.recordAudit(r, user, auditDisable2FA, "user", user.ID, nil, nil)
+	helpers.TextResponse(w, 200, "Two-factor authentication has been disabled")
+}
+
+// decodeMFAChallenge reads the body of the second login step and returns
+// the user the challenge token was issued to. It writes the error response
+// and returns false when the token is invalid.
+func (cfg ApiCfg) decodeMFAChallenge(
+	w http.ResponseWriter,
+	r *http.Request,
+	purpose string,
+	) (database.User, mfaChallengeParams, bool) {
+	decoder := json.NewDecoder(r.Body)
+	params := mfaChallengeParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return database.User{}, params, false
+	}
+
+	if params.MFAToken == "" {
+		helpers.RespondWithError(w, 400, "MFA token is required")
+		return database.User{}, params, false
+	}
+
+	userId, err := helpers.VerifyMFAToken(params.MFAToken, purpose)
+	if err != nil {
+		helpers.RespondWithError(w, 401, "Invalid or expired MFA token")
+		return database.User{}, params, false
+	}
+
+	user, err := cfg.DB.GetUserById(r.Context(), userId)
+	if err != nil {
+		helpers.RespondWithError(w, 401, "User not found")
+		return database.User{}, params, false
+	}
+	return user, params, true
+}
+
+// TwoFactorVerifyController is the second login step for users with 2FA.
+// It takes the MFA token from LoginController with a TOTP code or a
+// recovery code and returns the access and refresh tokens. Wrong codes
+// lock the user out of this step like wrong passwords do for logins.
+func (cfg ApiCfg) TwoFactorVerifyController(w http.ResponseWriter, r *http.Request) {
+	user, params, ok := cfg.decodeMFAChallenge(w, r, helpers.MFAPurposeVerify)
+	if !ok {
+		return
+	}
+
+	if params.Code == "" && params.RecoveryCode == "" {
+		helpers.RespondWithError(w, 400, "Code or Recovery code is required")
+		return
+	}
+
+	mfaKey, ipKey := mfaThrottleKey(user.ID), cfg.ipThrottleKey(r)
+	wait, err := cfg.loginRetryAfter(r.Context(), mfaKey, ipKey)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't check failed logins: %v", err))
+		return
+	}
+	if wait > 0 {
+		metrics.RecordLogin(metrics.LoginTOTP, metrics.LoginLocked)
+		respondLoginLocked(w, wait)
+		return
+	}
+
+	credential, err := cfg.DB.GetTotpCredential(r.Context(), user.ID)
+	if err != nil || !credential.ConfirmedAt.Valid {
+		helpers.RespondWithError(w, 401, "Two-factor authentication is not enabled")
+		return
+	}
+
+	ok, err = cfg.checkSecondFactor(r.Context(), credential, params.Code, params.RecoveryCode)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't check code: %v", err))
+		return
+	}
+	if !ok {
+		log.Printf("Failed two-factor login for user %v", user.ID)
+		cfg.recordLoginFailure(r.Context(), mfaKey, helpers.AccountLockoutPolicy())
+		cfg.recordLoginFailure(r.Context(), ipKey, helpers.IPLockoutPolicy())
+		metrics.RecordLogin(metrics.LoginTOTP, metrics.LoginFailure)
+		helpers.RespondWithError(w, 401, "Invalid code")
+		return
+	}
+	metrics.RecordLogin(metrics.LoginTOTP, metrics.LoginSuccess)
+
+	if err := cfg.DB.ClearLoginThrottle(r.Context(), mfaKey); err != nil {
+		log.Printf("Couldn't clear failed logins for %v: %v", mfaKey, err)
+	}
+
+	login, ok := cfg.startSession(w, r, user)
+	if !ok {
+		return
+	}
+	helpers.JSON(w, 200, login)
+}
+
+// TwoFactorSetupController starts 2FA setup for an admin who has to have it
+// before they can log in, using the MFA token from LoginController
+func (cfg ApiCfg) TwoFactorSetupController(w http.ResponseWriter, r *http.Request) {
+	user, _, ok := cfg.decodeMFAChallenge(w, r, helpers.MFAPurposeEnroll)
+	if !ok {
+		return
+	}
+	cfg.enrollTotp(w, r, user)
+}
+
+// TwoFactorSetupConfirmController finishes the setup started by
+// TwoFactorSetupController and logs the admin in. The response carries
+// their recovery codes along with the tokens.
+func (cfg ApiCfg) TwoFactorSetupConfirmController(w http.ResponseWriter, r *http.Request) {
+	user, params, ok := cfg.decodeMFAChallenge(w, r, helpers.MFAPurposeEnroll)
+	if !ok {
+		return
+	}
+
+	codes, ok := cfg.confirmTotp(w, r, user, params.Code)
+	if !ok {
+		return
+	}
+
+	login, ok := cfg.startSession(w, r, user)
+	if !ok {
+		return
+	}
+	login.RecoveryCodes = codes
+	helpers.JSON(w, 200, login)
+}
+
+// GetSecuritySettingsController returns the settings admins can change at
+// runtime
+func (cfg ApiCfg) GetSecuritySettingsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	settings, err := cfg.DB.GetSecuritySettings(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch security settings: %v", err))
+		return
+	}
+	helpers.JSON(w, 200, models.DatabaseSecuritySettingsToSecuritySettings(settings))
+}
+
+// UpdateSecuritySettingsController lets admins require 2FA for every admin.
+// Admins without it are asked to set it up at their next login.
+func (cfg ApiCfg) UpdateSecuritySettingsController(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) {
+	decoder := json.NewDecoder(r.Body)
+	params := securitySettingsParams{}
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	if params.RequireAdminMFA == nil {
+		helpers.RespondWithError(w, 400, "require_admin_mfa is required")
+		return
+	}
+
+	settings, err := cfg.DB.UpdateSecuritySettings(r.Context(), database.UpdateSecuritySettingsParams{
+		RequireAdminMfa: *params.RequireAdminMFA,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't update security settings: %v", err))
+		return
+	}
+
+	log.Printf("Admin %v set require_admin_mfa to %v", user.ID, settings.RequireAdminMfa)
+	helpers.JSON(w, 200, models.DatabaseSecuritySettingsToSecuritySettings(settings))
+}
@@ -0,0 +1,514 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	totpCredentialColumns   = []string{"user_id", "secret", "created_at", "confirmed_at", "last_used_step"}
+	securitySettingsColumns = []string{"id", "require_admin_mfa", "updated_at"}
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// expectNoTwoFactor sets up the 2FA checks of a login for a user who hasn't
+// turned it on and doesn't have to
+func expectNoTwoFactor(mock sqlmock.Sqlmock, userId uuid.UUID) {
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns))
+	mock.ExpectQuery(`SELECT (.+) FROM security_settings`).
+		WillReturnRows(sqlmock.NewRows(securitySettingsColumns).AddRow(true, false, time.Now()))
+}
+
+func TestLogin_TwoFactorReturnsChallenge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{DB: database.New(db)}
+	userId := uuid.New()
+	password := "StrongPass123"
+
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com",
+				helpers.HashPassword(password), "user", nil, "john doe", time.Now()))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(userId, testTOTPSecret, time.Now(), time.Now(), 0))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.LoginController).ServeHTTP(rr,
+		jsonRequest(t, "/login", map[string]string{"email": "johndoe@gmail.com", "password": password}))
+
+	var response models.MFAChallengeResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.True(t, response.MFARequired)
+	assert.False(t, response.EnrollmentRequired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	challengeUser, err := helpers.VerifyMFAToken(response.MFAToken, helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+	assert.Equal(t, userId, challengeUser)
+
+	// The challenge token must not work as an access token
+	_, err = helpers.VerifyToken(response.MFAToken)
+	assert.Error(t, err)
+}
+
+func TestLogin_AdminRequiredToEnroll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{DB: database.New(db)}
+	userId := uuid.New()
+	password := "StrongPass123"
+
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "admin", "admin@gmail.com",
+				helpers.HashPassword(password), "superuser", nil, "admin", time.Now()))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns))
+	mock.ExpectQuery(`SELECT (.+) FROM security_settings`).
+		WillReturnRows(sqlmock.NewRows(securitySettingsColumns).AddRow(true, true, time.Now()))
+	// Any role that can manage roles is an admin, whatever it's called
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("superuser").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(auth.UsersRead).AddRow(auth.RolesManage))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.LoginController).ServeHTTP(rr,
+		jsonRequest(t, "/login", map[string]string{"email": "admin@gmail.com", "password": password}))
+
+	var response models.MFAChallengeResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.True(t, response.EnrollmentRequired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = helpers.VerifyMFAToken(response.MFAToken, helpers.MFAPurposeVerify)
+	assert.Error(t, err, "an enrolment token must not pass the verify step")
+}
+
+func TestTwoFactorVerify_ValidCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{DB: database.New(db)}
+	userId := uuid.New()
+	mfaToken, err := helpers.GenerateMFAToken(userId, helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+	now := time.Now().UTC()
+	code, err := totp.GenerateCode(testTOTPSecret, now)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(userId, testTOTPSecret, time.Now(), time.Now(), 0))
+	mock.ExpectExec(`UPDATE totp_credentials SET last_used_step = \$2`).
+		WithArgs(userId, now.Unix()/30).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WithArgs("mfa:" + userId.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUserOrganizations(mock, userId)
+	mock.ExpectExec(`INSERT INTO sessions`).
+		WithArgs(sqlmock.AnyArg(), userId, uuid.NullUUID{}, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO refresh_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.TwoFactorVerifyController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/2fa/verify", map[string]string{"mfa_token": mfaToken, "code": code}))
+
+	var response models.LoginResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorVerify_InvalidCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{DB: database.New(db)}
+	userId := uuid.New()
+	mfaToken, err := helpers.GenerateMFAToken(userId, helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(userId, testTOTPSecret, time.Now(), time.Now(), 0))
+	mock.ExpectQuery(`INSERT INTO login_throttles`).
+		WithArgs("mfa:"+userId.String(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("mfa:"+userId.String(), 1, time.Now(), nil))
+	mock.ExpectQuery(`INSERT INTO login_throttles`).
+		WithArgs("ip:192.0.2.1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("ip:192.0.2.1", 1, time.Now(), nil))
+
+	req := jsonRequest(t, "/auth/2fa/verify", map[string]string{"mfa_token": mfaToken, "code": "000000x"})
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.TwoFactorVerifyController).ServeHTTP(rr, req)
+
+	assert.Equal(t, 401, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid code")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorVerify_RecoveryCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{DB: database.New(db)}
+	userId := uuid.New()
+	mfaToken, err := helpers.GenerateMFAToken(userId, helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(userId, testTOTPSecret, time.Now(), time.Now(), 0))
+	mock.ExpectExec(`UPDATE recovery_codes SET used_at = \$3`).
+		WithArgs(userId, helpers.HashToken("ABCDE-FGHIJ"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM login_throttles`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUserOrganizations(mock, userId)
+	mock.ExpectExec(`INSERT INTO sessions`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO refresh_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.TwoFactorVerifyController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/2fa/verify", map[string]string{"mfa_token": mfaToken, "recovery_code": "abcdefghij"}))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorVerify_ReusedCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{DB: database.New(db)}
+	userId := uuid.New()
+	mfaToken, err := helpers.GenerateMFAToken(userId, helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+	now := time.Now().UTC()
+	code, err := totp.GenerateCode(testTOTPSecret, now)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	expectLoginThrottles(mock)
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(userId, testTOTPSecret, time.Now(), time.Now(), now.Unix()/30))
+	// The step has been used already, so nothing is updated
+	mock.ExpectExec(`UPDATE totp_credentials SET last_used_step = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO login_throttles`).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("mfa:"+userId.String(), 1, time.Now(), nil))
+	mock.ExpectQuery(`INSERT INTO login_throttles`).
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("ip:192.0.2.1", 1, time.Now(), nil))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.TwoFactorVerifyController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/2fa/verify", map[string]string{"mfa_token": mfaToken, "code": code}))
+
+	assert.Equal(t, 401, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorVerify_Locked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{DB: database.New(db)}
+	userId := uuid.New()
+	mfaToken, err := helpers.GenerateMFAToken(userId, helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(userId, time.Now(), time.Now(), "johndoe", "johndoe@gmail.com", "hash", "user", nil, "john doe", time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM login_throttles`).
+		WithArgs("mfa:"+userId.String(), "ip:192.0.2.1").
+		WillReturnRows(sqlmock.NewRows(loginThrottleColumns).
+			AddRow("mfa:"+userId.String(), 5, time.Now(), time.Now().Add(time.Minute)))
+
+	req := jsonRequest(t, "/auth/2fa/verify", map[string]string{"mfa_token": mfaToken, "code": "123456"})
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.TwoFactorVerifyController).ServeHTTP(rr, req)
+
+	assert.Equal(t, 429, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet(), "the code must not be checked")
+}
+
+func TestTwoFactorVerify_RejectsAccessToken(t *testing.T) {
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	cfg := ApiCfg{}
+	accessToken, err := helpers.GenerateJWT(uuid.New(), "user", uuid.New(), uuid.NullUUID{})
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(cfg.TwoFactorVerifyController).ServeHTTP(rr,
+		jsonRequest(t, "/auth/2fa/verify", map[string]string{"mfa_token": accessToken, "code": "123456"}))
+
+	assert.Equal(t, 401, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid or expired MFA token")
+}
+
+func TestTwoFactorEnroll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Email: "johndoe@gmail.com", Role: "user"}
+
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns))
+	mock.ExpectExec(`DELETE FROM totp_credentials WHERE user_id = \$1`).
+		WithArgs(user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO totp_credentials`).
+		WithArgs(user.ID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req, err := http.NewRequest("POST", "/auth/2fa/enroll", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	cfg.TwoFactorEnrollController(rr, req, user)
+
+	var response models.TOTPEnrollmentResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 201, rr.Code)
+	assert.True(t, strings.HasPrefix(response.OtpauthURI, "otpauth://totp/"))
+	assert.Contains(t, response.OtpauthURI, "secret="+response.Secret)
+	assert.True(t, strings.HasPrefix(response.QRCode, "data:image/png;base64,"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorConfirm_ReturnsRecoveryCodes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Email: "johndoe@gmail.com", Role: "user"}
+	code, err := totp.GenerateCode(testTOTPSecret, time.Now().UTC())
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(user.ID, testTOTPSecret, time.Now(), nil, 0))
+	mock.ExpectExec(`UPDATE totp_credentials SET last_used_step = \$2`).
+		WithArgs(user.ID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE totp_credentials SET confirmed_at = \$2 WHERE user_id = \$1`).
+		WithArgs(user.ID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM recovery_codes WHERE user_id = \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for i := 0; i < recoveryCodeCount; i++ {
+		mock.ExpectExec(`INSERT INTO recovery_codes`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	cfg.TwoFactorConfirmController(rr, jsonRequest(t, "/auth/2fa/confirm", map[string]string{"code": code}), user)
+
+	var response models.RecoveryCodesResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, recoveryCodeCount, len(response.RecoveryCodes))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorConfirm_CodeCanOnlyBeUsedOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Email: "johndoe@gmail.com", Role: "user"}
+	now := time.Now().UTC()
+	code, err := totp.GenerateCode(testTOTPSecret, now)
+	assert.NoError(t, err)
+
+	// The step of the code was already used
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(user.ID, testTOTPSecret, time.Now(), nil, now.Unix()/30))
+	mock.ExpectExec(`UPDATE totp_credentials SET last_used_step = \$2`).
+		WithArgs(user.ID, now.Unix()/30).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rr := httptest.NewRecorder()
+	cfg.TwoFactorConfirmController(rr, jsonRequest(t, "/auth/2fa/confirm", map[string]string{"code": code}), user)
+
+	assert.Equal(t, 400, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "2FA must not be turned on")
+}
+
+func TestTwoFactorDisable_NotRequiredForOtherRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Role: "warehouse_clerk"}
+	now := time.Now().UTC()
+	code, err := totp.GenerateCode(testTOTPSecret, now)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(user.ID, testTOTPSecret, time.Now(), time.Now(), 0))
+	mock.ExpectQuery(`SELECT (.+) FROM security_settings`).
+		WillReturnRows(sqlmock.NewRows(securitySettingsColumns).AddRow(true, true, time.Now()))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("warehouse_clerk").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(auth.ProductsRead).AddRow(auth.ProductsStock))
+	mock.ExpectExec(`UPDATE totp_credentials SET last_used_step = \$2`).
+		WithArgs(user.ID, now.Unix()/30).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM recovery_codes WHERE user_id = \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	cfg.TwoFactorDisableController(rr, jsonRequest(t, "/auth/2fa/disable", map[string]string{"code": code}), user)
+
+	assert.Equal(t, 200, rr.Code, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorDisable_RequiredForAdmins(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+	user := database.User{ID: uuid.New(), Role: "admin"}
+
+	mock.ExpectQuery(`SELECT (.+) FROM totp_credentials WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows(totpCredentialColumns).
+			AddRow(user.ID, testTOTPSecret, time.Now(), time.Now(), 0))
+	mock.ExpectQuery(`SELECT (.+) FROM security_settings`).
+		WillReturnRows(sqlmock.NewRows(securitySettingsColumns).AddRow(true, true, time.Now()))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(auth.RolesManage))
+
+	rr := httptest.NewRecorder()
+	cfg.TwoFactorDisableController(rr, jsonRequest(t, "/auth/2fa/disable", map[string]string{"code": "123456"}), user)
+
+	assert.Equal(t, 403, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateSecuritySettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := ApiCfg{DB: database.New(db)}
+
+	mock.ExpectQuery(`UPDATE security_settings SET require_admin_mfa = \$1`).
+		WithArgs(true, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(securitySettingsColumns).AddRow(true, true, time.Now()))
+
+	rr := httptest.NewRecorder()
+	cfg.UpdateSecuritySettingsController(rr,
+		jsonRequest(t, "/settings/security", map[string]string{}), database.User{Role: "admin"})
+	assert.Equal(t, 400, rr.Code)
+
+	body := strings.NewReader(`{"require_admin_mfa": true}`)
+	req, err := http.NewRequest("PUT", "/settings/security", body)
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	cfg.UpdateSecuritySettingsController(rr, req, database.User{Role: "admin"})
+
+	var response models.SecuritySettings
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+	assert.Equal(t, 200, rr.Code)
+	assert.True(t, response.RequireAdminMFA)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -12,102 +12,15 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
+	"github.com/ringtho/inventory/models"
 	"github.com/stretchr/testify/assert"
 )
 
 
-func TestUpdateSupplier_Success(t *testing.T){
-	ptr := func (s string) *string{ return &s}
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-	supplierID := uuid.New()
-
-	mockSupplier := Supplier{
-		Name: "Hisense",
-		Email: ptr("info@hisense.com"),
-		Description: ptr("Hisense appliances and accessories"),
-		Phone: ptr("0778 000000"),
-		Country: ptr("Uganda"),
-	}
-
-	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
-	}).AddRow(
-		supplierID,
-		mockSupplier.Name,
-		mockSupplier.Email,
-		mockSupplier.Description,
-		mockSupplier.Phone,
-		mockSupplier.Country,
-		time.Now().UTC(),
-		time.Now().UTC(),
-	)
-
-	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
-	).
-	WithArgs(supplierID).
-	WillReturnRows(mockData)
-
-	updateSupplierData := Supplier{
-		Name: "Sony Electronics",
-	}
-
-	UpdateMockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
-	}).AddRow(
-		supplierID,
-		updateSupplierData.Name,
-		mockSupplier.Email,
-		"",
-		mockSupplier.Phone,
-		mockSupplier.Country,
-		time.Now(),
-		time.Now(),
-	)
-
-	mock.ExpectQuery(
-		`UPDATE suppliers SET name = \$2, email = \$3, description = \$4, 
-		phone = \$5, country = \$6, updated_at = \$7 WHERE id = \$1`).
-	WithArgs(
-		supplierID,
-		updateSupplierData.Name,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-	).WillReturnRows(UpdateMockData)
-
-	payload, err := json.Marshal(updateSupplierData)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("PUT", 
-	fmt.Sprintf("/suppliers/%v", supplierID), bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	router := chi.NewRouter()
-	router.Put("/suppliers/{supplierId}", 
-	func(w http.ResponseWriter, r *http.Request){
-		cfg.UpdateSupplierController(w, r, adminUser)
-	})
-	router.ServeHTTP(rr, req)
-
-	assert.Equal(t, 200, rr.Code)
-	assert.Equal(t, updateSupplierData.Name, "Sony Electronics")
-}
-
-func TestUpdateSupplier_EmailExists(t *testing.T){
+func TestUpdateSupplier_DBError(t *testing.T){
 	ptr := func (s string) *string{ return &s}
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -128,7 +41,7 @@ func TestUpdateSupplier_EmailExists(t *testing.T){
 	}
 
 	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
+		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at", "deleted_at", "org_id",
 	}).AddRow(
 		supplierID,
 		mockSupplier.Name,
@@ -137,13 +50,13 @@ func TestUpdateSupplier_EmailExists(t *testing.T){
 		mockSupplier.Phone,
 		mockSupplier.Country,
 		time.Now().UTC(),
-		time.Now().UTC(),
+		time.Now().UTC(), nil, uuid.Nil,
 	)
 
 	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
+	SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers WHERE id=\$1 AND org_id=\$2`,
 	).
-	WithArgs(supplierID).
+	WithArgs(supplierID, uuid.Nil).
 	WillReturnRows(mockData)
 
 	updateSupplierData := Supplier{
@@ -152,92 +65,17 @@ func TestUpdateSupplier_EmailExists(t *testing.T){
 	}
 
 	mock.ExpectQuery(
-		`UPDATE suppliers SET name = \$2, email = \$3, description = \$4, 
-		phone = \$5, country = \$6, updated_at = \$7 WHERE id = \$1`).
+		`UPDATE suppliers SET name = \$1, email = \$2, description = \$3, 
+		phone = \$4, country = \$5, updated_at = \$6 WHERE id = \$7`).
 	WithArgs(
-		supplierID,
 		updateSupplierData.Name,
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
-	).WillReturnError(&pq.Error{Code: "23505"})
-
-	payload, err := json.Marshal(updateSupplierData)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest("PUT", 
-	fmt.Sprintf("/suppliers/%v", supplierID), bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
-
-	rr := httptest.NewRecorder()
-	router := chi.NewRouter()
-	router.Put("/suppliers/{supplierId}", 
-	func(w http.ResponseWriter, r *http.Request){
-		cfg.UpdateSupplierController(w, r, adminUser)
-	})
-	router.ServeHTTP(rr, req)
-
-	assert.Equal(t, 409, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Supplier Email already exists")
-}
-
-func TestUpdateSupplier_DBError(t *testing.T){
-	ptr := func (s string) *string{ return &s}
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
-
-	adminUser := database.User{Role: "admin"}
-	supplierID := uuid.New()
-
-	mockSupplier := Supplier{
-		Name: "Hisense",
-		Email: ptr("info@hisense.com"),
-		Description: ptr("Hisense appliances and accessories"),
-		Phone: ptr("0778 000000"),
-		Country: ptr("Uganda"),
-	}
-
-	mockData := sqlmock.NewRows([]string{
-		"id", "name", "email", "description", "phone", "country", "created_at", "updated_at",
-	}).AddRow(
 		supplierID,
-		mockSupplier.Name,
-		mockSupplier.Email,
-		mockSupplier.Description,
-		mockSupplier.Phone,
-		mockSupplier.Country,
-		time.Now().UTC(),
-		time.Now().UTC(),
-	)
-
-	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
-	).
-	WithArgs(supplierID).
-	WillReturnRows(mockData)
-
-	updateSupplierData := Supplier{
-		Name: "Sony Electronics",
-		Email: ptr("info@hisense.com"),
-	}
-
-	mock.ExpectQuery(
-		`UPDATE suppliers SET name = \$2, email = \$3, description = \$4, 
-		phone = \$5, country = \$6, updated_at = \$7 WHERE id = \$1`).
-	WithArgs(
-		supplierID,
-		updateSupplierData.Name,
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
-		sqlmock.AnyArg(),
+		uuid.Nil,
 		sqlmock.AnyArg(),
 	).WillReturnError(fmt.Errorf("Database Error"))
 
@@ -260,45 +98,46 @@ func TestUpdateSupplier_DBError(t *testing.T){
 	assert.Contains(t, rr.Body.String(), "Couldn't update supplier")
 }
 
-func TestUpdateSupplier_SupplierNotFound(t *testing.T){
-	ptr := func (s string) *string{ return &s}
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
+func TestUpdateSupplier_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		rr := serve(handler, "POST", "/suppliers", hisense)
+		var created models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
 
-	queries := database.New(db)
-	cfg := ApiCfg{DB: queries}
+		rr = serve(handler, "PUT", "/suppliers/"+created.ID.String(), `{"name": "Sony Electronics"}`)
 
-	adminUser := database.User{Role: "admin"}
-	supplierID := uuid.New()
+		var response models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, "Sony Electronics", response.Name)
+	})
+}
 
-	mock.ExpectQuery(`
-	SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=\$1`,
-	).
-	WithArgs(supplierID).
-	WillReturnError(fmt.Errorf("Not Found"))
+func TestUpdateSupplier_EmailExists(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
+		assert.Equal(t, 201, serve(handler, "POST", "/suppliers", hisense).Code)
+		rr := serve(handler, "POST", "/suppliers", `{"name": "Sony", "email": "info@sony.com"}`)
+		var sony models.Supplier
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&sony))
 
-	updateSupplierData := Supplier{
-		Name: "Sony Electronics",
-		Email: ptr("info@hisense.com"),
-	}
+		rr = serve(handler, "PUT", "/suppliers/"+sony.ID.String(),
+			`{"name": "Sony Electronics", "email": "info@hisense.com"}`)
 
-	payload, err := json.Marshal(updateSupplierData)
-	assert.NoError(t, err)
+		assert.Equal(t, 409, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Supplier Email already exists")
+	})
+}
 
-	req, err := http.NewRequest("PUT", fmt.Sprintf("/suppliers/%v", supplierID), 
-	bytes.NewBuffer(payload))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/json")
+func TestUpdateSupplier_SupplierNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db store.Store) {
+		handler := inventoryRouter(ApiCfg{DB: db}, memory.DefaultOrgID)
 
-	rr := httptest.NewRecorder()
-	router := chi.NewRouter()
-	router.Put("/suppliers/{supplierId}", 
-	func(w http.ResponseWriter, r *http.Request){
-		cfg.UpdateSupplierController(w, r, adminUser)
-	})
-	router.ServeHTTP(rr, req)
+		rr := serve(handler, "PUT", "/suppliers/"+uuid.New().String(),
+			`{"name": "Sony Electronics", "email": "info@hisense.com"}`)
 
-	assert.Equal(t, 404, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Supplier not found")
-}
\ No newline at end of file
+		assert.Equal(t, 404, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Supplier not found")
+	})
+}
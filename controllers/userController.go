@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
 
 	"time"
@@ -13,15 +15,53 @@ import (
 	"github.com/lib/pq"
 	"github.com/ringtho/inventory/helpers"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/metrics"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/mailer"
 	"github.com/ringtho/inventory/models"
+	"github.com/ringtho/inventory/sso"
 )
 
 type ApiCfg struct {
-	DB *database.Queries
+	DB store.Store
+	// RequireIfMatch rejects writes to catalogue entities that don't send
+	// an If-Match header with 428 Precondition Required
+	RequireIfMatch bool
+	Mailer mailer.Mailer
+	// PasswordResetURL is the page of the frontend that takes a reset
+	// token, which is appended to it as ?token=
+	PasswordResetURL string
+	// PublicURL is where the API is reachable from outside, used to build
+	// links in emails. The request's own host is used when it's empty.
+	PublicURL string
+	// AllowUnverifiedLogin lets users log in before verifying their email
+	AllowUnverifiedLogin bool
+	// SSO is the OpenID Connect identity provider, nil when single sign-on
+	// isn't configured
+	SSO *sso.Provider
+	// DisableRegistration turns off /register so accounts can only be made
+	// from an invitation
+	DisableRegistration bool
+	// InvitationURL is the page of the frontend that accepts an invitation
+	// token, which is appended to it as ?token=
+	InvitationURL string
+	// DefaultOrg is the slug of the organisation registered and SSO users
+	// join, and invitees whose invitation doesn't name one
+	DefaultOrg string
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For and
+	// X-Real-IP headers are believed, see clientIP
+	TrustedProxies []*net.IPNet
 }
 
-// CreateUserController creates a new user
+// CreateUserController registers a new user. Registrants always get the
+// user role, anything else has to come from an admin or an invitation.
+// They join the default organisation with it.
 func (apiCfg ApiCfg) CreateUserController(w http.ResponseWriter, r *http.Request) {
+	if apiCfg.DisableRegistration {
+		helpers.RespondWithError(w, 403, "Public registration is disabled")
+		return
+	}
+
 	decoder := json.NewDecoder(r.Body)
 	var params models.User
 	err := decoder.Decode(&params)
@@ -46,10 +86,6 @@ func (apiCfg ApiCfg) CreateUserController(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if params.Role != "admin" && params.Role != "user" {
-		params.Role = "user"
-	}
-
 	password := helpers.HashPassword(params.Password)
 	profilePic := helpers.NewNullString(params.ProfilePictureUrl)
 
@@ -61,7 +97,7 @@ func (apiCfg ApiCfg) CreateUserController(w http.ResponseWriter, r *http.Request
 		Username: 	params.Username,
 		Email: 		params.Email,
 		Password: 	password,
-		Role: 		params.Role,
+		Role: 		defaultRole,
 		ProfilePictureUrl: profilePic,
 	})
 
@@ -76,7 +112,22 @@ func (apiCfg ApiCfg) CreateUserController(w http.ResponseWriter, r *http.Request
 		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create user: %v", err))
 		return
 	}
-	helpers.JSON(w, 201, models.DatabaseUserToUserResponse(user))
+
+	if err := apiCfg.joinOrganization(r.Context(), user.ID, uuid.NullUUID{}, defaultRole); err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't add organisation member: %v", err))
+		return
+	}
+
+	// Registration is anonymous, so the new user is recorded as its own actor
+	created := models.DatabaseUserToUserResponse(user)
+	apiCfg.recordAudit(r, database.User{ID: user.ID, Email: user.Email},
+		auditCreate, "user", user.ID, nil, created)
+
+	// The account exists either way, a failed email can be resent later
+	if err := apiCfg.sendVerificationEmail(r, user.ID, user.Email); err != nil {
+		log.Printf("Couldn't send verification email to %v: %v", user.Email, err)
+	}
+	helpers.JSON(w, 201, created)
 }
 
 // Login user
@@ -96,21 +147,119 @@ func (apiCfg ApiCfg) LoginController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Locked out clients are turned away before the expensive bcrypt check
+	accountKey, ipKey := accountThrottleKey(params.Email), apiCfg.ipThrottleKey(r)
+	wait, err := apiCfg.loginRetryAfter(r.Context(), accountKey, ipKey)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't check failed logins: %v", err))
+		return
+	}
+	if wait > 0 {
+		metrics.RecordLogin(metrics.LoginPassword, metrics.LoginLocked)
+		respondLoginLocked(w, wait)
+		return
+	}
+
 	user, err := apiCfg.DB.GetUserByEmail(r.Context(), params.Email)
 
 	if err != nil || !helpers.CheckPasswordHash(user.Password, params.Password){
+		apiCfg.recordLoginFailure(r.Context(), accountKey, helpers.AccountLockoutPolicy())
+		apiCfg.recordLoginFailure(r.Context(), ipKey, helpers.IPLockoutPolicy())
+		metrics.RecordLogin(metrics.LoginPassword, metrics.LoginFailure)
 		helpers.RespondWithError(w, 400, "Invalid email or password")
 		return
 	}
 
-	//	Generate JWT token
-	token, err := helpers.GenerateJWT(user.ID, user.Role)
+	if err := apiCfg.DB.ClearLoginThrottle(r.Context(), accountKey); err != nil {
+		log.Printf("Couldn't clear failed logins for %v: %v", accountKey, err)
+	}
+
+	if !user.EmailVerifiedAt.Valid && !apiCfg.AllowUnverifiedLogin {
+		metrics.RecordLogin(metrics.LoginPassword, metrics.LoginUnverified)
+		helpers.RespondWithError(w, 403, "Email address has not been verified")
+		return
+	}
+
+	metrics.RecordLogin(metrics.LoginPassword, metrics.LoginSuccess)
+	apiCfg.completeLogin(w, r, user)
+}
+
+// completeLogin answers a login whose credentials have been checked, with
+// either a second factor challenge or the tokens for a new session
+func (apiCfg ApiCfg) completeLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	purpose, err := apiCfg.mfaPurpose(r.Context(), user)
 	if err != nil {
-		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't generate token: %v", err))
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't check two-factor authentication: %v", err))
+		return
+	}
+
+	// The JWT is only issued once the second factor has been checked
+	if purpose != "" {
+		mfaToken, err := helpers.GenerateMFAToken(user.ID, purpose)
+		if err != nil {
+			helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't generate token: %v", err))
+			return
+		}
+		helpers.JSON(w, 200, models.MFAChallengeResponse{
+			MFARequired: true,
+			EnrollmentRequired: purpose == helpers.MFAPurposeEnroll,
+			MFAToken: mfaToken,
+		})
 		return
 	}
 
-	helpers.JSON(w, 200, models.SanitizeLoginResponse(user, token))
+	login, ok := apiCfg.startSession(w, r, user)
+	if !ok {
+		return
+	}
+	helpers.JSON(w, 200, login)
+}
+
+// startSession issues the access and refresh tokens for a new session. It
+// writes the error response and returns false when that fails.
+func (apiCfg ApiCfg) startSession(
+	w http.ResponseWriter,
+	r *http.Request,
+	user database.User,
+	) (models.LoginResponse, bool) {
+	// Sessions start in the user's oldest organisation, they can switch
+	// to another with /auth/switch-org
+	orgs, err := apiCfg.DB.GetUserOrganizations(r.Context(), user.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisations: %v", err))
+		return models.LoginResponse{}, false
+	}
+	orgId := uuid.NullUUID{}
+	if len(orgs) > 0 {
+		orgId = uuid.NullUUID{UUID: orgs[0].ID, Valid: true}
+	}
+
+	//	Generate JWT token for a new session
+	sessionId := uuid.New()
+	token, err := helpers.GenerateJWT(user.ID, user.Role, sessionId, orgId)
+	if err != nil {
+		helpers.RespondWithError(w, 400, fmt.Sprintf("Couldn't generate token: %v", err))
+		return models.LoginResponse{}, false
+	}
+
+	err = apiCfg.DB.CreateSession(r.Context(), database.CreateSessionParams{
+		ID: 		sessionId,
+		UserID: 	user.ID,
+		OrgID: 		orgId,
+		CreatedAt: 	time.Now().UTC(),
+	})
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create session: %v", err))
+		return models.LoginResponse{}, false
+	}
+
+	refreshToken, err := apiCfg.issueRefreshToken(r.Context(), sessionId)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't create refresh token: %v", err))
+		return models.LoginResponse{}, false
+	}
+
+	return models.SanitizeLoginResponse(user, token, refreshToken), true
 }
 
 // Get All users
@@ -119,8 +268,7 @@ func (apiCfg ApiCfg) GetAllUsersController(
 	r *http.Request, 
 	user database.User,
 	) {
-	if user.Role != "admin" {
-		helpers.RespondWithError(w, 403, "Unauthorized")
+	if exportList(w, r, "users", models.UserExportColumns, apiCfg.DB.IterUsers) {
 		return
 	}
 
@@ -137,11 +285,6 @@ func (apiCfg ApiCfg) DeleteUserController(
 	r *http.Request, 
 	user database.User,
 	) {
-		if user.Role != "admin" {
-			helpers.RespondWithError(w, 403, "Unauthorized")
-			return
-		}
-
 		idStr := chi.URLParam(r, "userId")
 		id, err := uuid.Parse(idStr)
 		if err != nil {
@@ -150,7 +293,7 @@ func (apiCfg ApiCfg) DeleteUserController(
 		}
 
 		// Check if the user exists
-		_, err = apiCfg.DB.GetUserById(r.Context(), id)
+		existing, err := apiCfg.DB.GetUserById(r.Context(), id)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				helpers.RespondWithError(w, 404, "User not found")
@@ -166,5 +309,10 @@ func (apiCfg ApiCfg) DeleteUserController(
 			helpers.RespondWithError(w, 500, fmt.Sprintf("Failed to delete user: %v", err))
 			return
 		}
+		// DeleteUser never removes admins, so there is nothing to record for them
+		if existing.Role != "admin" {
+			apiCfg.recordAudit(r, user, auditDelete, "user", id,
+				models.DatabaseUserToUser(existing), nil)
+		}
 		helpers.TextResponse(w, 200, fmt.Sprintf("Successfully deleted user with id: %v", id))
 }
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- name: CreateAuditLog :exec
+INSERT INTO audit_logs(
+    id, created_at, actor_id, actor_email, action, entity, entity_id, changes, request_id, ip
+)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: GetAuditLogs :many
+SELECT * FROM audit_logs
+WHERE (sqlc.narg(entity)::text IS NULL OR entity = sqlc.narg(entity))
+AND (sqlc.narg(entity_id)::uuid IS NULL OR entity_id = sqlc.narg(entity_id))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
@@ -1,24 +1,42 @@
 -- name: CreateCategory :one
 INSERT INTO categories (
-    id, name, description, created_at, updated_at, created_by
+    id, org_id, name, description, created_at, updated_at, created_by
 )
-VALUES ($1,$2,$3,$4,$5,$6)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
 RETURNING *;
 
 -- name: GetCategories :many
-SELECT * FROM categories;
+SELECT * FROM categories
+WHERE org_id = sqlc.arg(org_id)
+AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::boolean);
 
 -- name: GetCategoryById :one
-SELECT * FROM categories WHERE id = $1;
+SELECT * FROM categories WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL;
+
+-- name: GetCategoryByIdIncludingDeleted :one
+SELECT * FROM categories WHERE id = $1 AND org_id = $2;
 
 -- name: UpdateCategory :one
 UPDATE categories
 SET
-name = $2,
-description = $3,
-updated_at = $4
-WHERE id = $1
+name = sqlc.arg(name),
+description = sqlc.arg(description),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id) AND org_id = sqlc.arg(org_id) AND deleted_at IS NULL
+AND updated_at = sqlc.arg(expected_updated_at)
+RETURNING *;
+
+-- name: SoftDeleteCategory :execrows
+UPDATE categories SET deleted_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL AND updated_at = $4;
+
+-- name: RestoreCategory :one
+UPDATE categories
+SET
+deleted_at = NULL,
+updated_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
 RETURNING *;
 
--- name: DeleteCategory :exec
-DELETE FROM categories WHERE id = $1;
\ No newline at end of file
+-- name: PurgeDeletedCategories :execrows
+DELETE FROM categories WHERE deleted_at < sqlc.arg(before)::timestamp;
@@ -0,0 +1,20 @@
+-- name: GetLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE key = sqlc.arg(account_key) OR key = sqlc.arg(ip_key);
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles(key, failures, last_failure_at)
+VALUES (sqlc.arg(key), 1, sqlc.arg(failed_at)::timestamp)
+ON CONFLICT (key) DO UPDATE SET
+    failures = CASE
+        WHEN login_throttles.last_failure_at < sqlc.arg(window_start)::timestamp THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = EXCLUDED.last_failure_at
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_throttles SET locked_until = $2 WHERE key = $1;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles WHERE key = $1;
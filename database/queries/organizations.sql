@@ -0,0 +1,38 @@
+-- name: CreateOrganization :one
+INSERT INTO organizations(id, name, slug, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $4)
+RETURNING *;
+
+-- name: GetOrganizations :many
+SELECT * FROM organizations ORDER BY name;
+
+-- name: GetOrganizationById :one
+SELECT * FROM organizations WHERE id = $1;
+
+-- name: GetOrganizationBySlug :one
+SELECT * FROM organizations WHERE slug = $1;
+
+-- name: SetOrganizationMember :one
+INSERT INTO organization_members(org_id, user_id, role, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (org_id, user_id) DO UPDATE SET role = EXCLUDED.role
+RETURNING *;
+
+-- name: GetOrganizationMember :one
+SELECT * FROM organization_members WHERE org_id = $1 AND user_id = $2;
+
+-- name: GetOrganizationMembers :many
+SELECT * FROM organization_members WHERE org_id = $1 ORDER BY created_at;
+
+-- name: RemoveOrganizationMember :execrows
+DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2;
+
+-- name: GetUserOrganizations :many
+SELECT organizations.id, organizations.name, organizations.slug, organization_members.role
+FROM organization_members
+JOIN organizations ON organizations.id = organization_members.org_id
+WHERE organization_members.user_id = $1
+ORDER BY organization_members.created_at;
+
+-- name: CountOrganizationMembersWithRole :one
+SELECT COUNT(*) FROM organization_members WHERE role = $1;
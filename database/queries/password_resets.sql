@@ -0,0 +1,12 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens(id, user_id, token_hash, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetPasswordResetTokenByHash :one
+SELECT * FROM password_reset_tokens WHERE token_hash = $1;
+
+-- name: UsePasswordResetToken :execrows
+UPDATE password_reset_tokens SET used_at = $2 WHERE id = $1 AND used_at IS NULL;
+
+-- name: RevokeUserPasswordResetTokens :exec
+UPDATE password_reset_tokens SET used_at = $2 WHERE user_id = $1 AND used_at IS NULL;
@@ -1,6 +1,7 @@
 -- name: CreateProduct :one
 INSERT INTO products(
     id,
+    org_id,
     name,
     description,
     price,
@@ -11,28 +12,65 @@ INSERT INTO products(
     created_at,
     updated_at
 )
-VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
 -- name: GetProducts :many
-SELECT * FROM products;
+SELECT * FROM products
+WHERE org_id = sqlc.arg(org_id)
+AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::boolean);
 
 -- name: GetProduct :one
-SELECT * FROM products WHERE id = $1;
+SELECT * FROM products WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL;
 
--- name: DeleteProduct :exec
-DELETE FROM products WHERE id = $1;
+-- name: GetProductIncludingDeleted :one
+SELECT * FROM products WHERE id = $1 AND org_id = $2;
+
+-- name: SoftDeleteProduct :execrows
+UPDATE products SET deleted_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL AND updated_at = $4;
+
+-- name: RestoreProduct :one
+UPDATE products
+SET
+deleted_at = NULL,
+updated_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeDeletedProducts :execrows
+DELETE FROM products WHERE deleted_at < sqlc.arg(before)::timestamp;
 
 -- name: UpdateProduct :one
 UPDATE products
 SET
-name = $2,
-description = $3,
-price = $4,
-stock_level = $5,
-category_id = $6,
-supplier_id = $7,
-sku = $8,
-updated_at = $9
-WHERE id = $1
-RETURNING *;
\ No newline at end of file
+name = sqlc.arg(name),
+description = sqlc.arg(description),
+price = sqlc.arg(price),
+stock_level = sqlc.arg(stock_level),
+category_id = sqlc.arg(category_id),
+supplier_id = sqlc.arg(supplier_id),
+sku = sqlc.arg(sku),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id) AND org_id = sqlc.arg(org_id) AND deleted_at IS NULL
+AND updated_at = sqlc.arg(expected_updated_at)
+RETURNING *;
+
+-- name: AdjustProductStock :one
+UPDATE products
+SET
+stock_level = COALESCE(stock_level, 0) + sqlc.arg(adjustment)::int,
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id) AND org_id = sqlc.arg(org_id) AND deleted_at IS NULL
+AND COALESCE(stock_level, 0) + sqlc.arg(adjustment)::int >= 0
+RETURNING *;
+
+-- name: CountProductsByOrganization :many
+SELECT
+organizations.slug,
+COUNT(products.id) AS products,
+COUNT(CASE WHEN COALESCE(products.stock_level, 0) = 0 THEN products.id END) AS out_of_stock
+FROM organizations
+LEFT JOIN products ON products.org_id = organizations.id AND products.deleted_at IS NULL
+GROUP BY organizations.slug
+ORDER BY organizations.slug;
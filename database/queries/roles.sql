@@ -0,0 +1,33 @@
+-- name: GetRolePermissions :many
+SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission;
+
+-- name: GetRoles :many
+SELECT * FROM roles ORDER BY name;
+
+-- name: GetRole :one
+SELECT * FROM roles WHERE name = $1;
+
+-- name: GetAllRolePermissions :many
+SELECT * FROM role_permissions ORDER BY role, permission;
+
+-- name: CreateRole :one
+INSERT INTO roles(name, description, built_in, created_at, updated_at)
+VALUES ($1, $2, FALSE, $3, $3)
+RETURNING *;
+
+-- name: UpdateRole :one
+UPDATE roles SET description = $2, updated_at = $3
+WHERE name = $1
+RETURNING *;
+
+-- name: DeleteRole :execrows
+DELETE FROM roles WHERE name = $1 AND built_in = FALSE;
+
+-- name: AddRolePermission :exec
+INSERT INTO role_permissions(role, permission) VALUES ($1, $2);
+
+-- name: DeleteRolePermissions :exec
+DELETE FROM role_permissions WHERE role = $1;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users WHERE role = $1;
@@ -0,0 +1,29 @@
+-- name: CreateSession :exec
+INSERT INTO sessions(id, user_id, org_id, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetSessionById :one
+SELECT * FROM sessions WHERE id = $1;
+
+-- name: SetSessionOrg :exec
+UPDATE sessions SET org_id = $2 WHERE id = $1;
+
+-- name: RevokeSession :exec
+UPDATE sessions SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens(id, session_id, token_hash, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens WHERE token_hash = $1;
+
+-- name: MarkRefreshTokenUsed :execrows
+UPDATE refresh_tokens SET used_at = $2 WHERE id = $1 AND used_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserSessions :exec
+UPDATE sessions SET revoked_at = $3
+WHERE user_id = $1 AND id != $2 AND revoked_at IS NULL;
@@ -1,27 +1,45 @@
 -- name: CreateSupplier :one
 INSERT INTO suppliers(
-    id, name, email, description, phone, country, created_at, updated_at
+    id, org_id, name, email, description, phone, country, created_at, updated_at
 ) 
-VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetAllSuppliers :many
-SELECT * FROM suppliers;
+SELECT * FROM suppliers
+WHERE org_id = sqlc.arg(org_id)
+AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::boolean);
 
 -- name: GetSupplierById :one
-SELECT * FROM suppliers WHERE id=$1;
+SELECT * FROM suppliers WHERE id=$1 AND org_id=$2 AND deleted_at IS NULL;
 
--- name: DeleteSupplier :exec
-DELETE FROM suppliers WHERE id=$1;
+-- name: GetSupplierByIdIncludingDeleted :one
+SELECT * FROM suppliers WHERE id=$1 AND org_id=$2;
+
+-- name: SoftDeleteSupplier :execrows
+UPDATE suppliers SET deleted_at = $3
+WHERE id=$1 AND org_id=$2 AND deleted_at IS NULL AND updated_at = $4;
+
+-- name: RestoreSupplier :one
+UPDATE suppliers
+SET
+deleted_at = NULL,
+updated_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeDeletedSuppliers :execrows
+DELETE FROM suppliers WHERE deleted_at < sqlc.arg(before)::timestamp;
 
 -- name: UpdateSupplier :one
 UPDATE suppliers
 SET 
-name = $2,
-email = $3,
-description = $4,
-phone = $5,
-country = $6,
-updated_at = $7
-WHERE id = $1
+name = sqlc.arg(name),
+email = sqlc.arg(email),
+description = sqlc.arg(description),
+phone = sqlc.arg(phone),
+country = sqlc.arg(country),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id) AND org_id = sqlc.arg(org_id) AND deleted_at IS NULL
+AND updated_at = sqlc.arg(expected_updated_at)
 RETURNING *;
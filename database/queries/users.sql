@@ -18,3 +18,26 @@ FROM users;
 
 -- name: DeleteUser :exec
 DELETE FROM users WHERE id = $1 AND role != 'admin';
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET password = $2, updated_at = $3 WHERE id = $1;
+
+-- name: SetUserEmailVerified :exec
+UPDATE users SET email_verified_at = $2, updated_at = $2 WHERE id = $1;
+
+-- name: UpdateUserRole :one
+UPDATE users SET role = $2, updated_at = $3 WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET name = $2, username = $3, profile_picture_url = $4, updated_at = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUser :one
+UPDATE users
+SET name = $2, username = $3, email = $4, role = $5, profile_picture_url = $6, updated_at = $7,
+    email_verified_at = CASE WHEN email = $4 THEN email_verified_at END
+WHERE id = $1
+RETURNING *;
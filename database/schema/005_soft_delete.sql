@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE suppliers ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMP;
+
+-- Soft deleted rows shouldn't block their name, email or SKU from being reused
+ALTER TABLE categories DROP CONSTRAINT categories_name_key;
+CREATE UNIQUE INDEX categories_name_key ON categories(name) WHERE deleted_at IS NULL;
+ALTER TABLE suppliers DROP CONSTRAINT suppliers_email_key;
+CREATE UNIQUE INDEX suppliers_email_key ON suppliers(email) WHERE deleted_at IS NULL;
+ALTER TABLE products DROP CONSTRAINT products_sku_key;
+CREATE UNIQUE INDEX products_sku_key ON products(sku) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX products_sku_key;
+ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+DROP INDEX suppliers_email_key;
+ALTER TABLE suppliers ADD CONSTRAINT suppliers_email_key UNIQUE (email);
+DROP INDEX categories_name_key;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
+
+ALTER TABLE products DROP COLUMN deleted_at;
+ALTER TABLE suppliers DROP COLUMN deleted_at;
+ALTER TABLE categories DROP COLUMN deleted_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Audit logs outlive the users and entities they describe, so there are no
+-- foreign keys here
+CREATE TABLE audit_logs(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID NOT NULL,
+    actor_email VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    entity VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    changes JSONB NOT NULL,
+    request_id TEXT NOT NULL,
+    ip TEXT NOT NULL
+);
+
+CREATE INDEX audit_logs_entity_idx ON audit_logs(entity, entity_id, created_at);
+
+-- +goose Down
+DROP TABLE audit_logs;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE totp_credentials(
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP
+);
+
+CREATE TABLE recovery_codes(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE(user_id, code_hash)
+);
+
+-- A single row of settings admins can change at runtime
+CREATE TABLE security_settings(
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    require_admin_mfa BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO security_settings(id, require_admin_mfa, updated_at) VALUES (TRUE, FALSE, NOW());
+
+-- +goose Down
+DROP TABLE security_settings;
+DROP TABLE recovery_codes;
+DROP TABLE totp_credentials;
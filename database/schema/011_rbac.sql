@@ -0,0 +1,54 @@
+-- +goose Up
+CREATE TABLE roles(
+    name VARCHAR(20) PRIMARY KEY,
+    description TEXT,
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE role_permissions(
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY(role, permission)
+);
+
+INSERT INTO roles(name, description, built_in, created_at, updated_at) VALUES
+    ('admin', 'Full access', TRUE, NOW(), NOW()),
+    ('user', 'Can browse the catalogue', TRUE, NOW(), NOW()),
+    ('warehouse_clerk', 'Can adjust stock levels but not prices', TRUE, NOW(), NOW());
+
+INSERT INTO role_permissions(role, permission) VALUES
+    ('admin', 'products:read'),
+    ('admin', 'products:write'),
+    ('admin', 'products:delete'),
+    ('admin', 'products:stock'),
+    ('admin', 'categories:read'),
+    ('admin', 'categories:write'),
+    ('admin', 'categories:delete'),
+    ('admin', 'suppliers:read'),
+    ('admin', 'suppliers:write'),
+    ('admin', 'suppliers:delete'),
+    ('admin', 'users:read'),
+    ('admin', 'users:delete'),
+    ('admin', 'roles:manage'),
+    ('admin', 'audit:read'),
+    ('admin', 'settings:manage'),
+    ('user', 'products:read'),
+    ('user', 'categories:read'),
+    ('warehouse_clerk', 'products:read'),
+    ('warehouse_clerk', 'products:stock'),
+    ('warehouse_clerk', 'categories:read'),
+    ('warehouse_clerk', 'suppliers:read');
+
+-- Registration only ever allowed admin and user, anything else is treated
+-- as user so the foreign key can be added
+UPDATE users SET role = 'user' WHERE role NOT IN (SELECT name FROM roles);
+
+ALTER TABLE users ADD CONSTRAINT users_role_fkey
+    FOREIGN KEY (role) REFERENCES roles(name);
+
+-- +goose Down
+ALTER TABLE users DROP CONSTRAINT users_role_fkey;
+DROP TABLE role_permissions;
+DROP TABLE roles;
@@ -0,0 +1,5 @@
+-- +goose Up
+INSERT INTO role_permissions(role, permission) VALUES ('admin', 'users:write');
+
+-- +goose Down
+DELETE FROM role_permissions WHERE permission = 'users:write';
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE invitations(
+    id UUID PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE invitations;
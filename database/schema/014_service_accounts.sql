@@ -0,0 +1,40 @@
+-- +goose Up
+CREATE TABLE service_accounts(
+    id UUID PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT,
+    role VARCHAR(20) NOT NULL REFERENCES roles(name),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Only the hash of the secret is kept. The prefix is public and is how a
+-- key is found and told apart in listings.
+CREATE TABLE api_keys(
+    id UUID PRIMARY KEY,
+    service_account_id UUID NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    prefix VARCHAR(16) UNIQUE NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX api_keys_service_account_idx ON api_keys(service_account_id);
+
+CREATE TABLE api_key_scopes(
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY(api_key_id, permission)
+);
+
+INSERT INTO role_permissions(role, permission) VALUES ('admin', 'service_accounts:manage');
+
+-- +goose Down
+DELETE FROM role_permissions WHERE permission = 'service_accounts:manage';
+DROP TABLE api_key_scopes;
+DROP TABLE api_keys;
+DROP TABLE service_accounts;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- A single sign-on login in progress, between the redirect to the identity
+-- provider and its callback
+CREATE TABLE oidc_logins(
+    id UUID PRIMARY KEY,
+    state_hash VARCHAR(64) UNIQUE NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- The identity provider accounts users have signed in with
+CREATE TABLE user_identities(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP NOT NULL,
+    UNIQUE(issuer, subject)
+);
+
+CREATE INDEX user_identities_user_idx ON user_identities(user_id);
+
+-- +goose Down
+DROP TABLE user_identities;
+DROP TABLE oidc_logins;
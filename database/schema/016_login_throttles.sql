@@ -0,0 +1,13 @@
+-- +goose Up
+-- Failed logins per account ("account:<email>") and per client IP
+-- ("ip:<address>"). Keys that are locked can't try to log in until
+-- locked_until.
+CREATE TABLE login_throttles(
+    key VARCHAR(150) PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_throttles;
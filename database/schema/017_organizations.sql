@@ -0,0 +1,93 @@
+-- +goose Up
+CREATE TABLE organizations(
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(50) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- A member's role only applies to the organisation's products, categories
+-- and suppliers. users.role still decides what they can do deployment
+-- wide, such as managing users.
+CREATE TABLE organization_members(
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL REFERENCES roles(name),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(org_id, user_id)
+);
+
+CREATE INDEX organization_members_user_idx ON organization_members(user_id);
+
+-- Everything from before organisations moves into a default one, which
+-- every existing user joins with the role they already had
+INSERT INTO organizations(id, name, slug, created_at, updated_at)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default', 'default', NOW(), NOW());
+
+INSERT INTO organization_members(org_id, user_id, role, created_at)
+SELECT '00000000-0000-0000-0000-000000000001', id, role, NOW() FROM users;
+
+ALTER TABLE categories ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE categories SET org_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE categories ALTER COLUMN org_id SET NOT NULL;
+
+ALTER TABLE suppliers ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE suppliers SET org_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE suppliers ALTER COLUMN org_id SET NOT NULL;
+
+ALTER TABLE products ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE products SET org_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE products ALTER COLUMN org_id SET NOT NULL;
+
+-- Service accounts act in the one organisation they belong to
+ALTER TABLE service_accounts ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE service_accounts SET org_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE service_accounts ALTER COLUMN org_id SET NOT NULL;
+
+-- The organisation a session is working in. Switching organisations
+-- changes it so refreshed access tokens keep the choice.
+ALTER TABLE sessions ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+-- Names, emails and SKUs only have to be unique within an organisation
+DROP INDEX categories_name_key;
+CREATE UNIQUE INDEX categories_name_key ON categories(org_id, name) WHERE deleted_at IS NULL;
+DROP INDEX suppliers_email_key;
+CREATE UNIQUE INDEX suppliers_email_key ON suppliers(org_id, email) WHERE deleted_at IS NULL;
+DROP INDEX products_sku_key;
+CREATE UNIQUE INDEX products_sku_key ON products(org_id, sku) WHERE deleted_at IS NULL;
+
+-- Products can only use their own organisation's categories and suppliers
+ALTER TABLE categories ADD CONSTRAINT categories_org_id_id_key UNIQUE (org_id, id);
+ALTER TABLE suppliers ADD CONSTRAINT suppliers_org_id_id_key UNIQUE (org_id, id);
+ALTER TABLE products ADD CONSTRAINT products_org_category_fkey
+    FOREIGN KEY (org_id, category_id) REFERENCES categories(org_id, id);
+ALTER TABLE products ADD CONSTRAINT products_org_supplier_fkey
+    FOREIGN KEY (org_id, supplier_id) REFERENCES suppliers(org_id, id);
+
+INSERT INTO role_permissions(role, permission) VALUES ('admin', 'organizations:manage');
+
+-- +goose Down
+DELETE FROM role_permissions WHERE permission = 'organizations:manage';
+
+ALTER TABLE products DROP CONSTRAINT products_org_supplier_fkey;
+ALTER TABLE products DROP CONSTRAINT products_org_category_fkey;
+ALTER TABLE suppliers DROP CONSTRAINT suppliers_org_id_id_key;
+ALTER TABLE categories DROP CONSTRAINT categories_org_id_id_key;
+
+-- Fails if two organisations use the same name, email or SKU
+DROP INDEX products_sku_key;
+CREATE UNIQUE INDEX products_sku_key ON products(sku) WHERE deleted_at IS NULL;
+DROP INDEX suppliers_email_key;
+CREATE UNIQUE INDEX suppliers_email_key ON suppliers(email) WHERE deleted_at IS NULL;
+DROP INDEX categories_name_key;
+CREATE UNIQUE INDEX categories_name_key ON categories(name) WHERE deleted_at IS NULL;
+
+ALTER TABLE sessions DROP COLUMN org_id;
+ALTER TABLE service_accounts DROP COLUMN org_id;
+ALTER TABLE products DROP COLUMN org_id;
+ALTER TABLE suppliers DROP COLUMN org_id;
+ALTER TABLE categories DROP COLUMN org_id;
+
+DROP TABLE organization_members;
+DROP TABLE organizations;
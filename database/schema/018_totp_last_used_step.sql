@@ -0,0 +1,7 @@
+-- +goose Up
+-- The time step of the last TOTP code accepted for the user, so a code
+-- can't be used twice
+ALTER TABLE totp_credentials ADD COLUMN last_used_step BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE totp_credentials DROP COLUMN last_used_step;
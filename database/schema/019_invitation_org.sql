@@ -0,0 +1,7 @@
+-- +goose Up
+-- The organisation an invitee joins with the invited role. Invitations
+-- without one join the default organisation, like registrations do.
+ALTER TABLE invitations ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE invitations DROP COLUMN org_id;
@@ -0,0 +1,8 @@
+// Package schema embeds the Postgres migrations, so the binary can apply
+// them without the goose command or a copy of this directory.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var Migrations embed.FS
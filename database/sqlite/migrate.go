@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+// NewMigrator returns the SQLite migrations for conn. SQLite only lets
+// one connection write at a time, so it needs no lock.
+func NewMigrator(conn *sql.DB) (*goose.Provider, error) {
+	return goose.NewProvider(goose.DialectSQLite3, conn, Migrations)
+}
+
+// Migrate applies the migrations that haven't been applied yet
+func Migrate(ctx context.Context, conn *sql.DB) error {
+	migrator, err := NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(ctx)
+	return err
+}
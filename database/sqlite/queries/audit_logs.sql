@@ -0,0 +1,12 @@
+-- name: CreateAuditLog :exec
+INSERT INTO audit_logs(
+    id, created_at, actor_id, actor_email, action, entity, entity_id, changes, request_id, ip
+)
+VALUES(?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10);
+
+-- name: GetAuditLogs :many
+SELECT id, created_at, actor_id, actor_email, action, entity, entity_id, changes, request_id, ip FROM audit_logs
+WHERE (?1 IS NULL OR entity = ?1)
+AND (?2 IS NULL OR entity_id = ?2)
+ORDER BY created_at DESC
+LIMIT ?3;
@@ -0,0 +1,42 @@
+-- name: CreateCategory :one
+INSERT INTO categories (
+    id, org_id, name, description, created_at, updated_at, created_by
+)
+VALUES (?1,?2,?3,?4,?5,?6,?7)
+RETURNING id, created_at, updated_at, name, description, created_by, deleted_at, org_id;
+
+-- name: GetCategories :many
+SELECT id, created_at, updated_at, name, description, created_by, deleted_at, org_id FROM categories
+WHERE org_id = ?1
+AND (deleted_at IS NULL OR ?2);
+
+-- name: GetCategoryById :one
+SELECT id, created_at, updated_at, name, description, created_by, deleted_at, org_id FROM categories WHERE id = ?1 AND org_id = ?2 AND deleted_at IS NULL;
+
+-- name: GetCategoryByIdIncludingDeleted :one
+SELECT id, created_at, updated_at, name, description, created_by, deleted_at, org_id FROM categories WHERE id = ?1 AND org_id = ?2;
+
+-- name: PurgeDeletedCategories :execrows
+DELETE FROM categories WHERE deleted_at < ?1;
+
+-- name: RestoreCategory :one
+UPDATE categories
+SET
+deleted_at = NULL,
+updated_at = ?3
+WHERE id = ?1 AND org_id = ?2 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, name, description, created_by, deleted_at, org_id;
+
+-- name: SoftDeleteCategory :execrows
+UPDATE categories SET deleted_at = ?3
+WHERE id = ?1 AND org_id = ?2 AND deleted_at IS NULL AND updated_at = ?4;
+
+-- name: UpdateCategory :one
+UPDATE categories
+SET
+name = ?1,
+description = ?2,
+updated_at = ?3
+WHERE id = ?4 AND org_id = ?5 AND deleted_at IS NULL
+AND updated_at = ?6
+RETURNING id, created_at, updated_at, name, description, created_by, deleted_at, org_id;
@@ -0,0 +1,18 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens(id, user_id, token_hash, created_at, expires_at)
+VALUES (?1, ?2, ?3, ?4, ?5);
+
+-- name: GetEmailVerificationTokenByHash :one
+SELECT id, user_id, token_hash, created_at, expires_at, used_at FROM email_verification_tokens WHERE token_hash = ?1;
+
+-- name: GetLatestEmailVerificationToken :one
+SELECT id, user_id, token_hash, created_at, expires_at, used_at FROM email_verification_tokens
+WHERE user_id = ?1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: UseEmailVerificationToken :execrows
+UPDATE email_verification_tokens SET used_at = ?2 WHERE id = ?1 AND used_at IS NULL;
+
+-- name: RevokeUserEmailVerificationTokens :exec
+UPDATE email_verification_tokens SET used_at = ?2 WHERE user_id = ?1 AND used_at IS NULL;
@@ -0,0 +1,18 @@
+-- name: AcceptInvitation :execrows
+UPDATE invitations SET accepted_at = ?2 WHERE id = ?1 AND accepted_at IS NULL;
+
+-- name: CreateInvitation :one
+INSERT INTO invitations(id, email, role, token_hash, invited_by, created_at, expires_at, org_id)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+RETURNING id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at, org_id;
+
+-- name: DeleteInvitation :execrows
+DELETE FROM invitations WHERE id = ?1 AND accepted_at IS NULL;
+
+-- name: GetInvitationByHash :one
+SELECT id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at, org_id FROM invitations WHERE token_hash = ?1;
+
+-- name: GetPendingInvitations :many
+SELECT id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at, org_id FROM invitations
+WHERE accepted_at IS NULL AND expires_at > ?1
+ORDER BY created_at DESC;
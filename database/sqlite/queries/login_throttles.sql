@@ -0,0 +1,20 @@
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles WHERE key = ?1;
+
+-- name: GetLoginThrottles :many
+SELECT key, failures, last_failure_at, locked_until FROM login_throttles
+WHERE key = ?1 OR key = ?2;
+
+-- name: LockLogin :exec
+UPDATE login_throttles SET locked_until = ?2 WHERE key = ?1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles(key, failures, last_failure_at)
+VALUES (?1, 1, ?2)
+ON CONFLICT (key) DO UPDATE SET
+    failures = CASE
+        WHEN login_throttles.last_failure_at < ?3 THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = EXCLUDED.last_failure_at
+RETURNING key, failures, last_failure_at, locked_until;
@@ -0,0 +1,19 @@
+-- name: CreateOIDCLogin :exec
+INSERT INTO oidc_logins(id, state_hash, nonce, code_verifier, created_at, expires_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6);
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities(id, user_id, issuer, subject, created_at, last_login_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?5);
+
+-- name: GetOIDCLoginByStateHash :one
+SELECT id, state_hash, nonce, code_verifier, created_at, expires_at, used_at FROM oidc_logins WHERE state_hash = ?1;
+
+-- name: GetUserIdentity :one
+SELECT id, user_id, issuer, subject, created_at, last_login_at FROM user_identities WHERE issuer = ?1 AND subject = ?2;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities SET last_login_at = ?2 WHERE id = ?1;
+
+-- name: UseOIDCLogin :execrows
+UPDATE oidc_logins SET used_at = ?2 WHERE id = ?1 AND used_at IS NULL;
@@ -0,0 +1,38 @@
+-- name: CountOrganizationMembersWithRole :one
+SELECT COUNT(*) FROM organization_members WHERE role = ?1;
+
+-- name: CreateOrganization :one
+INSERT INTO organizations(id, name, slug, created_at, updated_at)
+VALUES (?1, ?2, ?3, ?4, ?4)
+RETURNING id, name, slug, created_at, updated_at;
+
+-- name: GetOrganizationById :one
+SELECT id, name, slug, created_at, updated_at FROM organizations WHERE id = ?1;
+
+-- name: GetOrganizationBySlug :one
+SELECT id, name, slug, created_at, updated_at FROM organizations WHERE slug = ?1;
+
+-- name: GetOrganizationMember :one
+SELECT org_id, user_id, role, created_at FROM organization_members WHERE org_id = ?1 AND user_id = ?2;
+
+-- name: GetOrganizationMembers :many
+SELECT org_id, user_id, role, created_at FROM organization_members WHERE org_id = ?1 ORDER BY created_at;
+
+-- name: GetOrganizations :many
+SELECT id, name, slug, created_at, updated_at FROM organizations ORDER BY name;
+
+-- name: GetUserOrganizations :many
+SELECT organizations.id, organizations.name, organizations.slug, organization_members.role
+FROM organization_members
+JOIN organizations ON organizations.id = organization_members.org_id
+WHERE organization_members.user_id = ?1
+ORDER BY organization_members.created_at;
+
+-- name: RemoveOrganizationMember :execrows
+DELETE FROM organization_members WHERE org_id = ?1 AND user_id = ?2;
+
+-- name: SetOrganizationMember :one
+INSERT INTO organization_members(org_id, user_id, role, created_at)
+VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT (org_id, user_id) DO UPDATE SET role = EXCLUDED.role
+RETURNING org_id, user_id, role, created_at;
@@ -0,0 +1,76 @@
+-- name: AdjustProductStock :one
+UPDATE products
+SET
+stock_level = COALESCE(stock_level, 0) + ?1,
+updated_at = ?2
+WHERE id = ?3 AND org_id = ?4 AND deleted_at IS NULL
+AND COALESCE(stock_level, 0) + ?1 >= 0
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id;
+
+-- name: CountProductsByOrganization :many
+SELECT
+organizations.slug,
+COUNT(products.id) AS products,
+COUNT(CASE WHEN COALESCE(products.stock_level, 0) = 0 THEN products.id END) AS out_of_stock
+FROM organizations
+LEFT JOIN products ON products.org_id = organizations.id AND products.deleted_at IS NULL
+GROUP BY organizations.slug
+ORDER BY organizations.slug;
+
+-- name: CreateProduct :one
+INSERT INTO products(
+    id,
+    org_id,
+    name,
+    description,
+    price,
+    stock_level,
+    category_id,
+    supplier_id,
+    sku,
+    created_at,
+    updated_at
+)
+VALUES(?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11)
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id;
+
+-- name: GetProduct :one
+SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id FROM products WHERE id = ?1 AND org_id = ?2 AND deleted_at IS NULL;
+
+-- name: GetProductIncludingDeleted :one
+SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id FROM products WHERE id = ?1 AND org_id = ?2;
+
+-- name: GetProducts :many
+SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id FROM products
+WHERE org_id = ?1
+AND (deleted_at IS NULL OR ?2);
+
+-- name: PurgeDeletedProducts :execrows
+DELETE FROM products WHERE deleted_at < ?1;
+
+-- name: RestoreProduct :one
+UPDATE products
+SET
+deleted_at = NULL,
+updated_at = ?3
+WHERE id = ?1 AND org_id = ?2 AND deleted_at IS NOT NULL
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id;
+
+-- name: SoftDeleteProduct :execrows
+UPDATE products SET deleted_at = ?3
+WHERE id = ?1 AND org_id = ?2 AND deleted_at IS NULL AND updated_at = ?4;
+
+-- name: UpdateProduct :one
+UPDATE products
+SET
+name = ?1,
+description = ?2,
+price = ?3,
+stock_level = ?4,
+category_id = ?5,
+supplier_id = ?6,
+sku = ?7,
+updated_at = ?8
+WHERE id = ?9 AND org_id = ?10 AND deleted_at IS NULL
+AND updated_at = ?11
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id;
@@ -0,0 +1,46 @@
+-- name: AddAPIKeyScope :exec
+INSERT INTO api_key_scopes(api_key_id, permission) VALUES (?1, ?2);
+
+-- name: CountServiceAccountsWithRole :one
+SELECT COUNT(*) FROM service_accounts WHERE role = ?1;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys(id, service_account_id, name, prefix, secret_hash, created_at, expires_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+RETURNING id, service_account_id, name, prefix, secret_hash, created_at, expires_at, last_used_at, revoked_at;
+
+-- name: CreateServiceAccount :one
+INSERT INTO service_accounts(id, org_id, name, description, role, created_by, created_at, updated_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?7)
+RETURNING id, name, description, role, created_by, created_at, updated_at, org_id;
+
+-- name: DeleteServiceAccount :execrows
+DELETE FROM service_accounts WHERE id = ?1 AND org_id = ?2;
+
+-- name: GetAPIKeyByPrefix :one
+SELECT id, service_account_id, name, prefix, secret_hash, created_at, expires_at, last_used_at, revoked_at FROM api_keys WHERE prefix = ?1;
+
+-- name: GetAPIKeyScopes :many
+SELECT permission FROM api_key_scopes WHERE api_key_id = ?1 ORDER BY permission;
+
+-- name: GetServiceAccountAPIKeyScopes :many
+SELECT api_key_scopes.api_key_id, api_key_scopes.permission FROM api_key_scopes
+JOIN api_keys ON api_keys.id = api_key_scopes.api_key_id
+WHERE api_keys.service_account_id = ?1
+ORDER BY api_key_scopes.permission;
+
+-- name: GetServiceAccountAPIKeys :many
+SELECT id, service_account_id, name, prefix, secret_hash, created_at, expires_at, last_used_at, revoked_at FROM api_keys WHERE service_account_id = ?1 ORDER BY created_at DESC;
+
+-- name: GetServiceAccountById :one
+SELECT id, name, description, role, created_by, created_at, updated_at, org_id FROM service_accounts WHERE id = ?1;
+
+-- name: GetServiceAccounts :many
+SELECT id, name, description, role, created_by, created_at, updated_at, org_id FROM service_accounts WHERE org_id = ?1 ORDER BY name;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys SET revoked_at = ?3
+WHERE id = ?1 AND service_account_id = ?2 AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys SET last_used_at = ?2 WHERE id = ?1;
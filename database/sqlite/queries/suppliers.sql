@@ -0,0 +1,45 @@
+-- name: CreateSupplier :one
+INSERT INTO suppliers(
+    id, org_id, name, email, description, phone, country, created_at, updated_at
+)
+VALUES(?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
+RETURNING id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id;
+
+-- name: GetAllSuppliers :many
+SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers
+WHERE org_id = ?1
+AND (deleted_at IS NULL OR ?2);
+
+-- name: GetSupplierById :one
+SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers WHERE id=?1 AND org_id=?2 AND deleted_at IS NULL;
+
+-- name: GetSupplierByIdIncludingDeleted :one
+SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers WHERE id=?1 AND org_id=?2;
+
+-- name: PurgeDeletedSuppliers :execrows
+DELETE FROM suppliers WHERE deleted_at < ?1;
+
+-- name: RestoreSupplier :one
+UPDATE suppliers
+SET
+deleted_at = NULL,
+updated_at = ?3
+WHERE id = ?1 AND org_id = ?2 AND deleted_at IS NOT NULL
+RETURNING id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id;
+
+-- name: SoftDeleteSupplier :execrows
+UPDATE suppliers SET deleted_at = ?3
+WHERE id=?1 AND org_id=?2 AND deleted_at IS NULL AND updated_at = ?4;
+
+-- name: UpdateSupplier :one
+UPDATE suppliers
+SET
+name = ?1,
+email = ?2,
+description = ?3,
+phone = ?4,
+country = ?5,
+updated_at = ?6
+WHERE id = ?7 AND org_id = ?8 AND deleted_at IS NULL
+AND updated_at = ?9
+RETURNING id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id;
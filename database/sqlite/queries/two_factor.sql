@@ -0,0 +1,35 @@
+-- name: ConfirmTotpCredential :exec
+UPDATE totp_credentials SET confirmed_at = ?2 WHERE user_id = ?1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes(id, user_id, code_hash, created_at)
+VALUES (?1, ?2, ?3, ?4);
+
+-- name: CreateTotpCredential :exec
+INSERT INTO totp_credentials(user_id, secret, created_at)
+VALUES (?1, ?2, ?3);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = ?1;
+
+-- name: DeleteTotpCredential :exec
+DELETE FROM totp_credentials WHERE user_id = ?1;
+
+-- name: GetSecuritySettings :one
+SELECT id, require_admin_mfa, updated_at FROM security_settings WHERE id = TRUE;
+
+-- name: GetTotpCredential :one
+SELECT user_id, secret, created_at, confirmed_at, last_used_step FROM totp_credentials WHERE user_id = ?1;
+
+-- name: UpdateSecuritySettings :one
+UPDATE security_settings SET require_admin_mfa = ?1, updated_at = ?2
+WHERE id = TRUE
+RETURNING id, require_admin_mfa, updated_at;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = ?3
+WHERE user_id = ?1 AND code_hash = ?2 AND used_at IS NULL;
+
+-- name: UseTotpStep :execrows
+UPDATE totp_credentials SET last_used_step = ?2
+WHERE user_id = ?1 AND last_used_step < ?2;
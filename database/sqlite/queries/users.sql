@@ -0,0 +1,43 @@
+-- name: CreateUser :one
+INSERT INTO users(
+    id, username, email, name, password, role, profile_picture_url, created_at, updated_at
+)
+VALUES(?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
+RETURNING id, username, email, name, role, profile_picture_url, created_at, updated_at;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = ?1 AND role != 'admin';
+
+-- name: GetAllUsers :many
+SELECT
+    id, username, email, name, role, profile_picture_url, created_at, updated_at
+FROM users;
+
+-- name: GetUserByEmail :one
+SELECT id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at FROM users WHERE email = ?1;
+
+-- name: GetUserById :one
+SELECT id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at FROM users WHERE id = ?1;
+
+-- name: SetUserEmailVerified :exec
+UPDATE users SET email_verified_at = ?2, updated_at = ?2 WHERE id = ?1;
+
+-- name: UpdateUser :one
+UPDATE users
+SET name = ?2, username = ?3, email = ?4, role = ?5, profile_picture_url = ?6, updated_at = ?7,
+    email_verified_at = CASE WHEN email = ?4 THEN email_verified_at END
+WHERE id = ?1
+RETURNING id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at;
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET password = ?2, updated_at = ?3 WHERE id = ?1;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET name = ?2, username = ?3, profile_picture_url = ?4, updated_at = ?5
+WHERE id = ?1
+RETURNING id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at;
+
+-- name: UpdateUserRole :one
+UPDATE users SET role = ?2, updated_at = ?3 WHERE id = ?1
+RETURNING id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at;
@@ -0,0 +1,306 @@
+-- +goose Up
+-- The SQLite schema starts from where database/schema is at 017, so it
+-- has no history to replay. UUIDs are kept as text and timestamps in the
+-- format the sqlite3 driver writes them, which sorts by time when in UTC.
+CREATE TABLE roles(
+    name VARCHAR(20) PRIMARY KEY,
+    description TEXT,
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE role_permissions(
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY(role, permission)
+);
+
+CREATE TABLE users (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    username VARCHAR(50) UNIQUE NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    role VARCHAR(20) DEFAULT 'user' NOT NULL REFERENCES roles(name),
+    profile_picture_url VARCHAR(255),
+    name VARCHAR(100) NOT NULL,
+    email_verified_at TIMESTAMP
+);
+
+CREATE TABLE organizations(
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(50) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE organization_members(
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL REFERENCES roles(name),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(org_id, user_id)
+);
+
+CREATE INDEX organization_members_user_idx ON organization_members(user_id);
+
+CREATE TABLE categories (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_by UUID NOT NULL,
+    deleted_at TIMESTAMP,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT categories_org_id_id_key UNIQUE (org_id, id)
+);
+
+CREATE UNIQUE INDEX categories_name_key ON categories(org_id, name) WHERE deleted_at IS NULL;
+
+CREATE TABLE suppliers (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    description TEXT,
+    phone VARCHAR(10),
+    country VARCHAR(100),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT suppliers_org_id_id_key UNIQUE (org_id, id)
+);
+
+CREATE UNIQUE INDEX suppliers_email_key ON suppliers(org_id, email) WHERE deleted_at IS NULL;
+
+CREATE TABLE products(
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    price INT NOT NULL,
+    stock_level INT DEFAULT 0,
+    category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    supplier_id UUID REFERENCES suppliers(id) ON DELETE SET NULL,
+    sku VARCHAR(50),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT products_org_category_fkey
+        FOREIGN KEY (org_id, category_id) REFERENCES categories(org_id, id),
+    CONSTRAINT products_org_supplier_fkey
+        FOREIGN KEY (org_id, supplier_id) REFERENCES suppliers(org_id, id)
+);
+
+CREATE UNIQUE INDEX products_sku_key ON products(org_id, sku) WHERE deleted_at IS NULL;
+
+-- Audit logs outlive the users and entities they describe, so there are no
+-- foreign keys here
+CREATE TABLE audit_logs(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID NOT NULL,
+    actor_email VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    entity VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    changes JSONB NOT NULL,
+    request_id TEXT NOT NULL,
+    ip TEXT NOT NULL
+);
+
+CREATE INDEX audit_logs_entity_idx ON audit_logs(entity, entity_id, created_at);
+
+CREATE TABLE sessions(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    org_id UUID REFERENCES organizations(id) ON DELETE SET NULL
+);
+
+CREATE TABLE refresh_tokens(
+    id UUID PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE TABLE password_reset_tokens(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE TABLE email_verification_tokens(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE TABLE totp_credentials(
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP
+);
+
+CREATE TABLE recovery_codes(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE(user_id, code_hash)
+);
+
+-- A single row of settings admins can change at runtime
+CREATE TABLE security_settings(
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    require_admin_mfa BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE invitations(
+    id UUID PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP
+);
+
+CREATE TABLE service_accounts(
+    id UUID PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT,
+    role VARCHAR(20) NOT NULL REFERENCES roles(name),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE TABLE api_keys(
+    id UUID PRIMARY KEY,
+    service_account_id UUID NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    prefix VARCHAR(16) UNIQUE NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX api_keys_service_account_idx ON api_keys(service_account_id);
+
+CREATE TABLE api_key_scopes(
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY(api_key_id, permission)
+);
+
+CREATE TABLE oidc_logins(
+    id UUID PRIMARY KEY,
+    state_hash VARCHAR(64) UNIQUE NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE TABLE user_identities(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP NOT NULL,
+    UNIQUE(issuer, subject)
+);
+
+CREATE INDEX user_identities_user_idx ON user_identities(user_id);
+
+CREATE TABLE login_throttles(
+    key VARCHAR(150) PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+INSERT INTO security_settings(id, require_admin_mfa, updated_at) VALUES (TRUE, FALSE, CURRENT_TIMESTAMP);
+
+INSERT INTO roles(name, description, built_in, created_at, updated_at) VALUES
+    ('admin', 'Full access', TRUE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+    ('user', 'Can browse the catalogue', TRUE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+    ('warehouse_clerk', 'Can adjust stock levels but not prices', TRUE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+INSERT INTO role_permissions(role, permission) VALUES
+    ('admin', 'products:read'),
+    ('admin', 'products:write'),
+    ('admin', 'products:delete'),
+    ('admin', 'products:stock'),
+    ('admin', 'categories:read'),
+    ('admin', 'categories:write'),
+    ('admin', 'categories:delete'),
+    ('admin', 'suppliers:read'),
+    ('admin', 'suppliers:write'),
+    ('admin', 'suppliers:delete'),
+    ('admin', 'users:read'),
+    ('admin', 'users:write'),
+    ('admin', 'users:delete'),
+    ('admin', 'roles:manage'),
+    ('admin', 'audit:read'),
+    ('admin', 'settings:manage'),
+    ('admin', 'service_accounts:manage'),
+    ('admin', 'organizations:manage'),
+    ('user', 'products:read'),
+    ('user', 'categories:read'),
+    ('warehouse_clerk', 'products:read'),
+    ('warehouse_clerk', 'products:stock'),
+    ('warehouse_clerk', 'categories:read'),
+    ('warehouse_clerk', 'suppliers:read');
+
+INSERT INTO organizations(id, name, slug, created_at, updated_at)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default', 'default', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- +goose Down
+DROP TABLE login_throttles;
+DROP TABLE user_identities;
+DROP TABLE oidc_logins;
+DROP TABLE api_key_scopes;
+DROP TABLE api_keys;
+DROP TABLE service_accounts;
+DROP TABLE invitations;
+DROP TABLE security_settings;
+DROP TABLE recovery_codes;
+DROP TABLE totp_credentials;
+DROP TABLE email_verification_tokens;
+DROP TABLE password_reset_tokens;
+DROP TABLE refresh_tokens;
+DROP TABLE sessions;
+DROP TABLE audit_logs;
+DROP TABLE products;
+DROP TABLE suppliers;
+DROP TABLE categories;
+DROP TABLE organization_members;
+DROP TABLE organizations;
+DROP TABLE users;
+DROP TABLE role_permissions;
+DROP TABLE roles;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE totp_credentials ADD COLUMN last_used_step INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE totp_credentials DROP COLUMN last_used_step;
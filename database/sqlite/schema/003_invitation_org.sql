@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE invitations ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE invitations DROP COLUMN org_id;
@@ -0,0 +1,224 @@
+// Package sqlite runs the sqlc generated queries in internal/database
+// against SQLite, for local and edge deployments that don't have Postgres.
+//
+// The generated code is written for Postgres, so connections opened here
+// look up each query by the name sqlc puts at the start of it and run the
+// SQLite version from queries/ instead. Unique and foreign key violations
+// are returned as *pq.Error with codes 23505 and 23503, so handlers that
+// check for those behave the same on both databases.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+//go:embed queries/*.sql
+var queryFiles embed.FS
+
+//go:embed schema/*.sql
+var schemaFiles embed.FS
+
+// Migrations are the goose migrations for the SQLite schema
+var Migrations, _ = fs.Sub(schemaFiles, "schema")
+
+// queryName matches the comment sqlc starts every query with
+var queryName = regexp.MustCompile(`^-- name: (\w+) :\w+`)
+
+// loadQueries reads the SQLite queries by name
+func loadQueries() (map[string]string, error) {
+	files, err := fs.Glob(queryFiles, "queries/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	queries := map[string]string{}
+	for _, file := range files {
+		content, err := queryFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, query := range strings.Split(string(content), "\n\n") {
+			query = strings.TrimSpace(query)
+			match := queryName.FindStringSubmatch(query)
+			if match == nil {
+				continue
+			}
+			queries[match[1]] = query
+		}
+	}
+	return queries, nil
+}
+
+// Open opens the SQLite database at path, creating it when it doesn't
+// exist. Use ":memory:" for a database that only lives as long as the
+// returned *sql.DB.
+func Open(path string) (*sql.DB, error) {
+	queries, err := loadQueries()
+	if err != nil {
+		return nil, err
+	}
+
+	options := "_foreign_keys=on&_busy_timeout=5000"
+	if path != ":memory:" {
+		options += "&_journal_mode=WAL"
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	conn := sql.OpenDB(&connector{
+		dsn: "file:" + path + separator + options,
+		queries: queries,
+	})
+	if path == ":memory:" {
+		// Every connection to :memory: gets a database of its own
+		conn.SetMaxOpenConns(1)
+	}
+	return conn, nil
+}
+
+// Is reports whether conn is a SQLite database
+func Is(conn *sql.DB) bool {
+	_, ok := conn.Driver().(*sqlite3.SQLiteDriver)
+	return ok
+}
+
+type connector struct {
+	dsn string
+	queries map[string]string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	sqliteConn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{SQLiteConn: sqliteConn.(*sqlite3.SQLiteConn), queries: c.queries}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+// conn swaps the Postgres queries it is given for their SQLite versions
+type conn struct {
+	*sqlite3.SQLiteConn
+	queries map[string]string
+}
+
+// rewrite returns the SQLite version of a named query. Queries without a
+// name, such as migrations, are run as they are.
+func (c *conn) rewrite(query string) (string, error) {
+	match := queryName.FindStringSubmatch(query)
+	if match == nil {
+		return query, nil
+	}
+	sqliteQuery, ok := c.queries[match[1]]
+	if !ok {
+		return "", fmt.Errorf("there's no SQLite version of query %s", match[1])
+	}
+	return sqliteQuery, nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query, err := c.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	sqliteStmt, err := c.SQLiteConn.Prepare(query)
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &stmt{sqliteStmt.(*sqlite3.SQLiteStmt)}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	query, err := c.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.SQLiteConn.ExecContext(ctx, query, inUTC(args))
+	return result, translate(err)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query, err := c.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	sqliteRows, err := c.SQLiteConn.QueryContext(ctx, query, inUTC(args))
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &rows{sqliteRows}, nil
+}
+
+type stmt struct {
+	*sqlite3.SQLiteStmt
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	result, err := s.SQLiteStmt.ExecContext(ctx, inUTC(args))
+	return result, translate(err)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	sqliteRows, err := s.SQLiteStmt.QueryContext(ctx, inUTC(args))
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &rows{sqliteRows}, nil
+}
+
+// rows translates errors from statements that fail while stepping, such
+// as an INSERT ... RETURNING that breaks a constraint
+type rows struct {
+	driver.Rows
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	return translate(r.Rows.Next(dest))
+}
+
+// inUTC converts times to UTC, since timestamps are compared as text
+func inUTC(args []driver.NamedValue) []driver.NamedValue {
+	converted := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		if t, ok := arg.Value.(time.Time); ok {
+			arg.Value = t.UTC()
+		}
+		converted[i] = arg
+	}
+	return converted
+}
+
+// translate returns constraint violations as the *pq.Error Postgres would
+// have returned
+func translate(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return &pq.Error{Severity: "ERROR", Code: "23505", Message: sqliteErr.Error()}
+	case sqlite3.ErrConstraintForeignKey:
+		return &pq.Error{Severity: "ERROR", Code: "23503", Message: sqliteErr.Error()}
+	}
+	return err
+}
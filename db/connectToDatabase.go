@@ -1,23 +1,77 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
-	"os"
-)
+	"strings"
+	"time"
 
-// ConnectToDatabase connects to the database and returns the connection
-func ConnectToDatabase() *sql.DB {
-	dbURL := os.Getenv("DB_URL")
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/database/sqlite"
+)
 
-	if dbURL == "" {
-		log.Fatal("DB_URL not found in the environment")
+// ConnectToDatabase connects to the database and returns the connection.
+// DB_URL is a Postgres URL, or sqlite://<path> for a SQLite database file,
+// which is created when it doesn't exist.
+func ConnectToDatabase(cfg config.Database) *sql.DB {
+	if cfg.URL == "" {
+		log.Fatal("DB_URL isn't configured")
+	}
+	if path, ok := strings.CutPrefix(cfg.URL, "sqlite://"); ok {
+		return connectToSQLite(path, cfg)
 	}
-	conn, err := sql.Open("postgres", dbURL)
+	conn, err := sql.Open("postgres", cfg.URL)
 
 	if err != nil {
 		log.Fatal("Cant connect to database ", err)
 	}
+	configurePool(conn, cfg)
 	log.Printf("Connected to database!")
 	return conn
-}
\ No newline at end of file
+}
+
+func connectToSQLite(path string, cfg config.Database) *sql.DB {
+	conn, err := sqlite.Open(path)
+	if err != nil {
+		log.Fatal("Cant open the SQLite database ", err)
+	}
+	// An in-memory database has to stay on its one connection
+	if path != ":memory:" {
+		configurePool(conn, cfg)
+	}
+	log.Printf("Connected to SQLite database %s!", path)
+	return conn
+}
+
+// configurePool sizes the connection pool from the DB_ settings
+func configurePool(conn *sql.DB, cfg config.Database) {
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// WaitForDatabase pings conn until it answers, backing off between
+// attempts, so the server can start before its database is ready. It gives
+// up after timeout.
+func WaitForDatabase(ctx context.Context, conn *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := 250 * time.Millisecond
+	for {
+		err := conn.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the database didn't answer within %v: %w", timeout, err)
+		case <-time.After(delay):
+		}
+		log.Printf("Database isn't ready, retrying: %v", err)
+		delay = min(2*delay, 5*time.Second)
+	}
+}
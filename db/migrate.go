@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"github.com/ringtho/inventory/database/schema"
+	"github.com/ringtho/inventory/database/sqlite"
+)
+
+// MigrateCommands are the commands Migrate accepts
+var MigrateCommands = []string{"up", "down", "status", "redo"}
+
+// NewMigrator returns the embedded migrations for the database conn is
+// connected to, applied through conn
+func NewMigrator(conn *sql.DB) (*goose.Provider, error) {
+	if sqlite.Is(conn) {
+		return sqlite.NewMigrator(conn)
+	}
+	// Replicas that start together hold a session advisory lock while
+	// migrating, so the others wait and then find nothing left to apply
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, conn, schema.Migrations, goose.WithSessionLocker(locker))
+}
+
+// Migrate runs one of MigrateCommands against conn and writes what it did
+// to out. up applies every pending migration, down rolls back the latest
+// one, redo rolls it back and applies it again and status lists them all.
+func Migrate(ctx context.Context, conn *sql.DB, command string, out io.Writer) error {
+	migrator, err := NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			fmt.Fprintln(out, result)
+		}
+		if err == nil && len(results) == 0 {
+			fmt.Fprintln(out, "No migrations to apply")
+		}
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Fprintln(out, "No migrations to roll back")
+			return nil
+		}
+		if result != nil {
+			fmt.Fprintln(out, result)
+		}
+		return err
+	case "redo":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, result)
+		result, err = migrator.UpByOne(ctx)
+		if result != nil {
+			fmt.Fprintln(out, result)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "Pending"
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%-20s %s\n", appliedAt, status.Source.Path)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q, use one of %s", command, strings.Join(MigrateCommands, ", "))
+}
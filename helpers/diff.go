@@ -0,0 +1,68 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// FieldChange holds the old and new JSON value of a single field
+type FieldChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// JSONDiff marshals before and after to JSON objects and returns the fields
+// that differ between them. Either side may be nil, as on a create or a
+// delete, in which case every field of the other side is reported. Fields
+// named in ignore are left out.
+func JSONDiff(before, after interface{}, ignore ...string) (json.RawMessage, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]FieldChange{}
+	for name, value := range beforeFields {
+		if !bytes.Equal(value, afterFields[name]) {
+			changes[name] = FieldChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = FieldChange{After: value}
+		}
+	}
+	for _, name := range ignore {
+		delete(changes, name)
+	}
+
+	for name, change := range changes {
+		if change.Before == nil {
+			change.Before = json.RawMessage("null")
+		}
+		if change.After == nil {
+			change.After = json.RawMessage("null")
+		}
+		changes[name] = change
+	}
+	return json.Marshal(changes)
+}
+
+func jsonFields(value interface{}) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if value == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag builds a strong entity tag from the time a row was last updated
+func ETag(updatedAt time.Time) string {
+	return fmt.Sprintf(`"%x"`, updatedAt.UTC().UnixNano())
+}
+
+// IfMatch reports whether the If-Match header of the request allows a write
+// to the resource currently tagged with etag. Weak tags never match.
+func IfMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IfNoneMatch reports whether the If-None-Match header of the request
+// matches etag, meaning the client's cached copy is still current
+func IfNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,337 @@
+package helpers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	CSVContentType  = "text/csv"
+	XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// ExportColumn describes one column of an exported spreadsheet
+type ExportColumn[T any] struct {
+	Name    string
+	Numeric bool
+	Value   func(T) string
+}
+
+// ExportFormat returns "csv" or "xlsx" when the client asked for an export
+// through the format query parameter or the Accept header, and "" for JSON
+func ExportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch strings.ToLower(format) {
+		case "csv", "xlsx":
+			return strings.ToLower(format), nil
+		case "json":
+			return "", nil
+		}
+		return "", fmt.Errorf("unsupported export format %q", format)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case CSVContentType:
+			return "csv", nil
+		case XLSXContentType:
+			return "xlsx", nil
+		}
+	}
+	return "", nil
+}
+
+// SelectColumns keeps the columns named in the comma separated fields list,
+// in the order they were requested. An empty list keeps every column.
+func SelectColumns[T any](columns []ExportColumn[T], fields string) ([]ExportColumn[T], error) {
+	if strings.TrimSpace(fields) == "" {
+		return columns, nil
+	}
+
+	selected := []ExportColumn[T]{}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		found := false
+		for _, column := range columns {
+			if column.Name == field {
+				selected = append(selected, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return selected, nil
+}
+
+// Export streams the rows produced by iterate as a CSV or XLSX attachment.
+// Nothing is written until the first row arrives, so an error from the
+// query itself is still reported as a normal JSON error response.
+func Export[T any](
+	w http.ResponseWriter,
+	format string,
+	filename string,
+	columns []ExportColumn[T],
+	iterate func(func(T) error) error,
+) {
+	var rw rowWriter
+	start := func() error {
+		contentType := CSVContentType
+		if format == "xlsx" {
+			contentType = XLSXContentType
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+		w.WriteHeader(http.StatusOK)
+
+		if format == "xlsx" {
+			rw = newXLSXWriter(w)
+		} else {
+			rw = newCSVWriter(w)
+		}
+
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.Name
+		}
+		return rw.WriteRow(header, nil)
+	}
+
+	numeric := make([]bool, len(columns))
+	for i, column := range columns {
+		numeric[i] = column.Numeric
+	}
+
+	err := iterate(func(item T) error {
+		if rw == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = column.Value(item)
+		}
+		return rw.WriteRow(values, numeric)
+	})
+
+	if err != nil {
+		if rw == nil {
+			RespondWithError(w, 500, fmt.Sprintf("Couldn't export %s: %v", filename, err))
+			return
+		}
+		// The status line has already been sent, all we can do is stop
+		log.Printf("Error exporting %s: %v", filename, err)
+		return
+	}
+
+	if rw == nil {
+		if err := start(); err != nil {
+			log.Printf("Error exporting %s: %v", filename, err)
+			return
+		}
+	}
+	if err := rw.Close(); err != nil {
+		log.Printf("Error exporting %s: %v", filename, err)
+	}
+}
+
+// WriteExport writes the rows produced by iterate to w as CSV or XLSX, for
+// exports that aren't HTTP responses
+func WriteExport[T any](
+	w io.Writer,
+	format string,
+	columns []ExportColumn[T],
+	iterate func(func(T) error) error,
+) error {
+	var rw rowWriter
+	switch format {
+	case "csv":
+		rw = newCSVWriter(w)
+	case "xlsx":
+		rw = newXLSXWriter(w)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+
+	header := make([]string, len(columns))
+	numeric := make([]bool, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+		numeric[i] = column.Numeric
+	}
+	if err := rw.WriteRow(header, nil); err != nil {
+		return err
+	}
+
+	err := iterate(func(item T) error {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = column.Value(item)
+		}
+		return rw.WriteRow(values, numeric)
+	})
+	if err != nil {
+		return err
+	}
+	return rw.Close()
+}
+
+// escapeFormula stops spreadsheet applications from running text cells as
+// formulas. Product names and descriptions come from users, so a value like
+// =HYPERLINK(...) is written with a leading quote and shown as plain text.
+func escapeFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
+
+type rowWriter interface {
+	WriteRow(values []string, numeric []bool) error
+	Close() error
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteRow(values []string, numeric []bool) error {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		if numeric != nil && numeric[i] {
+			escaped[i] = value
+			continue
+		}
+		escaped[i] = escapeFormula(value)
+	}
+	return c.w.Write(escaped)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// xlsxWriter writes a single sheet workbook with inline strings. The sheet is
+// the last entry of the zip archive so rows can be written as they arrive.
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+	err   error
+}
+
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func newXLSXWriter(w io.Writer) *xlsxWriter {
+	x := &xlsxWriter{zw: zip.NewWriter(w)}
+	for _, part := range xlsxStaticParts {
+		f, err := x.zw.Create(part.name)
+		if err != nil {
+			x.err = err
+			return x
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			x.err = err
+			return x
+		}
+	}
+
+	x.sheet, x.err = x.zw.Create("xl/worksheets/sheet1.xml")
+	if x.err == nil {
+		_, x.err = io.WriteString(x.sheet, xml.Header+
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	}
+	return x
+}
+
+func (x *xlsxWriter) WriteRow(values []string, numeric []bool) error {
+	if x.err != nil {
+		return x.err
+	}
+	x.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.row)
+	for i, value := range values {
+		ref := xlsxColumnName(i) + fmt.Sprint(x.row)
+		if numeric != nil && numeric[i] {
+			if value == "" {
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s"><v>`, ref)
+			xml.EscapeText(&b, []byte(value))
+			b.WriteString(`</v></c>`)
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		xml.EscapeText(&b, []byte(escapeFormula(value)))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+
+	_, x.err = io.WriteString(x.sheet, b.String())
+	return x.err
+}
+
+func (x *xlsxWriter) Close() error {
+	if x.err != nil {
+		return x.err
+	}
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// xlsxColumnName converts a zero based column index to A, B, ..., Z, AA, ...
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -3,12 +3,13 @@ package helpers
 import (
 	"log"
 
+	"github.com/ringtho/inventory/config"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword hashes a the provided password
+// HashPassword hashes a the provided password with BCRYPT_COST
 func HashPassword(password string) string {
-	passwordByte, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	passwordByte, err := bcrypt.GenerateFromPassword([]byte(password), config.Current().Auth.BcryptCost)
 
 	if err != nil {
 		log.Fatal("Error hashing password: ", err)
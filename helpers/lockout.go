@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"time"
+
+	"github.com/ringtho/inventory/config"
+)
+
+// LockoutPolicy decides when too many failed logins lock a key out
+type LockoutPolicy struct {
+	// MaxFailures is how many failures in a row are allowed before a lock
+	MaxFailures int
+	// Base is the first lock, each failure after that doubles it up to Max
+	Base time.Duration
+	Max  time.Duration
+	// Window is how long failures are remembered, a failure after a quiet
+	// Window starts counting again
+	Window time.Duration
+}
+
+// AccountLockoutPolicy applies to failed logins for one email, from
+// LOGIN_MAX_FAILURES, LOGIN_LOCKOUT_BASE, LOGIN_LOCKOUT_MAX and
+// LOGIN_FAILURE_WINDOW
+func AccountLockoutPolicy() LockoutPolicy {
+	lockout := config.Current().Lockout
+	return LockoutPolicy{
+		MaxFailures: lockout.MaxFailures,
+		Base: lockout.Base,
+		Max: lockout.Max,
+		Window: lockout.FailureWindow,
+	}
+}
+
+// IPLockoutPolicy applies to failed logins from one IP address across all
+// accounts. It allows LOGIN_IP_MAX_FAILURES failures and otherwise matches
+// AccountLockoutPolicy.
+func IPLockoutPolicy() LockoutPolicy {
+	policy := AccountLockoutPolicy()
+	policy.MaxFailures = config.Current().Lockout.IPMaxFailures
+	return policy
+}
+
+// LockDuration is how long to lock a key out for after failures in a row,
+// 0 while it's under MaxFailures
+func (p LockoutPolicy) LockDuration(failures int) time.Duration {
+	if failures < p.MaxFailures {
+		return 0
+	}
+
+	duration := p.Base
+	for i := p.MaxFailures; i < failures && duration < p.Max; i++ {
+		duration *= 2
+	}
+	if duration > p.Max {
+		return p.Max
+	}
+	return duration
+}
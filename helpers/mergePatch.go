@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch applies an RFC 7396 JSON merge patch to the original document.
+// Members set to null in the patch are removed, objects are merged
+// recursively and every other value replaces the original one.
+func MergePatch(original, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := decodeJSONNumber(original, &target); err != nil {
+		return nil, err
+	}
+
+	var changes interface{}
+	if err := decodeJSONNumber(patch, &changes); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
+
+func decodeJSONNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/config"
+)
+
+// mfaAudience marks challenge tokens so they can never be mistaken for an
+// access token, which has no audience
+const mfaAudience = "mfa"
+
+const (
+	// MFAPurposeVerify is for users with 2FA who still have to send a code
+	MFAPurposeVerify = "verify"
+	// MFAPurposeEnroll is for admins who have to set up 2FA before they
+	// can log in
+	MFAPurposeEnroll = "enroll"
+)
+
+type MFAClaims struct {
+	UserID  uuid.UUID `json:"uid"`
+	Purpose string    `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// MFAChallengeTTL is how long a user has to finish a two step login,
+// MFA_CHALLENGE_TTL
+func MFAChallengeTTL() time.Duration {
+	return config.Current().Auth.MFAChallengeTTL
+}
+
+// GenerateMFAToken issues the challenge token returned by the first step of
+// a login that needs a second factor
+func GenerateMFAToken(userId uuid.UUID, purpose string) (string, error) {
+	now := time.Now()
+	claims := &MFAClaims{
+		UserID: userId,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{mfaAudience},
+			IssuedAt: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFAChallengeTTL())),
+		},
+	}
+	return signToken(claims)
+}
+
+// VerifyMFAToken checks a challenge token was issued for purpose and
+// returns the user it belongs to
+func VerifyMFAToken(tokenString string, purpose string) (uuid.UUID, error) {
+	token, err := parseToken(tokenString, &MFAClaims{},
+		jwt.WithAudience(mfaAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claims, ok := token.Claims.(*MFAClaims)
+	if !ok || !token.Valid {
+		return uuid.Nil, errors.New("invalid challenge token")
+	}
+	if claims.Purpose != purpose {
+		return uuid.Nil, errors.New("challenge token was issued for another purpose")
+	}
+	return claims.UserID, nil
+}
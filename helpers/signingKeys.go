@@ -0,0 +1,287 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ringtho/inventory/config"
+)
+
+// SigningKey is an asymmetric key tokens are signed with. It signs from
+// ActiveFrom until a newer key becomes active, and verifies tokens until
+// RetireAt, so tokens signed just before a rotation keep working.
+type SigningKey struct {
+	ID         string
+	PrivateKey crypto.Signer
+	ActiveFrom time.Time
+	// RetireAt is when the key stops being trusted, never when zero
+	RetireAt time.Time
+}
+
+// Algorithm is the JWS algorithm the key signs with, RS256 for RSA keys
+// and EdDSA for Ed25519 keys
+func (k SigningKey) Algorithm() string {
+	return k.method().Alg()
+}
+
+func (k SigningKey) method() jwt.SigningMethod {
+	if _, ok := k.PrivateKey.(ed25519.PrivateKey); ok {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+func (k SigningKey) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// KeyRing holds the signing keys in the order they become active
+type KeyRing struct {
+	keys []SigningKey
+}
+
+// NewKeyRing checks keys have unique IDs and are RSA or Ed25519 keys
+func NewKeyRing(keys []SigningKey) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+
+	ids := map[string]bool{}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("signing keys need an ID")
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("signing key %v is listed twice", key.ID)
+		}
+		ids[key.ID] = true
+
+		switch k := key.PrivateKey.(type) {
+		case *rsa.PrivateKey:
+			if k.N.BitLen() < 2048 {
+				return nil, fmt.Errorf("signing key %v must be at least 2048 bits", key.ID)
+			}
+		case ed25519.PrivateKey:
+		default:
+			return nil, fmt.Errorf("signing key %v must be an RSA or Ed25519 key", key.ID)
+		}
+
+		if !key.RetireAt.IsZero() && !key.RetireAt.After(key.ActiveFrom) {
+			return nil, fmt.Errorf("signing key %v retires before it's active", key.ID)
+		}
+	}
+
+	sorted := append([]SigningKey{}, keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom)
+	})
+	return &KeyRing{keys: sorted}, nil
+}
+
+// Signer is the key new tokens are signed with, the most recent one to
+// have become active
+func (k *KeyRing) Signer(now time.Time) (SigningKey, error) {
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		key := k.keys[i]
+		if !key.ActiveFrom.After(now) && !key.retired(now) {
+			return key, nil
+		}
+	}
+	return SigningKey{}, errors.New("no signing key is active")
+}
+
+// Key finds a key that can still verify tokens. Keys that aren't active
+// yet are included since other instances may have started using them.
+func (k *KeyRing) Key(id string, now time.Time) (SigningKey, bool) {
+	for _, key := range k.keys {
+		if key.ID == id && !key.retired(now) {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// JWKS is the public half of every key that isn't retired, for
+// /.well-known/jwks.json
+func (k *KeyRing) JWKS(now time.Time) jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range k.keys {
+		if key.retired(now) {
+			continue
+		}
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key: key.PrivateKey.Public(),
+			KeyID: key.ID,
+			Algorithm: key.Algorithm(),
+			Use: "sig",
+		})
+	}
+	return set
+}
+
+type signingKeyFile struct {
+	ID             string     `json:"kid"`
+	PrivateKeyFile string     `json:"private_key_file"`
+	ActiveFrom     time.Time  `json:"active_from"`
+	RetireAt       *time.Time `json:"retire_at"`
+}
+
+// LoadKeyRing reads a JSON list of keys like
+//
+//	[{"kid": "2024-01", "private_key_file": "2024-01.pem",
+//	  "active_from": "2024-01-01T00:00:00Z", "retire_at": "2024-04-08T00:00:00Z"}]
+//
+// Key files are PEM encoded PKCS #8 or PKCS #1 private keys, relative to
+// the list.
+func LoadKeyRing(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []signingKeyFile{}
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("couldn't parse %v: %w", path, err)
+	}
+
+	keys := []SigningKey{}
+	for _, file := range files {
+		keyPath := file.PrivateKeyFile
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(filepath.Dir(path), keyPath)
+		}
+		privateKey, err := readPrivateKey(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read signing key %v: %w", file.ID, err)
+		}
+
+		key := SigningKey{ID: file.ID, PrivateKey: privateKey, ActiveFrom: file.ActiveFrom}
+		if file.RetireAt != nil {
+			key.RetireAt = *file.RetireAt
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyRing(keys)
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+// SigningKeysFromConfig loads the key ring listed in JWT_KEYS_FILE. It
+// returns nil when it isn't set, leaving tokens signed with SECRET_KEY.
+func SigningKeysFromConfig(auth config.Auth) (*KeyRing, error) {
+	if auth.JWTKeysFile == "" {
+		return nil, nil
+	}
+	return LoadKeyRing(auth.JWTKeysFile)
+}
+
+var (
+	signingKeysMu sync.RWMutex
+	signingKeys   *KeyRing
+)
+
+// SetSigningKeys switches token signing to keys. With nil, tokens are
+// signed with HS256 and SECRET_KEY.
+func SetSigningKeys(keys *KeyRing) {
+	signingKeysMu.Lock()
+	defer signingKeysMu.Unlock()
+	signingKeys = keys
+}
+
+// SigningKeys is the key ring tokens are signed with, nil when it's
+// SECRET_KEY
+func SigningKeys() *KeyRing {
+	signingKeysMu.RLock()
+	defer signingKeysMu.RUnlock()
+	return signingKeys
+}
+
+// signToken signs claims with the active key, or with SECRET_KEY when no
+// key ring is set up
+func signToken(claims jwt.Claims) (string, error) {
+	keys := SigningKeys()
+	if keys == nil {
+		secret_key := config.Current().Auth.SecretKey
+		if secret_key == "" {
+			return "", errors.New("SECRET_KEY isn't configured")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret_key))
+	}
+
+	key, err := keys.Signer(time.Now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// parseToken checks a token's signature and standard claims. With a key
+// ring, tokens need a kid from it. Without one, tokens are checked against
+// SECRET_KEY. HS256 tokens stop working once a key ring is set up, since
+// anyone holding the old secret could otherwise keep minting them. Clients
+// get a signed access token again from their refresh token.
+func parseToken(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) (*jwt.Token, error) {
+	keys := SigningKeys()
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if keys == nil {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			secret_key := config.Current().Auth.SecretKey
+			if secret_key == "" {
+				return nil, errors.New("SECRET_KEY isn't configured")
+			}
+			return []byte(secret_key), nil
+		}
+
+		if kid == "" {
+			return nil, errors.New("token has no kid")
+		}
+		key, ok := keys.Key(kid, time.Now())
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %v", kid)
+		}
+		if token.Method.Alg() != key.Algorithm() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.PrivateKey.Public(), nil
+	}, options...)
+}
@@ -2,65 +2,94 @@ package helpers
 
 import (
 	"errors"
-	"log"
-	"os"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/ringtho/inventory/config"
 )
 
 
 type Claims struct {
-	ID 		uuid.UUID `json:"id"`
-	Role 	string    `json:"role"`
-	jwt.StandardClaims
+	ID 			uuid.UUID `json:"id"`
+	Role 		string    `json:"role"`
+	SessionID 	uuid.UUID `json:"sid"`
+	// OrgID is the organisation the session is working in
+	OrgID 		uuid.NullUUID `json:"org"`
+	jwt.RegisteredClaims
 }
 
-func GenerateJWT(id uuid.UUID, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// AccessTokenTTL is how long an access token is valid for, ACCESS_TOKEN_TTL
+func AccessTokenTTL() time.Duration {
+	return config.Current().Auth.AccessTokenTTL
+}
 
-	claims := &Claims{
-		ID: id,
-		Role: role,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
-	}
+// RefreshTokenTTL is how long a refresh token is valid for,
+// REFRESH_TOKEN_TTL
+func RefreshTokenTTL() time.Duration {
+	return config.Current().Auth.RefreshTokenTTL
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// PasswordResetTTL is how long a password reset token is valid for,
+// PASSWORD_RESET_TTL
+func PasswordResetTTL() time.Duration {
+	return config.Current().Auth.PasswordResetTTL
+}
 
-	secret_key := os.Getenv("SECRET_KEY")
-	if secret_key == "" {
-		return "", errors.New("SECRET_KEY not found in the environment")
-	}
-	tokenString, err := token.SignedString([]byte(secret_key))
+// EmailVerificationTTL is how long an email verification link is valid
+// for, EMAIL_VERIFICATION_TTL
+func EmailVerificationTTL() time.Duration {
+	return config.Current().Auth.EmailVerificationTTL
+}
 
-	if err != nil {
-		return "", err
-	}
+// InvitationTTL is how long an invitation can be accepted for,
+// INVITATION_TTL
+func InvitationTTL() time.Duration {
+	return config.Current().Auth.InvitationTTL
+}
 
-	return tokenString, nil
+// VerificationResendDelay is how long a user has to wait before another
+// verification email is sent, EMAIL_VERIFICATION_RESEND_DELAY
+func VerificationResendDelay() time.Duration {
+	return config.Current().Auth.VerificationResendDelay
 }
 
-func VerifyToken(tokenString string) (*Claims, error) {
-	secret_key := os.Getenv("SECRET_KEY")
-	if secret_key == "" {
-		log.Fatal("SECRET_KEY not found in the environment")
+// GenerateJWT issues a short lived access token for the session sessionId,
+// working in the organisation orgId
+func GenerateJWT(id uuid.UUID, role string, sessionId uuid.UUID, orgId uuid.NullUUID) (string, error) {
+	now := time.Now()
+
+	claims := &Claims{
+		ID: id,
+		Role: role,
+		SessionID: sessionId,
+		OrgID: orgId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+		},
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret_key), nil
-	})
+	return signToken(claims)
+}
 
+// VerifyToken checks an access token and returns its claims
+func VerifyToken(tokenString string) (*Claims, error) {
+	token, err := parseToken(tokenString, &Claims{}, jwt.WithExpirationRequired())
 	if err != nil {
 		return nil, err
 	}
 
 	claims, ok := token.Claims.(*Claims)
 	if !ok || !token.Valid {
-		return nil, err
+		return nil, errors.New("invalid access token")
+	}
+
+	// MFA challenge tokens are signed with the same keys but aren't access
+	// tokens
+	if len(claims.Audience) != 0 {
+		return nil, errors.New("not an access token")
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/ringtho/inventory/config"
+)
+
+const (
+	totpQRCodeSize    = 256
+	totpPeriod        = 30
+	recoveryCodeBytes = 5
+)
+
+var totpOpts = totp.ValidateOpts{
+	Period: totpPeriod,
+	Digits: otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// TOTPKey is a newly generated RFC 6238 secret along with the ways of
+// getting it into an authenticator app
+type TOTPKey struct {
+	Secret string
+	URI    string
+	QRCode []byte
+}
+
+// GenerateTOTPKey creates a secret for accountName. The issuer shown in
+// authenticator apps is TOTP_ISSUER.
+func GenerateTOTPKey(accountName string) (TOTPKey, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer: config.Current().Auth.TOTPIssuer,
+		AccountName: accountName,
+	})
+	if err != nil {
+		return TOTPKey{}, err
+	}
+
+	img, err := key.Image(totpQRCodeSize, totpQRCodeSize)
+	if err != nil {
+		return TOTPKey{}, err
+	}
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, img); err != nil {
+		return TOTPKey{}, err
+	}
+
+	return TOTPKey{Secret: key.Secret(), URI: key.URL(), QRCode: qrCode.Bytes()}, nil
+}
+
+// ValidateTOTP checks code against secret, allowing one period of clock
+// drift either way
+func ValidateTOTP(code string, secret string) bool {
+	_, valid := MatchTOTP(code, secret)
+	return valid
+}
+
+// MatchTOTP checks code like ValidateTOTP and also returns the time step it
+// was generated for, so a code that has been used can be refused
+func MatchTOTP(code string, secret string) (int64, bool) {
+	code = strings.TrimSpace(code)
+	now := time.Now().UTC()
+	for skew := -1; skew <= 1; skew++ {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns n random single use codes such as
+// "ABCDE-FGHIJ". Store them with HashToken.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, recoveryCodeBytes*2)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := base32.StdEncoding.EncodeToString(b)
+		codes = append(codes, code[:5]+"-"+code[5:10])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode lets users type recovery codes in lower case and
+// with or without the dash
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
@@ -0,0 +1,105 @@
+package initializers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+)
+
+// defaultOrgID is the organisation the migrations create, which admins
+// made here join so they can manage its inventory straight away
+var defaultOrgID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// AdminParams describe an admin account to create
+type AdminParams struct {
+	Email    string
+	Username string
+	Name     string
+	Password string
+}
+
+// CreateAdmin creates a verified admin user who is an admin of the default
+// organisation
+func CreateAdmin(ctx context.Context, DB store.Store, params AdminParams) (database.CreateUserRow, error) {
+	if params.Email == "" || params.Username == "" || params.Name == "" || params.Password == "" {
+		return database.CreateUserRow{}, errors.New("email, username, name and password are required")
+	}
+	if !helpers.IsValidEmail(params.Email) {
+		return database.CreateUserRow{}, fmt.Errorf("invalid email address %q", params.Email)
+	}
+	if !helpers.IsStrongPassword(params.Password) {
+		return database.CreateUserRow{}, errors.New("password is not strong enough")
+	}
+
+	now := time.Now().UTC()
+	user, err := DB.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name: params.Name,
+		Username: params.Username,
+		Email: params.Email,
+		Password: helpers.HashPassword(params.Password),
+		Role: "admin",
+	})
+	if err != nil {
+		return database.CreateUserRow{}, err
+	}
+
+	err = DB.SetUserEmailVerified(ctx, database.SetUserEmailVerifiedParams{
+		ID: user.ID,
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		return user, err
+	}
+
+	_, err = DB.SetOrganizationMember(ctx, database.SetOrganizationMemberParams{
+		OrgID: defaultOrgID,
+		UserID: user.ID,
+		Role: "admin",
+		CreatedAt: now,
+	})
+	return user, err
+}
+
+// BootstrapAdmin creates the first admin from BOOTSTRAP_ADMIN_EMAIL,
+// BOOTSTRAP_ADMIN_PASSWORD, BOOTSTRAP_ADMIN_USERNAME and
+// BOOTSTRAP_ADMIN_NAME. Nothing happens when the email isn't set or an admin
+// already exists, so the settings can be left in place after first start.
+func BootstrapAdmin(ctx context.Context, DB store.Store) error {
+	cfg := config.Current().BootstrapAdmin
+	if cfg.Email == "" {
+		return nil
+	}
+
+	admins, err := DB.CountUsersWithRole(ctx, "admin")
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	params := AdminParams{
+		Email: cfg.Email,
+		Username: cfg.Username,
+		Name: cfg.Name,
+		Password: cfg.Password,
+	}
+	user, err := CreateAdmin(ctx, DB, params)
+	if err != nil {
+		return fmt.Errorf("couldn't create bootstrap admin: %w", err)
+	}
+	log.Printf("Created bootstrap admin %v (%v)", user.Email, user.ID)
+	return nil
+}
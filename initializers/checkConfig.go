@@ -0,0 +1,81 @@
+package initializers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/db"
+	"github.com/ringtho/inventory/helpers"
+)
+
+// ConfigCheck is the result of checking one part of the configuration.
+// Err is nil when it's fine.
+type ConfigCheck struct {
+	Name string
+	Err  error
+}
+
+// CheckConfig validates each section of cfg and checks what the server
+// would need when it starts, such as being able to reach the database,
+// reporting every problem instead of stopping at the first one
+func CheckConfig(ctx context.Context, cfg config.Config) []ConfigCheck {
+	return []ConfigCheck{
+		{"server", cfg.Server.Validate()},
+		{"database", checkDatabase(ctx, cfg.Database)},
+		{"auth", checkSigningKeys(cfg.Auth)},
+		{"login lockout", cfg.Lockout.Validate()},
+		{"mailer", cfg.Mail.Validate()},
+		{"single sign-on", cfg.OIDC.Validate()},
+		{"bootstrap admin", checkBootstrapAdmin(cfg.BootstrapAdmin)},
+		{"jobs", cfg.Jobs.Validate()},
+	}
+}
+
+func checkDatabase(ctx context.Context, cfg config.Database) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	conn := db.ConnectToDatabase(cfg)
+	defer conn.Close()
+	if err := conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("couldn't connect: %w", err)
+	}
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.HasPending(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't read the schema version: %w", err)
+	}
+	if pending && !cfg.MigrateOnStart {
+		return errors.New("there are pending migrations, run inventory migrate up or set MIGRATE_ON_START=true")
+	}
+	return nil
+}
+
+func checkSigningKeys(cfg config.Auth) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if _, err := helpers.SigningKeysFromConfig(cfg); err != nil {
+		return fmt.Errorf("invalid JWT_KEYS_FILE: %w", err)
+	}
+	return nil
+}
+
+func checkBootstrapAdmin(cfg config.BootstrapAdmin) error {
+	if err := cfg.Validate(); err != nil || cfg.Email == "" {
+		return err
+	}
+	if !helpers.IsValidEmail(cfg.Email) {
+		return fmt.Errorf("invalid BOOTSTRAP_ADMIN_EMAIL %q", cfg.Email)
+	}
+	if !helpers.IsStrongPassword(cfg.Password) {
+		return errors.New("BOOTSTRAP_ADMIN_PASSWORD is not strong enough")
+	}
+	return nil
+}
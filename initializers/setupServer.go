@@ -1,29 +1,75 @@
 package initializers
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
 
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/controllers"
 	"github.com/ringtho/inventory/db"
+	"github.com/ringtho/inventory/helpers"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/mailer"
 	"github.com/ringtho/inventory/routers"
+	"github.com/ringtho/inventory/sso"
 )
 
-func SetupServer()(*http.Server, *database.Queries, *sql.DB){
-	port := os.Getenv("PORT")
-	if port == "" {
-		log.Fatal("PORT not found in the environment")
+// NewServices builds the mailer and single sign-on provider from settings
+// and loads the JWT signing keys tokens are signed and verified with, so a
+// bad mailer, SSO or key file configuration fails before anything starts
+func NewServices(settings config.Config) (routers.Services, error) {
+	mail, err := mailer.FromConfig(settings.Mail)
+	if err != nil {
+		return routers.Services{}, fmt.Errorf("invalid mailer configuration: %w", err)
 	}
+	provider, err := sso.FromConfig(settings.OIDC)
+	if err != nil {
+		return routers.Services{}, fmt.Errorf("invalid single sign-on configuration: %w", err)
+	}
+	keys, err := helpers.SigningKeysFromConfig(settings.Auth)
+	if err != nil {
+		return routers.Services{}, fmt.Errorf("invalid JWT signing keys: %w", err)
+	}
+	helpers.SetSigningKeys(keys)
+	return routers.Services{Settings: settings, Mailer: mail, SSO: provider}, nil
+}
 
-	conn := db.ConnectToDatabase()
-	DB := database.New(conn)
+// SetupServer builds the HTTP server along with the health probes, which
+// the caller marks as shutting down before stopping it
+func SetupServer(services routers.Services)(*http.Server, *controllers.HealthCfg, *database.Queries, *sql.DB){
+	DB, conn := SetupDatabase()
 
-	address := ":" + port
+	cfg := services.Settings.Server
+	health := &controllers.HealthCfg{Conn: conn}
+	address := ":" + strconv.Itoa(cfg.Port)
 	server := &http.Server{
 		Addr:    address,
-		Handler: routers.Router(DB),
+		Handler: routers.Router(DB, health, services),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout: cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout: cfg.IdleTimeout,
+	}
+	return server, health, DB, conn
+}
+
+// SetupDatabase connects to DB_URL, waiting up to DB_CONNECT_TIMEOUT for
+// it to answer, and applies pending migrations when MIGRATE_ON_START is
+// true
+func SetupDatabase() (*database.Queries, *sql.DB) {
+	cfg := config.Current().Database
+	conn := db.ConnectToDatabase(cfg)
+	if err := db.WaitForDatabase(context.Background(), conn, cfg.ConnectTimeout); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.MigrateOnStart {
+		if err := db.Migrate(context.Background(), conn, "up", log.Writer()); err != nil {
+			log.Fatal("Couldn't migrate the database: ", err)
+		}
 	}
-	return server, DB, conn
-}
\ No newline at end of file
+	return database.New(conn), conn
+}
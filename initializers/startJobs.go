@@ -0,0 +1,25 @@
+package initializers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/jobs"
+)
+
+// StartJobs runs the background jobs until ctx is cancelled. The function
+// it returns waits for them to stop, so the database isn't closed while a
+// job is using it.
+func StartJobs(ctx context.Context, DB store.Store) (wait func()) {
+	cfg := config.Current().Jobs
+	var running sync.WaitGroup
+
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		jobs.RunPurgeDeleted(ctx, DB, cfg.SoftDeleteRetention, cfg.PurgeInterval)
+	}()
+	return running.Wait
+}
@@ -6,25 +6,53 @@ import (
 	"strings"
 )
 
-// GetAPIKey extracts an API Key from
-// the headers of an HTTP request
+// Schemes accepted in the Authorization header
+const (
+	SchemeBearer = "Bearer"
+	SchemeApiKey = "ApiKey"
+)
+
+// Credentials are the scheme and value of an Authorization header
+type Credentials struct {
+	Scheme string
+	Value  string
+}
+
+// GetCredentials extracts a JWT or an API key from the headers of an HTTP
+// request
 // Example:
-// Authorization: Bearer {insert apiKey here}
-func GetToken(headers http.Header) (string, error) {
+// Authorization: Bearer {insert token here}
+// Authorization: ApiKey {insert apiKey here}
+func GetCredentials(headers http.Header) (Credentials, error) {
 	val := headers.Get("Authorization")
 
 	if val == "" {
-		return "", errors.New("no authentication info found")
+		return Credentials{}, errors.New("no authentication info found")
 	}
 
 	vals := strings.Split(val, " ")
 
 	if len(vals) != 2 {
-		return "", errors.New("malformed auth header")
+		return Credentials{}, errors.New("malformed auth header")
+	}
+
+	if vals[0] != SchemeBearer && vals[0] != SchemeApiKey {
+		return Credentials{}, errors.New("malformed first part of auth header")
+	}
+	return Credentials{Scheme: vals[0], Value: vals[1]}, nil
+}
+
+// GetToken extracts a bearer token from the headers of an HTTP request
+// Example:
+// Authorization: Bearer {insert token here}
+func GetToken(headers http.Header) (string, error) {
+	credentials, err := GetCredentials(headers)
+	if err != nil {
+		return "", err
 	}
 
-	if vals[0] != "Bearer" {
+	if credentials.Scheme != SchemeBearer {
 		return "", errors.New("malformed first part of auth header")
 	}
-	return vals[1], nil
+	return credentials.Value, nil
 }
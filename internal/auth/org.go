@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type orgIDKey struct{}
+
+// WithOrgID stores the organisation the request works in
+func WithOrgID(ctx context.Context, orgId uuid.UUID) context.Context {
+	return context.WithValue(ctx, orgIDKey{}, orgId)
+}
+
+// OrgID is the organisation the request works in, uuid.Nil when there
+// isn't one
+func OrgID(ctx context.Context) uuid.UUID {
+	orgId, _ := ctx.Value(orgIDKey{}).(uuid.UUID)
+	return orgId
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// Permissions that can be granted to roles. Routes declare the one they
+// need with RequirePermission in the middlewares package.
+const (
+	ProductsRead   = "products:read"
+	ProductsWrite  = "products:write"
+	ProductsDelete = "products:delete"
+	// ProductsStock only allows stock adjustments, not other product edits
+	ProductsStock = "products:stock"
+
+	CategoriesRead   = "categories:read"
+	CategoriesWrite  = "categories:write"
+	CategoriesDelete = "categories:delete"
+
+	SuppliersRead   = "suppliers:read"
+	SuppliersWrite  = "suppliers:write"
+	SuppliersDelete = "suppliers:delete"
+
+	UsersRead   = "users:read"
+	UsersWrite  = "users:write"
+	UsersDelete = "users:delete"
+
+	RolesManage           = "roles:manage"
+	AuditRead             = "audit:read"
+	SettingsManage        = "settings:manage"
+	ServiceAccountsManage = "service_accounts:manage"
+	OrganizationsManage   = "organizations:manage"
+)
+
+// AllPermissions lists every known permission, used to validate the
+// permissions given to new roles
+var AllPermissions = []string{
+	ProductsRead, ProductsWrite, ProductsDelete, ProductsStock,
+	CategoriesRead, CategoriesWrite, CategoriesDelete,
+	SuppliersRead, SuppliersWrite, SuppliersDelete,
+	UsersRead, UsersWrite, UsersDelete,
+	RolesManage, AuditRead, SettingsManage, ServiceAccountsManage, OrganizationsManage,
+}
+
+// administratorPermissions let a role change who can do what. Roles that
+// grant any of them are administrator roles, whatever they are called.
+var administratorPermissions = []string{RolesManage, UsersDelete, SettingsManage}
+
+// Administrator reports whether permissions make a role an administrator
+// role, which security settings like required 2FA apply to
+func Administrator(permissions []string) bool {
+	for _, p := range permissions {
+		for _, admin := range administratorPermissions {
+			if p == admin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// orgScopedPrefixes are the entities that belong to an organisation
+var orgScopedPrefixes = []string{"products:", "categories:", "suppliers:"}
+
+// OrgScoped reports whether permission is for something that belongs to an
+// organisation. Those are granted by the user's role in the active
+// organisation rather than their own role.
+func OrgScoped(permission string) bool {
+	for _, prefix := range orgScopedPrefixes {
+		if strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OrgPermissions combines the permissions of a user's own role with those
+// of their role in the active organisation. Pass nil for org when there's
+// no active organisation, which leaves the user with no organisation
+// scoped permissions.
+func OrgPermissions(own []string, org []string) []string {
+	permissions := []string{}
+	for _, p := range own {
+		if !OrgScoped(p) {
+			permissions = append(permissions, p)
+		}
+	}
+	for _, p := range org {
+		if OrgScoped(p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+// IsPermission reports whether permission is one of AllPermissions
+func IsPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type permissionsKey struct{}
+
+// WithPermissions stores the permissions of the authenticated user in ctx
+func WithPermissions(ctx context.Context, permissions []string) context.Context {
+	set := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		set[p] = true
+	}
+	return context.WithValue(ctx, permissionsKey{}, set)
+}
+
+// HasPermission reports whether the authenticated user of the request has
+// permission. Anonymous requests have no permissions.
+func HasPermission(ctx context.Context, permission string) bool {
+	set, _ := ctx.Value(permissionsKey{}).(map[string]bool)
+	return set[permission]
+}
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type sessionKey struct{}
+
+// WithSessionID stores the session the request's access token belongs to
+func WithSessionID(ctx context.Context, sessionId uuid.UUID) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionId)
+}
+
+// SessionID returns the session of the request's access token, or uuid.Nil
+// for anonymous requests
+func SessionID(ctx context.Context) uuid.UUID {
+	sessionId, _ := ctx.Value(sessionKey{}).(uuid.UUID)
+	return sessionId
+}
@@ -15,14 +15,15 @@ import (
 
 const createCategory = `-- name: CreateCategory :one
 INSERT INTO categories (
-    id, name, description, created_at, updated_at, created_by
+    id, org_id, name, description, created_at, updated_at, created_by
 )
-VALUES ($1,$2,$3,$4,$5, $6)
-RETURNING id, created_at, updated_at, name, description, created_by
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+RETURNING id, created_at, updated_at, name, description, created_by, deleted_at, org_id
 `
 
 type CreateCategoryParams struct {
 	ID          uuid.UUID
+	OrgID       uuid.UUID
 	Name        string
 	Description sql.NullString
 	CreatedAt   time.Time
@@ -33,6 +34,7 @@ type CreateCategoryParams struct {
 func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
 	row := q.db.QueryRowContext(ctx, createCategory,
 		arg.ID,
+		arg.OrgID,
 		arg.Name,
 		arg.Description,
 		arg.CreatedAt,
@@ -47,25 +49,28 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		&i.Name,
 		&i.Description,
 		&i.CreatedBy,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
 
-const deleteCategory = `-- name: DeleteCategory :exec
-DELETE FROM categories WHERE id = $1
+const getCategories = `-- name: GetCategories :many
+SELECT id, created_at, updated_at, name, description, created_by, deleted_at, org_id FROM categories
+WHERE org_id = $1
+AND (deleted_at IS NULL OR $2::boolean)
 `
 
-func (q *Queries) DeleteCategory(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteCategory, id)
-	return err
+type GetCategoriesParams struct {
+	OrgID          uuid.UUID
+	IncludeDeleted bool
 }
 
-const getCategories = `-- name: GetCategories :many
-SELECT id, created_at, updated_at, name, description, created_by FROM categories
-`
-
-func (q *Queries) GetCategories(ctx context.Context) ([]Category, error) {
-	rows, err := q.db.QueryContext(ctx, getCategories)
+func (q *Queries) GetCategories(ctx context.Context, arg GetCategoriesParams) ([]Category, error) {
+	rows, err := q.db.QueryContext(ctx, getCategories,
+		arg.OrgID,
+		arg.IncludeDeleted,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +85,8 @@ func (q *Queries) GetCategories(ctx context.Context) ([]Category, error) {
 			&i.Name,
 			&i.Description,
 			&i.CreatedBy,
+			&i.DeletedAt,
+			&i.OrgID,
 		); err != nil {
 			return nil, err
 		}
@@ -95,11 +102,47 @@ func (q *Queries) GetCategories(ctx context.Context) ([]Category, error) {
 }
 
 const getCategoryById = `-- name: GetCategoryById :one
-SELECT id, created_at, updated_at, name, description, created_by FROM categories WHERE id = $1
+SELECT id, created_at, updated_at, name, description, created_by, deleted_at, org_id FROM categories WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
+`
+
+type GetCategoryByIdParams struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+}
+
+func (q *Queries) GetCategoryById(ctx context.Context, arg GetCategoryByIdParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, getCategoryById,
+		arg.ID,
+		arg.OrgID,
+	)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.DeletedAt,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const getCategoryByIdIncludingDeleted = `-- name: GetCategoryByIdIncludingDeleted :one
+SELECT id, created_at, updated_at, name, description, created_by, deleted_at, org_id FROM categories WHERE id = $1 AND org_id = $2
 `
 
-func (q *Queries) GetCategoryById(ctx context.Context, id uuid.UUID) (Category, error) {
-	row := q.db.QueryRowContext(ctx, getCategoryById, id)
+type GetCategoryByIdIncludingDeletedParams struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+}
+
+func (q *Queries) GetCategoryByIdIncludingDeleted(ctx context.Context, arg GetCategoryByIdIncludingDeletedParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, getCategoryByIdIncludingDeleted,
+		arg.ID,
+		arg.OrgID,
+	)
 	var i Category
 	err := row.Scan(
 		&i.ID,
@@ -108,33 +151,112 @@ func (q *Queries) GetCategoryById(ctx context.Context, id uuid.UUID) (Category,
 		&i.Name,
 		&i.Description,
 		&i.CreatedBy,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
 
+const purgeDeletedCategories = `-- name: PurgeDeletedCategories :execrows
+DELETE FROM categories WHERE deleted_at < $1::timestamp
+`
+
+func (q *Queries) PurgeDeletedCategories(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedCategories, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreCategory = `-- name: RestoreCategory :one
+UPDATE categories
+SET
+deleted_at = NULL,
+updated_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, name, description, created_by, deleted_at, org_id
+`
+
+type RestoreCategoryParams struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) RestoreCategory(ctx context.Context, arg RestoreCategoryParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, restoreCategory,
+		arg.ID,
+		arg.OrgID,
+		arg.UpdatedAt,
+	)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.DeletedAt,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const softDeleteCategory = `-- name: SoftDeleteCategory :execrows
+UPDATE categories SET deleted_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL AND updated_at = $4
+`
+
+type SoftDeleteCategoryParams struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	DeletedAt sql.NullTime
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SoftDeleteCategory(ctx context.Context, arg SoftDeleteCategoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteCategory,
+		arg.ID,
+		arg.OrgID,
+		arg.DeletedAt,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateCategory = `-- name: UpdateCategory :one
 UPDATE categories
 SET
-name = $2,
-description = $3,
-updated_at = $4
-WHERE id = $1
-RETURNING id, created_at, updated_at, name, description, created_by
+name = $1,
+description = $2,
+updated_at = $3
+WHERE id = $4 AND org_id = $5 AND deleted_at IS NULL
+AND updated_at = $6
+RETURNING id, created_at, updated_at, name, description, created_by, deleted_at, org_id
 `
 
 type UpdateCategoryParams struct {
-	ID          uuid.UUID
-	Name        string
-	Description sql.NullString
-	UpdatedAt   time.Time
+	Name              string
+	Description       sql.NullString
+	UpdatedAt         time.Time
+	ID                uuid.UUID
+	OrgID             uuid.UUID
+	ExpectedUpdatedAt time.Time
 }
 
 func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error) {
 	row := q.db.QueryRowContext(ctx, updateCategory,
-		arg.ID,
 		arg.Name,
 		arg.Description,
 		arg.UpdatedAt,
+		arg.ID,
+		arg.OrgID,
+		arg.ExpectedUpdatedAt,
 	)
 	var i Category
 	err := row.Scan(
@@ -144,6 +266,8 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		&i.Name,
 		&i.Description,
 		&i.CreatedBy,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
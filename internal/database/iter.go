@@ -0,0 +1,113 @@
+package database
+
+// This file is not generated by sqlc. It reuses the generated list queries
+// but hands each row to a callback instead of collecting them in a slice,
+// so large result sets can be streamed to the client.
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+func iterate[T any](
+	ctx context.Context,
+	db DBTX,
+	query string,
+	args []interface{},
+	scan func(*sql.Rows, *T) error,
+	fn func(T) error,
+) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i T
+		if err := scan(rows, &i); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// IterProducts calls fn for every product of an organisation returned by
+// GetProducts
+func (q *Queries) IterProducts(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(Product) error) error {
+	return iterate(ctx, q.db, getProducts, []interface{}{orgId, includeDeleted}, func(rows *sql.Rows, i *Product) error {
+		return rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.StockLevel,
+			&i.CategoryID,
+			&i.SupplierID,
+			&i.Sku,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.OrgID,
+		)
+	}, fn)
+}
+
+// IterSuppliers calls fn for every supplier of an organisation returned by
+// GetAllSuppliers
+func (q *Queries) IterSuppliers(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(Supplier) error) error {
+	return iterate(ctx, q.db, getAllSuppliers, []interface{}{orgId, includeDeleted}, func(rows *sql.Rows, i *Supplier) error {
+		return rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Description,
+			&i.Phone,
+			&i.Country,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.OrgID,
+		)
+	}, fn)
+}
+
+// IterCategories calls fn for every category of an organisation returned
+// by GetCategories
+func (q *Queries) IterCategories(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(Category) error) error {
+	return iterate(ctx, q.db, getCategories, []interface{}{orgId, includeDeleted}, func(rows *sql.Rows, i *Category) error {
+		return rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Description,
+			&i.CreatedBy,
+			&i.DeletedAt,
+			&i.OrgID,
+		)
+	}, fn)
+}
+
+// IterUsers calls fn for every user returned by GetAllUsers
+func (q *Queries) IterUsers(ctx context.Context, fn func(GetAllUsersRow) error) error {
+	return iterate(ctx, q.db, getAllUsers, nil, func(rows *sql.Rows, i *GetAllUsersRow) error {
+		return rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.Name,
+			&i.Role,
+			&i.ProfilePictureUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		)
+	}, fn)
+}
@@ -6,11 +6,42 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID               uuid.UUID
+	ServiceAccountID uuid.UUID
+	Name             string
+	Prefix           string
+	SecretHash       string
+	CreatedAt        time.Time
+	ExpiresAt        sql.NullTime
+	LastUsedAt       sql.NullTime
+	RevokedAt        sql.NullTime
+}
+
+type ApiKeyScope struct {
+	ApiKeyID   uuid.UUID
+	Permission string
+}
+
+type AuditLog struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ActorID    uuid.UUID
+	ActorEmail string
+	Action     string
+	Entity     string
+	EntityID   uuid.UUID
+	Changes    json.RawMessage
+	RequestID  string
+	Ip         string
+}
+
 type Category struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
@@ -18,6 +49,70 @@ type Category struct {
 	Name        string
 	Description sql.NullString
 	CreatedBy   uuid.UUID
+	DeletedAt   sql.NullTime
+	OrgID       uuid.UUID
+}
+
+type EmailVerificationToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Invitation struct {
+	ID         uuid.UUID
+	Email      string
+	Role       string
+	TokenHash  string
+	InvitedBy  uuid.NullUUID
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	AcceptedAt sql.NullTime
+	OrgID      uuid.NullUUID
+}
+
+type LoginThrottle struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type OidcLogin struct {
+	ID           uuid.UUID
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	UsedAt       sql.NullTime
+}
+
+type Organization struct {
+	ID        uuid.UUID
+	Name      string
+	Slug      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type OrganizationMember struct {
+	OrgID     uuid.UUID
+	UserID    uuid.UUID
+	Role      string
+	CreatedAt time.Time
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 }
 
 type Product struct {
@@ -31,6 +126,63 @@ type Product struct {
 	Sku         sql.NullString
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   sql.NullTime
+	OrgID       uuid.UUID
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID
+	SessionID uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Role struct {
+	Name        string
+	Description sql.NullString
+	BuiltIn     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type RolePermission struct {
+	Role       string
+	Permission string
+}
+
+type SecuritySetting struct {
+	ID              bool
+	RequireAdminMfa bool
+	UpdatedAt       time.Time
+}
+
+type ServiceAccount struct {
+	ID          uuid.UUID
+	Name        string
+	Description sql.NullString
+	Role        string
+	CreatedBy   uuid.NullUUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	OrgID       uuid.UUID
+}
+
+type Session struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	RevokedAt sql.NullTime
+	OrgID     uuid.NullUUID
 }
 
 type Supplier struct {
@@ -42,6 +194,16 @@ type Supplier struct {
 	Country     sql.NullString
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   sql.NullTime
+	OrgID       uuid.UUID
+}
+
+type TotpCredential struct {
+	UserID       uuid.UUID
+	Secret       string
+	CreatedAt    time.Time
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
 }
 
 type User struct {
@@ -54,4 +216,14 @@ type User struct {
 	Role              string
 	ProfilePictureUrl sql.NullString
 	Name              string
+	EmailVerifiedAt   sql.NullTime
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Issuer      string
+	Subject     string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
 }
@@ -13,9 +13,96 @@ import (
 	"github.com/google/uuid"
 )
 
+const adjustProductStock = `-- name: AdjustProductStock :one
+UPDATE products
+SET
+stock_level = COALESCE(stock_level, 0) + $1::int,
+updated_at = $2
+WHERE id = $3 AND org_id = $4 AND deleted_at IS NULL
+AND COALESCE(stock_level, 0) + $1::int >= 0
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id
+`
+
+type AdjustProductStockParams struct {
+	Adjustment int32
+	UpdatedAt  time.Time
+	ID         uuid.UUID
+	OrgID      uuid.UUID
+}
+
+func (q *Queries) AdjustProductStock(ctx context.Context, arg AdjustProductStockParams) (Product, error) {
+	row := q.db.QueryRowContext(ctx, adjustProductStock,
+		arg.Adjustment,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.OrgID,
+	)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.StockLevel,
+		&i.CategoryID,
+		&i.SupplierID,
+		&i.Sku,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const countProductsByOrganization = `-- name: CountProductsByOrganization :many
+SELECT
+organizations.slug,
+COUNT(products.id) AS products,
+COUNT(CASE WHEN COALESCE(products.stock_level, 0) = 0 THEN products.id END) AS out_of_stock
+FROM organizations
+LEFT JOIN products ON products.org_id = organizations.id AND products.deleted_at IS NULL
+GROUP BY organizations.slug
+ORDER BY organizations.slug
+`
+
+type CountProductsByOrganizationRow struct {
+	Slug       string
+	Products   int64
+	OutOfStock int64
+}
+
+func (q *Queries) CountProductsByOrganization(ctx context.Context) ([]CountProductsByOrganizationRow, error) {
+	rows, err := q.db.QueryContext(ctx, countProductsByOrganization)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountProductsByOrganizationRow
+	for rows.Next() {
+		var i CountProductsByOrganizationRow
+		if err := rows.Scan(
+			&i.Slug,
+			&i.Products,
+			&i.OutOfStock,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createProduct = `-- name: CreateProduct :one
 INSERT INTO products(
     id,
+    org_id,
     name,
     description,
     price,
@@ -26,12 +113,13 @@ INSERT INTO products(
     created_at,
     updated_at
 )
-VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id
 `
 
 type CreateProductParams struct {
 	ID          uuid.UUID
+	OrgID       uuid.UUID
 	Name        string
 	Description sql.NullString
 	Price       int32
@@ -46,6 +134,7 @@ type CreateProductParams struct {
 func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error) {
 	row := q.db.QueryRowContext(ctx, createProduct,
 		arg.ID,
+		arg.OrgID,
 		arg.Name,
 		arg.Description,
 		arg.Price,
@@ -68,25 +157,58 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 		&i.Sku,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
 
-const deleteProduct = `-- name: DeleteProduct :exec
-DELETE FROM products WHERE id = $1
+const getProduct = `-- name: GetProduct :one
+SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id FROM products WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteProduct(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteProduct, id)
-	return err
+type GetProductParams struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
 }
 
-const getProduct = `-- name: GetProduct :one
-SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at FROM products WHERE id = $1
+func (q *Queries) GetProduct(ctx context.Context, arg GetProductParams) (Product, error) {
+	row := q.db.QueryRowContext(ctx, getProduct,
+		arg.ID,
+		arg.OrgID,
+	)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.StockLevel,
+		&i.CategoryID,
+		&i.SupplierID,
+		&i.Sku,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const getProductIncludingDeleted = `-- name: GetProductIncludingDeleted :one
+SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id FROM products WHERE id = $1 AND org_id = $2
 `
 
-func (q *Queries) GetProduct(ctx context.Context, id uuid.UUID) (Product, error) {
-	row := q.db.QueryRowContext(ctx, getProduct, id)
+type GetProductIncludingDeletedParams struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+}
+
+func (q *Queries) GetProductIncludingDeleted(ctx context.Context, arg GetProductIncludingDeletedParams) (Product, error) {
+	row := q.db.QueryRowContext(ctx, getProductIncludingDeleted,
+		arg.ID,
+		arg.OrgID,
+	)
 	var i Product
 	err := row.Scan(
 		&i.ID,
@@ -99,16 +221,28 @@ func (q *Queries) GetProduct(ctx context.Context, id uuid.UUID) (Product, error)
 		&i.Sku,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
 
 const getProducts = `-- name: GetProducts :many
-SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at FROM products
+SELECT id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id FROM products
+WHERE org_id = $1
+AND (deleted_at IS NULL OR $2::boolean)
 `
 
-func (q *Queries) GetProducts(ctx context.Context) ([]Product, error) {
-	rows, err := q.db.QueryContext(ctx, getProducts)
+type GetProductsParams struct {
+	OrgID          uuid.UUID
+	IncludeDeleted bool
+}
+
+func (q *Queries) GetProducts(ctx context.Context, arg GetProductsParams) ([]Product, error) {
+	rows, err := q.db.QueryContext(ctx, getProducts,
+		arg.OrgID,
+		arg.IncludeDeleted,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +261,8 @@ func (q *Queries) GetProducts(ctx context.Context) ([]Product, error) {
 			&i.Sku,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.OrgID,
 		); err != nil {
 			return nil, err
 		}
@@ -141,36 +277,114 @@ func (q *Queries) GetProducts(ctx context.Context) ([]Product, error) {
 	return items, nil
 }
 
+const purgeDeletedProducts = `-- name: PurgeDeletedProducts :execrows
+DELETE FROM products WHERE deleted_at < $1::timestamp
+`
+
+func (q *Queries) PurgeDeletedProducts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedProducts, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreProduct = `-- name: RestoreProduct :one
+UPDATE products
+SET
+deleted_at = NULL,
+updated_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id
+`
+
+type RestoreProductParams struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) RestoreProduct(ctx context.Context, arg RestoreProductParams) (Product, error) {
+	row := q.db.QueryRowContext(ctx, restoreProduct,
+		arg.ID,
+		arg.OrgID,
+		arg.UpdatedAt,
+	)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.StockLevel,
+		&i.CategoryID,
+		&i.SupplierID,
+		&i.Sku,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const softDeleteProduct = `-- name: SoftDeleteProduct :execrows
+UPDATE products SET deleted_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL AND updated_at = $4
+`
+
+type SoftDeleteProductParams struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	DeletedAt sql.NullTime
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SoftDeleteProduct(ctx context.Context, arg SoftDeleteProductParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteProduct,
+		arg.ID,
+		arg.OrgID,
+		arg.DeletedAt,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateProduct = `-- name: UpdateProduct :one
 UPDATE products
 SET
-name = $2,
-description = $3,
-price = $4,
-stock_level = $5,
-category_id = $6,
-supplier_id = $7,
-sku = $8,
-updated_at = $9
-WHERE id = $1
-RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at
+name = $1,
+description = $2,
+price = $3,
+stock_level = $4,
+category_id = $5,
+supplier_id = $6,
+sku = $7,
+updated_at = $8
+WHERE id = $9 AND org_id = $10 AND deleted_at IS NULL
+AND updated_at = $11
+RETURNING id, name, description, price, stock_level, category_id, supplier_id, sku, created_at, updated_at, deleted_at, org_id
 `
 
 type UpdateProductParams struct {
-	ID          uuid.UUID
-	Name        string
-	Description sql.NullString
-	Price       int32
-	StockLevel  sql.NullInt32
-	CategoryID  uuid.NullUUID
-	SupplierID  uuid.NullUUID
-	Sku         sql.NullString
-	UpdatedAt   time.Time
+	Name              string
+	Description       sql.NullString
+	Price             int32
+	StockLevel        sql.NullInt32
+	CategoryID        uuid.NullUUID
+	SupplierID        uuid.NullUUID
+	Sku               sql.NullString
+	UpdatedAt         time.Time
+	ID                uuid.UUID
+	OrgID             uuid.UUID
+	ExpectedUpdatedAt time.Time
 }
 
 func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (Product, error) {
 	row := q.db.QueryRowContext(ctx, updateProduct,
-		arg.ID,
 		arg.Name,
 		arg.Description,
 		arg.Price,
@@ -179,6 +393,9 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 		arg.SupplierID,
 		arg.Sku,
 		arg.UpdatedAt,
+		arg.ID,
+		arg.OrgID,
+		arg.ExpectedUpdatedAt,
 	)
 	var i Product
 	err := row.Scan(
@@ -192,6 +409,8 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 		&i.Sku,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
@@ -0,0 +1,66 @@
+package database
+
+// This file is not generated by sqlc. It runs the generated delete and
+// insert queries that replace a set of rows in one transaction, so a
+// failure halfway through leaves the old rows in place.
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// inTx calls fn with queries running in a transaction, which is committed
+// when fn succeeds. Queries that already run in a transaction are used as
+// they are.
+func (q *Queries) inTx(ctx context.Context, fn func(*Queries) error) error {
+	db, ok := q.db.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(q.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRolePermissions gives role exactly permissions
+func (q *Queries) ReplaceRolePermissions(ctx context.Context, role string, permissions []string) error {
+	return q.inTx(ctx, func(q *Queries) error {
+		if err := q.DeleteRolePermissions(ctx, role); err != nil {
+			return err
+		}
+		for _, permission := range permissions {
+			err := q.AddRolePermission(ctx, AddRolePermissionParams{
+				Role:       role,
+				Permission: permission,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceRecoveryCodes throws away the recovery codes of userId and stores
+// codes instead
+func (q *Queries) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codes []CreateRecoveryCodeParams) error {
+	return q.inTx(ctx, func(q *Queries) error {
+		if err := q.DeleteRecoveryCodes(ctx, userId); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			if err := q.CreateRecoveryCode(ctx, code); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -15,14 +15,15 @@ import (
 
 const createSupplier = `-- name: CreateSupplier :one
 INSERT INTO suppliers(
-    id, name, email, description, phone, country, created_at, updated_at
+    id, org_id, name, email, description, phone, country, created_at, updated_at
 ) 
-VALUES($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, name, email, description, phone, country, created_at, updated_at
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id
 `
 
 type CreateSupplierParams struct {
 	ID          uuid.UUID
+	OrgID       uuid.UUID
 	Name        string
 	Email       sql.NullString
 	Description sql.NullString
@@ -35,6 +36,7 @@ type CreateSupplierParams struct {
 func (q *Queries) CreateSupplier(ctx context.Context, arg CreateSupplierParams) (Supplier, error) {
 	row := q.db.QueryRowContext(ctx, createSupplier,
 		arg.ID,
+		arg.OrgID,
 		arg.Name,
 		arg.Email,
 		arg.Description,
@@ -53,25 +55,28 @@ func (q *Queries) CreateSupplier(ctx context.Context, arg CreateSupplierParams)
 		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
 
-const deleteSupplier = `-- name: DeleteSupplier :exec
-DELETE FROM suppliers WHERE id=$1
+const getAllSuppliers = `-- name: GetAllSuppliers :many
+SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers
+WHERE org_id = $1
+AND (deleted_at IS NULL OR $2::boolean)
 `
 
-func (q *Queries) DeleteSupplier(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteSupplier, id)
-	return err
+type GetAllSuppliersParams struct {
+	OrgID          uuid.UUID
+	IncludeDeleted bool
 }
 
-const getAllSuppliers = `-- name: GetAllSuppliers :many
-SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers
-`
-
-func (q *Queries) GetAllSuppliers(ctx context.Context) ([]Supplier, error) {
-	rows, err := q.db.QueryContext(ctx, getAllSuppliers)
+func (q *Queries) GetAllSuppliers(ctx context.Context, arg GetAllSuppliersParams) ([]Supplier, error) {
+	rows, err := q.db.QueryContext(ctx, getAllSuppliers,
+		arg.OrgID,
+		arg.IncludeDeleted,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +93,8 @@ func (q *Queries) GetAllSuppliers(ctx context.Context) ([]Supplier, error) {
 			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.OrgID,
 		); err != nil {
 			return nil, err
 		}
@@ -103,11 +110,19 @@ func (q *Queries) GetAllSuppliers(ctx context.Context) ([]Supplier, error) {
 }
 
 const getSupplierById = `-- name: GetSupplierById :one
-SELECT id, name, email, description, phone, country, created_at, updated_at FROM suppliers WHERE id=$1
+SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers WHERE id=$1 AND org_id=$2 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetSupplierById(ctx context.Context, id uuid.UUID) (Supplier, error) {
-	row := q.db.QueryRowContext(ctx, getSupplierById, id)
+type GetSupplierByIdParams struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+}
+
+func (q *Queries) GetSupplierById(ctx context.Context, arg GetSupplierByIdParams) (Supplier, error) {
+	row := q.db.QueryRowContext(ctx, getSupplierById,
+		arg.ID,
+		arg.OrgID,
+	)
 	var i Supplier
 	err := row.Scan(
 		&i.ID,
@@ -118,42 +133,153 @@ func (q *Queries) GetSupplierById(ctx context.Context, id uuid.UUID) (Supplier,
 		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
 
+const getSupplierByIdIncludingDeleted = `-- name: GetSupplierByIdIncludingDeleted :one
+SELECT id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id FROM suppliers WHERE id=$1 AND org_id=$2
+`
+
+type GetSupplierByIdIncludingDeletedParams struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+}
+
+func (q *Queries) GetSupplierByIdIncludingDeleted(ctx context.Context, arg GetSupplierByIdIncludingDeletedParams) (Supplier, error) {
+	row := q.db.QueryRowContext(ctx, getSupplierByIdIncludingDeleted,
+		arg.ID,
+		arg.OrgID,
+	)
+	var i Supplier
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Description,
+		&i.Phone,
+		&i.Country,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const purgeDeletedSuppliers = `-- name: PurgeDeletedSuppliers :execrows
+DELETE FROM suppliers WHERE deleted_at < $1::timestamp
+`
+
+func (q *Queries) PurgeDeletedSuppliers(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedSuppliers, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreSupplier = `-- name: RestoreSupplier :one
+UPDATE suppliers
+SET
+deleted_at = NULL,
+updated_at = $3
+WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id
+`
+
+type RestoreSupplierParams struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) RestoreSupplier(ctx context.Context, arg RestoreSupplierParams) (Supplier, error) {
+	row := q.db.QueryRowContext(ctx, restoreSupplier,
+		arg.ID,
+		arg.OrgID,
+		arg.UpdatedAt,
+	)
+	var i Supplier
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Description,
+		&i.Phone,
+		&i.Country,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const softDeleteSupplier = `-- name: SoftDeleteSupplier :execrows
+UPDATE suppliers SET deleted_at = $3
+WHERE id=$1 AND org_id=$2 AND deleted_at IS NULL AND updated_at = $4
+`
+
+type SoftDeleteSupplierParams struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	DeletedAt sql.NullTime
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SoftDeleteSupplier(ctx context.Context, arg SoftDeleteSupplierParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteSupplier,
+		arg.ID,
+		arg.OrgID,
+		arg.DeletedAt,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateSupplier = `-- name: UpdateSupplier :one
 UPDATE suppliers
 SET 
-name = $2,
-email = $3,
-description = $4,
-phone = $5,
-country = $6,
-updated_at = $7
-WHERE id = $1
-RETURNING id, name, email, description, phone, country, created_at, updated_at
+name = $1,
+email = $2,
+description = $3,
+phone = $4,
+country = $5,
+updated_at = $6
+WHERE id = $7 AND org_id = $8 AND deleted_at IS NULL
+AND updated_at = $9
+RETURNING id, name, email, description, phone, country, created_at, updated_at, deleted_at, org_id
 `
 
 type UpdateSupplierParams struct {
-	ID          uuid.UUID
-	Name        string
-	Email       sql.NullString
-	Description sql.NullString
-	Phone       sql.NullString
-	Country     sql.NullString
-	UpdatedAt   time.Time
+	Name              string
+	Email             sql.NullString
+	Description       sql.NullString
+	Phone             sql.NullString
+	Country           sql.NullString
+	UpdatedAt         time.Time
+	ID                uuid.UUID
+	OrgID             uuid.UUID
+	ExpectedUpdatedAt time.Time
 }
 
 func (q *Queries) UpdateSupplier(ctx context.Context, arg UpdateSupplierParams) (Supplier, error) {
 	row := q.db.QueryRowContext(ctx, updateSupplier,
-		arg.ID,
 		arg.Name,
 		arg.Email,
 		arg.Description,
 		arg.Phone,
 		arg.Country,
 		arg.UpdatedAt,
+		arg.ID,
+		arg.OrgID,
+		arg.ExpectedUpdatedAt,
 	)
 	var i Supplier
 	err := row.Scan(
@@ -165,6 +291,8 @@ func (q *Queries) UpdateSupplier(ctx context.Context, arg UpdateSupplierParams)
 		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.OrgID,
 	)
 	return i, err
 }
@@ -129,7 +129,7 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]GetAllUsersRow, error) {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, username, email, password, role, profile_picture_url, name FROM users WHERE email = $1
+SELECT id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -145,12 +145,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Role,
 		&i.ProfilePictureUrl,
 		&i.Name,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, created_at, updated_at, username, email, password, role, profile_picture_url, name FROM users WHERE id = $1
+SELECT id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
@@ -166,6 +167,159 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Role,
 		&i.ProfilePictureUrl,
 		&i.Name,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const setUserEmailVerified = `-- name: SetUserEmailVerified :exec
+UPDATE users SET email_verified_at = $2, updated_at = $2 WHERE id = $1
+`
+
+type SetUserEmailVerifiedParams struct {
+	ID              uuid.UUID
+	EmailVerifiedAt sql.NullTime
+}
+
+func (q *Queries) SetUserEmailVerified(ctx context.Context, arg SetUserEmailVerifiedParams) error {
+	_, err := q.db.ExecContext(ctx, setUserEmailVerified,
+		arg.ID,
+		arg.EmailVerifiedAt,
+	)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET name = $2, username = $3, email = $4, role = $5, profile_picture_url = $6, updated_at = $7,
+    email_verified_at = CASE WHEN email = $4 THEN email_verified_at END
+WHERE id = $1
+RETURNING id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at
+`
+
+type UpdateUserParams struct {
+	ID                uuid.UUID
+	Name              string
+	Username          string
+	Email             string
+	Role              string
+	ProfilePictureUrl sql.NullString
+	UpdatedAt         time.Time
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.ID,
+		arg.Name,
+		arg.Username,
+		arg.Email,
+		arg.Role,
+		arg.ProfilePictureUrl,
+		arg.UpdatedAt,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.Role,
+		&i.ProfilePictureUrl,
+		&i.Name,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users SET password = $2, updated_at = $3 WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID        uuid.UUID
+	Password  string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword,
+		arg.ID,
+		arg.Password,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET name = $2, username = $3, profile_picture_url = $4, updated_at = $5
+WHERE id = $1
+RETURNING id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at
+`
+
+type UpdateUserProfileParams struct {
+	ID                uuid.UUID
+	Name              string
+	Username          string
+	ProfilePictureUrl sql.NullString
+	UpdatedAt         time.Time
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.Name,
+		arg.Username,
+		arg.ProfilePictureUrl,
+		arg.UpdatedAt,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.Role,
+		&i.ProfilePictureUrl,
+		&i.Name,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users SET role = $2, updated_at = $3 WHERE id = $1
+RETURNING id, created_at, updated_at, username, email, password, role, profile_picture_url, name, email_verified_at
+`
+
+type UpdateUserRoleParams struct {
+	ID        uuid.UUID
+	Role      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole,
+		arg.ID,
+		arg.Role,
+		arg.UpdatedAt,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.Role,
+		&i.ProfilePictureUrl,
+		&i.Name,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,669 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+// Roles
+
+func (s *Store) CreateRole(ctx context.Context, arg database.CreateRoleParams) (database.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.roles[arg.Name]; ok {
+		return database.Role{}, uniqueViolation("roles_pkey")
+	}
+	role := database.Role{
+		Name: arg.Name,
+		Description: arg.Description,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.CreatedAt,
+	}
+	s.roles[role.Name] = role
+	return role, nil
+}
+
+func (s *Store) GetRoles(ctx context.Context) ([]database.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.roles, nil, func(a, b database.Role) bool {
+		return a.Name < b.Name
+	}), nil
+}
+
+func (s *Store) GetRole(ctx context.Context, name string) (database.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role, ok := s.roles[name]
+	if !ok {
+		return database.Role{}, sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func (s *Store) UpdateRole(ctx context.Context, arg database.UpdateRoleParams) (database.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role, ok := s.roles[arg.Name]
+	if !ok {
+		return database.Role{}, sql.ErrNoRows
+	}
+	role.Description = arg.Description
+	role.UpdatedAt = arg.UpdatedAt
+	s.roles[role.Name] = role
+	return role, nil
+}
+
+// DeleteRole only deletes roles that aren't built in, and like the foreign
+// keys refuses while anything still has the role
+func (s *Store) DeleteRole(ctx context.Context, name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role, ok := s.roles[name]
+	if !ok || role.BuiltIn {
+		return 0, nil
+	}
+	for _, u := range s.users {
+		if u.Role == name {
+			return 0, foreignKeyViolation("users_role_fkey")
+		}
+	}
+	for _, member := range s.members {
+		if member.Role == name {
+			return 0, foreignKeyViolation("organization_members_role_fkey")
+		}
+	}
+	for _, account := range s.serviceAccounts {
+		if account.Role == name {
+			return 0, foreignKeyViolation("service_accounts_role_fkey")
+		}
+	}
+	for _, invitation := range s.invitations {
+		if invitation.Role == name {
+			return 0, foreignKeyViolation("invitations_role_fkey")
+		}
+	}
+	delete(s.roles, name)
+	for permission := range s.rolePermissions {
+		if permission.Role == name {
+			delete(s.rolePermissions, permission)
+		}
+	}
+	return 1, nil
+}
+
+func (s *Store) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var permissions []string
+	for permission := range s.rolePermissions {
+		if permission.Role == role {
+			permissions = append(permissions, permission.Permission)
+		}
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (s *Store) GetAllRolePermissions(ctx context.Context) ([]database.RolePermission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var permissions []database.RolePermission
+	for permission := range s.rolePermissions {
+		permissions = append(permissions, permission)
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].Role != permissions[j].Role {
+			return permissions[i].Role < permissions[j].Role
+		}
+		return permissions[i].Permission < permissions[j].Permission
+	})
+	return permissions, nil
+}
+
+func (s *Store) AddRolePermission(ctx context.Context, arg database.AddRolePermissionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.roles[arg.Role]; !ok {
+		return foreignKeyViolation("role_permissions_role_fkey")
+	}
+	permission := database.RolePermission{Role: arg.Role, Permission: arg.Permission}
+	if s.rolePermissions[permission] {
+		return uniqueViolation("role_permissions_pkey")
+	}
+	s.rolePermissions[permission] = true
+	return nil
+}
+
+func (s *Store) DeleteRolePermissions(ctx context.Context, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for permission := range s.rolePermissions {
+		if permission.Role == role {
+			delete(s.rolePermissions, permission)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ReplaceRolePermissions(ctx context.Context, role string, permissions []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.roles[role]; !ok {
+		return foreignKeyViolation("role_permissions_role_fkey")
+	}
+	replacement := map[database.RolePermission]bool{}
+	for _, permission := range permissions {
+		key := database.RolePermission{Role: role, Permission: permission}
+		if replacement[key] {
+			return uniqueViolation("role_permissions_pkey")
+		}
+		replacement[key] = true
+	}
+
+	for permission := range s.rolePermissions {
+		if permission.Role == role {
+			delete(s.rolePermissions, permission)
+		}
+	}
+	for permission := range replacement {
+		s.rolePermissions[permission] = true
+	}
+	return nil
+}
+
+// Organisations
+
+func (s *Store) CreateOrganization(ctx context.Context, arg database.CreateOrganizationParams) (database.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.organizations[arg.ID]; ok {
+		return database.Organization{}, uniqueViolation("organizations_pkey")
+	}
+	for _, other := range s.organizations {
+		if other.Slug == arg.Slug {
+			return database.Organization{}, uniqueViolation("organizations_slug_key")
+		}
+	}
+	org := database.Organization{
+		ID: arg.ID,
+		Name: arg.Name,
+		Slug: arg.Slug,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.CreatedAt,
+	}
+	s.organizations[org.ID] = org
+	return org, nil
+}
+
+func (s *Store) GetOrganizations(ctx context.Context) ([]database.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.organizations, nil, func(a, b database.Organization) bool {
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID.String() < b.ID.String()
+	}), nil
+}
+
+func (s *Store) GetOrganizationById(ctx context.Context, id uuid.UUID) (database.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org, ok := s.organizations[id]
+	if !ok {
+		return database.Organization{}, sql.ErrNoRows
+	}
+	return org, nil
+}
+
+func (s *Store) GetOrganizationBySlug(ctx context.Context, slug string) (database.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, org := range s.organizations {
+		if org.Slug == slug {
+			return org, nil
+		}
+	}
+	return database.Organization{}, sql.ErrNoRows
+}
+
+// SetOrganizationMember adds the member, or changes their role when they
+// already are one
+func (s *Store) SetOrganizationMember(ctx context.Context, arg database.SetOrganizationMemberParams) (database.OrganizationMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.organizations[arg.OrgID]; !ok {
+		return database.OrganizationMember{}, foreignKeyViolation("organization_members_org_id_fkey")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.OrganizationMember{}, foreignKeyViolation("organization_members_user_id_fkey")
+	}
+	if _, ok := s.roles[arg.Role]; !ok {
+		return database.OrganizationMember{}, foreignKeyViolation("organization_members_role_fkey")
+	}
+	key := memberKey{orgId: arg.OrgID, userId: arg.UserID}
+	member, ok := s.members[key]
+	if !ok {
+		member = database.OrganizationMember{
+			OrgID: arg.OrgID,
+			UserID: arg.UserID,
+			CreatedAt: arg.CreatedAt,
+		}
+	}
+	member.Role = arg.Role
+	s.members[key] = member
+	return member, nil
+}
+
+func (s *Store) GetOrganizationMember(ctx context.Context, arg database.GetOrganizationMemberParams) (database.OrganizationMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	member, ok := s.members[memberKey{orgId: arg.OrgID, userId: arg.UserID}]
+	if !ok {
+		return database.OrganizationMember{}, sql.ErrNoRows
+	}
+	return member, nil
+}
+
+func (s *Store) GetOrganizationMembers(ctx context.Context, orgID uuid.UUID) ([]database.OrganizationMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.members, func(member database.OrganizationMember) bool {
+		return member.OrgID == orgID
+	}, func(a, b database.OrganizationMember) bool {
+		return createdFirst(a.CreatedAt, b.CreatedAt, a.UserID, b.UserID)
+	}), nil
+}
+
+func (s *Store) RemoveOrganizationMember(ctx context.Context, arg database.RemoveOrganizationMemberParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := memberKey{orgId: arg.OrgID, userId: arg.UserID}
+	if _, ok := s.members[key]; !ok {
+		return 0, nil
+	}
+	delete(s.members, key)
+	return 1, nil
+}
+
+func (s *Store) GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]database.GetUserOrganizationsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := sorted(s.members, func(member database.OrganizationMember) bool {
+		return member.UserID == userID
+	}, func(a, b database.OrganizationMember) bool {
+		return createdFirst(a.CreatedAt, b.CreatedAt, a.OrgID, b.OrgID)
+	})
+	var rows []database.GetUserOrganizationsRow
+	for _, member := range members {
+		org := s.organizations[member.OrgID]
+		rows = append(rows, database.GetUserOrganizationsRow{
+			ID: org.ID,
+			Name: org.Name,
+			Slug: org.Slug,
+			Role: member.Role,
+		})
+	}
+	return rows, nil
+}
+
+func (s *Store) CountOrganizationMembersWithRole(ctx context.Context, role string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for _, member := range s.members {
+		if member.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Service accounts and API keys
+
+func (s *Store) CreateServiceAccount(ctx context.Context, arg database.CreateServiceAccountParams) (database.ServiceAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.serviceAccounts[arg.ID]; ok {
+		return database.ServiceAccount{}, uniqueViolation("service_accounts_pkey")
+	}
+	for _, other := range s.serviceAccounts {
+		if other.Name == arg.Name {
+			return database.ServiceAccount{}, uniqueViolation("service_accounts_name_key")
+		}
+	}
+	if _, ok := s.organizations[arg.OrgID]; !ok {
+		return database.ServiceAccount{}, foreignKeyViolation("service_accounts_org_id_fkey")
+	}
+	if _, ok := s.roles[arg.Role]; !ok {
+		return database.ServiceAccount{}, foreignKeyViolation("service_accounts_role_fkey")
+	}
+	if arg.CreatedBy.Valid {
+		if _, ok := s.users[arg.CreatedBy.UUID]; !ok {
+			return database.ServiceAccount{}, foreignKeyViolation("service_accounts_created_by_fkey")
+		}
+	}
+	account := database.ServiceAccount{
+		ID: arg.ID,
+		OrgID: arg.OrgID,
+		Name: arg.Name,
+		Description: arg.Description,
+		Role: arg.Role,
+		CreatedBy: arg.CreatedBy,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.CreatedAt,
+	}
+	s.serviceAccounts[account.ID] = account
+	return account, nil
+}
+
+func (s *Store) GetServiceAccounts(ctx context.Context, orgID uuid.UUID) ([]database.ServiceAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.serviceAccounts, func(a database.ServiceAccount) bool {
+		return a.OrgID == orgID
+	}, func(a, b database.ServiceAccount) bool {
+		return a.Name < b.Name
+	}), nil
+}
+
+func (s *Store) GetServiceAccountById(ctx context.Context, id uuid.UUID) (database.ServiceAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.serviceAccounts[id]
+	if !ok {
+		return database.ServiceAccount{}, sql.ErrNoRows
+	}
+	return account, nil
+}
+
+// DeleteServiceAccount deletes the account along with its keys and their
+// scopes
+func (s *Store) DeleteServiceAccount(ctx context.Context, arg database.DeleteServiceAccountParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := arg.ID
+	if account, ok := s.serviceAccounts[id]; !ok || account.OrgID != arg.OrgID {
+		return 0, nil
+	}
+	delete(s.serviceAccounts, id)
+	for keyId, key := range s.apiKeys {
+		if key.ServiceAccountID != id {
+			continue
+		}
+		delete(s.apiKeys, keyId)
+		for scope := range s.apiKeyScopes {
+			if scope.apiKeyId == keyId {
+				delete(s.apiKeyScopes, scope)
+			}
+		}
+	}
+	return 1, nil
+}
+
+func (s *Store) CountServiceAccountsWithRole(ctx context.Context, role string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for _, account := range s.serviceAccounts {
+		if account.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apiKeys[arg.ID]; ok {
+		return database.ApiKey{}, uniqueViolation("api_keys_pkey")
+	}
+	if _, ok := s.serviceAccounts[arg.ServiceAccountID]; !ok {
+		return database.ApiKey{}, foreignKeyViolation("api_keys_service_account_id_fkey")
+	}
+	for _, other := range s.apiKeys {
+		if other.Prefix == arg.Prefix {
+			return database.ApiKey{}, uniqueViolation("api_keys_prefix_key")
+		}
+	}
+	key := database.ApiKey{
+		ID: arg.ID,
+		ServiceAccountID: arg.ServiceAccountID,
+		Name: arg.Name,
+		Prefix: arg.Prefix,
+		SecretHash: arg.SecretHash,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	s.apiKeys[key.ID] = key
+	return key, nil
+}
+
+func (s *Store) GetAPIKeyByPrefix(ctx context.Context, prefix string) (database.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.apiKeys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+	return database.ApiKey{}, sql.ErrNoRows
+}
+
+func (s *Store) GetServiceAccountAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]database.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.apiKeys, func(key database.ApiKey) bool {
+		return key.ServiceAccountID == serviceAccountID
+	}, func(a, b database.ApiKey) bool {
+		return createdFirst(b.CreatedAt, a.CreatedAt, b.ID, a.ID)
+	}), nil
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, arg database.RevokeAPIKeyParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.apiKeys[arg.ID]
+	if !ok || key.ServiceAccountID != arg.ServiceAccountID || key.RevokedAt.Valid {
+		return 0, nil
+	}
+	key.RevokedAt = arg.RevokedAt
+	s.apiKeys[key.ID] = key
+	return 1, nil
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, arg database.TouchAPIKeyParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.apiKeys[arg.ID]; ok {
+		key.LastUsedAt = arg.LastUsedAt
+		s.apiKeys[key.ID] = key
+	}
+	return nil
+}
+
+func (s *Store) AddAPIKeyScope(ctx context.Context, arg database.AddAPIKeyScopeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apiKeys[arg.ApiKeyID]; !ok {
+		return foreignKeyViolation("api_key_scopes_api_key_id_fkey")
+	}
+	scope := apiKeyScope{apiKeyId: arg.ApiKeyID, permission: arg.Permission}
+	if s.apiKeyScopes[scope] {
+		return uniqueViolation("api_key_scopes_pkey")
+	}
+	s.apiKeyScopes[scope] = true
+	return nil
+}
+
+func (s *Store) GetAPIKeyScopes(ctx context.Context, apiKeyID uuid.UUID) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var permissions []string
+	for scope := range s.apiKeyScopes {
+		if scope.apiKeyId == apiKeyID {
+			permissions = append(permissions, scope.permission)
+		}
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (s *Store) GetServiceAccountAPIKeyScopes(ctx context.Context, serviceAccountID uuid.UUID) ([]database.ApiKeyScope, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var scopes []database.ApiKeyScope
+	for scope := range s.apiKeyScopes {
+		if s.apiKeys[scope.apiKeyId].ServiceAccountID == serviceAccountID {
+			scopes = append(scopes, database.ApiKeyScope{ApiKeyID: scope.apiKeyId, Permission: scope.permission})
+		}
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].Permission != scopes[j].Permission {
+			return scopes[i].Permission < scopes[j].Permission
+		}
+		return scopes[i].ApiKeyID.String() < scopes[j].ApiKeyID.String()
+	})
+	return scopes, nil
+}
+
+// Audit log
+
+func (s *Store) CreateAuditLog(ctx context.Context, arg database.CreateAuditLogParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.auditLogs {
+		if other.ID == arg.ID {
+			return uniqueViolation("audit_logs_pkey")
+		}
+	}
+	s.auditLogs = append(s.auditLogs, database.AuditLog{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		ActorID: arg.ActorID,
+		ActorEmail: arg.ActorEmail,
+		Action: arg.Action,
+		Entity: arg.Entity,
+		EntityID: arg.EntityID,
+		Changes: arg.Changes,
+		RequestID: arg.RequestID,
+		Ip: arg.Ip,
+	})
+	return nil
+}
+
+// GetAuditLogs returns the newest entries first, optionally only those for
+// one entity
+func (s *Store) GetAuditLogs(ctx context.Context, arg database.GetAuditLogsParams) ([]database.AuditLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var logs []database.AuditLog
+	for i := len(s.auditLogs) - 1; i >= 0; i-- {
+		entry := s.auditLogs[i]
+		if arg.Entity.Valid && entry.Entity != arg.Entity.String {
+			continue
+		}
+		if arg.EntityID.Valid && entry.EntityID != arg.EntityID.UUID {
+			continue
+		}
+		logs = append(logs, entry)
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].CreatedAt.After(logs[j].CreatedAt)
+	})
+	if int(arg.RowLimit) < len(logs) {
+		logs = logs[:arg.RowLimit]
+	}
+	return logs, nil
+}
+
+// Invitations
+
+func (s *Store) CreateInvitation(ctx context.Context, arg database.CreateInvitationParams) (database.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.invitations[arg.ID]; ok {
+		return database.Invitation{}, uniqueViolation("invitations_pkey")
+	}
+	if _, ok := s.roles[arg.Role]; !ok {
+		return database.Invitation{}, foreignKeyViolation("invitations_role_fkey")
+	}
+	if arg.InvitedBy.Valid {
+		if _, ok := s.users[arg.InvitedBy.UUID]; !ok {
+			return database.Invitation{}, foreignKeyViolation("invitations_invited_by_fkey")
+		}
+	}
+	if arg.OrgID.Valid {
+		if _, ok := s.organizations[arg.OrgID.UUID]; !ok {
+			return database.Invitation{}, foreignKeyViolation("invitations_org_id_fkey")
+		}
+	}
+	for _, other := range s.invitations {
+		if other.TokenHash == arg.TokenHash {
+			return database.Invitation{}, uniqueViolation("invitations_token_hash_key")
+		}
+	}
+	invitation := database.Invitation{
+		ID: arg.ID,
+		Email: arg.Email,
+		Role: arg.Role,
+		TokenHash: arg.TokenHash,
+		InvitedBy: arg.InvitedBy,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+		OrgID: arg.OrgID,
+	}
+	s.invitations[invitation.ID] = invitation
+	return invitation, nil
+}
+
+func (s *Store) GetInvitationByHash(ctx context.Context, tokenHash string) (database.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, invitation := range s.invitations {
+		if invitation.TokenHash == tokenHash {
+			return invitation, nil
+		}
+	}
+	return database.Invitation{}, sql.ErrNoRows
+}
+
+func (s *Store) GetPendingInvitations(ctx context.Context, expiresAt time.Time) ([]database.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.invitations, func(invitation database.Invitation) bool {
+		return !invitation.AcceptedAt.Valid && invitation.ExpiresAt.After(expiresAt)
+	}, func(a, b database.Invitation) bool {
+		return createdFirst(b.CreatedAt, a.CreatedAt, b.ID, a.ID)
+	}), nil
+}
+
+func (s *Store) AcceptInvitation(ctx context.Context, arg database.AcceptInvitationParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invitation, ok := s.invitations[arg.ID]
+	if !ok || invitation.AcceptedAt.Valid {
+		return 0, nil
+	}
+	invitation.AcceptedAt = arg.AcceptedAt
+	s.invitations[arg.ID] = invitation
+	return 1, nil
+}
+
+func (s *Store) DeleteInvitation(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invitation, ok := s.invitations[id]
+	if !ok || invitation.AcceptedAt.Valid {
+		return 0, nil
+	}
+	delete(s.invitations, id)
+	return 1, nil
+}
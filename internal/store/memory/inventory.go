@@ -0,0 +1,523 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+// Products
+
+func (s *Store) checkProduct(p database.Product) error {
+	if _, ok := s.organizations[p.OrgID]; !ok {
+		return foreignKeyViolation("products_org_id_fkey")
+	}
+	if p.CategoryID.Valid {
+		if c, ok := s.categories[p.CategoryID.UUID]; !ok || c.OrgID != p.OrgID {
+			return foreignKeyViolation("products_org_category_fkey")
+		}
+	}
+	if p.SupplierID.Valid {
+		if sup, ok := s.suppliers[p.SupplierID.UUID]; !ok || sup.OrgID != p.OrgID {
+			return foreignKeyViolation("products_org_supplier_fkey")
+		}
+	}
+	if p.Sku.Valid && !p.DeletedAt.Valid {
+		for _, other := range s.products {
+			if other.ID != p.ID && other.OrgID == p.OrgID && !other.DeletedAt.Valid &&
+				other.Sku.Valid && other.Sku.String == p.Sku.String {
+				return uniqueViolation("products_sku_key")
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateProduct(ctx context.Context, arg database.CreateProductParams) (database.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[arg.ID]; ok {
+		return database.Product{}, uniqueViolation("products_pkey")
+	}
+	p := database.Product{
+		ID: arg.ID,
+		OrgID: arg.OrgID,
+		Name: arg.Name,
+		Description: arg.Description,
+		Price: arg.Price,
+		StockLevel: arg.StockLevel,
+		CategoryID: arg.CategoryID,
+		SupplierID: arg.SupplierID,
+		Sku: arg.Sku,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+	}
+	if err := s.checkProduct(p); err != nil {
+		return database.Product{}, err
+	}
+	s.products[p.ID] = p
+	return p, nil
+}
+
+func (s *Store) listProducts(orgId uuid.UUID, includeDeleted bool) []database.Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.products, func(p database.Product) bool {
+		return p.OrgID == orgId && (!p.DeletedAt.Valid || includeDeleted)
+	}, func(a, b database.Product) bool {
+		return createdFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID)
+	})
+}
+
+func (s *Store) GetProducts(ctx context.Context, arg database.GetProductsParams) ([]database.Product, error) {
+	return s.listProducts(arg.OrgID, arg.IncludeDeleted), nil
+}
+
+func (s *Store) IterProducts(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(database.Product) error) error {
+	return iterate(s.listProducts(orgId, includeDeleted), fn)
+}
+
+// product returns the organisation's product, skipping soft deleted ones
+// unless includeDeleted is set
+func (s *Store) product(id, orgId uuid.UUID, includeDeleted bool) (database.Product, bool) {
+	p, ok := s.products[id]
+	if !ok || p.OrgID != orgId || (p.DeletedAt.Valid && !includeDeleted) {
+		return database.Product{}, false
+	}
+	return p, true
+}
+
+func (s *Store) GetProduct(ctx context.Context, arg database.GetProductParams) (database.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.product(arg.ID, arg.OrgID, false)
+	if !ok {
+		return database.Product{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (s *Store) GetProductIncludingDeleted(ctx context.Context, arg database.GetProductIncludingDeletedParams) (database.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.product(arg.ID, arg.OrgID, true)
+	if !ok {
+		return database.Product{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (s *Store) UpdateProduct(ctx context.Context, arg database.UpdateProductParams) (database.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.product(arg.ID, arg.OrgID, false)
+	if !ok || !p.UpdatedAt.Equal(arg.ExpectedUpdatedAt) {
+		return database.Product{}, sql.ErrNoRows
+	}
+	p.Name = arg.Name
+	p.Description = arg.Description
+	p.Price = arg.Price
+	p.StockLevel = arg.StockLevel
+	p.CategoryID = arg.CategoryID
+	p.SupplierID = arg.SupplierID
+	p.Sku = arg.Sku
+	p.UpdatedAt = arg.UpdatedAt
+	if err := s.checkProduct(p); err != nil {
+		return database.Product{}, err
+	}
+	s.products[p.ID] = p
+	return p, nil
+}
+
+func (s *Store) AdjustProductStock(ctx context.Context, arg database.AdjustProductStockParams) (database.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.product(arg.ID, arg.OrgID, false)
+	if !ok {
+		return database.Product{}, sql.ErrNoRows
+	}
+	level := p.StockLevel.Int32 + arg.Adjustment
+	if level < 0 {
+		return database.Product{}, sql.ErrNoRows
+	}
+	p.StockLevel = sql.NullInt32{Int32: level, Valid: true}
+	p.UpdatedAt = arg.UpdatedAt
+	s.products[p.ID] = p
+	return p, nil
+}
+
+func (s *Store) SoftDeleteProduct(ctx context.Context, arg database.SoftDeleteProductParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.product(arg.ID, arg.OrgID, false)
+	if !ok || !p.UpdatedAt.Equal(arg.UpdatedAt) {
+		return 0, nil
+	}
+	p.DeletedAt = arg.DeletedAt
+	s.products[p.ID] = p
+	return 1, nil
+}
+
+func (s *Store) RestoreProduct(ctx context.Context, arg database.RestoreProductParams) (database.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.product(arg.ID, arg.OrgID, true)
+	if !ok || !p.DeletedAt.Valid {
+		return database.Product{}, sql.ErrNoRows
+	}
+	p.DeletedAt = sql.NullTime{}
+	p.UpdatedAt = arg.UpdatedAt
+	if err := s.checkProduct(p); err != nil {
+		return database.Product{}, err
+	}
+	s.products[p.ID] = p
+	return p, nil
+}
+
+func (s *Store) PurgeDeletedProducts(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var purged int64
+	for id, p := range s.products {
+		if p.DeletedAt.Valid && p.DeletedAt.Time.Before(before) {
+			delete(s.products, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *Store) CountProductsByOrganization(ctx context.Context) ([]database.CountProductsByOrganizationRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := map[uuid.UUID]database.CountProductsByOrganizationRow{}
+	for id, org := range s.organizations {
+		counts[id] = database.CountProductsByOrganizationRow{Slug: org.Slug}
+	}
+	for _, p := range s.products {
+		count, ok := counts[p.OrgID]
+		if !ok || p.DeletedAt.Valid {
+			continue
+		}
+		count.Products++
+		if p.StockLevel.Int32 == 0 {
+			count.OutOfStock++
+		}
+		counts[p.OrgID] = count
+	}
+	return sorted(counts, nil, func(a, b database.CountProductsByOrganizationRow) bool {
+		return a.Slug < b.Slug
+	}), nil
+}
+
+// Categories
+
+func (s *Store) checkCategory(c database.Category) error {
+	if _, ok := s.organizations[c.OrgID]; !ok {
+		return foreignKeyViolation("categories_org_id_fkey")
+	}
+	if !c.DeletedAt.Valid {
+		for _, other := range s.categories {
+			if other.ID != c.ID && other.OrgID == c.OrgID && !other.DeletedAt.Valid && other.Name == c.Name {
+				return uniqueViolation("categories_name_key")
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateCategory(ctx context.Context, arg database.CreateCategoryParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.categories[arg.ID]; ok {
+		return database.Category{}, uniqueViolation("categories_pkey")
+	}
+	c := database.Category{
+		ID: arg.ID,
+		OrgID: arg.OrgID,
+		Name: arg.Name,
+		Description: arg.Description,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		CreatedBy: arg.CreatedBy,
+	}
+	if err := s.checkCategory(c); err != nil {
+		return database.Category{}, err
+	}
+	s.categories[c.ID] = c
+	return c, nil
+}
+
+func (s *Store) listCategories(orgId uuid.UUID, includeDeleted bool) []database.Category {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.categories, func(c database.Category) bool {
+		return c.OrgID == orgId && (!c.DeletedAt.Valid || includeDeleted)
+	}, func(a, b database.Category) bool {
+		return createdFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID)
+	})
+}
+
+func (s *Store) GetCategories(ctx context.Context, arg database.GetCategoriesParams) ([]database.Category, error) {
+	return s.listCategories(arg.OrgID, arg.IncludeDeleted), nil
+}
+
+func (s *Store) IterCategories(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(database.Category) error) error {
+	return iterate(s.listCategories(orgId, includeDeleted), fn)
+}
+
+func (s *Store) category(id, orgId uuid.UUID, includeDeleted bool) (database.Category, bool) {
+	c, ok := s.categories[id]
+	if !ok || c.OrgID != orgId || (c.DeletedAt.Valid && !includeDeleted) {
+		return database.Category{}, false
+	}
+	return c, true
+}
+
+func (s *Store) GetCategoryById(ctx context.Context, arg database.GetCategoryByIdParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.category(arg.ID, arg.OrgID, false)
+	if !ok {
+		return database.Category{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (s *Store) GetCategoryByIdIncludingDeleted(ctx context.Context, arg database.GetCategoryByIdIncludingDeletedParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.category(arg.ID, arg.OrgID, true)
+	if !ok {
+		return database.Category{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (s *Store) UpdateCategory(ctx context.Context, arg database.UpdateCategoryParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.category(arg.ID, arg.OrgID, false)
+	if !ok || !c.UpdatedAt.Equal(arg.ExpectedUpdatedAt) {
+		return database.Category{}, sql.ErrNoRows
+	}
+	c.Name = arg.Name
+	c.Description = arg.Description
+	c.UpdatedAt = arg.UpdatedAt
+	if err := s.checkCategory(c); err != nil {
+		return database.Category{}, err
+	}
+	s.categories[c.ID] = c
+	return c, nil
+}
+
+func (s *Store) SoftDeleteCategory(ctx context.Context, arg database.SoftDeleteCategoryParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.category(arg.ID, arg.OrgID, false)
+	if !ok || !c.UpdatedAt.Equal(arg.UpdatedAt) {
+		return 0, nil
+	}
+	c.DeletedAt = arg.DeletedAt
+	s.categories[c.ID] = c
+	return 1, nil
+}
+
+func (s *Store) RestoreCategory(ctx context.Context, arg database.RestoreCategoryParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.category(arg.ID, arg.OrgID, true)
+	if !ok || !c.DeletedAt.Valid {
+		return database.Category{}, sql.ErrNoRows
+	}
+	c.DeletedAt = sql.NullTime{}
+	c.UpdatedAt = arg.UpdatedAt
+	if err := s.checkCategory(c); err != nil {
+		return database.Category{}, err
+	}
+	s.categories[c.ID] = c
+	return c, nil
+}
+
+// PurgeDeletedCategories deletes the categories and, like ON DELETE SET
+// NULL, takes them off their products
+func (s *Store) PurgeDeletedCategories(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var purged int64
+	for id, c := range s.categories {
+		if c.DeletedAt.Valid && c.DeletedAt.Time.Before(before) {
+			delete(s.categories, id)
+			purged++
+			for pid, p := range s.products {
+				if p.CategoryID.Valid && p.CategoryID.UUID == id {
+					p.CategoryID = uuid.NullUUID{}
+					s.products[pid] = p
+				}
+			}
+		}
+	}
+	return purged, nil
+}
+
+// Suppliers
+
+func (s *Store) checkSupplier(sup database.Supplier) error {
+	if _, ok := s.organizations[sup.OrgID]; !ok {
+		return foreignKeyViolation("suppliers_org_id_fkey")
+	}
+	if sup.Email.Valid && !sup.DeletedAt.Valid {
+		for _, other := range s.suppliers {
+			if other.ID != sup.ID && other.OrgID == sup.OrgID && !other.DeletedAt.Valid &&
+				other.Email.Valid && other.Email.String == sup.Email.String {
+				return uniqueViolation("suppliers_email_key")
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateSupplier(ctx context.Context, arg database.CreateSupplierParams) (database.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.suppliers[arg.ID]; ok {
+		return database.Supplier{}, uniqueViolation("suppliers_pkey")
+	}
+	sup := database.Supplier{
+		ID: arg.ID,
+		OrgID: arg.OrgID,
+		Name: arg.Name,
+		Email: arg.Email,
+		Description: arg.Description,
+		Phone: arg.Phone,
+		Country: arg.Country,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+	}
+	if err := s.checkSupplier(sup); err != nil {
+		return database.Supplier{}, err
+	}
+	s.suppliers[sup.ID] = sup
+	return sup, nil
+}
+
+func (s *Store) listSuppliers(orgId uuid.UUID, includeDeleted bool) []database.Supplier {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.suppliers, func(sup database.Supplier) bool {
+		return sup.OrgID == orgId && (!sup.DeletedAt.Valid || includeDeleted)
+	}, func(a, b database.Supplier) bool {
+		return createdFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID)
+	})
+}
+
+func (s *Store) GetAllSuppliers(ctx context.Context, arg database.GetAllSuppliersParams) ([]database.Supplier, error) {
+	return s.listSuppliers(arg.OrgID, arg.IncludeDeleted), nil
+}
+
+func (s *Store) IterSuppliers(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(database.Supplier) error) error {
+	return iterate(s.listSuppliers(orgId, includeDeleted), fn)
+}
+
+func (s *Store) supplier(id, orgId uuid.UUID, includeDeleted bool) (database.Supplier, bool) {
+	sup, ok := s.suppliers[id]
+	if !ok || sup.OrgID != orgId || (sup.DeletedAt.Valid && !includeDeleted) {
+		return database.Supplier{}, false
+	}
+	return sup, true
+}
+
+func (s *Store) GetSupplierById(ctx context.Context, arg database.GetSupplierByIdParams) (database.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sup, ok := s.supplier(arg.ID, arg.OrgID, false)
+	if !ok {
+		return database.Supplier{}, sql.ErrNoRows
+	}
+	return sup, nil
+}
+
+func (s *Store) GetSupplierByIdIncludingDeleted(ctx context.Context, arg database.GetSupplierByIdIncludingDeletedParams) (database.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sup, ok := s.supplier(arg.ID, arg.OrgID, true)
+	if !ok {
+		return database.Supplier{}, sql.ErrNoRows
+	}
+	return sup, nil
+}
+
+func (s *Store) UpdateSupplier(ctx context.Context, arg database.UpdateSupplierParams) (database.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sup, ok := s.supplier(arg.ID, arg.OrgID, false)
+	if !ok || !sup.UpdatedAt.Equal(arg.ExpectedUpdatedAt) {
+		return database.Supplier{}, sql.ErrNoRows
+	}
+	sup.Name = arg.Name
+	sup.Email = arg.Email
+	sup.Description = arg.Description
+	sup.Phone = arg.Phone
+	sup.Country = arg.Country
+	sup.UpdatedAt = arg.UpdatedAt
+	if err := s.checkSupplier(sup); err != nil {
+		return database.Supplier{}, err
+	}
+	s.suppliers[sup.ID] = sup
+	return sup, nil
+}
+
+func (s *Store) SoftDeleteSupplier(ctx context.Context, arg database.SoftDeleteSupplierParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sup, ok := s.supplier(arg.ID, arg.OrgID, false)
+	if !ok || !sup.UpdatedAt.Equal(arg.UpdatedAt) {
+		return 0, nil
+	}
+	sup.DeletedAt = arg.DeletedAt
+	s.suppliers[sup.ID] = sup
+	return 1, nil
+}
+
+func (s *Store) RestoreSupplier(ctx context.Context, arg database.RestoreSupplierParams) (database.Supplier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sup, ok := s.supplier(arg.ID, arg.OrgID, true)
+	if !ok || !sup.DeletedAt.Valid {
+		return database.Supplier{}, sql.ErrNoRows
+	}
+	sup.DeletedAt = sql.NullTime{}
+	sup.UpdatedAt = arg.UpdatedAt
+	if err := s.checkSupplier(sup); err != nil {
+		return database.Supplier{}, err
+	}
+	s.suppliers[sup.ID] = sup
+	return sup, nil
+}
+
+// PurgeDeletedSuppliers deletes the suppliers and takes them off their
+// products
+func (s *Store) PurgeDeletedSuppliers(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var purged int64
+	for id, sup := range s.suppliers {
+		if sup.DeletedAt.Valid && sup.DeletedAt.Time.Before(before) {
+			delete(s.suppliers, id)
+			purged++
+			for pid, p := range s.products {
+				if p.SupplierID.Valid && p.SupplierID.UUID == id {
+					p.SupplierID = uuid.NullUUID{}
+					s.products[pid] = p
+				}
+			}
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,175 @@
+// Package memory is an in-memory store.Store for tests and trying the API
+// out without Postgres. It enforces the same unique and foreign key
+// constraints as the migrations and reports violations as *pq.Error, so
+// handlers can't tell it apart from the database.
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+)
+
+// DefaultOrgID is the organisation the migrations create
+var DefaultOrgID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+type apiKeyScope struct {
+	apiKeyId   uuid.UUID
+	permission string
+}
+
+type memberKey struct {
+	orgId  uuid.UUID
+	userId uuid.UUID
+}
+
+// Store keeps every table in maps guarded by one mutex
+type Store struct {
+	mu sync.Mutex
+
+	products   map[uuid.UUID]database.Product
+	categories map[uuid.UUID]database.Category
+	suppliers  map[uuid.UUID]database.Supplier
+
+	users          map[uuid.UUID]database.User
+	sessions       map[uuid.UUID]database.Session
+	refreshTokens  map[uuid.UUID]database.RefreshToken
+	passwordResets map[uuid.UUID]database.PasswordResetToken
+	verifications  map[uuid.UUID]database.EmailVerificationToken
+	totp           map[uuid.UUID]database.TotpCredential
+	recoveryCodes  map[uuid.UUID]database.RecoveryCode
+	settings       database.SecuritySetting
+	oidcLogins     map[uuid.UUID]database.OidcLogin
+	identities     map[uuid.UUID]database.UserIdentity
+	throttles      map[string]database.LoginThrottle
+
+	roles           map[string]database.Role
+	rolePermissions map[database.RolePermission]bool
+	organizations   map[uuid.UUID]database.Organization
+	members         map[memberKey]database.OrganizationMember
+	serviceAccounts map[uuid.UUID]database.ServiceAccount
+	apiKeys         map[uuid.UUID]database.ApiKey
+	apiKeyScopes    map[apiKeyScope]bool
+	auditLogs       []database.AuditLog
+	invitations     map[uuid.UUID]database.Invitation
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New returns an empty store holding what the migrations seed: the built
+// in roles, the security settings and the default organisation
+func New() *Store {
+	now := time.Now().UTC()
+	s := &Store{
+		products: map[uuid.UUID]database.Product{},
+		categories: map[uuid.UUID]database.Category{},
+		suppliers: map[uuid.UUID]database.Supplier{},
+		users: map[uuid.UUID]database.User{},
+		sessions: map[uuid.UUID]database.Session{},
+		refreshTokens: map[uuid.UUID]database.RefreshToken{},
+		passwordResets: map[uuid.UUID]database.PasswordResetToken{},
+		verifications: map[uuid.UUID]database.EmailVerificationToken{},
+		totp: map[uuid.UUID]database.TotpCredential{},
+		recoveryCodes: map[uuid.UUID]database.RecoveryCode{},
+		settings: database.SecuritySetting{ID: true, UpdatedAt: now},
+		oidcLogins: map[uuid.UUID]database.OidcLogin{},
+		identities: map[uuid.UUID]database.UserIdentity{},
+		throttles: map[string]database.LoginThrottle{},
+		roles: map[string]database.Role{},
+		rolePermissions: map[database.RolePermission]bool{},
+		organizations: map[uuid.UUID]database.Organization{},
+		members: map[memberKey]database.OrganizationMember{},
+		serviceAccounts: map[uuid.UUID]database.ServiceAccount{},
+		apiKeys: map[uuid.UUID]database.ApiKey{},
+		apiKeyScopes: map[apiKeyScope]bool{},
+		invitations: map[uuid.UUID]database.Invitation{},
+	}
+
+	builtIn := map[string]struct {
+		description string
+		permissions []string
+	}{
+		"admin": {"Full access", auth.AllPermissions},
+		"user": {"Can browse the catalogue", []string{auth.ProductsRead, auth.CategoriesRead}},
+		"warehouse_clerk": {"Can adjust stock levels but not prices", []string{
+			auth.ProductsRead, auth.ProductsStock, auth.CategoriesRead, auth.SuppliersRead,
+		}},
+	}
+	for name, role := range builtIn {
+		s.roles[name] = database.Role{
+			Name: name,
+			Description: sql.NullString{String: role.description, Valid: true},
+			BuiltIn: true,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		for _, permission := range role.permissions {
+			s.rolePermissions[database.RolePermission{Role: name, Permission: permission}] = true
+		}
+	}
+
+	s.organizations[DefaultOrgID] = database.Organization{
+		ID: DefaultOrgID,
+		Name: "Default",
+		Slug: "default",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return s
+}
+
+func uniqueViolation(constraint string) error {
+	return &pq.Error{
+		Code: "23505",
+		Constraint: constraint,
+		Message: fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+	}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &pq.Error{
+		Code: "23503",
+		Constraint: constraint,
+		Message: fmt.Sprintf("insert or update violates foreign key constraint %q", constraint),
+	}
+}
+
+// sorted returns the values of m ordered by less, which lists use where the
+// query has no ORDER BY so results are stable
+func sorted[K comparable, V any](m map[K]V, keep func(V) bool, less func(a, b V) bool) []V {
+	var values []V
+	for _, v := range m {
+		if keep == nil || keep(v) {
+			values = append(values, v)
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return less(values[i], values[j]) })
+	return values
+}
+
+// createdFirst orders rows by created_at then id
+func createdFirst(aCreated, bCreated time.Time, aId, bId uuid.UUID) bool {
+	if !aCreated.Equal(bCreated) {
+		return aCreated.Before(bCreated)
+	}
+	return aId.String() < bId.String()
+}
+
+// iterate hands rows to fn after the lock is released, so fn can use the
+// store
+func iterate[T any](rows []T, fn func(T) error) error {
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
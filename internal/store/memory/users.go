@@ -0,0 +1,817 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+// Users
+
+func (s *Store) checkUser(u database.User) error {
+	if _, ok := s.roles[u.Role]; !ok {
+		return foreignKeyViolation("users_role_fkey")
+	}
+	for _, other := range s.users {
+		if other.ID == u.ID {
+			continue
+		}
+		if other.Username == u.Username {
+			return uniqueViolation("users_username_key")
+		}
+		if other.Email == u.Email {
+			return uniqueViolation("users_email_key")
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.ID]; ok {
+		return database.CreateUserRow{}, uniqueViolation("users_pkey")
+	}
+	u := database.User{
+		ID: arg.ID,
+		Username: arg.Username,
+		Email: arg.Email,
+		Name: arg.Name,
+		Password: arg.Password,
+		Role: arg.Role,
+		ProfilePictureUrl: arg.ProfilePictureUrl,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+	}
+	if err := s.checkUser(u); err != nil {
+		return database.CreateUserRow{}, err
+	}
+	s.users[u.ID] = u
+	return database.CreateUserRow{
+		ID: u.ID,
+		Username: u.Username,
+		Email: u.Email,
+		Name: u.Name,
+		Role: u.Role,
+		ProfilePictureUrl: u.ProfilePictureUrl,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}, nil
+}
+
+func (s *Store) listUsers() []database.GetAllUsersRow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := sorted(s.users, nil, func(a, b database.User) bool {
+		return createdFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID)
+	})
+	var rows []database.GetAllUsersRow
+	for _, u := range users {
+		rows = append(rows, database.GetAllUsersRow{
+			ID: u.ID,
+			Username: u.Username,
+			Email: u.Email,
+			Name: u.Name,
+			Role: u.Role,
+			ProfilePictureUrl: u.ProfilePictureUrl,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+		})
+	}
+	return rows
+}
+
+func (s *Store) GetAllUsers(ctx context.Context) ([]database.GetAllUsersRow, error) {
+	return s.listUsers(), nil
+}
+
+func (s *Store) IterUsers(ctx context.Context, fn func(database.GetAllUsersRow) error) error {
+	return iterate(s.listUsers(), fn)
+}
+
+func (s *Store) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+// updateUser applies change to the user and saves it if it still meets the
+// constraints
+func (s *Store) updateUser(id uuid.UUID, change func(*database.User)) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	change(&u)
+	if err := s.checkUser(u); err != nil {
+		return database.User{}, err
+	}
+	s.users[id] = u
+	return u, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	return s.updateUser(arg.ID, func(u *database.User) {
+		u.Name = arg.Name
+		u.Username = arg.Username
+		if u.Email != arg.Email {
+			u.EmailVerifiedAt = sql.NullTime{}
+		}
+		u.Email = arg.Email
+		u.Role = arg.Role
+		u.ProfilePictureUrl = arg.ProfilePictureUrl
+		u.UpdatedAt = arg.UpdatedAt
+	})
+}
+
+func (s *Store) UpdateUserProfile(ctx context.Context, arg database.UpdateUserProfileParams) (database.User, error) {
+	return s.updateUser(arg.ID, func(u *database.User) {
+		u.Name = arg.Name
+		u.Username = arg.Username
+		u.ProfilePictureUrl = arg.ProfilePictureUrl
+		u.UpdatedAt = arg.UpdatedAt
+	})
+}
+
+func (s *Store) UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error) {
+	return s.updateUser(arg.ID, func(u *database.User) {
+		u.Role = arg.Role
+		u.UpdatedAt = arg.UpdatedAt
+	})
+}
+
+func (s *Store) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error {
+	_, err := s.updateUser(arg.ID, func(u *database.User) {
+		u.Password = arg.Password
+		u.UpdatedAt = arg.UpdatedAt
+	})
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) SetUserEmailVerified(ctx context.Context, arg database.SetUserEmailVerifiedParams) error {
+	_, err := s.updateUser(arg.ID, func(u *database.User) {
+		u.EmailVerifiedAt = arg.EmailVerifiedAt
+		u.UpdatedAt = arg.EmailVerifiedAt.Time
+	})
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// DeleteUser never deletes admins. Everything belonging to the user goes
+// with them, as the ON DELETE rules in the migrations do.
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok || u.Role == "admin" {
+		return nil
+	}
+	delete(s.users, id)
+
+	for sid, session := range s.sessions {
+		if session.UserID == id {
+			delete(s.sessions, sid)
+			for tid, token := range s.refreshTokens {
+				if token.SessionID == sid {
+					delete(s.refreshTokens, tid)
+				}
+			}
+		}
+	}
+	for tid, token := range s.passwordResets {
+		if token.UserID == id {
+			delete(s.passwordResets, tid)
+		}
+	}
+	for tid, token := range s.verifications {
+		if token.UserID == id {
+			delete(s.verifications, tid)
+		}
+	}
+	delete(s.totp, id)
+	for cid, code := range s.recoveryCodes {
+		if code.UserID == id {
+			delete(s.recoveryCodes, cid)
+		}
+	}
+	for iid, identity := range s.identities {
+		if identity.UserID == id {
+			delete(s.identities, iid)
+		}
+	}
+	for key := range s.members {
+		if key.userId == id {
+			delete(s.members, key)
+		}
+	}
+	for iid, invitation := range s.invitations {
+		if invitation.InvitedBy.Valid && invitation.InvitedBy.UUID == id {
+			invitation.InvitedBy = uuid.NullUUID{}
+			s.invitations[iid] = invitation
+		}
+	}
+	for aid, account := range s.serviceAccounts {
+		if account.CreatedBy.Valid && account.CreatedBy.UUID == id {
+			account.CreatedBy = uuid.NullUUID{}
+			s.serviceAccounts[aid] = account
+		}
+	}
+	return nil
+}
+
+func (s *Store) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for _, u := range s.users {
+		if u.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Sessions
+
+func (s *Store) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[arg.ID]; ok {
+		return uniqueViolation("sessions_pkey")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return foreignKeyViolation("sessions_user_id_fkey")
+	}
+	if arg.OrgID.Valid {
+		if _, ok := s.organizations[arg.OrgID.UUID]; !ok {
+			return foreignKeyViolation("sessions_org_id_fkey")
+		}
+	}
+	s.sessions[arg.ID] = database.Session{
+		ID: arg.ID,
+		UserID: arg.UserID,
+		OrgID: arg.OrgID,
+		CreatedAt: arg.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) GetSessionById(ctx context.Context, id uuid.UUID) (database.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return database.Session{}, sql.ErrNoRows
+	}
+	return session, nil
+}
+
+func (s *Store) SetSessionOrg(ctx context.Context, arg database.SetSessionOrgParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[arg.ID]
+	if !ok {
+		return nil
+	}
+	if arg.OrgID.Valid {
+		if _, ok := s.organizations[arg.OrgID.UUID]; !ok {
+			return foreignKeyViolation("sessions_org_id_fkey")
+		}
+	}
+	session.OrgID = arg.OrgID
+	s.sessions[arg.ID] = session
+	return nil
+}
+
+// revokeSessions revokes the sessions matching keep that aren't already
+func (s *Store) revokeSessions(revokedAt sql.NullTime, keep func(database.Session) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if !session.RevokedAt.Valid && keep(session) {
+			session.RevokedAt = revokedAt
+			s.sessions[id] = session
+		}
+	}
+}
+
+func (s *Store) RevokeSession(ctx context.Context, arg database.RevokeSessionParams) error {
+	s.revokeSessions(arg.RevokedAt, func(session database.Session) bool {
+		return session.ID == arg.ID
+	})
+	return nil
+}
+
+func (s *Store) RevokeUserSessions(ctx context.Context, arg database.RevokeUserSessionsParams) error {
+	s.revokeSessions(arg.RevokedAt, func(session database.Session) bool {
+		return session.UserID == arg.UserID
+	})
+	return nil
+}
+
+func (s *Store) RevokeOtherUserSessions(ctx context.Context, arg database.RevokeOtherUserSessionsParams) error {
+	s.revokeSessions(arg.RevokedAt, func(session database.Session) bool {
+		return session.UserID == arg.UserID && session.ID != arg.ID
+	})
+	return nil
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.refreshTokens[arg.ID]; ok {
+		return uniqueViolation("refresh_tokens_pkey")
+	}
+	if _, ok := s.sessions[arg.SessionID]; !ok {
+		return foreignKeyViolation("refresh_tokens_session_id_fkey")
+	}
+	for _, other := range s.refreshTokens {
+		if other.TokenHash == arg.TokenHash {
+			return uniqueViolation("refresh_tokens_token_hash_key")
+		}
+	}
+	s.refreshTokens[arg.ID] = database.RefreshToken{
+		ID: arg.ID,
+		SessionID: arg.SessionID,
+		TokenHash: arg.TokenHash,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *Store) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return database.RefreshToken{}, sql.ErrNoRows
+}
+
+func (s *Store) MarkRefreshTokenUsed(ctx context.Context, arg database.MarkRefreshTokenUsedParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.refreshTokens[arg.ID]
+	if !ok || token.UsedAt.Valid {
+		return 0, nil
+	}
+	token.UsedAt = arg.UsedAt
+	s.refreshTokens[arg.ID] = token
+	return 1, nil
+}
+
+// Password reset and email verification tokens
+
+func (s *Store) CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.passwordResets[arg.ID]; ok {
+		return uniqueViolation("password_reset_tokens_pkey")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return foreignKeyViolation("password_reset_tokens_user_id_fkey")
+	}
+	for _, other := range s.passwordResets {
+		if other.TokenHash == arg.TokenHash {
+			return uniqueViolation("password_reset_tokens_token_hash_key")
+		}
+	}
+	s.passwordResets[arg.ID] = database.PasswordResetToken{
+		ID: arg.ID,
+		UserID: arg.UserID,
+		TokenHash: arg.TokenHash,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *Store) GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (database.PasswordResetToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.passwordResets {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return database.PasswordResetToken{}, sql.ErrNoRows
+}
+
+func (s *Store) UsePasswordResetToken(ctx context.Context, arg database.UsePasswordResetTokenParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.passwordResets[arg.ID]
+	if !ok || token.UsedAt.Valid {
+		return 0, nil
+	}
+	token.UsedAt = arg.UsedAt
+	s.passwordResets[arg.ID] = token
+	return 1, nil
+}
+
+func (s *Store) RevokeUserPasswordResetTokens(ctx context.Context, arg database.RevokeUserPasswordResetTokensParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.passwordResets {
+		if token.UserID == arg.UserID && !token.UsedAt.Valid {
+			token.UsedAt = arg.UsedAt
+			s.passwordResets[id] = token
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateEmailVerificationToken(ctx context.Context, arg database.CreateEmailVerificationTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.verifications[arg.ID]; ok {
+		return uniqueViolation("email_verification_tokens_pkey")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return foreignKeyViolation("email_verification_tokens_user_id_fkey")
+	}
+	for _, other := range s.verifications {
+		if other.TokenHash == arg.TokenHash {
+			return uniqueViolation("email_verification_tokens_token_hash_key")
+		}
+	}
+	s.verifications[arg.ID] = database.EmailVerificationToken{
+		ID: arg.ID,
+		UserID: arg.UserID,
+		TokenHash: arg.TokenHash,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *Store) GetEmailVerificationTokenByHash(ctx context.Context, tokenHash string) (database.EmailVerificationToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.verifications {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return database.EmailVerificationToken{}, sql.ErrNoRows
+}
+
+func (s *Store) GetLatestEmailVerificationToken(ctx context.Context, userID uuid.UUID) (database.EmailVerificationToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := sorted(s.verifications, func(token database.EmailVerificationToken) bool {
+		return token.UserID == userID
+	}, func(a, b database.EmailVerificationToken) bool {
+		return createdFirst(b.CreatedAt, a.CreatedAt, b.ID, a.ID)
+	})
+	if len(tokens) == 0 {
+		return database.EmailVerificationToken{}, sql.ErrNoRows
+	}
+	return tokens[0], nil
+}
+
+func (s *Store) UseEmailVerificationToken(ctx context.Context, arg database.UseEmailVerificationTokenParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.verifications[arg.ID]
+	if !ok || token.UsedAt.Valid {
+		return 0, nil
+	}
+	token.UsedAt = arg.UsedAt
+	s.verifications[arg.ID] = token
+	return 1, nil
+}
+
+func (s *Store) RevokeUserEmailVerificationTokens(ctx context.Context, arg database.RevokeUserEmailVerificationTokensParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.verifications {
+		if token.UserID == arg.UserID && !token.UsedAt.Valid {
+			token.UsedAt = arg.UsedAt
+			s.verifications[id] = token
+		}
+	}
+	return nil
+}
+
+// Two factor authentication
+
+func (s *Store) CreateTotpCredential(ctx context.Context, arg database.CreateTotpCredentialParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[arg.UserID]; !ok {
+		return foreignKeyViolation("totp_credentials_user_id_fkey")
+	}
+	if _, ok := s.totp[arg.UserID]; ok {
+		return uniqueViolation("totp_credentials_pkey")
+	}
+	s.totp[arg.UserID] = database.TotpCredential{
+		UserID: arg.UserID,
+		Secret: arg.Secret,
+		CreatedAt: arg.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) GetTotpCredential(ctx context.Context, userID uuid.UUID) (database.TotpCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	credential, ok := s.totp[userID]
+	if !ok {
+		return database.TotpCredential{}, sql.ErrNoRows
+	}
+	return credential, nil
+}
+
+func (s *Store) ConfirmTotpCredential(ctx context.Context, arg database.ConfirmTotpCredentialParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if credential, ok := s.totp[arg.UserID]; ok {
+		credential.ConfirmedAt = arg.ConfirmedAt
+		s.totp[arg.UserID] = credential
+	}
+	return nil
+}
+
+func (s *Store) UseTotpStep(ctx context.Context, arg database.UseTotpStepParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	credential, ok := s.totp[arg.UserID]
+	if !ok || credential.LastUsedStep >= arg.LastUsedStep {
+		return 0, nil
+	}
+	credential.LastUsedStep = arg.LastUsedStep
+	s.totp[arg.UserID] = credential
+	return 1, nil
+}
+
+func (s *Store) DeleteTotpCredential(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.totp, userID)
+	return nil
+}
+
+func (s *Store) CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recoveryCodes[arg.ID]; ok {
+		return uniqueViolation("recovery_codes_pkey")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return foreignKeyViolation("recovery_codes_user_id_fkey")
+	}
+	for _, other := range s.recoveryCodes {
+		if other.UserID == arg.UserID && other.CodeHash == arg.CodeHash {
+			return uniqueViolation("recovery_codes_user_id_code_hash_key")
+		}
+	}
+	s.recoveryCodes[arg.ID] = database.RecoveryCode{
+		ID: arg.ID,
+		UserID: arg.UserID,
+		CodeHash: arg.CodeHash,
+		CreatedAt: arg.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var used int64
+	for id, code := range s.recoveryCodes {
+		if code.UserID == arg.UserID && code.CodeHash == arg.CodeHash && !code.UsedAt.Valid {
+			code.UsedAt = arg.UsedAt
+			s.recoveryCodes[id] = code
+			used++
+		}
+	}
+	return used, nil
+}
+
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, code := range s.recoveryCodes {
+		if code.UserID == userID {
+			delete(s.recoveryCodes, id)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []database.CreateRecoveryCodeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := map[uuid.UUID]bool{}
+	hashes := map[string]bool{}
+	for _, code := range codes {
+		if _, ok := s.users[code.UserID]; !ok {
+			return foreignKeyViolation("recovery_codes_user_id_fkey")
+		}
+		if existing, ok := s.recoveryCodes[code.ID]; ids[code.ID] || ok && existing.UserID != userID {
+			return uniqueViolation("recovery_codes_pkey")
+		}
+		if hashes[code.UserID.String()+code.CodeHash] {
+			return uniqueViolation("recovery_codes_user_id_code_hash_key")
+		}
+		ids[code.ID] = true
+		hashes[code.UserID.String()+code.CodeHash] = true
+	}
+
+	for id, code := range s.recoveryCodes {
+		if code.UserID == userID {
+			delete(s.recoveryCodes, id)
+		}
+	}
+	for _, code := range codes {
+		s.recoveryCodes[code.ID] = database.RecoveryCode{
+			ID: code.ID,
+			UserID: code.UserID,
+			CodeHash: code.CodeHash,
+			CreatedAt: code.CreatedAt,
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetSecuritySettings(ctx context.Context) (database.SecuritySetting, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settings, nil
+}
+
+func (s *Store) UpdateSecuritySettings(ctx context.Context, arg database.UpdateSecuritySettingsParams) (database.SecuritySetting, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings.RequireAdminMfa = arg.RequireAdminMfa
+	s.settings.UpdatedAt = arg.UpdatedAt
+	return s.settings, nil
+}
+
+// Single sign-on
+
+func (s *Store) CreateOIDCLogin(ctx context.Context, arg database.CreateOIDCLoginParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.oidcLogins[arg.ID]; ok {
+		return uniqueViolation("oidc_logins_pkey")
+	}
+	for _, other := range s.oidcLogins {
+		if other.StateHash == arg.StateHash {
+			return uniqueViolation("oidc_logins_state_hash_key")
+		}
+	}
+	s.oidcLogins[arg.ID] = database.OidcLogin{
+		ID: arg.ID,
+		StateHash: arg.StateHash,
+		Nonce: arg.Nonce,
+		CodeVerifier: arg.CodeVerifier,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *Store) GetOIDCLoginByStateHash(ctx context.Context, stateHash string) (database.OidcLogin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, login := range s.oidcLogins {
+		if login.StateHash == stateHash {
+			return login, nil
+		}
+	}
+	return database.OidcLogin{}, sql.ErrNoRows
+}
+
+func (s *Store) UseOIDCLogin(ctx context.Context, arg database.UseOIDCLoginParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	login, ok := s.oidcLogins[arg.ID]
+	if !ok || login.UsedAt.Valid {
+		return 0, nil
+	}
+	login.UsedAt = arg.UsedAt
+	s.oidcLogins[arg.ID] = login
+	return 1, nil
+}
+
+func (s *Store) CreateUserIdentity(ctx context.Context, arg database.CreateUserIdentityParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.identities[arg.ID]; ok {
+		return uniqueViolation("user_identities_pkey")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return foreignKeyViolation("user_identities_user_id_fkey")
+	}
+	for _, other := range s.identities {
+		if other.Issuer == arg.Issuer && other.Subject == arg.Subject {
+			return uniqueViolation("user_identities_issuer_subject_key")
+		}
+	}
+	s.identities[arg.ID] = database.UserIdentity{
+		ID: arg.ID,
+		UserID: arg.UserID,
+		Issuer: arg.Issuer,
+		Subject: arg.Subject,
+		CreatedAt: arg.CreatedAt,
+		LastLoginAt: arg.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) GetUserIdentity(ctx context.Context, arg database.GetUserIdentityParams) (database.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, identity := range s.identities {
+		if identity.Issuer == arg.Issuer && identity.Subject == arg.Subject {
+			return identity, nil
+		}
+	}
+	return database.UserIdentity{}, sql.ErrNoRows
+}
+
+func (s *Store) TouchUserIdentity(ctx context.Context, arg database.TouchUserIdentityParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if identity, ok := s.identities[arg.ID]; ok {
+		identity.LastLoginAt = arg.LastLoginAt
+		s.identities[arg.ID] = identity
+	}
+	return nil
+}
+
+// Login throttling
+
+func (s *Store) GetLoginThrottles(ctx context.Context, arg database.GetLoginThrottlesParams) ([]database.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sorted(s.throttles, func(throttle database.LoginThrottle) bool {
+		return throttle.Key == arg.AccountKey || throttle.Key == arg.IpKey
+	}, func(a, b database.LoginThrottle) bool {
+		return a.Key < b.Key
+	}), nil
+}
+
+// RecordLoginFailure counts a failure, starting again from one when the
+// last failure was before the window
+func (s *Store) RecordLoginFailure(ctx context.Context, arg database.RecordLoginFailureParams) (database.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	throttle, ok := s.throttles[arg.Key]
+	if !ok || throttle.LastFailureAt.Before(arg.WindowStart) {
+		throttle.Key = arg.Key
+		throttle.Failures = 1
+	} else {
+		throttle.Failures++
+	}
+	throttle.LastFailureAt = arg.FailedAt
+	s.throttles[arg.Key] = throttle
+	return throttle, nil
+}
+
+func (s *Store) LockLogin(ctx context.Context, arg database.LockLoginParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if throttle, ok := s.throttles[arg.Key]; ok {
+		throttle.LockedUntil = arg.LockedUntil
+		s.throttles[arg.Key] = throttle
+	}
+	return nil
+}
+
+func (s *Store) ClearLoginThrottle(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.throttles, key)
+	return nil
+}
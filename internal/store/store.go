@@ -0,0 +1,200 @@
+// Package store describes the persistence the handlers rely on as
+// interfaces, so they can run against Postgres or SQLite (see
+// database/sqlite) through the sqlc generated *database.Queries, or against
+// the in-memory store in store/memory.
+//
+// Implementations report not found as sql.ErrNoRows, and unique and
+// foreign key violations as *pq.Error with codes 23505 and 23503, the same
+// as Postgres does.
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+type ProductStore interface {
+	CreateProduct(ctx context.Context, arg database.CreateProductParams) (database.Product, error)
+	GetProducts(ctx context.Context, arg database.GetProductsParams) ([]database.Product, error)
+	IterProducts(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(database.Product) error) error
+	GetProduct(ctx context.Context, arg database.GetProductParams) (database.Product, error)
+	GetProductIncludingDeleted(ctx context.Context, arg database.GetProductIncludingDeletedParams) (database.Product, error)
+	UpdateProduct(ctx context.Context, arg database.UpdateProductParams) (database.Product, error)
+	AdjustProductStock(ctx context.Context, arg database.AdjustProductStockParams) (database.Product, error)
+	SoftDeleteProduct(ctx context.Context, arg database.SoftDeleteProductParams) (int64, error)
+	RestoreProduct(ctx context.Context, arg database.RestoreProductParams) (database.Product, error)
+	PurgeDeletedProducts(ctx context.Context, before time.Time) (int64, error)
+	CountProductsByOrganization(ctx context.Context) ([]database.CountProductsByOrganizationRow, error)
+}
+
+type CategoryStore interface {
+	CreateCategory(ctx context.Context, arg database.CreateCategoryParams) (database.Category, error)
+	GetCategories(ctx context.Context, arg database.GetCategoriesParams) ([]database.Category, error)
+	IterCategories(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(database.Category) error) error
+	GetCategoryById(ctx context.Context, arg database.GetCategoryByIdParams) (database.Category, error)
+	GetCategoryByIdIncludingDeleted(ctx context.Context, arg database.GetCategoryByIdIncludingDeletedParams) (database.Category, error)
+	UpdateCategory(ctx context.Context, arg database.UpdateCategoryParams) (database.Category, error)
+	SoftDeleteCategory(ctx context.Context, arg database.SoftDeleteCategoryParams) (int64, error)
+	RestoreCategory(ctx context.Context, arg database.RestoreCategoryParams) (database.Category, error)
+	PurgeDeletedCategories(ctx context.Context, before time.Time) (int64, error)
+}
+
+type SupplierStore interface {
+	CreateSupplier(ctx context.Context, arg database.CreateSupplierParams) (database.Supplier, error)
+	GetAllSuppliers(ctx context.Context, arg database.GetAllSuppliersParams) ([]database.Supplier, error)
+	IterSuppliers(ctx context.Context, orgId uuid.UUID, includeDeleted bool, fn func(database.Supplier) error) error
+	GetSupplierById(ctx context.Context, arg database.GetSupplierByIdParams) (database.Supplier, error)
+	GetSupplierByIdIncludingDeleted(ctx context.Context, arg database.GetSupplierByIdIncludingDeletedParams) (database.Supplier, error)
+	UpdateSupplier(ctx context.Context, arg database.UpdateSupplierParams) (database.Supplier, error)
+	SoftDeleteSupplier(ctx context.Context, arg database.SoftDeleteSupplierParams) (int64, error)
+	RestoreSupplier(ctx context.Context, arg database.RestoreSupplierParams) (database.Supplier, error)
+	PurgeDeletedSuppliers(ctx context.Context, before time.Time) (int64, error)
+}
+
+type UserStore interface {
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error)
+	GetAllUsers(ctx context.Context) ([]database.GetAllUsersRow, error)
+	IterUsers(ctx context.Context, fn func(database.GetAllUsersRow) error) error
+	GetUserById(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetUserByEmail(ctx context.Context, email string) (database.User, error)
+	UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error)
+	UpdateUserProfile(ctx context.Context, arg database.UpdateUserProfileParams) (database.User, error)
+	UpdateUserRole(ctx context.Context, arg database.UpdateUserRoleParams) (database.User, error)
+	UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error
+	SetUserEmailVerified(ctx context.Context, arg database.SetUserEmailVerifiedParams) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	CountUsersWithRole(ctx context.Context, role string) (int64, error)
+}
+
+type SessionStore interface {
+	CreateSession(ctx context.Context, arg database.CreateSessionParams) error
+	GetSessionById(ctx context.Context, id uuid.UUID) (database.Session, error)
+	SetSessionOrg(ctx context.Context, arg database.SetSessionOrgParams) error
+	RevokeSession(ctx context.Context, arg database.RevokeSessionParams) error
+	RevokeUserSessions(ctx context.Context, arg database.RevokeUserSessionsParams) error
+	RevokeOtherUserSessions(ctx context.Context, arg database.RevokeOtherUserSessionsParams) error
+	CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (database.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, arg database.MarkRefreshTokenUsedParams) (int64, error)
+}
+
+type RoleStore interface {
+	CreateRole(ctx context.Context, arg database.CreateRoleParams) (database.Role, error)
+	GetRoles(ctx context.Context) ([]database.Role, error)
+	GetRole(ctx context.Context, name string) (database.Role, error)
+	UpdateRole(ctx context.Context, arg database.UpdateRoleParams) (database.Role, error)
+	DeleteRole(ctx context.Context, name string) (int64, error)
+	GetRolePermissions(ctx context.Context, role string) ([]string, error)
+	GetAllRolePermissions(ctx context.Context) ([]database.RolePermission, error)
+	AddRolePermission(ctx context.Context, arg database.AddRolePermissionParams) error
+	DeleteRolePermissions(ctx context.Context, role string) error
+	ReplaceRolePermissions(ctx context.Context, role string, permissions []string) error
+}
+
+type OrganizationStore interface {
+	CreateOrganization(ctx context.Context, arg database.CreateOrganizationParams) (database.Organization, error)
+	GetOrganizations(ctx context.Context) ([]database.Organization, error)
+	GetOrganizationById(ctx context.Context, id uuid.UUID) (database.Organization, error)
+	GetOrganizationBySlug(ctx context.Context, slug string) (database.Organization, error)
+	SetOrganizationMember(ctx context.Context, arg database.SetOrganizationMemberParams) (database.OrganizationMember, error)
+	GetOrganizationMember(ctx context.Context, arg database.GetOrganizationMemberParams) (database.OrganizationMember, error)
+	GetOrganizationMembers(ctx context.Context, orgID uuid.UUID) ([]database.OrganizationMember, error)
+	RemoveOrganizationMember(ctx context.Context, arg database.RemoveOrganizationMemberParams) (int64, error)
+	GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]database.GetUserOrganizationsRow, error)
+	CountOrganizationMembersWithRole(ctx context.Context, role string) (int64, error)
+}
+
+type ServiceAccountStore interface {
+	CreateServiceAccount(ctx context.Context, arg database.CreateServiceAccountParams) (database.ServiceAccount, error)
+	GetServiceAccounts(ctx context.Context, orgID uuid.UUID) ([]database.ServiceAccount, error)
+	GetServiceAccountById(ctx context.Context, id uuid.UUID) (database.ServiceAccount, error)
+	DeleteServiceAccount(ctx context.Context, arg database.DeleteServiceAccountParams) (int64, error)
+	CountServiceAccountsWithRole(ctx context.Context, role string) (int64, error)
+	CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (database.ApiKey, error)
+	GetServiceAccountAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]database.ApiKey, error)
+	RevokeAPIKey(ctx context.Context, arg database.RevokeAPIKeyParams) (int64, error)
+	TouchAPIKey(ctx context.Context, arg database.TouchAPIKeyParams) error
+	AddAPIKeyScope(ctx context.Context, arg database.AddAPIKeyScopeParams) error
+	GetAPIKeyScopes(ctx context.Context, apiKeyID uuid.UUID) ([]string, error)
+	GetServiceAccountAPIKeyScopes(ctx context.Context, serviceAccountID uuid.UUID) ([]database.ApiKeyScope, error)
+}
+
+type AuditStore interface {
+	CreateAuditLog(ctx context.Context, arg database.CreateAuditLogParams) error
+	GetAuditLogs(ctx context.Context, arg database.GetAuditLogsParams) ([]database.AuditLog, error)
+}
+
+type InvitationStore interface {
+	CreateInvitation(ctx context.Context, arg database.CreateInvitationParams) (database.Invitation, error)
+	GetInvitationByHash(ctx context.Context, tokenHash string) (database.Invitation, error)
+	GetPendingInvitations(ctx context.Context, expiresAt time.Time) ([]database.Invitation, error)
+	AcceptInvitation(ctx context.Context, arg database.AcceptInvitationParams) (int64, error)
+	DeleteInvitation(ctx context.Context, id uuid.UUID) (int64, error)
+}
+
+// TokenStore holds the single use tokens sent by email
+type TokenStore interface {
+	CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) error
+	GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (database.PasswordResetToken, error)
+	UsePasswordResetToken(ctx context.Context, arg database.UsePasswordResetTokenParams) (int64, error)
+	RevokeUserPasswordResetTokens(ctx context.Context, arg database.RevokeUserPasswordResetTokensParams) error
+	CreateEmailVerificationToken(ctx context.Context, arg database.CreateEmailVerificationTokenParams) error
+	GetEmailVerificationTokenByHash(ctx context.Context, tokenHash string) (database.EmailVerificationToken, error)
+	GetLatestEmailVerificationToken(ctx context.Context, userID uuid.UUID) (database.EmailVerificationToken, error)
+	UseEmailVerificationToken(ctx context.Context, arg database.UseEmailVerificationTokenParams) (int64, error)
+	RevokeUserEmailVerificationTokens(ctx context.Context, arg database.RevokeUserEmailVerificationTokensParams) error
+}
+
+type TwoFactorStore interface {
+	CreateTotpCredential(ctx context.Context, arg database.CreateTotpCredentialParams) error
+	GetTotpCredential(ctx context.Context, userID uuid.UUID) (database.TotpCredential, error)
+	ConfirmTotpCredential(ctx context.Context, arg database.ConfirmTotpCredentialParams) error
+	DeleteTotpCredential(ctx context.Context, userID uuid.UUID) error
+	CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error
+	UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error)
+	UseTotpStep(ctx context.Context, arg database.UseTotpStepParams) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []database.CreateRecoveryCodeParams) error
+	GetSecuritySettings(ctx context.Context) (database.SecuritySetting, error)
+	UpdateSecuritySettings(ctx context.Context, arg database.UpdateSecuritySettingsParams) (database.SecuritySetting, error)
+}
+
+type IdentityStore interface {
+	CreateOIDCLogin(ctx context.Context, arg database.CreateOIDCLoginParams) error
+	GetOIDCLoginByStateHash(ctx context.Context, stateHash string) (database.OidcLogin, error)
+	UseOIDCLogin(ctx context.Context, arg database.UseOIDCLoginParams) (int64, error)
+	CreateUserIdentity(ctx context.Context, arg database.CreateUserIdentityParams) error
+	GetUserIdentity(ctx context.Context, arg database.GetUserIdentityParams) (database.UserIdentity, error)
+	TouchUserIdentity(ctx context.Context, arg database.TouchUserIdentityParams) error
+}
+
+type LoginThrottleStore interface {
+	GetLoginThrottles(ctx context.Context, arg database.GetLoginThrottlesParams) ([]database.LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, arg database.RecordLoginFailureParams) (database.LoginThrottle, error)
+	LockLogin(ctx context.Context, arg database.LockLoginParams) error
+	ClearLoginThrottle(ctx context.Context, key string) error
+}
+
+// Store is everything the API needs
+type Store interface {
+	ProductStore
+	CategoryStore
+	SupplierStore
+	UserStore
+	SessionStore
+	RoleStore
+	OrganizationStore
+	ServiceAccountStore
+	AuditStore
+	InvitationStore
+	TokenStore
+	TwoFactorStore
+	IdentityStore
+	LoginThrottleStore
+}
+
+var _ Store = (*database.Queries)(nil)
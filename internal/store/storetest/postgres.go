@@ -0,0 +1,69 @@
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/db"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+)
+
+// Postgres returns a store on a schema of its own in the database given in
+// TEST_DATABASE_URL. The schema is migrated to the latest version and
+// dropped when t ends, so tests never see each other's rows. t is skipped
+// when TEST_DATABASE_URL isn't set.
+func Postgres(t *testing.T) store.Store {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL isn't set")
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("Couldn't open the test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if err := admin.Ping(); err != nil {
+		t.Fatalf("Couldn't connect to the test database: %v", err)
+	}
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("Couldn't create schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("Couldn't drop schema %s: %v", schema, err)
+		}
+	})
+
+	conn, err := sql.Open("postgres", withSearchPath(dbURL, schema))
+	if err != nil {
+		t.Fatalf("Couldn't open the test database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := db.Migrate(context.Background(), conn, "up", io.Discard); err != nil {
+		t.Fatalf("Couldn't migrate schema %s: %v", schema, err)
+	}
+	return database.New(conn)
+}
+
+// withSearchPath makes every connection to dbURL use schema. lib/pq sends
+// parameters it doesn't know to the server, which sets them for the session.
+func withSearchPath(dbURL string, schema string) string {
+	if u, err := url.Parse(dbURL); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return fmt.Sprintf("%s search_path=%s", dbURL, schema)
+}
@@ -0,0 +1,1414 @@
+// Package storetest is the behaviour every store.Store has to share. Run it
+// from a test against each implementation so the in-memory store can't
+// drift from Postgres.
+//
+// The tests only look at rows they create, with random names, so they can
+// run against a database that already holds data.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run runs the conformance tests, calling newStore for each one
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		test func(*testing.T, store.Store)
+	}{
+		{"Products", testProducts},
+		{"ProductReferences", testProductReferences},
+		{"ProductStock", testProductStock},
+		{"ProductCounts", testProductCounts},
+		{"Categories", testCategories},
+		{"Suppliers", testSuppliers},
+		{"PurgeDeleted", testPurgeDeleted},
+		{"Users", testUsers},
+		{"DeleteUser", testDeleteUser},
+		{"Sessions", testSessions},
+		{"Tokens", testTokens},
+		{"Roles", testRoles},
+		{"Organizations", testOrganizations},
+		{"ServiceAccounts", testServiceAccounts},
+		{"AuditLogs", testAuditLogs},
+		{"Invitations", testInvitations},
+		{"TwoFactor", testTwoFactor},
+		{"Identities", testIdentities},
+		{"LoginThrottles", testLoginThrottles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+// now is the current time at the precision Postgres keeps
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// unique returns name with a random suffix, at most max characters long
+func unique(name string, max int) string {
+	value := name + "-" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if len(value) > max {
+		value = value[:max]
+	}
+	return value
+}
+
+func hasCode(err error, code pq.ErrorCode) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == code
+}
+
+func assertUnique(t *testing.T, err error) {
+	t.Helper()
+	assert.True(t, hasCode(err, "23505"), "expected a unique violation, got %v", err)
+}
+
+func assertForeignKey(t *testing.T, err error) {
+	t.Helper()
+	assert.True(t, hasCode(err, "23503"), "expected a foreign key violation, got %v", err)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+// changedOne is given the results of a write and fails the test unless it
+// changed exactly one row
+func changedOne(t *testing.T) func(int64, error) {
+	return func(changed int64, err error) {
+		t.Helper()
+		must(t, err)
+		if !assert.Equal(t, int64(1), changed) {
+			t.FailNow()
+		}
+	}
+}
+
+func newOrg(t *testing.T, s store.Store) database.Organization {
+	t.Helper()
+	org, err := s.CreateOrganization(context.Background(), database.CreateOrganizationParams{
+		ID: uuid.New(),
+		Name: "Org",
+		Slug: unique("org", 50),
+		CreatedAt: now(),
+	})
+	must(t, err)
+	return org
+}
+
+func newUser(t *testing.T, s store.Store, role string) database.User {
+	t.Helper()
+	ctx := context.Background()
+	created := now()
+	name := unique("user", 40)
+	row, err := s.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(),
+		Username: name,
+		Email: name + "@example.com",
+		Name: "Test User",
+		Password: "hash",
+		Role: role,
+		CreatedAt: created,
+		UpdatedAt: created,
+	})
+	must(t, err)
+	user, err := s.GetUserById(ctx, row.ID)
+	must(t, err)
+	return user
+}
+
+func newProduct(t *testing.T, s store.Store, orgId uuid.UUID, sku string) database.Product {
+	t.Helper()
+	created := now()
+	product, err := s.CreateProduct(context.Background(), database.CreateProductParams{
+		ID: uuid.New(),
+		OrgID: orgId,
+		Name: "Widget",
+		Price: 100,
+		StockLevel: sql.NullInt32{Int32: 5, Valid: true},
+		Sku: sql.NullString{String: sku, Valid: sku != ""},
+		CreatedAt: created,
+		UpdatedAt: created,
+	})
+	must(t, err)
+	return product
+}
+
+func newCategory(t *testing.T, s store.Store, orgId uuid.UUID, name string) database.Category {
+	t.Helper()
+	created := now()
+	category, err := s.CreateCategory(context.Background(), database.CreateCategoryParams{
+		ID: uuid.New(),
+		OrgID: orgId,
+		Name: name,
+		CreatedAt: created,
+		UpdatedAt: created,
+		CreatedBy: uuid.New(),
+	})
+	must(t, err)
+	return category
+}
+
+func newSupplier(t *testing.T, s store.Store, orgId uuid.UUID, email string) database.Supplier {
+	t.Helper()
+	created := now()
+	supplier, err := s.CreateSupplier(context.Background(), database.CreateSupplierParams{
+		ID: uuid.New(),
+		OrgID: orgId,
+		Name: "Acme",
+		Email: sql.NullString{String: email, Valid: email != ""},
+		CreatedAt: created,
+		UpdatedAt: created,
+	})
+	must(t, err)
+	return supplier
+}
+
+func productIds(products []database.Product) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func testProducts(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org, other := newOrg(t, s), newOrg(t, s)
+
+	product := newProduct(t, s, org.ID, "SKU-1")
+	assert.Equal(t, org.ID, product.OrgID)
+	assert.Equal(t, int32(100), product.Price)
+	assert.False(t, product.DeletedAt.Valid)
+
+	got, err := s.GetProduct(ctx, database.GetProductParams{ID: product.ID, OrgID: org.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, "SKU-1", got.Sku.String)
+
+	_, err = s.GetProduct(ctx, database.GetProductParams{ID: product.ID, OrgID: other.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// SKUs are unique within an organisation only
+	_, err = s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: org.ID, Name: "Copy", Price: 1,
+		Sku: sql.NullString{String: "SKU-1", Valid: true}, CreatedAt: now(), UpdatedAt: now(),
+	})
+	assertUnique(t, err)
+	newProduct(t, s, other.ID, "SKU-1")
+
+	// Products without a SKU don't clash
+	newProduct(t, s, org.ID, "")
+	newProduct(t, s, org.ID, "")
+
+	updated, err := s.UpdateProduct(ctx, database.UpdateProductParams{
+		ID: product.ID, OrgID: org.ID, Name: "Gadget", Price: 250,
+		StockLevel: product.StockLevel, Sku: product.Sku,
+		UpdatedAt: now().Add(time.Second), ExpectedUpdatedAt: product.UpdatedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadget", updated.Name)
+	assert.Equal(t, int32(250), updated.Price)
+
+	_, err = s.UpdateProduct(ctx, database.UpdateProductParams{
+		ID: product.ID, OrgID: other.ID, Name: "Gadget", UpdatedAt: now(), ExpectedUpdatedAt: updated.UpdatedAt,
+	})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// Writes expecting the version from before the update change nothing
+	_, err = s.UpdateProduct(ctx, database.UpdateProductParams{
+		ID: product.ID, OrgID: org.ID, Name: "Stale", Price: 1, UpdatedAt: now(), ExpectedUpdatedAt: product.UpdatedAt,
+	})
+	assert.Equal(t, sql.ErrNoRows, err)
+	deletedAt := sql.NullTime{Time: now(), Valid: true}
+	stale, err := s.SoftDeleteProduct(ctx, database.SoftDeleteProductParams{
+		ID: product.ID, OrgID: org.ID, DeletedAt: deletedAt, UpdatedAt: product.UpdatedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stale)
+
+	// Soft deleting hides the product and frees its SKU
+	changedOne(t)(s.SoftDeleteProduct(ctx, database.SoftDeleteProductParams{
+		ID: product.ID, OrgID: org.ID, DeletedAt: deletedAt, UpdatedAt: updated.UpdatedAt,
+	}))
+	_, err = s.GetProduct(ctx, database.GetProductParams{ID: product.ID, OrgID: org.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+	deleted, err := s.GetProductIncludingDeleted(ctx, database.GetProductIncludingDeletedParams{
+		ID: product.ID, OrgID: org.ID,
+	})
+	assert.NoError(t, err)
+	assert.True(t, deleted.DeletedAt.Valid)
+
+	active, err := s.GetProducts(ctx, database.GetProductsParams{OrgID: org.ID})
+	assert.NoError(t, err)
+	assert.Len(t, active, 2)
+	assert.NotContains(t, productIds(active), product.ID)
+
+	all, err := s.GetProducts(ctx, database.GetProductsParams{OrgID: org.ID, IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Contains(t, productIds(all), product.ID)
+
+	var iterated []uuid.UUID
+	assert.NoError(t, s.IterProducts(ctx, org.ID, true, func(p database.Product) error {
+		iterated = append(iterated, p.ID)
+		return nil
+	}))
+	assert.ElementsMatch(t, productIds(all), iterated)
+
+	reused := newProduct(t, s, org.ID, "SKU-1")
+
+	// Restoring would bring back a second SKU-1
+	_, err = s.RestoreProduct(ctx, database.RestoreProductParams{ID: product.ID, OrgID: org.ID, UpdatedAt: now()})
+	assertUnique(t, err)
+
+	changedOne(t)(s.SoftDeleteProduct(ctx, database.SoftDeleteProductParams{
+		ID: reused.ID, OrgID: org.ID, DeletedAt: deletedAt, UpdatedAt: reused.UpdatedAt,
+	}))
+	restored, err := s.RestoreProduct(ctx, database.RestoreProductParams{ID: product.ID, OrgID: org.ID, UpdatedAt: now()})
+	assert.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+
+	_, err = s.RestoreProduct(ctx, database.RestoreProductParams{ID: product.ID, OrgID: org.ID, UpdatedAt: now()})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testProductReferences(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org, other := newOrg(t, s), newOrg(t, s)
+	category := newCategory(t, s, org.ID, unique("Tools", 100))
+	supplier := newSupplier(t, s, org.ID, "")
+
+	product, err := s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: org.ID, Name: "Hammer", Price: 10,
+		CategoryID: uuid.NullUUID{UUID: category.ID, Valid: true},
+		SupplierID: uuid.NullUUID{UUID: supplier.ID, Valid: true},
+		CreatedAt: now(), UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, category.ID, product.CategoryID.UUID)
+	assert.Equal(t, supplier.ID, product.SupplierID.UUID)
+
+	// Another organisation can't use them
+	_, err = s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: other.ID, Name: "Hammer", Price: 10,
+		CategoryID: uuid.NullUUID{UUID: category.ID, Valid: true},
+		CreatedAt: now(), UpdatedAt: now(),
+	})
+	assertForeignKey(t, err)
+
+	_, err = s.UpdateProduct(ctx, database.UpdateProductParams{
+		ID: product.ID, OrgID: org.ID, Name: "Hammer", Price: 10,
+		SupplierID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		UpdatedAt: now(), ExpectedUpdatedAt: product.UpdatedAt,
+	})
+	assertForeignKey(t, err)
+
+	_, err = s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: uuid.New(), Name: "Orphan", Price: 1, CreatedAt: now(), UpdatedAt: now(),
+	})
+	assertForeignKey(t, err)
+}
+
+func testProductStock(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := newOrg(t, s)
+	product := newProduct(t, s, org.ID, "")
+
+	adjusted, err := s.AdjustProductStock(ctx, database.AdjustProductStockParams{
+		Adjustment: -3, UpdatedAt: now(), ID: product.ID, OrgID: org.ID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, sql.NullInt32{Int32: 2, Valid: true}, adjusted.StockLevel)
+
+	// Stock can't go below zero
+	_, err = s.AdjustProductStock(ctx, database.AdjustProductStockParams{
+		Adjustment: -3, UpdatedAt: now(), ID: product.ID, OrgID: org.ID,
+	})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	unstocked, err := s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: org.ID, Name: "Unstocked", Price: 1, CreatedAt: now(), UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.False(t, unstocked.StockLevel.Valid)
+
+	adjusted, err = s.AdjustProductStock(ctx, database.AdjustProductStockParams{
+		Adjustment: 4, UpdatedAt: now(), ID: unstocked.ID, OrgID: org.ID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, sql.NullInt32{Int32: 4, Valid: true}, adjusted.StockLevel)
+}
+
+func testProductCounts(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := newOrg(t, s)
+	empty := newOrg(t, s)
+	newProduct(t, s, org.ID, "")
+	_, err := s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: org.ID, Name: "Unstocked", Price: 1, CreatedAt: now(), UpdatedAt: now(),
+	})
+	must(t, err)
+	_, err = s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: org.ID, Name: "Sold out", Price: 1,
+		StockLevel: sql.NullInt32{Int32: 0, Valid: true},
+		CreatedAt: now(), UpdatedAt: now(),
+	})
+	must(t, err)
+	deleted := newProduct(t, s, org.ID, "")
+	changedOne(t)(s.SoftDeleteProduct(ctx, database.SoftDeleteProductParams{
+		ID: deleted.ID, OrgID: org.ID, DeletedAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: deleted.UpdatedAt,
+	}))
+
+	counts, err := s.CountProductsByOrganization(ctx)
+	assert.NoError(t, err)
+	bySlug := map[string]database.CountProductsByOrganizationRow{}
+	for _, count := range counts {
+		bySlug[count.Slug] = count
+	}
+	assert.Equal(t, database.CountProductsByOrganizationRow{Slug: org.Slug, Products: 3, OutOfStock: 2}, bySlug[org.Slug],
+		"soft deleted products aren't counted and a missing stock level counts as none")
+	assert.Equal(t, database.CountProductsByOrganizationRow{Slug: empty.Slug}, bySlug[empty.Slug])
+}
+
+func testCategories(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org, other := newOrg(t, s), newOrg(t, s)
+	name := unique("Tools", 100)
+
+	category := newCategory(t, s, org.ID, name)
+	newCategory(t, s, other.ID, name)
+
+	_, err := s.CreateCategory(ctx, database.CreateCategoryParams{
+		ID: uuid.New(), OrgID: org.ID, Name: name, CreatedAt: now(), UpdatedAt: now(), CreatedBy: uuid.New(),
+	})
+	assertUnique(t, err)
+
+	second := newCategory(t, s, org.ID, unique("Paint", 100))
+	_, err = s.UpdateCategory(ctx, database.UpdateCategoryParams{
+		ID: second.ID, OrgID: org.ID, Name: name, UpdatedAt: now(), ExpectedUpdatedAt: second.UpdatedAt,
+	})
+	assertUnique(t, err)
+
+	updated, err := s.UpdateCategory(ctx, database.UpdateCategoryParams{
+		ID: category.ID, OrgID: org.ID, Name: name,
+		Description: sql.NullString{String: "Hand tools", Valid: true},
+		UpdatedAt: now().Add(time.Second), ExpectedUpdatedAt: category.UpdatedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hand tools", updated.Description.String)
+
+	_, err = s.UpdateCategory(ctx, database.UpdateCategoryParams{
+		ID: category.ID, OrgID: org.ID, Name: name, UpdatedAt: now(), ExpectedUpdatedAt: category.UpdatedAt,
+	})
+	assert.Equal(t, sql.ErrNoRows, err)
+	stale, err := s.SoftDeleteCategory(ctx, database.SoftDeleteCategoryParams{
+		ID: category.ID, OrgID: org.ID, DeletedAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: category.UpdatedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stale)
+
+	_, err = s.GetCategoryById(ctx, database.GetCategoryByIdParams{ID: category.ID, OrgID: other.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	changedOne(t)(s.SoftDeleteCategory(ctx, database.SoftDeleteCategoryParams{
+		ID: category.ID, OrgID: org.ID, DeletedAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: updated.UpdatedAt,
+	}))
+	_, err = s.GetCategoryById(ctx, database.GetCategoryByIdParams{ID: category.ID, OrgID: org.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+	_, err = s.GetCategoryByIdIncludingDeleted(ctx, database.GetCategoryByIdIncludingDeletedParams{
+		ID: category.ID, OrgID: org.ID,
+	})
+	assert.NoError(t, err)
+
+	categories, err := s.GetCategories(ctx, database.GetCategoriesParams{OrgID: org.ID})
+	assert.NoError(t, err)
+	assert.Len(t, categories, 1)
+
+	var count int
+	assert.NoError(t, s.IterCategories(ctx, org.ID, true, func(database.Category) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 2, count)
+
+	restored, err := s.RestoreCategory(ctx, database.RestoreCategoryParams{ID: category.ID, OrgID: org.ID, UpdatedAt: now()})
+	assert.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+}
+
+func testSuppliers(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org, other := newOrg(t, s), newOrg(t, s)
+	email := unique("sales", 60) + "@example.com"
+
+	supplier := newSupplier(t, s, org.ID, email)
+	newSupplier(t, s, other.ID, email)
+
+	_, err := s.CreateSupplier(ctx, database.CreateSupplierParams{
+		ID: uuid.New(), OrgID: org.ID, Name: "Copy",
+		Email: sql.NullString{String: email, Valid: true}, CreatedAt: now(), UpdatedAt: now(),
+	})
+	assertUnique(t, err)
+
+	// Suppliers without an email don't clash
+	newSupplier(t, s, org.ID, "")
+	newSupplier(t, s, org.ID, "")
+
+	updated, err := s.UpdateSupplier(ctx, database.UpdateSupplierParams{
+		ID: supplier.ID, OrgID: org.ID, Name: "Acme Ltd", Email: supplier.Email,
+		Country: sql.NullString{String: "Uganda", Valid: true},
+		UpdatedAt: now().Add(time.Second), ExpectedUpdatedAt: supplier.UpdatedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme Ltd", updated.Name)
+	assert.Equal(t, "Uganda", updated.Country.String)
+
+	_, err = s.UpdateSupplier(ctx, database.UpdateSupplierParams{
+		ID: supplier.ID, OrgID: org.ID, Name: "Stale", UpdatedAt: now(), ExpectedUpdatedAt: supplier.UpdatedAt,
+	})
+	assert.Equal(t, sql.ErrNoRows, err)
+	stale, err := s.SoftDeleteSupplier(ctx, database.SoftDeleteSupplierParams{
+		ID: supplier.ID, OrgID: org.ID, DeletedAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: supplier.UpdatedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stale)
+
+	_, err = s.GetSupplierById(ctx, database.GetSupplierByIdParams{ID: supplier.ID, OrgID: other.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	changedOne(t)(s.SoftDeleteSupplier(ctx, database.SoftDeleteSupplierParams{
+		ID: supplier.ID, OrgID: org.ID, DeletedAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: updated.UpdatedAt,
+	}))
+	suppliers, err := s.GetAllSuppliers(ctx, database.GetAllSuppliersParams{OrgID: org.ID})
+	assert.NoError(t, err)
+	assert.Len(t, suppliers, 2)
+
+	var count int
+	assert.NoError(t, s.IterSuppliers(ctx, org.ID, true, func(database.Supplier) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 3, count)
+
+	// The email is free again once the supplier is deleted
+	replacement := newSupplier(t, s, org.ID, email)
+	_, err = s.RestoreSupplier(ctx, database.RestoreSupplierParams{ID: supplier.ID, OrgID: org.ID, UpdatedAt: now()})
+	assertUnique(t, err)
+
+	_, err = s.GetSupplierByIdIncludingDeleted(ctx, database.GetSupplierByIdIncludingDeletedParams{
+		ID: replacement.ID, OrgID: org.ID,
+	})
+	assert.NoError(t, err)
+}
+
+func testPurgeDeleted(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := newOrg(t, s)
+	category := newCategory(t, s, org.ID, unique("Old", 100))
+	supplier := newSupplier(t, s, org.ID, "")
+
+	product, err := s.CreateProduct(ctx, database.CreateProductParams{
+		ID: uuid.New(), OrgID: org.ID, Name: "Kept", Price: 1,
+		CategoryID: uuid.NullUUID{UUID: category.ID, Valid: true},
+		SupplierID: uuid.NullUUID{UUID: supplier.ID, Valid: true},
+		CreatedAt: now(), UpdatedAt: now(),
+	})
+	must(t, err)
+	gone := newProduct(t, s, org.ID, "")
+
+	longAgo := sql.NullTime{Time: now().Add(-48 * time.Hour), Valid: true}
+	changedOne(t)(s.SoftDeleteProduct(ctx, database.SoftDeleteProductParams{
+		ID: gone.ID, OrgID: org.ID, DeletedAt: longAgo, UpdatedAt: gone.UpdatedAt,
+	}))
+	changedOne(t)(s.SoftDeleteCategory(ctx, database.SoftDeleteCategoryParams{
+		ID: category.ID, OrgID: org.ID, DeletedAt: longAgo, UpdatedAt: category.UpdatedAt,
+	}))
+	changedOne(t)(s.SoftDeleteSupplier(ctx, database.SoftDeleteSupplierParams{
+		ID: supplier.ID, OrgID: org.ID, DeletedAt: longAgo, UpdatedAt: supplier.UpdatedAt,
+	}))
+
+	before := now().Add(-24 * time.Hour)
+	purged, err := s.PurgeDeletedProducts(ctx, before)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, purged, int64(1))
+	purged, err = s.PurgeDeletedCategories(ctx, before)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, purged, int64(1))
+	purged, err = s.PurgeDeletedSuppliers(ctx, before)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, purged, int64(1))
+
+	_, err = s.GetProductIncludingDeleted(ctx, database.GetProductIncludingDeletedParams{ID: gone.ID, OrgID: org.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+	_, err = s.GetCategoryByIdIncludingDeleted(ctx, database.GetCategoryByIdIncludingDeletedParams{
+		ID: category.ID, OrgID: org.ID,
+	})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// Products of purged categories and suppliers lose them
+	kept, err := s.GetProduct(ctx, database.GetProductParams{ID: product.ID, OrgID: org.ID})
+	assert.NoError(t, err)
+	assert.False(t, kept.CategoryID.Valid)
+	assert.False(t, kept.SupplierID.Valid)
+}
+
+func testUsers(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := newUser(t, s, "user")
+	assert.Equal(t, "user", user.Role)
+	assert.False(t, user.EmailVerifiedAt.Valid)
+
+	byEmail, err := s.GetUserByEmail(ctx, user.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, byEmail.ID)
+
+	_, err = s.GetUserByEmail(ctx, unique("nobody", 40)+"@example.com")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = s.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(), Username: unique("user", 40), Email: user.Email, Name: "Copy",
+		Password: "hash", Role: "user", CreatedAt: now(), UpdatedAt: now(),
+	})
+	assertUnique(t, err)
+	_, err = s.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(), Username: user.Username, Email: unique("copy", 40) + "@example.com", Name: "Copy",
+		Password: "hash", Role: "user", CreatedAt: now(), UpdatedAt: now(),
+	})
+	assertUnique(t, err)
+	_, err = s.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(), Username: unique("user", 40), Email: unique("user", 40) + "@example.com", Name: "Copy",
+		Password: "hash", Role: "no_such_role", CreatedAt: now(), UpdatedAt: now(),
+	})
+	assertForeignKey(t, err)
+
+	promoted, err := s.UpdateUserRole(ctx, database.UpdateUserRoleParams{
+		ID: user.ID, Role: "warehouse_clerk", UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "warehouse_clerk", promoted.Role)
+
+	_, err = s.UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: uuid.New(), Role: "user", UpdatedAt: now()})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	other := newUser(t, s, "user")
+	_, err = s.UpdateUserProfile(ctx, database.UpdateUserProfileParams{
+		ID: other.ID, Name: other.Name, Username: user.Username, UpdatedAt: now(),
+	})
+	assertUnique(t, err)
+
+	profile, err := s.UpdateUserProfile(ctx, database.UpdateUserProfileParams{
+		ID: other.ID, Name: "Renamed", Username: other.Username,
+		ProfilePictureUrl: sql.NullString{String: "https://example.com/me.png", Valid: true}, UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed", profile.Name)
+
+	verifiedAt := now()
+	assert.NoError(t, s.SetUserEmailVerified(ctx, database.SetUserEmailVerifiedParams{
+		ID: user.ID, EmailVerifiedAt: sql.NullTime{Time: verifiedAt, Valid: true},
+	}))
+	assert.NoError(t, s.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID: user.ID, Password: "new hash", UpdatedAt: now(),
+	}))
+	got, err := s.GetUserById(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, got.EmailVerifiedAt.Valid)
+	assert.WithinDuration(t, verifiedAt, got.EmailVerifiedAt.Time, time.Millisecond)
+	assert.Equal(t, "new hash", got.Password)
+
+	updated, err := s.UpdateUser(ctx, database.UpdateUserParams{
+		ID: user.ID, Name: got.Name, Username: got.Username, Email: got.Email, Role: got.Role, UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.True(t, updated.EmailVerifiedAt.Valid, "keeping the email keeps it verified")
+	updated, err = s.UpdateUser(ctx, database.UpdateUserParams{
+		ID: user.ID, Name: got.Name, Username: got.Username, Email: unique("moved", 40) + "@example.com",
+		Role: got.Role, UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.False(t, updated.EmailVerifiedAt.Valid, "a new email has to be verified again")
+
+	clerks, err := s.CountUsersWithRole(ctx, "warehouse_clerk")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, clerks, int64(1))
+
+	users, err := s.GetAllUsers(ctx)
+	assert.NoError(t, err)
+	var listed []uuid.UUID
+	for _, u := range users {
+		listed = append(listed, u.ID)
+	}
+	assert.Contains(t, listed, user.ID)
+	assert.Contains(t, listed, other.ID)
+
+	var iterated int
+	assert.NoError(t, s.IterUsers(ctx, func(database.GetAllUsersRow) error {
+		iterated++
+		return nil
+	}))
+	assert.Equal(t, len(users), iterated)
+}
+
+func testDeleteUser(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := newOrg(t, s)
+	admin := newUser(t, s, "admin")
+	user := newUser(t, s, "user")
+
+	sessionId := uuid.New()
+	must(t, s.CreateSession(ctx, database.CreateSessionParams{ID: sessionId, UserID: user.ID, CreatedAt: now()}))
+	_, err := s.SetOrganizationMember(ctx, database.SetOrganizationMemberParams{
+		OrgID: org.ID, UserID: user.ID, Role: "user", CreatedAt: now(),
+	})
+	must(t, err)
+	invitation, err := s.CreateInvitation(ctx, database.CreateInvitationParams{
+		ID: uuid.New(), Email: unique("invitee", 40) + "@example.com", Role: "user",
+		TokenHash: unique("invite", 64), InvitedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+		CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+	})
+	must(t, err)
+
+	// Admins are never deleted
+	assert.NoError(t, s.DeleteUser(ctx, admin.ID))
+	_, err = s.GetUserById(ctx, admin.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.DeleteUser(ctx, user.ID))
+	_, err = s.GetUserById(ctx, user.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = s.GetSessionById(ctx, sessionId)
+	assert.Equal(t, sql.ErrNoRows, err)
+	_, err = s.GetOrganizationMember(ctx, database.GetOrganizationMemberParams{OrgID: org.ID, UserID: user.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+	kept, err := s.GetInvitationByHash(ctx, invitation.TokenHash)
+	assert.NoError(t, err)
+	assert.False(t, kept.InvitedBy.Valid)
+}
+
+func testSessions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := newOrg(t, s)
+	user := newUser(t, s, "user")
+
+	current, other := uuid.New(), uuid.New()
+	assert.NoError(t, s.CreateSession(ctx, database.CreateSessionParams{
+		ID: current, UserID: user.ID, OrgID: uuid.NullUUID{UUID: org.ID, Valid: true}, CreatedAt: now(),
+	}))
+	assert.NoError(t, s.CreateSession(ctx, database.CreateSessionParams{ID: other, UserID: user.ID, CreatedAt: now()}))
+	assertForeignKey(t, s.CreateSession(ctx, database.CreateSessionParams{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now()}))
+
+	session, err := s.GetSessionById(ctx, current)
+	assert.NoError(t, err)
+	assert.Equal(t, org.ID, session.OrgID.UUID)
+
+	assert.NoError(t, s.SetSessionOrg(ctx, database.SetSessionOrgParams{ID: other, OrgID: uuid.NullUUID{UUID: org.ID, Valid: true}}))
+	session, err = s.GetSessionById(ctx, other)
+	assert.NoError(t, err)
+	assert.True(t, session.OrgID.Valid)
+
+	revokedAt := sql.NullTime{Time: now(), Valid: true}
+	assert.NoError(t, s.RevokeOtherUserSessions(ctx, database.RevokeOtherUserSessionsParams{
+		UserID: user.ID, ID: current, RevokedAt: revokedAt,
+	}))
+	session, _ = s.GetSessionById(ctx, other)
+	assert.True(t, session.RevokedAt.Valid)
+	session, _ = s.GetSessionById(ctx, current)
+	assert.False(t, session.RevokedAt.Valid)
+
+	// Revoking again keeps the first revocation time
+	later := sql.NullTime{Time: now().Add(time.Hour), Valid: true}
+	assert.NoError(t, s.RevokeUserSessions(ctx, database.RevokeUserSessionsParams{UserID: user.ID, RevokedAt: later}))
+	session, _ = s.GetSessionById(ctx, other)
+	assert.WithinDuration(t, revokedAt.Time, session.RevokedAt.Time, time.Millisecond)
+	session, _ = s.GetSessionById(ctx, current)
+	assert.True(t, session.RevokedAt.Valid)
+
+	tokenHash := unique("refresh", 64)
+	tokenId := uuid.New()
+	assert.NoError(t, s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID: tokenId, SessionID: current, TokenHash: tokenHash, CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+	}))
+	assertUnique(t, s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID: uuid.New(), SessionID: current, TokenHash: tokenHash, CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+	}))
+
+	token, err := s.GetRefreshTokenByHash(ctx, tokenHash)
+	assert.NoError(t, err)
+	assert.Equal(t, tokenId, token.ID)
+
+	used, err := s.MarkRefreshTokenUsed(ctx, database.MarkRefreshTokenUsedParams{ID: tokenId, UsedAt: revokedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+	used, err = s.MarkRefreshTokenUsed(ctx, database.MarkRefreshTokenUsedParams{ID: tokenId, UsedAt: revokedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+
+	assert.NoError(t, s.RevokeSession(ctx, database.RevokeSessionParams{ID: uuid.New(), RevokedAt: revokedAt}))
+}
+
+func testTokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := newUser(t, s, "user")
+	usedAt := sql.NullTime{Time: now(), Valid: true}
+
+	resetHash := unique("reset", 64)
+	resetId := uuid.New()
+	assert.NoError(t, s.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		ID: resetId, UserID: user.ID, TokenHash: resetHash, CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+	}))
+	assertUnique(t, s.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		ID: uuid.New(), UserID: user.ID, TokenHash: resetHash, CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+	}))
+	reset, err := s.GetPasswordResetTokenByHash(ctx, resetHash)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, reset.UserID)
+
+	used, err := s.UsePasswordResetToken(ctx, database.UsePasswordResetTokenParams{ID: resetId, UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+	used, err = s.UsePasswordResetToken(ctx, database.UsePasswordResetTokenParams{ID: resetId, UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+
+	pendingHash := unique("reset", 64)
+	assert.NoError(t, s.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		ID: uuid.New(), UserID: user.ID, TokenHash: pendingHash, CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+	}))
+	assert.NoError(t, s.RevokeUserPasswordResetTokens(ctx, database.RevokeUserPasswordResetTokensParams{
+		UserID: user.ID, UsedAt: usedAt,
+	}))
+	pending, err := s.GetPasswordResetTokenByHash(ctx, pendingHash)
+	assert.NoError(t, err)
+	assert.True(t, pending.UsedAt.Valid)
+
+	_, err = s.GetLatestEmailVerificationToken(ctx, user.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	older, newer := uuid.New(), uuid.New()
+	assert.NoError(t, s.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		ID: older, UserID: user.ID, TokenHash: unique("verify", 64),
+		CreatedAt: now().Add(-time.Minute), ExpiresAt: now().Add(time.Hour),
+	}))
+	newerHash := unique("verify", 64)
+	assert.NoError(t, s.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		ID: newer, UserID: user.ID, TokenHash: newerHash, CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+	}))
+	assertForeignKey(t, s.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		ID: uuid.New(), UserID: uuid.New(), TokenHash: unique("verify", 64), CreatedAt: now(), ExpiresAt: now(),
+	}))
+
+	latest, err := s.GetLatestEmailVerificationToken(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, newer, latest.ID)
+
+	byHash, err := s.GetEmailVerificationTokenByHash(ctx, newerHash)
+	assert.NoError(t, err)
+	assert.Equal(t, newer, byHash.ID)
+
+	used, err = s.UseEmailVerificationToken(ctx, database.UseEmailVerificationTokenParams{ID: newer, UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+	used, err = s.UseEmailVerificationToken(ctx, database.UseEmailVerificationTokenParams{ID: newer, UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+
+	assert.NoError(t, s.RevokeUserEmailVerificationTokens(ctx, database.RevokeUserEmailVerificationTokensParams{
+		UserID: user.ID, UsedAt: usedAt,
+	}))
+	used, err = s.UseEmailVerificationToken(ctx, database.UseEmailVerificationTokenParams{ID: older, UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+}
+
+func testRoles(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	admin, err := s.GetRole(ctx, "admin")
+	assert.NoError(t, err)
+	assert.True(t, admin.BuiltIn)
+
+	permissions, err := s.GetRolePermissions(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"categories:read", "products:read"}, permissions)
+
+	name := unique("r", 20)
+	role, err := s.CreateRole(ctx, database.CreateRoleParams{
+		Name: name, Description: sql.NullString{String: "Custom", Valid: true}, CreatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.False(t, role.BuiltIn)
+	assert.Equal(t, role.CreatedAt, role.UpdatedAt)
+
+	_, err = s.CreateRole(ctx, database.CreateRoleParams{Name: name, CreatedAt: now()})
+	assertUnique(t, err)
+
+	assert.NoError(t, s.AddRolePermission(ctx, database.AddRolePermissionParams{Role: name, Permission: "suppliers:read"}))
+	assert.NoError(t, s.AddRolePermission(ctx, database.AddRolePermissionParams{Role: name, Permission: "products:read"}))
+	assertUnique(t, s.AddRolePermission(ctx, database.AddRolePermissionParams{Role: name, Permission: "products:read"}))
+	assertForeignKey(t, s.AddRolePermission(ctx, database.AddRolePermissionParams{
+		Role: unique("r", 20), Permission: "products:read",
+	}))
+
+	permissions, err = s.GetRolePermissions(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"products:read", "suppliers:read"}, permissions)
+
+	all, err := s.GetAllRolePermissions(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, all, database.RolePermission{Role: name, Permission: "suppliers:read"})
+
+	// Replacing permissions is all or nothing
+	assertUnique(t, s.ReplaceRolePermissions(ctx, name, []string{"audit:read", "audit:read"}))
+	permissions, err = s.GetRolePermissions(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"products:read", "suppliers:read"}, permissions)
+	assertForeignKey(t, s.ReplaceRolePermissions(ctx, unique("r", 20), []string{"products:read"}))
+	assert.NoError(t, s.ReplaceRolePermissions(ctx, name, []string{"audit:read", "products:read"}))
+	permissions, err = s.GetRolePermissions(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"audit:read", "products:read"}, permissions)
+
+	roles, err := s.GetRoles(ctx)
+	assert.NoError(t, err)
+	for i := 1; i < len(roles); i++ {
+		assert.Less(t, roles[i-1].Name, roles[i].Name)
+	}
+
+	updated, err := s.UpdateRole(ctx, database.UpdateRoleParams{
+		Name: name, Description: sql.NullString{String: "Changed", Valid: true}, UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Changed", updated.Description.String)
+
+	// Built in roles and roles in use stay
+	deleted, err := s.DeleteRole(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	user := newUser(t, s, name)
+	count, err := s.CountUsersWithRole(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, err = s.DeleteRole(ctx, name)
+	assertForeignKey(t, err)
+
+	_, err = s.UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: user.ID, Role: "user", UpdatedAt: now()})
+	must(t, err)
+	deleted, err = s.DeleteRole(ctx, name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	// Empty lists are nil, as sqlc returns them
+	permissions, err = s.GetRolePermissions(ctx, name)
+	assert.NoError(t, err)
+	assert.Nil(t, permissions)
+
+	assert.NoError(t, s.DeleteRolePermissions(ctx, name))
+}
+
+func testOrganizations(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	defaultOrg, err := s.GetOrganizationBySlug(ctx, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", defaultOrg.ID.String())
+
+	org := newOrg(t, s)
+	_, err = s.CreateOrganization(ctx, database.CreateOrganizationParams{
+		ID: uuid.New(), Name: "Copy", Slug: org.Slug, CreatedAt: now(),
+	})
+	assertUnique(t, err)
+
+	got, err := s.GetOrganizationById(ctx, org.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, org.Slug, got.Slug)
+	_, err = s.GetOrganizationById(ctx, uuid.New())
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	orgs, err := s.GetOrganizations(ctx)
+	assert.NoError(t, err)
+	var ids []uuid.UUID
+	for _, o := range orgs {
+		ids = append(ids, o.ID)
+	}
+	assert.Contains(t, ids, org.ID)
+
+	user := newUser(t, s, "user")
+	member, err := s.SetOrganizationMember(ctx, database.SetOrganizationMemberParams{
+		OrgID: org.ID, UserID: user.ID, Role: "user", CreatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "user", member.Role)
+
+	// Setting an existing member changes their role
+	member, err = s.SetOrganizationMember(ctx, database.SetOrganizationMemberParams{
+		OrgID: org.ID, UserID: user.ID, Role: "warehouse_clerk", CreatedAt: now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "warehouse_clerk", member.Role)
+	assert.True(t, member.CreatedAt.Before(now().Add(time.Minute)))
+
+	_, err = s.SetOrganizationMember(ctx, database.SetOrganizationMemberParams{
+		OrgID: org.ID, UserID: uuid.New(), Role: "user", CreatedAt: now(),
+	})
+	assertForeignKey(t, err)
+
+	clerks, err := s.CountOrganizationMembersWithRole(ctx, "warehouse_clerk")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, clerks, int64(1))
+
+	second := newOrg(t, s)
+	_, err = s.SetOrganizationMember(ctx, database.SetOrganizationMemberParams{
+		OrgID: second.ID, UserID: user.ID, Role: "admin", CreatedAt: now().Add(time.Second),
+	})
+	must(t, err)
+
+	memberships, err := s.GetUserOrganizations(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []database.GetUserOrganizationsRow{
+		{ID: org.ID, Name: org.Name, Slug: org.Slug, Role: "warehouse_clerk"},
+		{ID: second.ID, Name: second.Name, Slug: second.Slug, Role: "admin"},
+	}, memberships)
+
+	members, err := s.GetOrganizationMembers(ctx, org.ID)
+	assert.NoError(t, err)
+	assert.Len(t, members, 1)
+
+	removed, err := s.RemoveOrganizationMember(ctx, database.RemoveOrganizationMemberParams{OrgID: org.ID, UserID: user.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	removed, err = s.RemoveOrganizationMember(ctx, database.RemoveOrganizationMemberParams{OrgID: org.ID, UserID: user.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), removed)
+
+	_, err = s.GetOrganizationMember(ctx, database.GetOrganizationMemberParams{OrgID: org.ID, UserID: user.ID})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testServiceAccounts(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := newOrg(t, s)
+	creator := newUser(t, s, "admin")
+	name := unique("sa", 50)
+
+	account, err := s.CreateServiceAccount(ctx, database.CreateServiceAccountParams{
+		ID: uuid.New(), OrgID: org.ID, Name: name, Role: "warehouse_clerk",
+		CreatedBy: uuid.NullUUID{UUID: creator.ID, Valid: true}, CreatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, account.CreatedAt, account.UpdatedAt)
+
+	_, err = s.CreateServiceAccount(ctx, database.CreateServiceAccountParams{
+		ID: uuid.New(), OrgID: org.ID, Name: name, Role: "user", CreatedAt: now(),
+	})
+	assertUnique(t, err)
+	_, err = s.CreateServiceAccount(ctx, database.CreateServiceAccountParams{
+		ID: uuid.New(), OrgID: uuid.New(), Name: unique("sa", 50), Role: "user", CreatedAt: now(),
+	})
+	assertForeignKey(t, err)
+
+	count, err := s.CountServiceAccountsWithRole(ctx, "warehouse_clerk")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, count, int64(1))
+
+	accounts, err := s.GetServiceAccounts(ctx, org.ID)
+	assert.NoError(t, err)
+	var names []string
+	for _, a := range accounts {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{name}, names)
+	accounts, err = s.GetServiceAccounts(ctx, newOrg(t, s).ID)
+	assert.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	prefix := unique("k", 16)
+	older, err := s.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+		ID: uuid.New(), ServiceAccountID: account.ID, Name: "ci", Prefix: prefix,
+		SecretHash: "hash", CreatedAt: now().Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+	_, err = s.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+		ID: uuid.New(), ServiceAccountID: account.ID, Name: "copy", Prefix: prefix, SecretHash: "hash", CreatedAt: now(),
+	})
+	assertUnique(t, err)
+	newer, err := s.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+		ID: uuid.New(), ServiceAccountID: account.ID, Name: "deploy", Prefix: unique("k", 16),
+		SecretHash: "hash", CreatedAt: now(), ExpiresAt: sql.NullTime{Time: now().Add(time.Hour), Valid: true},
+	})
+	assert.NoError(t, err)
+
+	keys, err := s.GetServiceAccountAPIKeys(ctx, account.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, newer.ID, keys[0].ID)
+		assert.Equal(t, older.ID, keys[1].ID)
+	}
+
+	byPrefix, err := s.GetAPIKeyByPrefix(ctx, prefix)
+	assert.NoError(t, err)
+	assert.Equal(t, older.ID, byPrefix.ID)
+
+	assert.NoError(t, s.AddAPIKeyScope(ctx, database.AddAPIKeyScopeParams{ApiKeyID: older.ID, Permission: "products:stock"}))
+	assert.NoError(t, s.AddAPIKeyScope(ctx, database.AddAPIKeyScopeParams{ApiKeyID: older.ID, Permission: "products:read"}))
+	assertUnique(t, s.AddAPIKeyScope(ctx, database.AddAPIKeyScopeParams{ApiKeyID: older.ID, Permission: "products:read"}))
+
+	scopes, err := s.GetAPIKeyScopes(ctx, older.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"products:read", "products:stock"}, scopes)
+
+	accountScopes, err := s.GetServiceAccountAPIKeyScopes(ctx, account.ID)
+	assert.NoError(t, err)
+	assert.Len(t, accountScopes, 2)
+
+	touchedAt := sql.NullTime{Time: now(), Valid: true}
+	assert.NoError(t, s.TouchAPIKey(ctx, database.TouchAPIKeyParams{ID: older.ID, LastUsedAt: touchedAt}))
+	byPrefix, _ = s.GetAPIKeyByPrefix(ctx, prefix)
+	assert.True(t, byPrefix.LastUsedAt.Valid)
+
+	revoked, err := s.RevokeAPIKey(ctx, database.RevokeAPIKeyParams{
+		ID: older.ID, ServiceAccountID: uuid.New(), RevokedAt: touchedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), revoked)
+	revoked, err = s.RevokeAPIKey(ctx, database.RevokeAPIKeyParams{
+		ID: older.ID, ServiceAccountID: account.ID, RevokedAt: touchedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+
+	deleted, err := s.DeleteServiceAccount(ctx, database.DeleteServiceAccountParams{ID: account.ID, OrgID: uuid.New()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+	deleted, err = s.DeleteServiceAccount(ctx, database.DeleteServiceAccountParams{ID: account.ID, OrgID: org.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, err = s.GetAPIKeyByPrefix(ctx, prefix)
+	assert.Equal(t, sql.ErrNoRows, err)
+	_, err = s.GetServiceAccountById(ctx, account.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testAuditLogs(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	entityId := uuid.New()
+	entity := unique("e", 20)
+
+	for i, action := range []string{"create", "update", "delete"} {
+		assert.NoError(t, s.CreateAuditLog(ctx, database.CreateAuditLogParams{
+			ID: uuid.New(),
+			CreatedAt: now().Add(time.Duration(i) * time.Second),
+			ActorID: uuid.New(),
+			ActorEmail: "admin@example.com",
+			Action: action,
+			Entity: entity,
+			EntityID: entityId,
+			Changes: json.RawMessage(`{}`),
+			RequestID: "request",
+			Ip: "127.0.0.1",
+		}))
+	}
+
+	logs, err := s.GetAuditLogs(ctx, database.GetAuditLogsParams{
+		Entity: sql.NullString{String: entity, Valid: true}, RowLimit: 2,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, "delete", logs[0].Action)
+		assert.Equal(t, "update", logs[1].Action)
+	}
+
+	logs, err = s.GetAuditLogs(ctx, database.GetAuditLogsParams{
+		EntityID: uuid.NullUUID{UUID: entityId, Valid: true}, RowLimit: 10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, logs, 3)
+
+	logs, err = s.GetAuditLogs(ctx, database.GetAuditLogsParams{
+		Entity: sql.NullString{String: entity, Valid: true}, EntityID: uuid.NullUUID{UUID: uuid.New(), Valid: true}, RowLimit: 10,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, logs)
+}
+
+func testInvitations(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	inviter := newUser(t, s, "admin")
+	invite := func(created, expires time.Time) database.Invitation {
+		invitation, err := s.CreateInvitation(ctx, database.CreateInvitationParams{
+			ID: uuid.New(), Email: unique("invitee", 40) + "@example.com", Role: "user",
+			TokenHash: unique("invite", 64), InvitedBy: uuid.NullUUID{UUID: inviter.ID, Valid: true},
+			CreatedAt: created, ExpiresAt: expires,
+		})
+		must(t, err)
+		return invitation
+	}
+
+	older := invite(now().Add(-time.Hour), now().Add(time.Hour))
+	newer := invite(now(), now().Add(time.Hour))
+	expired := invite(now().Add(-2*time.Hour), now().Add(-time.Hour))
+
+	_, err := s.CreateInvitation(ctx, database.CreateInvitationParams{
+		ID: uuid.New(), Email: "x@example.com", Role: unique("r", 20), TokenHash: unique("invite", 64),
+		CreatedAt: now(), ExpiresAt: now(),
+	})
+	assertForeignKey(t, err)
+
+	org := newOrg(t, s)
+	orgInvite, err := s.CreateInvitation(ctx, database.CreateInvitationParams{
+		ID: uuid.New(), Email: unique("invitee", 40) + "@example.com", Role: "user",
+		TokenHash: unique("invite", 64), CreatedAt: now(), ExpiresAt: now().Add(time.Hour),
+		OrgID: uuid.NullUUID{UUID: org.ID, Valid: true},
+	})
+	must(t, err)
+	byToken, err := s.GetInvitationByHash(ctx, orgInvite.TokenHash)
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.NullUUID{UUID: org.ID, Valid: true}, byToken.OrgID)
+	_, err = s.CreateInvitation(ctx, database.CreateInvitationParams{
+		ID: uuid.New(), Email: "y@example.com", Role: "user", TokenHash: unique("invite", 64),
+		CreatedAt: now(), ExpiresAt: now(), OrgID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	})
+	assertForeignKey(t, err)
+
+	pending, err := s.GetPendingInvitations(ctx, now())
+	assert.NoError(t, err)
+	var ids []uuid.UUID
+	for _, invitation := range pending {
+		ids = append(ids, invitation.ID)
+	}
+	assert.NotContains(t, ids, expired.ID)
+	assert.Contains(t, ids, older.ID)
+	assert.Contains(t, ids, newer.ID)
+
+	accepted, err := s.AcceptInvitation(ctx, database.AcceptInvitationParams{
+		ID: older.ID, AcceptedAt: sql.NullTime{Time: now(), Valid: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), accepted)
+	accepted, err = s.AcceptInvitation(ctx, database.AcceptInvitationParams{
+		ID: older.ID, AcceptedAt: sql.NullTime{Time: now(), Valid: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), accepted)
+
+	// Accepted invitations are kept
+	deleted, err := s.DeleteInvitation(ctx, older.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+	deleted, err = s.DeleteInvitation(ctx, newer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = s.GetInvitationByHash(ctx, newer.TokenHash)
+	assert.Equal(t, sql.ErrNoRows, err)
+	got, err := s.GetInvitationByHash(ctx, older.TokenHash)
+	assert.NoError(t, err)
+	assert.True(t, got.AcceptedAt.Valid)
+}
+
+func testTwoFactor(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := newUser(t, s, "user")
+
+	_, err := s.GetTotpCredential(ctx, user.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	assert.NoError(t, s.CreateTotpCredential(ctx, database.CreateTotpCredentialParams{
+		UserID: user.ID, Secret: "secret", CreatedAt: now(),
+	}))
+	assertUnique(t, s.CreateTotpCredential(ctx, database.CreateTotpCredentialParams{
+		UserID: user.ID, Secret: "other", CreatedAt: now(),
+	}))
+	assert.NoError(t, s.ConfirmTotpCredential(ctx, database.ConfirmTotpCredentialParams{
+		UserID: user.ID, ConfirmedAt: sql.NullTime{Time: now(), Valid: true},
+	}))
+	credential, err := s.GetTotpCredential(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", credential.Secret)
+	assert.True(t, credential.ConfirmedAt.Valid)
+
+	used, err := s.UseTotpStep(ctx, database.UseTotpStepParams{UserID: user.ID, LastUsedStep: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+	used, err = s.UseTotpStep(ctx, database.UseTotpStepParams{UserID: user.ID, LastUsedStep: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used, "a time step can only be used once")
+	used, err = s.UseTotpStep(ctx, database.UseTotpStepParams{UserID: user.ID, LastUsedStep: 99})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used, "an earlier time step can't be used after a later one")
+	credential, err = s.GetTotpCredential(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), credential.LastUsedStep)
+
+	assert.NoError(t, s.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+		ID: uuid.New(), UserID: user.ID, CodeHash: "code", CreatedAt: now(),
+	}))
+	assertUnique(t, s.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+		ID: uuid.New(), UserID: user.ID, CodeHash: "code", CreatedAt: now(),
+	}))
+
+	usedAt := sql.NullTime{Time: now(), Valid: true}
+	used, err = s.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{UserID: user.ID, CodeHash: "code", UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+	used, err = s.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{UserID: user.ID, CodeHash: "code", UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+
+	assert.NoError(t, s.DeleteRecoveryCodes(ctx, user.ID))
+	assert.NoError(t, s.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+		ID: uuid.New(), UserID: user.ID, CodeHash: "code", CreatedAt: now(),
+	}))
+
+	// Replacing codes is all or nothing, a failed replacement keeps the old ones
+	assertUnique(t, s.ReplaceRecoveryCodes(ctx, user.ID, []database.CreateRecoveryCodeParams{
+		{ID: uuid.New(), UserID: user.ID, CodeHash: "new", CreatedAt: now()},
+		{ID: uuid.New(), UserID: user.ID, CodeHash: "new", CreatedAt: now()},
+	}))
+	used, err = s.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{UserID: user.ID, CodeHash: "new", UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+	assertUnique(t, s.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+		ID: uuid.New(), UserID: user.ID, CodeHash: "code", CreatedAt: now(),
+	}))
+	assert.NoError(t, s.ReplaceRecoveryCodes(ctx, user.ID, []database.CreateRecoveryCodeParams{
+		{ID: uuid.New(), UserID: user.ID, CodeHash: "new", CreatedAt: now()},
+	}))
+	used, err = s.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{UserID: user.ID, CodeHash: "code", UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used, "replaced codes stop working")
+	used, err = s.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{UserID: user.ID, CodeHash: "new", UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+
+	assert.NoError(t, s.DeleteTotpCredential(ctx, user.ID))
+	_, err = s.GetTotpCredential(ctx, user.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// Settings are shared so put them back as they were
+	settings, err := s.GetSecuritySettings(ctx)
+	assert.NoError(t, err)
+	updated, err := s.UpdateSecuritySettings(ctx, database.UpdateSecuritySettingsParams{
+		RequireAdminMfa: !settings.RequireAdminMfa, UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, !settings.RequireAdminMfa, updated.RequireAdminMfa)
+	_, err = s.UpdateSecuritySettings(ctx, database.UpdateSecuritySettingsParams{
+		RequireAdminMfa: settings.RequireAdminMfa, UpdatedAt: now(),
+	})
+	assert.NoError(t, err)
+}
+
+func testIdentities(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := newUser(t, s, "user")
+
+	stateHash := unique("state", 64)
+	loginId := uuid.New()
+	assert.NoError(t, s.CreateOIDCLogin(ctx, database.CreateOIDCLoginParams{
+		ID: loginId, StateHash: stateHash, Nonce: "nonce", CodeVerifier: "verifier",
+		CreatedAt: now(), ExpiresAt: now().Add(10 * time.Minute),
+	}))
+	assertUnique(t, s.CreateOIDCLogin(ctx, database.CreateOIDCLoginParams{
+		ID: uuid.New(), StateHash: stateHash, Nonce: "nonce", CodeVerifier: "verifier",
+		CreatedAt: now(), ExpiresAt: now(),
+	}))
+
+	login, err := s.GetOIDCLoginByStateHash(ctx, stateHash)
+	assert.NoError(t, err)
+	assert.Equal(t, "verifier", login.CodeVerifier)
+
+	usedAt := sql.NullTime{Time: now(), Valid: true}
+	used, err := s.UseOIDCLogin(ctx, database.UseOIDCLoginParams{ID: loginId, UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+	used, err = s.UseOIDCLogin(ctx, database.UseOIDCLoginParams{ID: loginId, UsedAt: usedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+
+	subject := unique("subject", 100)
+	created := now()
+	identityId := uuid.New()
+	assert.NoError(t, s.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		ID: identityId, UserID: user.ID, Issuer: "https://idp.example.com", Subject: subject, CreatedAt: created,
+	}))
+	assertUnique(t, s.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		ID: uuid.New(), UserID: user.ID, Issuer: "https://idp.example.com", Subject: subject, CreatedAt: created,
+	}))
+
+	identity, err := s.GetUserIdentity(ctx, database.GetUserIdentityParams{Issuer: "https://idp.example.com", Subject: subject})
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, identity.UserID)
+	assert.WithinDuration(t, created, identity.LastLoginAt, time.Millisecond)
+
+	later := created.Add(time.Hour)
+	assert.NoError(t, s.TouchUserIdentity(ctx, database.TouchUserIdentityParams{ID: identityId, LastLoginAt: later}))
+	identity, _ = s.GetUserIdentity(ctx, database.GetUserIdentityParams{Issuer: "https://idp.example.com", Subject: subject})
+	assert.WithinDuration(t, later, identity.LastLoginAt, time.Millisecond)
+
+	_, err = s.GetUserIdentity(ctx, database.GetUserIdentityParams{Issuer: "https://other.example.com", Subject: subject})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func testLoginThrottles(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	accountKey, ipKey := unique("account", 150), unique("ip", 150)
+	start := now()
+
+	for i := 1; i <= 3; i++ {
+		throttle, err := s.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Key: accountKey, FailedAt: start.Add(time.Duration(i) * time.Second), WindowStart: start.Add(-time.Hour),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(i), throttle.Failures)
+	}
+
+	// A failure after the window starts counting again
+	throttle, err := s.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key: accountKey, FailedAt: start.Add(2 * time.Hour), WindowStart: start.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), throttle.Failures)
+
+	lockedUntil := sql.NullTime{Time: start.Add(3 * time.Hour), Valid: true}
+	assert.NoError(t, s.LockLogin(ctx, database.LockLoginParams{Key: accountKey, LockedUntil: lockedUntil}))
+	_, err = s.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key: ipKey, FailedAt: start, WindowStart: start.Add(-time.Hour),
+	})
+	assert.NoError(t, err)
+
+	throttles, err := s.GetLoginThrottles(ctx, database.GetLoginThrottlesParams{AccountKey: accountKey, IpKey: ipKey})
+	assert.NoError(t, err)
+	assert.Len(t, throttles, 2)
+	for _, throttle := range throttles {
+		if throttle.Key == accountKey {
+			assert.True(t, throttle.LockedUntil.Valid)
+		}
+	}
+
+	assert.NoError(t, s.ClearLoginThrottle(ctx, accountKey))
+	throttles, err = s.GetLoginThrottles(ctx, database.GetLoginThrottlesParams{AccountKey: accountKey, IpKey: ipKey})
+	assert.NoError(t, err)
+	if assert.Len(t, throttles, 1) {
+		assert.Equal(t, ipKey, throttles[0].Key)
+	}
+}
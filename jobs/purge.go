@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ringtho/inventory/internal/store"
+)
+
+// PurgeDeleted permanently removes products, categories and suppliers that
+// were soft deleted more than retention ago and returns how many rows went
+func PurgeDeleted(ctx context.Context, DB store.Store, retention time.Duration) (int64, error) {
+	before := time.Now().UTC().Add(-retention)
+
+	// Products go first so they keep their category and supplier until
+	// they are purged themselves
+	purges := []func(context.Context, time.Time) (int64, error){
+		DB.PurgeDeletedProducts,
+		DB.PurgeDeletedCategories,
+		DB.PurgeDeletedSuppliers,
+	}
+
+	var total int64
+	for _, purge := range purges {
+		count, err := purge(ctx, before)
+		if err != nil {
+			return total, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// RunPurgeDeleted calls PurgeDeleted every interval until ctx is cancelled
+func RunPurgeDeleted(
+	ctx context.Context,
+	DB store.Store,
+	retention time.Duration,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := PurgeDeleted(ctx, DB, retention)
+		if err != nil {
+			log.Printf("Error purging deleted rows: %v", err)
+		} else if count > 0 {
+			log.Printf("Purged %d deleted rows", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// LogMailer writes emails to the standard logger instead of sending them
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer appends emails to the file at Path instead of sending them
+type FileMailer struct {
+	Path string
+}
+
+var fileMailerLock sync.Mutex
+
+func (m FileMailer) Send(ctx context.Context, msg Message) error {
+	fileMailerLock.Lock()
+	defer fileMailerLock.Unlock()
+
+	file, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeMessage(file, msg)
+}
+
+func writeMessage(w io.Writer, msg Message) error {
+	_, err := fmt.Fprintf(w, "To: %s\nSubject: %s\n\n%s\n\n", msg.To, msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ringtho/inventory/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users, for password resets and the like
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromConfig picks a Mailer from MAILER. "smtp" sends through SMTP_HOST,
+// and "log" writes emails to MAIL_LOG_FILE, or to the log when that isn't
+// set, for local development.
+func FromConfig(cfg config.Mail) (Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		return SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	case "", "log":
+		if cfg.LogFile != "" {
+			return FileMailer{Path: cfg.LogFile}, nil
+		}
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", cfg.Mailer)
+	}
+}
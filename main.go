@@ -1,19 +1,17 @@
 package main
 
 import (
-	"log"
+	"context"
+	"os"
 
 	_ "github.com/lib/pq"
-	"github.com/ringtho/inventory/initializers"
+	"github.com/ringtho/inventory/cli"
 )
 
-func init() {
-	initializers.LoadDotEnvFile()
-}
-
 func main() {
-	server, _, conn := initializers.SetupServer()
-	defer conn.Close()
-	log.Printf("Server running on port %s\n", server.Addr)
-	log.Fatal(server.ListenAndServe())
+	os.Exit(cli.Run(context.Background(), os.Args[1:], cli.IO{
+		Stdin: os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}))
 }
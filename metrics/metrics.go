@@ -0,0 +1,159 @@
+// Package metrics records what the server is doing for Prometheus. The
+// counters and histograms are updated as requests are handled, while the
+// database pool and product numbers are read when /metrics is scraped.
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+const namespace = "inventory"
+
+// Registry holds the metrics that are recorded as the process runs, along
+// with the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// RequestsTotal counts the HTTP requests handled, by the route pattern
+	// they matched rather than their path, so IDs don't make new series
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	// RequestDuration is how long HTTP requests took to handle
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name: "http_request_duration_seconds",
+		Help: "Time taken to handle HTTP requests, by method, route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "logins_total",
+		Help: "Login attempts, by method and result.",
+	}, []string{"method", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RequestsTotal,
+		RequestDuration,
+		logins,
+	)
+}
+
+// Login methods
+const (
+	LoginPassword = "password"
+	LoginOIDC = "oidc"
+	LoginTOTP = "totp"
+)
+
+// Login results
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+	// LoginLocked is an attempt turned away because of earlier failures
+	LoginLocked = "locked"
+	// LoginUnverified is a correct password for an unverified email address
+	LoginUnverified = "unverified"
+)
+
+// RecordLogin counts a login attempt
+func RecordLogin(method string, result string) {
+	logins.WithLabelValues(method, result).Inc()
+}
+
+// ProductCounter counts the products in each organisation, it's
+// implemented by store.Store
+type ProductCounter interface {
+	CountProductsByOrganization(ctx context.Context) ([]database.CountProductsByOrganizationRow, error)
+}
+
+// productsTimeout bounds the count so a slow database doesn't hold up the
+// scrape
+const productsTimeout = 5 * time.Second
+
+var (
+	productsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "products"),
+		"Products that haven't been deleted, by organisation.",
+		[]string{"organization"}, nil,
+	)
+	outOfStockDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "products_out_of_stock"),
+		"Products with a stock level of zero or none set, by organisation.",
+		[]string{"organization"}, nil,
+	)
+)
+
+type productsCollector struct {
+	DB ProductCounter
+}
+
+func (c productsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- productsDesc
+	ch <- outOfStockDesc
+}
+
+func (c productsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), productsTimeout)
+	defer cancel()
+	counts, err := c.DB.CountProductsByOrganization(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(productsDesc, err)
+		return
+	}
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(productsDesc, prometheus.GaugeValue, float64(count.Products), count.Slug)
+		ch <- prometheus.MustNewConstMetric(outOfStockDesc, prometheus.GaugeValue, float64(count.OutOfStock), count.Slug)
+	}
+}
+
+// Handler serves the metrics in Registry and, when conn isn't nil, the
+// stats of its connection pool. With a token, scrapes have to send it as a
+// bearer token and also get the product counts from DB, which name every
+// organisation so aren't served to anyone. Metrics that can't be collected
+// are logged and left out.
+func Handler(DB ProductCounter, conn *sql.DB, token string) http.Handler {
+	scraped := prometheus.NewRegistry()
+	if token != "" {
+		scraped.MustRegister(productsCollector{DB: DB})
+	}
+	if conn != nil {
+		scraped.MustRegister(collectors.NewDBStatsCollector(conn, namespace))
+	}
+
+	handler := promhttp.HandlerFor(prometheus.Gatherers{Registry, scraped}, promhttp.HandlerOpts{
+		ErrorLog: log.Default(),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	if token == "" {
+		return handler
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			helpers.RespondWithError(w, 401, "A valid metrics token is required")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
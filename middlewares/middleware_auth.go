@@ -1,41 +1,261 @@
 package middlewares
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/ringtho/inventory/helpers"
 	"github.com/ringtho/inventory/internal/auth"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
 )
 
 
 type authedHandler func(http.ResponseWriter, *http.Request, database.User)
 
 type ApiCfg struct {
-	DB *database.Queries
+	DB store.Store
+	// DefaultOrg is the slug of the organisation anonymous requests read
+	// when they don't name one
+	DefaultOrg string
 }
 
+const apiKeyTouchInterval = time.Minute
+
+// MiddlewareAuth authenticates the request with either an access token or
+// a service account's API key and passes the principal on to next. Service
+// accounts are passed as a user with their ID, name and role.
 func (cfg ApiCfg) MiddlewareAuth(next authedHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Get the token from the Authorization header
-		token, err := auth.GetToken(r.Header) 
+		// Get the credentials from the Authorization header
+		credentials, err := auth.GetCredentials(r.Header)
 		if err != nil {
 			helpers.RespondWithError(w, 403, fmt.Sprintf("Auth error: %v", err))
 			return
 		}
 
-		claims, err := helpers.VerifyToken(token)
-		if err != nil {
-			helpers.RespondWithError(w, 403, fmt.Sprintf("Auth error: %v", err))
+		authenticate := cfg.authenticateToken
+		if credentials.Scheme == auth.SchemeApiKey {
+			authenticate = cfg.authenticateAPIKey
+		}
+
+		user, ctx, ok := authenticate(w, r, credentials.Value)
+		if !ok {
 			return
 		}
+		next(w, r.WithContext(ctx), user)
+	}
+}
+
+func (cfg ApiCfg) authenticateToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	token string,
+	) (database.User, context.Context, bool) {
+	claims, err := helpers.VerifyToken(token)
+	if err != nil {
+		helpers.RespondWithError(w, 403, fmt.Sprintf("Auth error: %v", err))
+		return database.User{}, nil, false
+	}
+
+	// Access tokens outlive a logout, so check their session is still live
+	session, err := cfg.DB.GetSessionById(r.Context(), claims.SessionID)
+	if err != nil || session.UserID != claims.ID || session.RevokedAt.Valid {
+		helpers.RespondWithError(w, 403, "Auth error: session has been revoked")
+		return database.User{}, nil, false
+	}
+
+	user, err := cfg.DB.GetUserById(r.Context(), claims.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 403, fmt.Sprintf("Couldn't fetch user: %v", err))
+		return database.User{}, nil, false
+	}
+
+	// Permissions come from the role in the database rather than the
+	// token, so changes to a role apply straight away
+	permissions, err := cfg.DB.GetRolePermissions(r.Context(), user.Role)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch permissions: %v", err))
+		return database.User{}, nil, false
+	}
+
+	ctx := auth.WithSessionID(r.Context(), session.ID)
+	var orgPermissions []string
+	if claims.OrgID.Valid {
+		// Membership is checked on every request so removing someone from
+		// an organisation doesn't wait for their token to expire
+		member, err := cfg.DB.GetOrganizationMember(r.Context(), database.GetOrganizationMemberParams{
+			OrgID: claims.OrgID.UUID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				helpers.RespondWithError(w, 403, "Auth error: not a member of the organisation")
+				return database.User{}, nil, false
+			}
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch membership: %v", err))
+			return database.User{}, nil, false
+		}
+
+		orgPermissions, err = cfg.DB.GetRolePermissions(r.Context(), member.Role)
+		if err != nil {
+			helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch permissions: %v", err))
+			return database.User{}, nil, false
+		}
+		ctx = auth.WithOrgID(ctx, member.OrgID)
+	}
+
+	ctx = auth.WithPermissions(ctx, auth.OrgPermissions(permissions, orgPermissions))
+	return user, ctx, true
+}
+
+func (cfg ApiCfg) authenticateAPIKey(
+	w http.ResponseWriter,
+	r *http.Request,
+	key string,
+	) (database.User, context.Context, bool) {
+	prefix, secret, err := auth.ParseAPIKey(key)
+	if err != nil {
+		helpers.RespondWithError(w, 403, fmt.Sprintf("Auth error: %v", err))
+		return database.User{}, nil, false
+	}
+
+	apiKey, err := cfg.DB.GetAPIKeyByPrefix(r.Context(), prefix)
+	if err != nil || !auth.CheckAPIKeySecret(apiKey.SecretHash, secret) {
+		helpers.RespondWithError(w, 403, "Auth error: invalid API key")
+		return database.User{}, nil, false
+	}
+
+	now := time.Now().UTC()
+	if apiKey.RevokedAt.Valid || (apiKey.ExpiresAt.Valid && now.After(apiKey.ExpiresAt.Time)) {
+		helpers.RespondWithError(w, 403, "Auth error: API key has expired or been revoked")
+		return database.User{}, nil, false
+	}
+
+	account, err := cfg.DB.GetServiceAccountById(r.Context(), apiKey.ServiceAccountID)
+	if err != nil {
+		helpers.RespondWithError(w, 403, fmt.Sprintf("Couldn't fetch service account: %v", err))
+		return database.User{}, nil, false
+	}
 
-		user, err := cfg.DB.GetUserById(r.Context(), claims.ID)
+	granted, err := cfg.DB.GetRolePermissions(r.Context(), account.Role)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch permissions: %v", err))
+		return database.User{}, nil, false
+	}
+	scopes, err := cfg.DB.GetAPIKeyScopes(r.Context(), apiKey.ID)
+	if err != nil {
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch API key scopes: %v", err))
+		return database.User{}, nil, false
+	}
+
+	// Only record use once a minute so busy integrations don't write on
+	// every request
+	if !apiKey.LastUsedAt.Valid || now.Sub(apiKey.LastUsedAt.Time) > apiKeyTouchInterval {
+		err = cfg.DB.TouchAPIKey(r.Context(), database.TouchAPIKeyParams{
+			ID: apiKey.ID,
+			LastUsedAt: sql.NullTime{Time: now, Valid: true},
+		})
 		if err != nil {
-			helpers.RespondWithError(w, 403, fmt.Sprintf("Couldn't fetch user: %v", err))
+			log.Printf("Couldn't record use of API key %v: %v", apiKey.Prefix, err)
+		}
+	}
+
+	// A service account's role applies in its own organisation
+	ctx := auth.WithPermissions(r.Context(), auth.Scope(granted, scopes))
+	ctx = auth.WithAPIKeyID(ctx, apiKey.ID)
+	ctx = auth.WithOrgID(ctx, account.OrgID)
+	return ServiceAccountUser(account), ctx, true
+}
+
+// ServiceAccountUser is the user handlers see for requests made with one of
+// account's API keys. The email is what the audit log shows as the actor.
+func ServiceAccountUser(account database.ServiceAccount) database.User {
+	return database.User{
+		ID: account.ID,
+		CreatedAt: account.CreatedAt,
+		UpdatedAt: account.UpdatedAt,
+		Username: account.Name,
+		Name: account.Name,
+		Email: "service:" + account.Name,
+		Role: account.Role,
+	}
+}
+
+// MiddlewareUserAuth is MiddlewareAuth for routes that only make sense for
+// people, such as changing a password, and rejects API keys
+func (cfg ApiCfg) MiddlewareUserAuth(next authedHandler) http.HandlerFunc {
+	return cfg.MiddlewareAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if auth.IsServiceAccount(r.Context()) {
+			helpers.RespondWithError(w, 403, "Service accounts can't use this endpoint")
+			return
+		}
+		next(w, r, user)
+	})
+}
+
+// RequirePermission authenticates the request like MiddlewareAuth and then
+// rejects it with a 403 unless the user's role grants permission
+func (cfg ApiCfg) RequirePermission(permission string, next authedHandler) http.HandlerFunc {
+	return cfg.MiddlewareAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if !auth.HasPermission(r.Context(), permission) {
+			helpers.RespondWithError(w, 403, "Unauthorized")
 			return
 		}
 		next(w, r, user)
+	})
+}
+// MiddlewareOptionalAuth lets anonymous requests through with an empty
+// user, but still rejects requests that send invalid credentials. Requests
+// without an active organisation name one with ?org=<slug>, or get the
+// default organisation.
+func (cfg ApiCfg) MiddlewareOptionalAuth(next authedHandler) http.HandlerFunc {
+	withOrg := func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if auth.OrgID(r.Context()) == uuid.Nil {
+			ctx, ok := cfg.requestedOrg(w, r)
+			if !ok {
+				return
+			}
+			r = r.WithContext(ctx)
+		}
+		next(w, r, user)
+	}
+
+	authed := cfg.MiddlewareAuth(withOrg)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			withOrg(w, r, database.User{})
+			return
+		}
+		authed(w, r)
 	}
-}
\ No newline at end of file
+}
+
+// requestedOrg looks up the organisation named by the ?org= slug, falling
+// back to DefaultOrg
+func (cfg ApiCfg) requestedOrg(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	slug := r.URL.Query().Get("org")
+	if slug == "" {
+		slug = cfg.DefaultOrg
+	}
+	if slug == "" {
+		helpers.RespondWithError(w, 400, "An organisation is required, pass ?org=<slug>")
+		return nil, false
+	}
+
+	org, err := cfg.DB.GetOrganizationBySlug(r.Context(), slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, 404, "Organisation not found")
+			return nil, false
+		}
+		helpers.RespondWithError(w, 500, fmt.Sprintf("Couldn't fetch organisation: %v", err))
+		return nil, false
+	}
+	return auth.WithOrgID(r.Context(), org.ID), true
+}
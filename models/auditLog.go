@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+type AuditLog struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ActorID uuid.UUID `json:"actor_id"`
+	ActorEmail string `json:"actor_email"`
+	Action string `json:"action"`
+	Entity string `json:"entity"`
+	EntityID uuid.UUID `json:"entity_id"`
+	Changes json.RawMessage `json:"changes"`
+	RequestID string `json:"request_id"`
+	IP string `json:"ip"`
+}
+
+func DatabaseAuditLogsToAuditLogs(dbAuditLogs []database.AuditLog) []AuditLog {
+	auditLogs := []AuditLog{}
+
+	for _, dbAuditLog := range dbAuditLogs {
+		auditLogs = append(auditLogs, AuditLog{
+			ID: dbAuditLog.ID,
+			CreatedAt: dbAuditLog.CreatedAt,
+			ActorID: dbAuditLog.ActorID,
+			ActorEmail: dbAuditLog.ActorEmail,
+			Action: dbAuditLog.Action,
+			Entity: dbAuditLog.Entity,
+			EntityID: dbAuditLog.EntityID,
+			Changes: dbAuditLog.Changes,
+			RequestID: dbAuditLog.RequestID,
+			IP: dbAuditLog.Ip,
+		})
+	}
+	return auditLogs
+}
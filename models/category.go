@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
 	"github.com/ringtho/inventory/internal/database"
 )
 
@@ -14,6 +15,7 @@ type Category struct {
 	CreatedAt time.Time	`json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	CreatedBy uuid.UUID `json:"created_by"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func DatabaseCategoryToCategory(dbCategory database.Category) Category{
@@ -24,6 +26,7 @@ func DatabaseCategoryToCategory(dbCategory database.Category) Category{
 		CreatedAt: dbCategory.CreatedAt,
 		UpdatedAt: dbCategory.UpdatedAt,
 		CreatedBy: dbCategory.CreatedBy,
+		DeletedAt: nullTimePtr(dbCategory.DeletedAt),
 	}
 }
 
@@ -39,9 +42,20 @@ func DatabaseCategoriesToCategories(dbCategories []database.Category) []Category
 			CreatedAt: dbCategory.CreatedAt,
 			UpdatedAt: dbCategory.UpdatedAt,
 			CreatedBy: dbCategory.CreatedBy,
+			DeletedAt: nullTimePtr(dbCategory.DeletedAt),
 		}
 		categories = append(categories, category)
 	}
 
 	return categories
-}
\ No newline at end of file
+}
+// CategoryExportColumns are the columns available to CSV and XLSX exports
+var CategoryExportColumns = []helpers.ExportColumn[database.Category]{
+	{Name: "id", Value: func(c database.Category) string { return c.ID.String() }},
+	{Name: "name", Value: func(c database.Category) string { return c.Name }},
+	{Name: "description", Value: func(c database.Category) string { return c.Description.String }},
+	{Name: "created_by", Value: func(c database.Category) string { return c.CreatedBy.String() }},
+	{Name: "created_at", Value: func(c database.Category) string { return c.CreatedAt.Format(time.RFC3339) }},
+	{Name: "updated_at", Value: func(c database.Category) string { return c.UpdatedAt.Format(time.RFC3339) }},
+	{Name: "deleted_at", Value: func(c database.Category) string { return nullTimeString(c.DeletedAt) }},
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+type Invitation struct {
+	ID uuid.UUID `json:"id"`
+	Email string `json:"email"`
+	Role string `json:"role"`
+	InvitedBy *uuid.UUID `json:"invited_by,omitempty"`
+	// OrgID is the organisation the invitee joins, the default one when
+	// it's empty
+	OrgID *uuid.UUID `json:"org_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Token is only returned when the invitation is created, so the admin
+	// can hand it over when email isn't set up
+	Token string `json:"token,omitempty"`
+}
+
+func DatabaseInvitationToInvitation(dbInvitation database.Invitation) Invitation {
+	invitation := Invitation{
+		ID: dbInvitation.ID,
+		Email: dbInvitation.Email,
+		Role: dbInvitation.Role,
+		CreatedAt: dbInvitation.CreatedAt,
+		ExpiresAt: dbInvitation.ExpiresAt,
+	}
+	if dbInvitation.InvitedBy.Valid {
+		invitation.InvitedBy = &dbInvitation.InvitedBy.UUID
+	}
+	if dbInvitation.OrgID.Valid {
+		invitation.OrgID = &dbInvitation.OrgID.UUID
+	}
+	return invitation
+}
+
+func DatabaseInvitationsToInvitations(dbInvitations []database.Invitation) []Invitation {
+	invitations := []Invitation{}
+	for _, dbInvitation := range dbInvitations {
+		invitations = append(invitations, DatabaseInvitationToInvitation(dbInvitation))
+	}
+	return invitations
+}
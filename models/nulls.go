@@ -0,0 +1,37 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func nullInt32String(i sql.NullInt32) string {
+	if !i.Valid {
+		return ""
+	}
+	return fmt.Sprint(i.Int32)
+}
+
+func nullUUIDString(u uuid.NullUUID) string {
+	if !u.Valid {
+		return ""
+	}
+	return u.UUID.String()
+}
+
+func nullTimeString(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format(time.RFC3339)
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
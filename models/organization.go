@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+type Organization struct {
+	ID uuid.UUID `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OrganizationMember struct {
+	OrgID uuid.UUID `json:"org_id"`
+	UserID uuid.UUID `json:"user_id"`
+	Role string `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership is an organisation a user belongs to and their role in it
+type Membership struct {
+	ID uuid.UUID `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	Role string `json:"role"`
+}
+
+func DatabaseOrganizationToOrganization(dbOrg database.Organization) Organization {
+	return Organization{
+		ID: dbOrg.ID,
+		Name: dbOrg.Name,
+		Slug: dbOrg.Slug,
+		CreatedAt: dbOrg.CreatedAt,
+		UpdatedAt: dbOrg.UpdatedAt,
+	}
+}
+
+func DatabaseOrganizationsToOrganizations(dbOrgs []database.Organization) []Organization {
+	orgs := []Organization{}
+	for _, dbOrg := range dbOrgs {
+		orgs = append(orgs, DatabaseOrganizationToOrganization(dbOrg))
+	}
+	return orgs
+}
+
+func DatabaseMemberToMember(dbMember database.OrganizationMember) OrganizationMember {
+	return OrganizationMember{
+		OrgID: dbMember.OrgID,
+		UserID: dbMember.UserID,
+		Role: dbMember.Role,
+		CreatedAt: dbMember.CreatedAt,
+	}
+}
+
+func DatabaseMembersToMembers(dbMembers []database.OrganizationMember) []OrganizationMember {
+	members := []OrganizationMember{}
+	for _, dbMember := range dbMembers {
+		members = append(members, DatabaseMemberToMember(dbMember))
+	}
+	return members
+}
+
+func DatabaseMembershipsToMemberships(rows []database.GetUserOrganizationsRow) []Membership {
+	memberships := []Membership{}
+	for _, row := range rows {
+		memberships = append(memberships, Membership{
+			ID: row.ID,
+			Name: row.Name,
+			Slug: row.Slug,
+			Role: row.Role,
+		})
+	}
+	return memberships
+}
@@ -1,9 +1,11 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
 	"github.com/ringtho/inventory/internal/database"
 )
 
@@ -19,6 +21,7 @@ type Product struct {
     Sku 		*string 	`json:"sku"`
 	UpdatedAt 	time.Time 	`json:"updated_at"`
 	CreatedAt 	time.Time 	`json:"created_at"`
+	DeletedAt 	*time.Time 	`json:"deleted_at,omitempty"`
 }
 
 func DatabaseProductToProduct(dbProduct database.Product) Product {
@@ -33,6 +36,7 @@ func DatabaseProductToProduct(dbProduct database.Product) Product {
 		Sku: 			&dbProduct.Sku.String,
 		CreatedAt: 		dbProduct.CreatedAt,
 		UpdatedAt: 		dbProduct.UpdatedAt,
+		DeletedAt: 		nullTimePtr(dbProduct.DeletedAt),
 	}
 }
 
@@ -51,9 +55,24 @@ func DatabaseProductsToProducts(dbProducts []database.Product) []Product {
 			Sku: &dbProduct.Sku.String,
 			CreatedAt: dbProduct.CreatedAt,
 			UpdatedAt: dbProduct.UpdatedAt,
+			DeletedAt: nullTimePtr(dbProduct.DeletedAt),
 		}
 		products = append(products, product)
 	}
 
 	return products
-}
\ No newline at end of file
+}
+// ProductExportColumns are the columns available to CSV and XLSX exports
+var ProductExportColumns = []helpers.ExportColumn[database.Product]{
+	{Name: "id", Value: func(p database.Product) string { return p.ID.String() }},
+	{Name: "name", Value: func(p database.Product) string { return p.Name }},
+	{Name: "description", Value: func(p database.Product) string { return p.Description.String }},
+	{Name: "price", Numeric: true, Value: func(p database.Product) string { return fmt.Sprint(p.Price) }},
+	{Name: "stock_level", Numeric: true, Value: func(p database.Product) string { return nullInt32String(p.StockLevel) }},
+	{Name: "category_id", Value: func(p database.Product) string { return nullUUIDString(p.CategoryID) }},
+	{Name: "supplier_id", Value: func(p database.Product) string { return nullUUIDString(p.SupplierID) }},
+	{Name: "sku", Value: func(p database.Product) string { return p.Sku.String }},
+	{Name: "created_at", Value: func(p database.Product) string { return p.CreatedAt.Format(time.RFC3339) }},
+	{Name: "updated_at", Value: func(p database.Product) string { return p.UpdatedAt.Format(time.RFC3339) }},
+	{Name: "deleted_at", Value: func(p database.Product) string { return nullTimeString(p.DeletedAt) }},
+}
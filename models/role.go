@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ringtho/inventory/internal/database"
+)
+
+type Role struct {
+	Name string `json:"name"`
+	Description string `json:"description"`
+	BuiltIn bool `json:"built_in"`
+	Permissions []string `json:"permissions"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func DatabaseRoleToRole(dbRole database.Role, permissions []string) Role {
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return Role{
+		Name: dbRole.Name,
+		Description: dbRole.Description.String,
+		BuiltIn: dbRole.BuiltIn,
+		Permissions: permissions,
+		CreatedAt: dbRole.CreatedAt,
+		UpdatedAt: dbRole.UpdatedAt,
+	}
+}
+
+// DatabaseRolesToRoles pairs each role with its rows from role_permissions
+func DatabaseRolesToRoles(dbRoles []database.Role, rolePermissions []database.RolePermission) []Role {
+	permissions := map[string][]string{}
+	for _, rp := range rolePermissions {
+		permissions[rp.Role] = append(permissions[rp.Role], rp.Permission)
+	}
+
+	roles := []Role{}
+	for _, dbRole := range dbRoles {
+		roles = append(roles, DatabaseRoleToRole(dbRole, permissions[dbRole.Name]))
+	}
+	return roles
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+type ServiceAccount struct {
+	ID uuid.UUID `json:"id"`
+	OrgID uuid.UUID `json:"org_id"`
+	Name string `json:"name"`
+	Description string `json:"description"`
+	Role string `json:"role"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type APIKey struct {
+	ID uuid.UUID `json:"id"`
+	ServiceAccountID uuid.UUID `json:"service_account_id"`
+	Name string `json:"name"`
+	Prefix string `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Key is only returned when the key is created
+	Key string `json:"key,omitempty"`
+}
+
+func DatabaseServiceAccountToServiceAccount(dbAccount database.ServiceAccount) ServiceAccount {
+	account := ServiceAccount{
+		ID: dbAccount.ID,
+		OrgID: dbAccount.OrgID,
+		Name: dbAccount.Name,
+		Description: dbAccount.Description.String,
+		Role: dbAccount.Role,
+		CreatedAt: dbAccount.CreatedAt,
+		UpdatedAt: dbAccount.UpdatedAt,
+	}
+	if dbAccount.CreatedBy.Valid {
+		account.CreatedBy = &dbAccount.CreatedBy.UUID
+	}
+	return account
+}
+
+func DatabaseServiceAccountsToServiceAccounts(dbAccounts []database.ServiceAccount) []ServiceAccount {
+	accounts := []ServiceAccount{}
+	for _, dbAccount := range dbAccounts {
+		accounts = append(accounts, DatabaseServiceAccountToServiceAccount(dbAccount))
+	}
+	return accounts
+}
+
+func DatabaseAPIKeyToAPIKey(dbKey database.ApiKey, scopes []string) APIKey {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return APIKey{
+		ID: dbKey.ID,
+		ServiceAccountID: dbKey.ServiceAccountID,
+		Name: dbKey.Name,
+		Prefix: dbKey.Prefix,
+		Scopes: scopes,
+		CreatedAt: dbKey.CreatedAt,
+		ExpiresAt: nullTimePtr(dbKey.ExpiresAt),
+		LastUsedAt: nullTimePtr(dbKey.LastUsedAt),
+		RevokedAt: nullTimePtr(dbKey.RevokedAt),
+	}
+}
+
+// DatabaseAPIKeysToAPIKeys pairs each key with its rows from api_key_scopes
+func DatabaseAPIKeysToAPIKeys(dbKeys []database.ApiKey, keyScopes []database.ApiKeyScope) []APIKey {
+	scopes := map[uuid.UUID][]string{}
+	for _, scope := range keyScopes {
+		scopes[scope.ApiKeyID] = append(scopes[scope.ApiKeyID], scope.Permission)
+	}
+
+	keys := []APIKey{}
+	for _, dbKey := range dbKeys {
+		keys = append(keys, DatabaseAPIKeyToAPIKey(dbKey, scopes[dbKey.ID]))
+	}
+	return keys
+}
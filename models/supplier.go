@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
 	"github.com/ringtho/inventory/internal/database"
 )
 
@@ -17,6 +18,7 @@ type Supplier struct {
 	Country *string `json:"country"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func DatabaseSupplierToSupplier(dbSupplier database.Supplier) Supplier {
@@ -29,6 +31,7 @@ func DatabaseSupplierToSupplier(dbSupplier database.Supplier) Supplier {
 		Country: &dbSupplier.Country.String,
 		CreatedAt: dbSupplier.CreatedAt,
 		UpdatedAt: dbSupplier.UpdatedAt,
+		DeletedAt: nullTimePtr(dbSupplier.DeletedAt),
 	}
 }
 
@@ -44,10 +47,23 @@ func DatabaseSuppliersToSuppliers(dbSuppliers []database.Supplier) []Supplier {
 			Country: &dbSupplier.Country.String,
 			CreatedAt: dbSupplier.CreatedAt,
 			UpdatedAt: dbSupplier.UpdatedAt,
+			DeletedAt: nullTimePtr(dbSupplier.DeletedAt),
 		}
 
 		suppliers = append(suppliers, supplier)
 	}
 
 	return suppliers
-}
\ No newline at end of file
+}
+// SupplierExportColumns are the columns available to CSV and XLSX exports
+var SupplierExportColumns = []helpers.ExportColumn[database.Supplier]{
+	{Name: "id", Value: func(s database.Supplier) string { return s.ID.String() }},
+	{Name: "name", Value: func(s database.Supplier) string { return s.Name }},
+	{Name: "email", Value: func(s database.Supplier) string { return s.Email.String }},
+	{Name: "description", Value: func(s database.Supplier) string { return s.Description.String }},
+	{Name: "phone", Value: func(s database.Supplier) string { return s.Phone.String }},
+	{Name: "country", Value: func(s database.Supplier) string { return s.Country.String }},
+	{Name: "created_at", Value: func(s database.Supplier) string { return s.CreatedAt.Format(time.RFC3339) }},
+	{Name: "updated_at", Value: func(s database.Supplier) string { return s.UpdatedAt.Format(time.RFC3339) }},
+	{Name: "deleted_at", Value: func(s database.Supplier) string { return nullTimeString(s.DeletedAt) }},
+}
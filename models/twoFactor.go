@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+)
+
+// MFAChallengeResponse is the first step of a login that needs a second
+// factor. MFAToken is exchanged for the real tokens at /auth/2fa/verify, or
+// at /auth/2fa/setup when EnrollmentRequired is set.
+type MFAChallengeResponse struct {
+	MFARequired 		bool 	`json:"mfa_required"`
+	EnrollmentRequired 	bool 	`json:"enrollment_required"`
+	MFAToken 			string 	`json:"mfa_token"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret 		string `json:"secret"`
+	OtpauthURI 	string `json:"otpauth_uri"`
+	// QRCode is a PNG of OtpauthURI as a data URI
+	QRCode 		string `json:"qr_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type SecuritySettings struct {
+	RequireAdminMFA bool 		`json:"require_admin_mfa"`
+	UpdatedAt 		time.Time 	`json:"updated_at"`
+}
+
+func TOTPKeyToEnrollmentResponse(key helpers.TOTPKey) TOTPEnrollmentResponse {
+	return TOTPEnrollmentResponse{
+		Secret: 	key.Secret,
+		OtpauthURI: key.URI,
+		QRCode: 	"data:image/png;base64," + base64.StdEncoding.EncodeToString(key.QRCode),
+	}
+}
+
+func DatabaseSecuritySettingsToSecuritySettings(settings database.SecuritySetting) SecuritySettings {
+	return SecuritySettings{
+		RequireAdminMFA: 	settings.RequireAdminMfa,
+		UpdatedAt: 			settings.UpdatedAt,
+	}
+}
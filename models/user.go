@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
 	"github.com/ringtho/inventory/internal/database"
 )
 
@@ -25,6 +26,7 @@ type UserResponse struct {
 	ProfilePictureUrl 	*string 	`json:"profile_picture_url"`
 	CreatedAt 			time.Time 	`json:"created_at"`
 	UpdatedAt 			time.Time 	`json:"updated_at"`
+	EmailVerifiedAt 	*time.Time 	`json:"email_verified_at,omitempty"`
 }
 
 func DatabaseUserToUserResponse(user database.CreateUserRow) UserResponse {
@@ -67,12 +69,39 @@ func DatabaseUsersToUsers(dbUsers []database.GetAllUsersRow) []UserResponse {
 	return userResponses
 }
 
+// DatabaseUserToUser strips the password hash from a full user row
+func DatabaseUserToUser(user database.User) UserResponse {
+	var profilePicture *string
+	if user.ProfilePictureUrl.Valid {
+		profilePicture = &user.ProfilePictureUrl.String
+	}
+	var emailVerifiedAt *time.Time
+	if user.EmailVerifiedAt.Valid {
+		emailVerifiedAt = &user.EmailVerifiedAt.Time
+	}
+
+	return UserResponse{
+		ID: 				user.ID,
+		Name: 				user.Name,
+		Username: 			user.Username,
+		Email: 				user.Email,
+		Role: 				user.Role,
+		ProfilePictureUrl: 	profilePicture,
+		CreatedAt: 			user.CreatedAt,
+		UpdatedAt: 			user.UpdatedAt,
+		EmailVerifiedAt: 	emailVerifiedAt,
+	}
+}
+
 type LoginResponse struct {
 	Token string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 	User UserResponse `json:"user"`
+	// RecoveryCodes is only set when 2FA was set up as part of the login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
-func SanitizeLoginResponse(user database.User, token string) LoginResponse {
+func SanitizeLoginResponse(user database.User, token string, refreshToken string) LoginResponse {
 	var profilePicture *string
 	if user.ProfilePictureUrl.Valid {
 		profilePicture = &user.ProfilePictureUrl.String
@@ -80,6 +109,7 @@ func SanitizeLoginResponse(user database.User, token string) LoginResponse {
 
 	return LoginResponse{
 		Token: 				token,
+		RefreshToken: 		refreshToken,
 		User: 				UserResponse{
 			ID: 				user.ID,
 			Name: 				user.Name,
@@ -89,4 +119,16 @@ func SanitizeLoginResponse(user database.User, token string) LoginResponse {
 			ProfilePictureUrl: 	profilePicture,
 		},
 	}
-}
\ No newline at end of file
+}
+// UserExportColumns are the columns available to CSV and XLSX exports.
+// Password hashes are never part of an export.
+var UserExportColumns = []helpers.ExportColumn[database.GetAllUsersRow]{
+	{Name: "id", Value: func(u database.GetAllUsersRow) string { return u.ID.String() }},
+	{Name: "name", Value: func(u database.GetAllUsersRow) string { return u.Name }},
+	{Name: "username", Value: func(u database.GetAllUsersRow) string { return u.Username }},
+	{Name: "email", Value: func(u database.GetAllUsersRow) string { return u.Email }},
+	{Name: "role", Value: func(u database.GetAllUsersRow) string { return u.Role }},
+	{Name: "profile_picture_url", Value: func(u database.GetAllUsersRow) string { return u.ProfilePictureUrl.String }},
+	{Name: "created_at", Value: func(u database.GetAllUsersRow) string { return u.CreatedAt.Format(time.RFC3339) }},
+	{Name: "updated_at", Value: func(u database.GetAllUsersRow) string { return u.UpdatedAt.Format(time.RFC3339) }},
+}
@@ -5,21 +5,53 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/ringtho/inventory/config"
 	"github.com/ringtho/inventory/controllers"
 	"github.com/ringtho/inventory/helpers"
-	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/mailer"
+	"github.com/ringtho/inventory/metrics"
 	"github.com/ringtho/inventory/middlewares"
+	"github.com/ringtho/inventory/sso"
 )
 
-// Router returns a new HTTP handler that implements the main server routes
-func Router(DB *database.Queries) http.Handler {
+// Services are what the routes need besides the store, built from the
+// configuration by initializers.NewServices
+type Services struct {
+	Settings config.Config
+	// Mailer sends emails, they're logged when it's nil
+	Mailer mailer.Mailer
+	// SSO is nil when single sign-on isn't configured
+	SSO *sso.Provider
+}
+
+// Router returns a new HTTP handler that implements the main server routes.
+// health answers the probes, and with nil the readiness probe doesn't
+// check a database and /metrics has no connection pool stats.
+func Router(DB store.Store, health *controllers.HealthCfg, services Services) http.Handler {
 	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
 	router.Use(middleware.Logger)
+	router.Use(middlewares.Metrics)
 
 	apiRouter := chi.NewRouter()
 
-	apiCfg := controllers.ApiCfg{DB: DB}
-	cfg := middlewares.ApiCfg{DB: DB}
+	settings := services.Settings
+	apiCfg := controllers.ApiCfg{
+		DB: DB,
+		RequireIfMatch: settings.Server.RequireIfMatch,
+		Mailer: services.Mailer,
+		PasswordResetURL: settings.Server.PasswordResetURL,
+		PublicURL: settings.Server.PublicURL,
+		AllowUnverifiedLogin: settings.Auth.AllowUnverifiedLogin,
+		DisableRegistration: settings.Auth.DisableRegistration,
+		InvitationURL: settings.Server.InvitationURL,
+		DefaultOrg: settings.Server.DefaultOrg,
+		SSO: services.SSO,
+		TrustedProxies: settings.Server.TrustedProxyNetworks(),
+	}
+	cfg := middlewares.ApiCfg{DB: DB, DefaultOrg: settings.Server.DefaultOrg}
 
 	apiRouter.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		type Message struct {
@@ -30,28 +62,93 @@ func Router(DB *database.Queries) http.Handler {
 	})
 
 	apiRouter.Post("/register", apiCfg.CreateUserController)
+	apiRouter.Post("/invitations/accept", apiCfg.AcceptInvitationController)
 	apiRouter.Post("/login", apiCfg.LoginController)
-	apiRouter.Get("/users", cfg.MiddlewareAuth(apiCfg.GetAllUsersController))
-	apiRouter.Delete("/users/{userId}", cfg.MiddlewareAuth(apiCfg.DeleteUserController))
-
-	apiRouter.Post("/categories", cfg.MiddlewareAuth(apiCfg.CreateCategoryController))
-	apiRouter.Get("/categories", apiCfg.GetCategoriesController)
-	apiRouter.Put("/categories/{categoryId}", cfg.MiddlewareAuth(apiCfg.UpdateCategoryController))
-	apiRouter.Delete("/categories/{categoryId}", cfg.MiddlewareAuth(apiCfg.DeleteCategoryController))
-	apiRouter.Get("/categories/{categoryId}", cfg.MiddlewareAuth(apiCfg.GetCategoryController))
-
-	apiRouter.Post("/suppliers", cfg.MiddlewareAuth(apiCfg.CreateSupplierController))
-	apiRouter.Get("/suppliers", cfg.MiddlewareAuth(apiCfg.GetAllSuppliersController))
-	apiRouter.Get("/suppliers/{supplierId}", cfg.MiddlewareAuth(apiCfg.GetSupplierController))
-	apiRouter.Delete("/suppliers/{supplierId}", cfg.MiddlewareAuth(apiCfg.DeleteSupplierController))
-	apiRouter.Put("/suppliers/{supplierId}", cfg.MiddlewareAuth(apiCfg.UpdateSupplierController))
-
-	apiRouter.Post("/products", cfg.MiddlewareAuth(apiCfg.CreateProductController))
-	apiRouter.Get("/products", apiCfg.GetAllProductsController)
-	apiRouter.Get("/products/{productId}", apiCfg.GetProductController)
-	apiRouter.Delete("/products/{productId}", cfg.MiddlewareAuth(apiCfg.DeleteProductController))
-	apiRouter.Put("/products/{productId}", cfg.MiddlewareAuth(apiCfg.UpdateProductController))
+	apiRouter.Post("/auth/refresh", apiCfg.RefreshController)
+	apiRouter.Post("/auth/logout", apiCfg.LogoutController)
+	apiRouter.Get("/auth/oidc/login", apiCfg.OIDCLoginController)
+	apiRouter.Get("/auth/oidc/callback", apiCfg.OIDCCallbackController)
+	apiRouter.Post("/auth/forgot-password", apiCfg.ForgotPasswordController)
+	apiRouter.Post("/auth/reset-password", apiCfg.ResetPasswordController)
+	apiRouter.Get("/auth/verify", apiCfg.VerifyEmailController)
+	apiRouter.Post("/auth/verify/resend", apiCfg.ResendVerificationController)
+	apiRouter.Post("/auth/2fa/verify", apiCfg.TwoFactorVerifyController)
+	apiRouter.Post("/auth/2fa/setup", apiCfg.TwoFactorSetupController)
+	apiRouter.Post("/auth/2fa/setup/confirm", apiCfg.TwoFactorSetupConfirmController)
+	apiRouter.Post("/auth/2fa/enroll", cfg.MiddlewareUserAuth(apiCfg.TwoFactorEnrollController))
+	apiRouter.Post("/auth/2fa/confirm", cfg.MiddlewareUserAuth(apiCfg.TwoFactorConfirmController))
+	apiRouter.Post("/auth/2fa/disable", cfg.MiddlewareUserAuth(apiCfg.TwoFactorDisableController))
+	apiRouter.Get("/me", cfg.MiddlewareUserAuth(apiCfg.GetMeController))
+	apiRouter.Patch("/me", cfg.MiddlewareUserAuth(apiCfg.PatchMeController))
+	apiRouter.Post("/me/password", cfg.MiddlewareUserAuth(apiCfg.ChangePasswordController))
+	apiRouter.Get("/me/organizations", cfg.MiddlewareUserAuth(apiCfg.GetMyOrganizationsController))
+	apiRouter.Post("/auth/switch-org", cfg.MiddlewareUserAuth(apiCfg.SwitchOrganizationController))
+	apiRouter.Get("/users", cfg.RequirePermission(auth.UsersRead, apiCfg.GetAllUsersController))
+	apiRouter.Delete("/users/{userId}", cfg.RequirePermission(auth.UsersDelete, apiCfg.DeleteUserController))
+	apiRouter.Put("/users/{userId}", cfg.RequirePermission(auth.UsersWrite, apiCfg.UpdateUserController))
+	apiRouter.Get("/invitations", cfg.RequirePermission(auth.UsersRead, apiCfg.GetInvitationsController))
+	apiRouter.Post("/invitations", cfg.RequirePermission(auth.UsersWrite, apiCfg.CreateInvitationController))
+	apiRouter.Delete("/invitations/{invitationId}", cfg.RequirePermission(auth.UsersWrite, apiCfg.DeleteInvitationController))
+	apiRouter.Post("/users/{userId}/unlock", cfg.RequirePermission(auth.UsersWrite, apiCfg.UnlockUserController))
+	apiRouter.Put("/users/{userId}/role", cfg.RequirePermission(auth.RolesManage, apiCfg.AssignUserRoleController))
+
+	apiRouter.Get("/permissions", cfg.RequirePermission(auth.RolesManage, apiCfg.GetPermissionsController))
+	apiRouter.Get("/roles", cfg.RequirePermission(auth.RolesManage, apiCfg.GetRolesController))
+	apiRouter.Post("/roles", cfg.RequirePermission(auth.RolesManage, apiCfg.CreateRoleController))
+	apiRouter.Put("/roles/{role}", cfg.RequirePermission(auth.RolesManage, apiCfg.UpdateRoleController))
+	apiRouter.Delete("/roles/{role}", cfg.RequirePermission(auth.RolesManage, apiCfg.DeleteRoleController))
+
+	apiRouter.Get("/service-accounts", cfg.RequirePermission(auth.ServiceAccountsManage, apiCfg.GetServiceAccountsController))
+	apiRouter.Post("/service-accounts", cfg.RequirePermission(auth.ServiceAccountsManage, apiCfg.CreateServiceAccountController))
+	apiRouter.Delete("/service-accounts/{serviceAccountId}", cfg.RequirePermission(auth.ServiceAccountsManage, apiCfg.DeleteServiceAccountController))
+	apiRouter.Get("/service-accounts/{serviceAccountId}/keys", cfg.RequirePermission(auth.ServiceAccountsManage, apiCfg.GetAPIKeysController))
+	apiRouter.Post("/service-accounts/{serviceAccountId}/keys", cfg.RequirePermission(auth.ServiceAccountsManage, apiCfg.CreateAPIKeyController))
+	apiRouter.Delete("/service-accounts/{serviceAccountId}/keys/{keyId}", cfg.RequirePermission(auth.ServiceAccountsManage, apiCfg.RevokeAPIKeyController))
+
+	apiRouter.Get("/organizations", cfg.RequirePermission(auth.OrganizationsManage, apiCfg.GetOrganizationsController))
+	apiRouter.Post("/organizations", cfg.RequirePermission(auth.OrganizationsManage, apiCfg.CreateOrganizationController))
+	apiRouter.Get("/organizations/{orgId}/members", cfg.RequirePermission(auth.OrganizationsManage, apiCfg.GetOrganizationMembersController))
+	apiRouter.Put("/organizations/{orgId}/members/{userId}", cfg.RequirePermission(auth.OrganizationsManage, apiCfg.SetOrganizationMemberController))
+	apiRouter.Delete("/organizations/{orgId}/members/{userId}", cfg.RequirePermission(auth.OrganizationsManage, apiCfg.RemoveOrganizationMemberController))
+
+	apiRouter.Get("/audit", cfg.RequirePermission(auth.AuditRead, apiCfg.GetAuditLogsController))
+	apiRouter.Get("/settings/security", cfg.RequirePermission(auth.SettingsManage, apiCfg.GetSecuritySettingsController))
+	apiRouter.Put("/settings/security", cfg.RequirePermission(auth.SettingsManage, apiCfg.UpdateSecuritySettingsController))
+
+	apiRouter.Post("/categories", cfg.RequirePermission(auth.CategoriesWrite, apiCfg.CreateCategoryController))
+	apiRouter.Get("/categories", cfg.MiddlewareOptionalAuth(apiCfg.GetCategoriesController))
+	apiRouter.Put("/categories/{categoryId}", cfg.RequirePermission(auth.CategoriesWrite, apiCfg.UpdateCategoryController))
+	apiRouter.Patch("/categories/{categoryId}", cfg.RequirePermission(auth.CategoriesWrite, apiCfg.PatchCategoryController))
+	apiRouter.Delete("/categories/{categoryId}", cfg.RequirePermission(auth.CategoriesDelete, apiCfg.DeleteCategoryController))
+	apiRouter.Get("/categories/{categoryId}", cfg.RequirePermission(auth.CategoriesRead, apiCfg.GetCategoryController))
+	apiRouter.Post("/categories/{categoryId}/restore", cfg.RequirePermission(auth.CategoriesDelete, apiCfg.RestoreCategoryController))
+
+	apiRouter.Post("/suppliers", cfg.RequirePermission(auth.SuppliersWrite, apiCfg.CreateSupplierController))
+	apiRouter.Get("/suppliers", cfg.RequirePermission(auth.SuppliersRead, apiCfg.GetAllSuppliersController))
+	apiRouter.Get("/suppliers/{supplierId}", cfg.RequirePermission(auth.SuppliersRead, apiCfg.GetSupplierController))
+	apiRouter.Delete("/suppliers/{supplierId}", cfg.RequirePermission(auth.SuppliersDelete, apiCfg.DeleteSupplierController))
+	apiRouter.Put("/suppliers/{supplierId}", cfg.RequirePermission(auth.SuppliersWrite, apiCfg.UpdateSupplierController))
+	apiRouter.Patch("/suppliers/{supplierId}", cfg.RequirePermission(auth.SuppliersWrite, apiCfg.PatchSupplierController))
+	apiRouter.Post("/suppliers/{supplierId}/restore", cfg.RequirePermission(auth.SuppliersDelete, apiCfg.RestoreSupplierController))
+
+	apiRouter.Post("/products", cfg.RequirePermission(auth.ProductsWrite, apiCfg.CreateProductController))
+	apiRouter.Get("/products", cfg.MiddlewareOptionalAuth(apiCfg.GetAllProductsController))
+	apiRouter.Get("/products/{productId}", cfg.MiddlewareOptionalAuth(apiCfg.GetProductController))
+	apiRouter.Delete("/products/{productId}", cfg.RequirePermission(auth.ProductsDelete, apiCfg.DeleteProductController))
+	apiRouter.Put("/products/{productId}", cfg.RequirePermission(auth.ProductsWrite, apiCfg.UpdateProductController))
+	apiRouter.Patch("/products/{productId}", cfg.RequirePermission(auth.ProductsWrite, apiCfg.PatchProductController))
+	apiRouter.Post("/products/{productId}/restore", cfg.RequirePermission(auth.ProductsDelete, apiCfg.RestoreProductController))
+	apiRouter.Post("/products/{productId}/stock", cfg.RequirePermission(auth.ProductsStock, apiCfg.AdjustProductStockController))
+
+	if health == nil {
+		health = &controllers.HealthCfg{}
+	}
+	router.Get("/healthz", health.HealthzController)
+	router.Get("/readyz", health.ReadyzController)
+	router.Get("/version", health.VersionController)
+	router.Handle("/metrics", metrics.Handler(DB, health.Conn, settings.Server.MetricsToken))
 
+	router.Get("/.well-known/jwks.json", apiCfg.JWKSController)
 	router.Mount("/api/v1", apiRouter)
 	return router
 }
\ No newline at end of file
@@ -0,0 +1,240 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/ringtho/inventory/config"
+	"golang.org/x/oauth2"
+)
+
+// Config describes an OpenID Connect identity provider and how its users
+// map onto roles
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is this API's callback, /api/v1/auth/oidc/callback
+	RedirectURL string
+	Scopes      []string
+	// RoleClaim names the ID token claim, a string or a list of strings,
+	// that RoleMapping is looked up in. Roles are only synced on login when
+	// it's set.
+	RoleClaim   string
+	RoleMapping map[string]string
+	// DefaultRole is given to users none of whose claim values are mapped
+	DefaultRole string
+	// TrustUnverifiedEmail treats ID tokens without an email_verified
+	// claim as having a verified email
+	TrustUnverifiedEmail bool
+}
+
+// FromConfig sets up the provider in the OIDC_ settings. It returns nil
+// when OIDC_ISSUER_URL isn't set.
+func FromConfig(cfg config.OIDC) (*Provider, error) {
+	if cfg.IssuerURL == "" {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return New(Config{
+		IssuerURL: cfg.IssuerURL,
+		ClientID: cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL: cfg.RedirectURL,
+		Scopes: cfg.Scopes,
+		RoleClaim: cfg.RoleClaim,
+		RoleMapping: cfg.RoleMapping,
+		DefaultRole: cfg.DefaultRole,
+		TrustUnverifiedEmail: cfg.TrustUnverifiedEmail,
+	}), nil
+}
+
+// Provider runs the authorization code flow against one identity provider.
+// Discovery happens on first use so the API starts when the provider is
+// down.
+type Provider struct {
+	config Config
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func New(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = "user"
+	}
+	return &Provider{config: config}
+}
+
+// Issuer is the identity provider's issuer URL
+func (p *Provider) Issuer() string {
+	return p.config.IssuerURL
+}
+
+func (p *Provider) discover() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// The provider keeps using this context to refresh its keys, so it
+	// mustn't be the request's
+	provider, err := oidc.NewProvider(context.Background(), p.config.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't discover identity provider: %w", err)
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID: p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL: p.config.RedirectURL,
+		Endpoint: provider.Endpoint(),
+		Scopes: p.config.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+	return p.oauth2, p.verifier, nil
+}
+
+// Login is what the callback needs to finish a login started by AuthCodeURL
+type Login struct {
+	URL          string
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// AuthCodeURL starts a login. The state, nonce and PKCE verifier have to be
+// kept until the callback.
+func (p *Provider) AuthCodeURL(state string, nonce string) (Login, error) {
+	config, _, err := p.discover()
+	if err != nil {
+		return Login{}, err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	return Login{
+		URL: config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State: state,
+		Nonce: nonce,
+		CodeVerifier: verifier,
+	}, nil
+}
+
+// Identity is who the identity provider says signed in
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	// Roles are the values of the role claim
+	Roles []string
+}
+
+type idTokenClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     *bool  `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// Exchange swaps the code from the callback for an ID token and checks its
+// signature against the provider's JWKS, its audience, expiry and nonce
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (Identity, error) {
+	config, verifier, err := p.discover()
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("couldn't exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("token response has no id_token")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("invalid id_token: nonce doesn't match")
+	}
+
+	claims := idTokenClaims{}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("couldn't read id_token claims: %w", err)
+	}
+
+	// Without email_verified the address is only trusted when the provider
+	// is configured to be, since a verified email links existing accounts
+	emailVerified := p.config.TrustUnverifiedEmail
+	if claims.EmailVerified != nil {
+		emailVerified = *claims.EmailVerified
+	}
+
+	identity := Identity{
+		Issuer: idToken.Issuer,
+		Subject: idToken.Subject,
+		Email: claims.Email,
+		EmailVerified: emailVerified,
+		Name: claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}
+
+	if p.config.RoleClaim != "" {
+		all := map[string]interface{}{}
+		if err := idToken.Claims(&all); err != nil {
+			return Identity{}, fmt.Errorf("couldn't read id_token claims: %w", err)
+		}
+		identity.Roles = claimValues(all[p.config.RoleClaim])
+	}
+	return identity, nil
+}
+
+// SyncsRoles reports whether users' roles follow the role claim
+func (p *Provider) SyncsRoles() bool {
+	return p.config.RoleClaim != ""
+}
+
+// Role maps an identity's role claim values to a role. The first value
+// with a mapping wins, otherwise it's the default role.
+func (p *Provider) Role(identity Identity) string {
+	for _, value := range identity.Roles {
+		if role, ok := p.config.RoleMapping[value]; ok {
+			return role
+		}
+	}
+	return p.config.DefaultRole
+}
+
+func claimValues(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := []string{}
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Package ssotest runs a stub OpenID Connect identity provider for tests
+package ssotest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// IdP signs ID tokens with its own RSA key and serves discovery, JWKS and
+// token endpoints. Users "sign in" with SignIn instead of a login page.
+type IdP struct {
+	Server   *httptest.Server
+	ClientID string
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	redirectURI   string
+	codeChallenge string
+	claims        map[string]interface{}
+}
+
+func NewIdP(clientID string) (*IdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	idp := &IdP{ClientID: clientID, key: key, codes: map[string]authorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	return idp, nil
+}
+
+func (idp *IdP) Close() {
+	idp.Server.Close()
+}
+
+// Issuer is the URL to configure the API with
+func (idp *IdP) Issuer() string {
+	return idp.Server.URL
+}
+
+// SignIn plays the part of the user signing in at authURL. It returns the
+// callback URL the browser would be sent back to, with claims added to the
+// ID token the code is exchanged for.
+func (idp *IdP) SignIn(authURL string, claims map[string]interface{}) (string, error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != idp.ClientID || query.Get("response_type") != "code" {
+		return "", errors.New("unexpected authorization request")
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", errors.New("authorization request has no PKCE challenge")
+	}
+
+	idTokenClaims := map[string]interface{}{"nonce": query.Get("nonce")}
+	for name, value := range claims {
+		idTokenClaims[name] = value
+	}
+
+	code := randomString()
+	idp.mu.Lock()
+	idp.codes[code] = authorization{
+		redirectURI: query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		claims: idTokenClaims,
+	}
+	idp.mu.Unlock()
+
+	callback, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		return "", err
+	}
+	values := callback.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	callback.RawQuery = values.Encode()
+	return callback.String(), nil
+}
+
+func (idp *IdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, map[string]interface{}{
+		"issuer": idp.Server.URL,
+		"authorization_endpoint": idp.Server.URL + "/authorize",
+		"token_endpoint": idp.Server.URL + "/token",
+		"jwks_uri": idp.Server.URL + "/jwks",
+		"response_types_supported": []string{"code"},
+		"subject_types_supported": []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+func (idp *IdP) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key: &idp.key.PublicKey,
+		KeyID: "stub",
+		Algorithm: string(jose.RS256),
+		Use: "sig",
+	}}})
+}
+
+func (idp *IdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, 400, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	auth, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	if !ok || auth.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		writeJSON(w, 400, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key: jose.JSONWebKey{Key: idp.key, KeyID: "stub"},
+	}, nil)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := jwt.Signed(signer).
+		Claims(jwt.Claims{
+			Issuer: idp.Server.URL,
+			Audience: jwt.Audience{idp.ClientID},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry: jwt.NewNumericDate(now.Add(time.Hour)),
+		}).
+		Claims(auth.claims).
+		Serialize()
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, 200, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type": "Bearer",
+		"expires_in": 3600,
+		"id_token": idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func randomString() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyColumns = []string{
+	"id", "service_account_id", "name", "prefix", "secret_hash", "created_at", "expires_at", "last_used_at", "revoked_at",
+}
+
+// expectAPIKey sets up the queries MiddlewareAuth makes for a live key of a
+// service account with role, limited to scopes
+func expectAPIKey(t *testing.T, mock sqlmock.Sqlmock, role string, scopes ...string) (string, uuid.UUID) {
+	key, err := auth.GenerateAPIKey()
+	assert.NoError(t, err)
+	keyId, accountId := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE prefix = \$1`).
+		WithArgs(key.Prefix).
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(
+			keyId, accountId, "pos", key.Prefix, key.SecretHash, time.Now(), nil, nil, nil))
+	mock.ExpectQuery(`SELECT (.+) FROM service_accounts WHERE id = \$1`).
+		WithArgs(accountId).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "description", "role", "created_by", "created_at", "updated_at", "org_id",
+		}).AddRow(accountId, "pos-terminal", nil, role, nil, time.Now(), time.Now(), uuid.New()))
+	permissions := sqlmock.NewRows([]string{"permission"})
+	for _, permission := range rolePermissions[role] {
+		permissions.AddRow(permission)
+	}
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs(role).
+		WillReturnRows(permissions)
+	scopeRows := sqlmock.NewRows([]string{"permission"})
+	for _, scope := range scopes {
+		scopeRows.AddRow(scope)
+	}
+	mock.ExpectQuery(`SELECT permission FROM api_key_scopes WHERE api_key_id = \$1`).
+		WithArgs(keyId).
+		WillReturnRows(scopeRows)
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = \$2`).
+		WithArgs(keyId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	return key.Key, accountId
+}
+
+func TestAPIKey_RoundTrip(t *testing.T) {
+	key, err := auth.GenerateAPIKey()
+	assert.NoError(t, err)
+
+	prefix, secret, err := auth.ParseAPIKey(key.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, key.Prefix, prefix)
+	assert.True(t, auth.CheckAPIKeySecret(key.SecretHash, secret))
+	assert.False(t, auth.CheckAPIKeySecret(key.SecretHash, secret+"x"))
+
+	for _, malformed := range []string{"", "inv_abc", "key_abc_def", "inv__secret"} {
+		_, _, err := auth.ParseAPIKey(malformed)
+		assert.Error(t, err, malformed)
+	}
+}
+
+func TestGetCredentials(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "ApiKey inv_abc_def")
+	credentials, err := auth.GetCredentials(headers)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.Credentials{Scheme: auth.SchemeApiKey, Value: "inv_abc_def"}, credentials)
+
+	_, err = auth.GetToken(headers)
+	assert.Error(t, err)
+
+	headers.Set("Authorization", "Basic dXNlcjpwYXNz")
+	_, err = auth.GetCredentials(headers)
+	assert.Error(t, err)
+}
+
+func TestScope(t *testing.T) {
+	granted := []string{auth.ProductsRead, auth.ProductsStock, auth.CategoriesRead}
+	scopes := []string{auth.ProductsStock, auth.ProductsWrite}
+
+	assert.Equal(t, []string{auth.ProductsStock}, auth.Scope(granted, scopes))
+}
+
+func TestMiddlewareAuth_APIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	key, accountId := expectAPIKey(t, mock, "warehouse_clerk", auth.ProductsStock, auth.ProductsWrite)
+
+	cfg := middlewares.ApiCfg{DB: database.New(db)}
+	var authed database.User
+	var canStock, canRead bool
+	handler := cfg.MiddlewareAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		authed = user
+		canStock = auth.HasPermission(r.Context(), auth.ProductsStock)
+		canRead = auth.HasPermission(r.Context(), auth.ProductsRead)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "ApiKey "+key)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, accountId, authed.ID)
+	assert.Equal(t, "service:pos-terminal", authed.Email)
+	assert.True(t, canStock)
+	assert.False(t, canRead, "scopes limit the role's permissions")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareAuth_RevokedAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	key, err := auth.GenerateAPIKey()
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE prefix = \$1`).
+		WithArgs(key.Prefix).
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(
+			uuid.New(), uuid.New(), "pos", key.Prefix, key.SecretHash, time.Now(), nil, nil, time.Now()))
+
+	cfg := middlewares.ApiCfg{DB: database.New(db)}
+	called := false
+	handler := cfg.MiddlewareAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		called = true
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "ApiKey "+key.Key)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.False(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRouter_APIKeyCantUseUserEndpoints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	router := newRouter(t, database.New(db), nil)
+
+	key, _ := expectAPIKey(t, mock, "user", auth.ProductsRead)
+
+	req := httptest.NewRequest("GET", "/api/v1/me", nil)
+	req.Header.Set("Authorization", "ApiKey "+key)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Service accounts")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
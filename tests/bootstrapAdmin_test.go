@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/initializers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrapAdmin_NotConfigured(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	t.Setenv("BOOTSTRAP_ADMIN_EMAIL", "")
+
+	assert.NoError(t, initializers.BootstrapAdmin(context.Background(), database.New(db)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBootstrapAdmin_AdminExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	t.Setenv("BOOTSTRAP_ADMIN_EMAIL", "admin@example.com")
+	t.Setenv("BOOTSTRAP_ADMIN_PASSWORD", "StrongPass123")
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	assert.NoError(t, initializers.BootstrapAdmin(context.Background(), database.New(db)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBootstrapAdmin_CreatesFirstAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	t.Setenv("BOOTSTRAP_ADMIN_EMAIL", "admin@example.com")
+	t.Setenv("BOOTSTRAP_ADMIN_PASSWORD", "StrongPass123")
+	t.Setenv("BOOTSTRAP_ADMIN_USERNAME", "")
+	t.Setenv("BOOTSTRAP_ADMIN_NAME", "")
+	userId := uuid.New()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs(sqlmock.AnyArg(), "admin", "admin@example.com", "Administrator", sqlmock.AnyArg(),
+			"admin", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "username", "email", "name", "role", "profile_picture_url", "created_at", "updated_at",
+		}).AddRow(userId, "admin", "admin@example.com", "Administrator", "admin", nil, time.Now(), time.Now()))
+	mock.ExpectExec(`UPDATE users SET email_verified_at = \$2`).
+		WithArgs(userId, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO organization_members`).
+		WithArgs(uuid.MustParse("00000000-0000-0000-0000-000000000001"), userId, "admin", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "user_id", "role", "created_at"}).
+			AddRow(uuid.MustParse("00000000-0000-0000-0000-000000000001"), userId, "admin", time.Now()))
+
+	assert.NoError(t, initializers.BootstrapAdmin(context.Background(), database.New(db)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBootstrapAdmin_WeakPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	t.Setenv("BOOTSTRAP_ADMIN_EMAIL", "admin@example.com")
+	t.Setenv("BOOTSTRAP_ADMIN_PASSWORD", "weak")
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	assert.Error(t, initializers.BootstrapAdmin(context.Background(), database.New(db)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,284 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/cli"
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/database/sqlite"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// runCLI runs the inventory command with stdin and returns its exit code
+// and output
+func runCLI(stdin string, args ...string) (int, string, string) {
+	// Run makes the configuration it loads current, which would hide the
+	// environment variables later tests set
+	defer config.Set(nil)
+
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(context.Background(), args, cli.IO{
+		Stdin: strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	return code, stdout.String(), stderr.String()
+}
+
+// setupCLIDatabase points DB_URL at a new, migrated SQLite database and
+// returns its path
+func setupCLIDatabase(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "inventory.db")
+	t.Setenv("DB_URL", "sqlite://"+path)
+	code, _, stderr := runCLI("", "migrate", "up")
+	assert.Equal(t, 0, code, stderr)
+	return path
+}
+
+func openCLIDatabase(t *testing.T, path string) *database.Queries {
+	conn, err := sqlite.Open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return database.New(conn)
+}
+
+func TestCLI_Usage(t *testing.T) {
+	code, stdout, _ := runCLI("", "help")
+	assert.Equal(t, 0, code)
+	for _, command := range []string{"serve", "migrate", "create-admin", "reset-password", "import", "export", "seed", "check-config", "config"} {
+		assert.Contains(t, stdout, command)
+	}
+
+	code, _, stderr := runCLI("", "frobnicate")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `Unknown command "frobnicate"`)
+
+	code, _, stderr = runCLI("", "migrate", "sideways")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: inventory migrate up|down|status|redo")
+}
+
+func TestCLI_CreateAdminAndResetPassword(t *testing.T) {
+	path := setupCLIDatabase(t)
+	DB := openCLIDatabase(t, path)
+
+	code, _, stderr := runCLI("weak\n", "create-admin", "--email", "ops@example.com")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "password is not strong enough")
+
+	code, stdout, stderr := runCLI("Sup3rSecret\n", "create-admin", "--email", "ops@example.com", "--username", "ops")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Created admin ops@example.com")
+
+	user, err := DB.GetUserByEmail(context.Background(), "ops@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", user.Role)
+	assert.Equal(t, "ops", user.Username)
+	assert.True(t, user.EmailVerifiedAt.Valid)
+
+	code, _, stderr = runCLI("An0therSecret\n", "reset-password", "--email", "nobody@example.com")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "there's no user with email nobody@example.com")
+
+	code, _, stderr = runCLI("An0therSecret\n", "reset-password", "--email", "ops@example.com")
+	assert.Equal(t, 0, code, stderr)
+	user, err = DB.GetUserByEmail(context.Background(), "ops@example.com")
+	assert.NoError(t, err)
+	assert.True(t, helpers.CheckPasswordHash(user.Password, "An0therSecret"))
+}
+
+func TestCLI_SeedImportExport(t *testing.T) {
+	path := setupCLIDatabase(t)
+	DB := openCLIDatabase(t, path)
+	ctx := context.Background()
+	org, err := DB.GetOrganizationBySlug(ctx, "default")
+	assert.NoError(t, err)
+
+	code, _, stderr := runCLI("", "seed")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "use --demo")
+
+	code, stdout, stderr := runCLI("", "seed", "--demo")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Added 3 categories, 2 suppliers and 7 products to default")
+
+	code, _, stderr = runCLI("", "seed", "--demo")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "already has products")
+
+	code, exported, stderr := runCLI("", "export", "products")
+	assert.Equal(t, 0, code, stderr)
+	lines := strings.Split(strings.TrimSpace(exported), "\n")
+	assert.Len(t, lines, 8)
+	assert.True(t, strings.HasPrefix(lines[0], "id,name,description,price,stock_level"))
+
+	// Importing an export updates the same products
+	file := filepath.Join(t.TempDir(), "products.csv")
+	assert.NoError(t, os.WriteFile(file, []byte(strings.Replace(exported, ",450000,", ",400000,", 1)), 0o600))
+	code, stdout, stderr = runCLI("", "import", file)
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Imported 7 products, 0 new and 7 updated")
+	product, err := DB.GetProduct(ctx, database.GetProductParams{
+		ID: uuidFromCSV(t, lines[1]),
+		OrgID: org.ID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(400000), product.Price)
+
+	code, stdout, stderr = runCLI("name,price,sku\nToaster,70000,KIT-004\n", "import", "-")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Imported 1 products, 1 new and 0 updated")
+
+	// Nothing is written when a row is invalid
+	code, _, stderr = runCLI("name,price\nFridge,900000\nFreezer,free\n", "import", "-")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "line 3: price must be a whole number greater than zero")
+	products, err := DB.GetProducts(ctx, database.GetProductsParams{OrgID: org.ID})
+	assert.NoError(t, err)
+	assert.Len(t, products, 8)
+
+	code, _, stderr = runCLI("name,price,sku\nToaster,70000,KIT-004\n", "import", "-")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "line 2: the SKU or id is already used")
+
+	code, stdout, stderr = runCLI("", "export", "--format", "csv", "categories")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Kitchen")
+
+	code, _, stderr = runCLI("", "export", "--org", "missing", "products")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `there's no organisation "missing"`)
+}
+
+func uuidFromCSV(t *testing.T, line string) uuid.UUID {
+	id, err := uuid.Parse(strings.Split(line, ",")[0])
+	assert.NoError(t, err)
+	return id
+}
+
+func TestCLI_CheckConfig(t *testing.T) {
+	setupCLIDatabase(t)
+	t.Setenv("PORT", "8080")
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("MAILER", "")
+	t.Setenv("OIDC_ISSUER_URL", "")
+	t.Setenv("JWT_KEYS_FILE", "")
+	t.Setenv("BOOTSTRAP_ADMIN_EMAIL", "")
+
+	code, stdout, stderr := runCLI("", "check-config")
+	assert.Equal(t, 0, code, stdout+stderr)
+	assert.Contains(t, stdout, "OK    database")
+
+	t.Setenv("PORT", "0")
+	t.Setenv("PURGE_INTERVAL", "-1h")
+	t.Setenv("LOGIN_LOCKOUT_BASE", "2h")
+	t.Setenv("LOGIN_IP_MAX_FAILURES", "-3")
+	code, stdout, stderr = runCLI("", "check-config")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "FAIL  server: PORT must be between 1 and 65535, got 0")
+	assert.Contains(t, stdout, "FAIL  jobs: PURGE_INTERVAL must be positive, got -1h0m0s")
+	assert.Contains(t, stdout, "FAIL  login lockout: LOGIN_IP_MAX_FAILURES must be at least 1, got -3\n"+
+		"      LOGIN_LOCKOUT_BASE (2h0m0s) can't be more than LOGIN_LOCKOUT_MAX (1h0m0s)")
+	assert.Contains(t, stderr, "3 of the checks failed")
+
+	t.Setenv("PURGE_INTERVAL", "often")
+	code, _, stderr = runCLI("", "check-config")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `Invalid configuration: PURGE_INTERVAL must be a duration such as 15m or 720h, got "often"`)
+}
+
+func TestCLI_Config(t *testing.T) {
+	t.Setenv("DB_URL", "sqlite://"+filepath.Join(t.TempDir(), "inventory.db"))
+	t.Setenv("SECRET_KEY", "hunter2")
+	t.Setenv("BCRYPT_COST", "10")
+
+	code, stdout, stderr := runCLI("", "config")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "SECRET_KEY=[redacted]")
+	assert.Contains(t, stdout, "BCRYPT_COST=10")
+	assert.NotContains(t, stdout, "hunter2")
+
+	t.Setenv("SECRET_KEY", "")
+	code, stdout, stderr = runCLI("", "config")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "# auth")
+	assert.Contains(t, stderr, "the configuration isn't valid:\n  either JWT_KEYS_FILE or SECRET_KEY has to be set")
+}
+
+func TestCLI_ServeShutsDown(t *testing.T) {
+	setupCLIDatabase(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	t.Setenv("PORT", strconv.Itoa(port))
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("BOOTSTRAP_ADMIN_EMAIL", "")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "2s")
+	defer config.Set(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stderr bytes.Buffer
+	exited := make(chan int, 1)
+	go func() {
+		exited <- cli.Run(ctx, []string{"serve"}, cli.IO{Stdin: strings.NewReader(""), Stdout: io.Discard, Stderr: &stderr})
+	}()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/v1/", port)
+	assert.Eventually(t, func() bool {
+		response, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	// Readiness fails while requests are still served during the drain
+	readyz := fmt.Sprintf("http://127.0.0.1:%d/readyz", port)
+	assert.Eventually(t, func() bool {
+		response, err := http.Get(readyz)
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 20*time.Millisecond)
+
+	select {
+	case code := <-exited:
+		assert.Equal(t, 0, code, stderr.String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve didn't stop")
+	}
+	_, err = http.Get(url)
+	assert.Error(t, err, "the server stops listening")
+}
+
+func TestCLI_ServeRejectsBadKeysFile(t *testing.T) {
+	setupCLIDatabase(t)
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("JWT_KEYS_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	defer config.Set(nil)
+
+	var stderr bytes.Buffer
+	code := cli.Run(context.Background(), []string{"serve"}, cli.IO{Stdin: strings.NewReader(""), Stdout: io.Discard, Stderr: &stderr})
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "invalid JWT signing keys")
+}
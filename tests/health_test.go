@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ringtho/inventory/controllers"
+	"github.com/ringtho/inventory/database/sqlite"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func probe(t *testing.T, router http.Handler, path string, body any) int {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), body))
+	return rr.Code
+}
+
+func TestProbes(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	conn, err := sqlite.Open(filepath.Join(t.TempDir(), "inventory.db"))
+	assert.NoError(t, err)
+	health := &controllers.HealthCfg{Conn: conn}
+	router := newRouter(t, database.New(conn), health)
+
+	var status map[string]string
+	assert.Equal(t, 200, probe(t, router, "/healthz", &status))
+	assert.Equal(t, "ok", status["status"])
+
+	var ready readiness
+	assert.Equal(t, 503, probe(t, router, "/readyz", &ready))
+	assert.Equal(t, "not ready", ready.Status)
+	assert.Equal(t, map[string]string{"shutdown": "ok", "database": "ok", "migrations": "pending"}, ready.Checks)
+
+	assert.NoError(t, sqlite.Migrate(context.Background(), conn))
+	ready = readiness{}
+	assert.Equal(t, 200, probe(t, router, "/readyz", &ready))
+	assert.Equal(t, "ready", ready.Status)
+
+	health.ShuttingDown()
+	ready = readiness{}
+	assert.Equal(t, 503, probe(t, router, "/readyz", &ready))
+	assert.Equal(t, "shutting down", ready.Checks["shutdown"])
+
+	conn.Close()
+	ready = readiness{}
+	assert.Equal(t, 503, probe(t, router, "/readyz", &ready))
+	assert.Equal(t, "unreachable", ready.Checks["database"])
+
+	// The process is still alive, it's up to readiness to take it out
+	assert.Equal(t, 200, probe(t, router, "/healthz", &status))
+
+	var build controllers.BuildInfo
+	assert.Equal(t, 200, probe(t, router, "/version", &build))
+	assert.Equal(t, runtime.Version(), build.GoVersion)
+}
@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ringtho/inventory/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockDuration_Backoff(t *testing.T) {
+	policy := helpers.LockoutPolicy{MaxFailures: 3, Base: time.Minute, Max: 10 * time.Minute}
+
+	assert.Equal(t, time.Duration(0), policy.LockDuration(2))
+	assert.Equal(t, time.Minute, policy.LockDuration(3))
+	assert.Equal(t, 2*time.Minute, policy.LockDuration(4))
+	assert.Equal(t, 8*time.Minute, policy.LockDuration(6))
+	assert.Equal(t, 10*time.Minute, policy.LockDuration(7), "locks are capped at Max")
+	assert.Equal(t, 10*time.Minute, policy.LockDuration(100))
+}
+
+func TestAccountLockoutPolicy_FromEnv(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "4")
+	t.Setenv("LOGIN_LOCKOUT_BASE", "30s")
+	t.Setenv("LOGIN_LOCKOUT_MAX", "5m")
+	t.Setenv("LOGIN_FAILURE_WINDOW", "1h")
+
+	policy := helpers.AccountLockoutPolicy()
+
+	assert.Equal(t, 4, policy.MaxFailures)
+	assert.Equal(t, 30*time.Second, policy.Base)
+	assert.Equal(t, 5*time.Minute, policy.Max)
+	assert.Equal(t, time.Hour, policy.Window)
+}
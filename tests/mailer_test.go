@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailerFromConfig(t *testing.T) {
+	cfg := config.Default().Mail
+	cfg.Mailer = "smtp"
+	_, err := mailer.FromConfig(cfg)
+	assert.ErrorContains(t, err, "SMTP_HOST is required with MAILER=smtp")
+
+	cfg.SMTPHost = "smtp.example.com"
+	cfg.SMTPPort = 2525
+	cfg.From = "inventory@example.com"
+	m, err := mailer.FromConfig(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, mailer.SMTPMailer{
+		Host: "smtp.example.com",
+		Port: 2525,
+		From: "inventory@example.com",
+	}, m)
+
+	m, err = mailer.FromConfig(config.Default().Mail)
+	assert.NoError(t, err)
+	assert.Equal(t, mailer.LogMailer{}, m)
+
+	cfg.Mailer = "pigeon"
+	_, err = mailer.FromConfig(cfg)
+	assert.Error(t, err)
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	m := mailer.FileMailer{Path: path}
+
+	err := m.Send(context.Background(), mailer.Message{
+		To: "johndoe@gmail.com",
+		Subject: "Reset your password",
+		Body: "token",
+	})
+	assert.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(contents), "To: johndoe@gmail.com")
+	assert.Contains(t, string(contents), "Subject: Reset your password")
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/controllers"
+	"github.com/ringtho/inventory/database/sqlite"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("METRICS_TOKEN", "scraper-token")
+	ctx := context.Background()
+	conn, err := sqlite.Open(filepath.Join(t.TempDir(), "inventory.db"))
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.NoError(t, sqlite.Migrate(ctx, conn))
+	DB := database.New(conn)
+
+	now := time.Now().UTC()
+	for _, stock := range []sql.NullInt32{{Int32: 3, Valid: true}, {Int32: 0, Valid: true}, {}} {
+		_, err := DB.CreateProduct(ctx, database.CreateProductParams{
+			ID: uuid.New(),
+			OrgID: memory.DefaultOrgID,
+			Name: "Widget " + uuid.NewString(),
+			Price: 100,
+			StockLevel: stock,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		assert.NoError(t, err)
+	}
+
+	router := newRouter(t, DB, &controllers.HealthCfg{Conn: conn})
+	productPath := "/api/v1/products/" + uuid.NewString()
+	for _, path := range []string{productPath, "/no-such-page"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	login := httptest.NewRequest("POST", "/api/v1/login",
+		strings.NewReader(`{"email": "nobody@example.com", "password": "wrong"}`))
+	router.ServeHTTP(httptest.NewRecorder(), login)
+
+	rr := httptest.NewRecorder()
+	scrape := httptest.NewRequest("GET", "/metrics", nil)
+	scrape.Header.Set("Authorization", "Bearer scraper-token")
+	router.ServeHTTP(rr, scrape)
+	assert.Equal(t, 200, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	assert.NoError(t, err)
+	metrics := string(body)
+
+	for _, line := range []string{
+		`inventory_http_requests_total{method="GET",route="/api/v1/products/{productId}",status=`,
+		`inventory_http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`inventory_http_request_duration_seconds_bucket{method="POST",route="/api/v1/login",status="400",le="+Inf"}`,
+		`inventory_logins_total{method="password",result="failure"}`,
+		`go_sql_open_connections{db_name="inventory"}`,
+		"inventory_products{organization=\"default\"} 3\n",
+		"inventory_products_out_of_stock{organization=\"default\"} 2\n",
+		"go_goroutines ",
+	} {
+		assert.Contains(t, metrics, line)
+	}
+	assert.NotContains(t, metrics, productPath, "paths with IDs aren't used as labels")
+}
+
+func TestMetrics_Token(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	DB := memory.New()
+
+	t.Setenv("METRICS_TOKEN", "scraper-token")
+	router := newRouter(t, DB, nil)
+	for _, authorization := range []string{"", "Bearer wrong", "scraper-token"} {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		req.Header.Set("Authorization", authorization)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, 401, rr.Code, authorization)
+		assert.Equal(t, `Bearer realm="metrics"`, rr.Header().Get("WWW-Authenticate"))
+	}
+
+	// Without a token anyone can scrape, so organisations aren't named
+	t.Setenv("METRICS_TOKEN", "")
+	rr := httptest.NewRecorder()
+	newRouter(t, DB, nil).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "inventory_http_requests_total")
+	assert.NotContains(t, rr.Body.String(), "inventory_products")
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareAuth_RevokedSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	defer os.Unsetenv("SECRET_KEY")
+
+	userId, sessionId := uuid.New(), uuid.New()
+	token, err := helpers.GenerateJWT(userId, "admin", sessionId, uuid.NullUUID{})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM sessions WHERE id = \$1`).
+		WithArgs(sessionId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at", "revoked_at", "org_id"}).
+			AddRow(sessionId, userId, time.Now(), time.Now(), nil))
+
+	cfg := middlewares.ApiCfg{DB: database.New(db)}
+	called := false
+	handler := cfg.MiddlewareAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		called = true
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.False(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMiddlewareAuth_ActiveSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	defer os.Unsetenv("SECRET_KEY")
+
+	userId, sessionId := uuid.New(), uuid.New()
+	token, err := helpers.GenerateJWT(userId, "admin", sessionId, uuid.NullUUID{})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM sessions WHERE id = \$1`).
+		WithArgs(sessionId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at", "revoked_at", "org_id"}).
+			AddRow(sessionId, userId, time.Now(), nil, nil))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "created_at", "updated_at", "username", "email", "password", "role", "profile_picture_url", "name", "email_verified_at",
+		}).AddRow(userId, time.Now(), time.Now(), "admin", "admin@example.com", "hash", "admin", nil, "Admin", time.Now()))
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow("users:write"))
+
+	cfg := middlewares.ApiCfg{DB: database.New(db)}
+	var authed database.User
+	var canWrite bool
+	handler := cfg.MiddlewareAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		authed = user
+		canWrite = auth.HasPermission(r.Context(), auth.UsersWrite)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, userId, authed.ID)
+	assert.True(t, canWrite)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate_SQLite(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("DB_URL", "sqlite://"+t.TempDir()+"/inventory.db")
+	conn := db.ConnectToDatabase(config.Current().Database)
+	defer conn.Close()
+
+	var out bytes.Buffer
+	assert.NoError(t, db.Migrate(ctx, conn, "status", &out))
+	assert.Contains(t, out.String(), "Pending              001_init.sql")
+
+	out.Reset()
+	assert.NoError(t, db.Migrate(ctx, conn, "up", &out))
+	assert.Contains(t, out.String(), "OK    up 001_init.sql")
+
+	out.Reset()
+	assert.NoError(t, db.Migrate(ctx, conn, "up", &out))
+	assert.Equal(t, "No migrations to apply\n", out.String())
+
+	files, err := filepath.Glob("../database/sqlite/schema/*.sql")
+	assert.NoError(t, err)
+	latest := filepath.Base(files[len(files)-1])
+
+	out.Reset()
+	assert.NoError(t, db.Migrate(ctx, conn, "redo", &out))
+	assert.Contains(t, out.String(), "OK    down "+latest)
+	assert.Contains(t, out.String(), "OK    up "+latest)
+
+	out.Reset()
+	for range files {
+		assert.NoError(t, db.Migrate(ctx, conn, "down", &out))
+	}
+	assert.Contains(t, out.String(), "OK    down 001_init.sql")
+	assert.NoError(t, db.Migrate(ctx, conn, "down", &out))
+	assert.Contains(t, out.String(), "No migrations to roll back")
+
+	assert.Error(t, db.Migrate(ctx, conn, "sideways", &out))
+}
+
+// The Postgres migrations are embedded in the binary, in order
+func TestMigrate_PostgresSources(t *testing.T) {
+	t.Setenv("DB_URL", "postgres://localhost/inventory")
+	conn, err := sql.Open("postgres", "postgres://localhost/inventory")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn)
+	assert.NoError(t, err)
+	files, err := filepath.Glob("../database/schema/*.sql")
+	assert.NoError(t, err)
+	sources := migrator.ListSources()
+	assert.Len(t, sources, len(files))
+	for i, source := range sources {
+		assert.Equal(t, int64(i+1), source.Version)
+		assert.Equal(t, filepath.Base(files[i]), source.Path)
+	}
+}
+
+func TestWaitForDatabase(t *testing.T) {
+	ctx := context.Background()
+	conn := db.ConnectToDatabase(config.Database{URL: "sqlite://" + filepath.Join(t.TempDir(), "inventory.db")})
+	defer conn.Close()
+	assert.NoError(t, db.WaitForDatabase(ctx, conn, time.Second))
+
+	// Nothing listens on port 1
+	conn = db.ConnectToDatabase(config.Database{URL: "postgres://inventory@127.0.0.1:1/inventory?sslmode=disable"})
+	defer conn.Close()
+	started := time.Now()
+	err := db.WaitForDatabase(ctx, conn, time.Second)
+	assert.ErrorContains(t, err, "the database didn't answer within 1s")
+	assert.Less(t, time.Since(started), 3*time.Second)
+}
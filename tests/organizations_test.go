@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgPermissions(t *testing.T) {
+	own := []string{auth.UsersRead, auth.ProductsWrite}
+	org := []string{auth.ProductsRead, auth.RolesManage}
+
+	assert.Equal(t, []string{auth.UsersRead, auth.ProductsRead}, auth.OrgPermissions(own, org),
+		"inventory permissions come from the organisation role, the rest from the user's own")
+	assert.Equal(t, []string{auth.UsersRead}, auth.OrgPermissions(own, nil))
+}
+
+func TestRouter_NonMemberForbidden(t *testing.T) {
+	t.Setenv("SECRET_KEY", "mysecretkey")
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	router := newRouter(t, database.New(db), nil)
+
+	// The user was removed from the organisation after the token was issued
+	userId, sessionId, orgId := uuid.New(), uuid.New(), uuid.New()
+	token, err := helpers.GenerateJWT(userId, "user", sessionId, uuid.NullUUID{UUID: orgId, Valid: true})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM sessions WHERE id = \$1`).
+		WithArgs(sessionId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at", "revoked_at", "org_id"}).
+			AddRow(sessionId, userId, time.Now(), nil, orgId))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "created_at", "updated_at", "username", "email", "password", "role", "profile_picture_url", "name", "email_verified_at",
+		}).AddRow(userId, time.Now(), time.Now(), "user", "user@example.com", "hash", "user", nil, "user", time.Now()))
+	expectRolePermissions(mock, "user")
+	mock.ExpectQuery(`SELECT (.+) FROM organization_members WHERE org_id = \$1 AND user_id = \$2`).
+		WithArgs(orgId, userId).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "user_id", "role", "created_at"}))
+
+	req := httptest.NewRequest("GET", "/api/v1/suppliers", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "not a member")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRouter_AnonymousProductsNeedOrg(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	router := newRouter(t, database.New(db), nil)
+
+	// Without ?org= anonymous reads see the default organisation
+	defaultOrgId := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM organizations WHERE slug = \$1`).
+		WithArgs("default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "created_at", "updated_at"}).
+			AddRow(defaultOrgId, "Default", "default", time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM products`).
+		WithArgs(defaultOrgId, false).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
+		}))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/products", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	mock.ExpectQuery(`SELECT (.+) FROM organizations WHERE slug = \$1`).
+		WithArgs("closed-shop").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "created_at", "updated_at"}))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/products?org=closed-shop", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	orgId := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM organizations WHERE slug = \$1`).
+		WithArgs("corner-shop").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "created_at", "updated_at"}).
+			AddRow(orgId, "Corner Shop", "corner-shop", time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM products`).
+		WithArgs(orgId, false).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
+		}).AddRow(uuid.New(), "Microwave", nil, 50000, 3, nil, nil, nil, time.Now(), time.Now(), nil, orgId))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/products?org=corner-shop", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Microwave")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
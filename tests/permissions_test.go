@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+var rolePermissions = map[string][]string{
+	"user": {auth.ProductsRead, auth.CategoriesRead},
+	"warehouse_clerk": {auth.ProductsRead, auth.ProductsStock, auth.CategoriesRead, auth.SuppliersRead},
+}
+
+// testOrgID is the organisation authedRequest's users work in
+var testOrgID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// authedRequest returns a request with an access token for a user with role,
+// in testOrgID with the same role, and sets up the queries MiddlewareAuth
+// makes for it
+func authedRequest(t *testing.T, mock sqlmock.Sqlmock, role, method, path, body string) *http.Request {
+	userId, sessionId := uuid.New(), uuid.New()
+	token, err := helpers.GenerateJWT(userId, role, sessionId, uuid.NullUUID{UUID: testOrgID, Valid: true})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM sessions WHERE id = \$1`).
+		WithArgs(sessionId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at", "revoked_at", "org_id"}).
+			AddRow(sessionId, userId, time.Now(), nil, nil))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "created_at", "updated_at", "username", "email", "password", "role", "profile_picture_url", "name", "email_verified_at",
+		}).AddRow(userId, time.Now(), time.Now(), role, role+"@example.com", "hash", role, nil, role, time.Now()))
+	expectRolePermissions(mock, role)
+	mock.ExpectQuery(`SELECT (.+) FROM organization_members WHERE org_id = \$1 AND user_id = \$2`).
+		WithArgs(testOrgID, userId).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "user_id", "role", "created_at"}).
+			AddRow(testOrgID, userId, role, time.Now()))
+	expectRolePermissions(mock, role)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func expectRolePermissions(mock sqlmock.Sqlmock, role string) {
+	permissions := sqlmock.NewRows([]string{"permission"})
+	for _, permission := range rolePermissions[role] {
+		permissions.AddRow(permission)
+	}
+	mock.ExpectQuery(`SELECT permission FROM role_permissions WHERE role = \$1`).
+		WithArgs(role).
+		WillReturnRows(permissions)
+}
+
+func TestRouter_UserRoleForbidden(t *testing.T) {
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	defer os.Unsetenv("SECRET_KEY")
+
+	id := uuid.New().String()
+	routes := []struct{ method, path string }{
+		{"DELETE", "/api/v1/users/" + id},
+		{"GET", "/api/v1/users"},
+		{"GET", "/api/v1/invitations"},
+		{"GET", "/api/v1/service-accounts"},
+		{"POST", "/api/v1/service-accounts/" + id + "/keys"},
+		{"POST", "/api/v1/invitations"},
+		{"PUT", "/api/v1/users/" + id},
+		{"PUT", "/api/v1/users/" + id + "/role"},
+		{"POST", "/api/v1/users/" + id + "/unlock"},
+		{"GET", "/api/v1/roles"},
+		{"POST", "/api/v1/roles"},
+		{"GET", "/api/v1/organizations"},
+		{"PUT", "/api/v1/organizations/" + id + "/members/" + id},
+		{"GET", "/api/v1/audit"},
+		{"PUT", "/api/v1/settings/security"},
+		{"POST", "/api/v1/categories"},
+		{"PUT", "/api/v1/categories/" + id},
+		{"PATCH", "/api/v1/categories/" + id},
+		{"DELETE", "/api/v1/categories/" + id},
+		{"POST", "/api/v1/suppliers"},
+		{"GET", "/api/v1/suppliers"},
+		{"GET", "/api/v1/suppliers/" + id},
+		{"DELETE", "/api/v1/suppliers/" + id},
+		{"PUT", "/api/v1/suppliers/" + id},
+		{"PATCH", "/api/v1/suppliers/" + id},
+		{"POST", "/api/v1/products"},
+		{"DELETE", "/api/v1/products/" + id},
+		{"PUT", "/api/v1/products/" + id},
+		{"PATCH", "/api/v1/products/" + id},
+		{"POST", "/api/v1/products/" + id + "/stock"},
+	}
+
+	for _, route := range routes {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		router := newRouter(t, database.New(db), nil)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, authedRequest(t, mock, "user", route.method, route.path, "{}"))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code, "%v %v", route.method, route.path)
+		assert.Contains(t, rr.Body.String(), "Unauthorized")
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	}
+}
+
+func TestRouter_WarehouseClerkCanAdjustStockButNotPrices(t *testing.T) {
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	defer os.Unsetenv("SECRET_KEY")
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	router := newRouter(t, database.New(db), nil)
+	productId := uuid.New()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, authedRequest(t, mock, "warehouse_clerk", "PATCH",
+		"/api/v1/products/"+productId.String(), `{"price": 1}`))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	req := authedRequest(t, mock, "warehouse_clerk", "POST",
+		"/api/v1/products/"+productId.String()+"/stock", `{"adjustment": 5}`)
+	productColumns := []string{
+		"id", "name", "description", "price", "stock_level", "category_id", "supplier_id", "sku", "created_at", "updated_at", "deleted_at", "org_id",
+	}
+	mock.ExpectQuery(`SELECT (.+) FROM products WHERE id = \$1`).
+		WithArgs(productId, testOrgID).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 3, nil, nil, nil, time.Now(), time.Now(), nil, uuid.Nil))
+	mock.ExpectQuery(`UPDATE products`).
+		WithArgs(5, sqlmock.AnyArg(), productId, testOrgID).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(productId, "Microwave", nil, 50000, 8, nil, nil, nil, time.Now(), time.Now(), nil, uuid.Nil))
+	mock.ExpectExec(`INSERT INTO audit_logs`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"stock_level":8`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRequirePermission(t *testing.T) {
+	os.Setenv("SECRET_KEY", "mysecretkey")
+	defer os.Unsetenv("SECRET_KEY")
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cfg := middlewares.ApiCfg{DB: database.New(db)}
+	called := false
+	handler := cfg.RequirePermission(auth.ProductsStock, func(w http.ResponseWriter, r *http.Request, user database.User) {
+		called = true
+	})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, authedRequest(t, mock, "warehouse_clerk", "POST", "/", ""))
+	assert.True(t, called)
+
+	called = false
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, authedRequest(t, mock, "user", "POST", "/", ""))
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
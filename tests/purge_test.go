@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/jobs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM products WHERE deleted_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM categories WHERE deleted_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM suppliers WHERE deleted_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	count, err := jobs.PurgeDeleted(context.Background(), database.New(db), 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeDeleted_StopsOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM products`).
+		WillReturnError(fmt.Errorf("database error"))
+
+	_, err = jobs.PurgeDeleted(context.Background(), database.New(db), time.Hour)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -6,17 +6,30 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/controllers"
+	"github.com/ringtho/inventory/initializers"
 	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
 	"github.com/ringtho/inventory/routers"
 	"github.com/stretchr/testify/assert"
 )
 
+// newRouter builds the router the way serve does, with the services made
+// from the current configuration
+func newRouter(t *testing.T, DB store.Store, health *controllers.HealthCfg) http.Handler {
+	t.Helper()
+	services, err := initializers.NewServices(config.Current())
+	assert.NoError(t, err)
+	return routers.Router(DB, health, services)
+}
+
 func TestRouter(t *testing.T) {
 	db, _, err := sqlmock.New()
 	assert.NoError(t, err)
 	queries := database.New(db)
 
-	router := routers.Router(queries)
+	router := newRouter(t, queries, nil)
 
 	// Test a specific route (e.g., /suppliers)
 	req := httptest.NewRequest("GET", "/api/v1/suppliers", nil)
@@ -1,9 +1,11 @@
 package tests
 
 import (
-	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/ringtho/inventory/config"
 	"github.com/ringtho/inventory/initializers"
 	"github.com/stretchr/testify/assert"
 )
@@ -11,14 +13,19 @@ import (
 
 func TestSetupServer(t *testing.T) {
 	// Set up environment variables
-	os.Setenv("PORT", "8080")
-	os.Setenv("DB_URL", "url")
-	defer os.Unsetenv("PORT")
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_URL", "sqlite://"+filepath.Join(t.TempDir(), "inventory.db"))
+	t.Setenv("HTTP_WRITE_TIMEOUT", "45s")
 
 	// Call the setupServer function
-	server, _, _ := initializers.SetupServer()
+	services, err := initializers.NewServices(config.Current())
+	assert.NoError(t, err)
+	server, _, _, conn := initializers.SetupServer(services)
+	defer conn.Close()
 
 	// Validate the server address
 	assert.Equal(t, ":8080", server.Addr)
 	assert.NotNil(t, server.Handler)
-}
\ No newline at end of file
+	assert.Equal(t, 10*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 45*time.Second, server.WriteTimeout)
+}
@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return key
+}
+
+func ed25519Key(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+// useSigningKeys signs tokens with keys for the rest of the test
+func useSigningKeys(t *testing.T, keys ...helpers.SigningKey) *helpers.KeyRing {
+	ring, err := helpers.NewKeyRing(keys)
+	assert.NoError(t, err)
+	helpers.SetSigningKeys(ring)
+	t.Cleanup(func() { helpers.SetSigningKeys(nil) })
+	return ring
+}
+
+func tokenKeyID(t *testing.T, token string) string {
+	parsed, err := jose.ParseSigned(token, []jose.SignatureAlgorithm{jose.RS256, jose.EdDSA, jose.HS256})
+	assert.NoError(t, err)
+	return parsed.Signatures[0].Header.KeyID
+}
+
+func TestSigningKeys_RS256(t *testing.T) {
+	useSigningKeys(t, helpers.SigningKey{ID: "rsa-1", PrivateKey: rsaKey(t)})
+
+	userId, sessionId := uuid.New(), uuid.New()
+	token, err := helpers.GenerateJWT(userId, "admin", sessionId, uuid.NullUUID{})
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-1", tokenKeyID(t, token))
+
+	claims, err := helpers.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userId, claims.ID)
+	assert.Equal(t, "admin", claims.Role)
+	assert.Equal(t, sessionId, claims.SessionID)
+}
+
+func TestSigningKeys_EdDSA(t *testing.T) {
+	useSigningKeys(t, helpers.SigningKey{ID: "ed-1", PrivateKey: ed25519Key(t)})
+
+	token, err := helpers.GenerateMFAToken(uuid.New(), helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+	assert.Equal(t, "ed-1", tokenKeyID(t, token))
+
+	_, err = helpers.VerifyMFAToken(token, helpers.MFAPurposeVerify)
+	assert.NoError(t, err)
+	_, err = helpers.VerifyToken(token)
+	assert.Error(t, err, "a challenge token must not work as an access token")
+}
+
+func TestSigningKeys_Rotation(t *testing.T) {
+	now := time.Now()
+	oldKey := helpers.SigningKey{
+		ID: "old",
+		PrivateKey: rsaKey(t),
+		ActiveFrom: now.Add(-48 * time.Hour),
+		RetireAt: now.Add(time.Hour),
+	}
+	currentKey := helpers.SigningKey{ID: "current", PrivateKey: ed25519Key(t), ActiveFrom: now.Add(-time.Hour)}
+	nextKey := helpers.SigningKey{ID: "next", PrivateKey: rsaKey(t), ActiveFrom: now.Add(24 * time.Hour)}
+
+	useSigningKeys(t, oldKey, nextKey, currentKey)
+	token, err := helpers.GenerateJWT(uuid.New(), "user", uuid.New(), uuid.NullUUID{})
+	assert.NoError(t, err)
+	assert.Equal(t, "current", tokenKeyID(t, token), "the newest active key signs")
+
+	// A token signed before the rotation still verifies while the old key
+	// overlaps with the new one
+	useSigningKeys(t, oldKey)
+	oldToken, err := helpers.GenerateJWT(uuid.New(), "user", uuid.New(), uuid.NullUUID{})
+	assert.NoError(t, err)
+
+	ring := useSigningKeys(t, oldKey, nextKey, currentKey)
+	_, err = helpers.VerifyToken(oldToken)
+	assert.NoError(t, err)
+
+	kids := []string{}
+	for _, key := range ring.JWKS(now).Keys {
+		kids = append(kids, key.KeyID)
+	}
+	assert.Equal(t, []string{"old", "current", "next"}, kids, "keys are published before they sign")
+
+	kids = []string{}
+	for _, key := range ring.JWKS(now.Add(2 * time.Hour)).Keys {
+		kids = append(kids, key.KeyID)
+	}
+	assert.Equal(t, []string{"current", "next"}, kids, "retired keys aren't published")
+
+	oldKey.RetireAt = now.Add(-time.Minute)
+	oldKey.ActiveFrom = now.Add(-time.Hour)
+	useSigningKeys(t, oldKey, currentKey)
+	_, err = helpers.VerifyToken(oldToken)
+	assert.Error(t, err, "tokens signed by retired keys are rejected")
+}
+
+func TestSigningKeys_RejectsSecretKeyTokensAfterSwitch(t *testing.T) {
+	t.Setenv("SECRET_KEY", "mysecretkey")
+	token, err := helpers.GenerateJWT(uuid.New(), "user", uuid.New(), uuid.NullUUID{})
+	assert.NoError(t, err)
+	_, err = helpers.VerifyToken(token)
+	assert.NoError(t, err)
+
+	// Anyone with the old secret could keep minting tokens, so they stop
+	// working as soon as there is a key ring
+	useSigningKeys(t, helpers.SigningKey{ID: "rsa-1", PrivateKey: rsaKey(t)})
+	_, err = helpers.VerifyToken(token)
+	assert.Error(t, err)
+}
+
+func TestSigningKeys_RejectsForgedAlgorithm(t *testing.T) {
+	key := rsaKey(t)
+	useSigningKeys(t, helpers.SigningKey{ID: "rsa-1", PrivateKey: key})
+	token, err := helpers.GenerateJWT(uuid.New(), "user", uuid.New(), uuid.NullUUID{})
+	assert.NoError(t, err)
+
+	// Swap in a header claiming EdDSA with the same kid
+	parts := strings.Split(token, ".")
+	header, err := json.Marshal(map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": "rsa-1"})
+	assert.NoError(t, err)
+	parts[0] = base64.RawURLEncoding.EncodeToString(header)
+	_, err = helpers.VerifyToken(strings.Join(parts, "."))
+	assert.Error(t, err)
+}
+
+func TestNewKeyRing_Invalid(t *testing.T) {
+	_, err := helpers.NewKeyRing(nil)
+	assert.Error(t, err)
+
+	key := ed25519Key(t)
+	_, err = helpers.NewKeyRing([]helpers.SigningKey{{ID: "a", PrivateKey: key}, {ID: "a", PrivateKey: key}})
+	assert.ErrorContains(t, err, "listed twice")
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	_, err = helpers.NewKeyRing([]helpers.SigningKey{{ID: "small", PrivateKey: small}})
+	assert.ErrorContains(t, err, "2048 bits")
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "rsa.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey(t)))
+	der, err := x509.MarshalPKCS8PrivateKey(ed25519Key(t))
+	assert.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "ed.pem"), "PRIVATE KEY", der)
+
+	list := `[
+		{"kid": "rsa", "private_key_file": "rsa.pem", "active_from": "2024-01-01T00:00:00Z", "retire_at": "2999-01-01T00:00:00Z"},
+		{"kid": "ed", "private_key_file": "ed.pem", "active_from": "2024-06-01T00:00:00Z"}
+	]`
+	path := filepath.Join(dir, "keys.json")
+	assert.NoError(t, os.WriteFile(path, []byte(list), 0600))
+
+	t.Setenv("JWT_KEYS_FILE", path)
+	ring, err := helpers.SigningKeysFromConfig(config.Current().Auth)
+	assert.NoError(t, err)
+
+	signer, err := ring.Signer(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "ed", signer.ID)
+	assert.Equal(t, "EdDSA", signer.Algorithm())
+
+	key, ok := ring.Key("rsa", time.Now())
+	assert.True(t, ok)
+	assert.Equal(t, "RS256", key.Algorithm())
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	router := newRouter(t, database.New(db), nil)
+
+	useSigningKeys(t, helpers.SigningKey{ID: "rsa-1", PrivateKey: rsaKey(t)})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	assert.Equal(t, 200, rr.Code)
+
+	var set jose.JSONWebKeySet
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&set))
+	if !assert.Len(t, set.Keys, 1) {
+		return
+	}
+	assert.Equal(t, "rsa-1", set.Keys[0].KeyID)
+	assert.Equal(t, "RS256", set.Keys[0].Algorithm)
+	assert.True(t, set.Keys[0].IsPublic(), "only public keys are published")
+
+	token, err := helpers.GenerateJWT(uuid.New(), "user", uuid.New(), uuid.NullUUID{})
+	assert.NoError(t, err)
+	parsed, err := jose.ParseSigned(token, []jose.SignatureAlgorithm{jose.RS256})
+	assert.NoError(t, err)
+	_, err = parsed.Verify(set.Keys[0])
+	assert.NoError(t, err, "other services can verify tokens with the published key")
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/ringtho/inventory/database/sqlite"
+	"github.com/ringtho/inventory/internal/auth"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func queryNames(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	assert.NoError(t, err)
+	names := []string{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		for _, match := range regexp.MustCompile(`-- name: (\w+)`).FindAllStringSubmatch(string(content), -1) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Every query has to have a SQLite version, or it fails at runtime there
+func TestSQLiteQueriesMatchPostgres(t *testing.T) {
+	postgres := queryNames(t, "../database/queries")
+	assert.NotEmpty(t, postgres)
+	assert.ElementsMatch(t, postgres, queryNames(t, "../database/sqlite/queries"))
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	ctx := context.Background()
+	conn, err := sqlite.Open(":memory:")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.NoError(t, sqlite.Migrate(ctx, conn))
+	// Running it again has nothing left to apply
+	assert.NoError(t, sqlite.Migrate(ctx, conn))
+
+	org, err := database.New(conn).GetOrganizationBySlug(ctx, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "Default", org.Name)
+
+	permissions, err := database.New(conn).GetRolePermissions(ctx, "admin")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, auth.AllPermissions, permissions)
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/ringtho/inventory/config"
+	"github.com/ringtho/inventory/sso"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSOFromConfig_NotConfigured(t *testing.T) {
+	t.Setenv("OIDC_ISSUER_URL", "")
+
+	provider, err := sso.FromConfig(config.Current().OIDC)
+	assert.NoError(t, err)
+	assert.Nil(t, provider)
+}
+
+func TestSSOFromConfig_RoleMapping(t *testing.T) {
+	t.Setenv("OIDC_ISSUER_URL", "https://idp.example.com")
+	t.Setenv("OIDC_CLIENT_ID", "inventory")
+	t.Setenv("OIDC_REDIRECT_URL", "https://inventory.example.com/api/v1/auth/oidc/callback")
+	t.Setenv("OIDC_ROLE_CLAIM", "groups")
+	t.Setenv("OIDC_ROLE_MAPPING", "inventory-admins=admin, warehouse=warehouse_clerk")
+	t.Setenv("OIDC_DEFAULT_ROLE", "")
+
+	provider, err := sso.FromConfig(config.Current().OIDC)
+	assert.NoError(t, err)
+	assert.True(t, provider.SyncsRoles())
+	assert.Equal(t, "warehouse_clerk", provider.Role(sso.Identity{Roles: []string{"staff", "warehouse"}}))
+	assert.Equal(t, "user", provider.Role(sso.Identity{Roles: []string{"staff"}}))
+
+	t.Setenv("OIDC_ROLE_MAPPING", "inventory-admins")
+	_, err = config.Load()
+	assert.ErrorContains(t, err, "OIDC_ROLE_MAPPING must be key=value pairs")
+
+	t.Setenv("OIDC_CLIENT_ID", "")
+	_, err = sso.FromConfig(config.Current().OIDC)
+	assert.ErrorContains(t, err, "OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required")
+}
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/ringtho/inventory/database/sqlite"
+	"github.com/ringtho/inventory/internal/database"
+	"github.com/ringtho/inventory/internal/store"
+	"github.com/ringtho/inventory/internal/store/memory"
+	"github.com/ringtho/inventory/internal/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return memory.New()
+	})
+}
+
+// TestSQLiteStore runs the tests against a new SQLite database each time
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		conn, err := sqlite.Open(t.TempDir() + "/inventory.db")
+		assert.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		assert.NoError(t, sqlite.Migrate(context.Background(), conn))
+		return database.New(conn)
+	})
+}
+
+// TestPostgresStore runs the same tests against the database given in
+// TEST_DATABASE_URL, each in a newly migrated schema of its own
+func TestPostgresStore(t *testing.T) {
+	if os.Getenv("TEST_DATABASE_URL") == "" {
+		t.Skip("TEST_DATABASE_URL isn't set")
+	}
+	storetest.Run(t, storetest.Postgres)
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/ringtho/inventory/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateTOTPKey(t *testing.T) {
+	key, err := helpers.GenerateTOTPKey("johndoe@gmail.com")
+	assert.NoError(t, err)
+	assert.Contains(t, key.URI, "otpauth://totp/Inventory:johndoe@gmail.com")
+	assert.NotEmpty(t, key.QRCode)
+
+	code, err := totp.GenerateCode(key.Secret, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.True(t, helpers.ValidateTOTP(code, key.Secret))
+	assert.False(t, helpers.ValidateTOTP(code+"0", key.Secret))
+	assert.False(t, helpers.ValidateTOTP("", key.Secret))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := helpers.GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, len(codes))
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, code, helpers.NormalizeRecoveryCode(code))
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+	assert.Equal(t, "ABCDE-FGHIJ", helpers.NormalizeRecoveryCode(" abcdefghij "))
+}